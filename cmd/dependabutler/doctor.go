@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// runDoctor checks the tool config, the connectivity to GitHub, the rate limit status and the permissions of the token,
+// for the repo given, and returns if all checks passed.
+func runDoctor(toolConfig config.ToolConfig, org string, repo string) bool {
+	passed := true
+	fail := func(format string, v ...any) {
+		log.Printf("ERROR "+format, v...)
+		passed = false
+	}
+
+	// tool config
+	problems := toolConfig.Validate()
+	for _, problem := range problems {
+		fail("Tool config: %v", problem)
+	}
+	if len(problems) == 0 {
+		log.Printf("INFO  Tool config: OK")
+	}
+
+	// token and connectivity
+	if toolConfig.Credentials.GitHubToken == "" && util.GetEnvParameter("GITHUB_TOKEN", false) == "" {
+		fail("Token: neither credentials.github-token nor the GITHUB_TOKEN environment variable is set.")
+		return false
+	}
+	client := getGitHubClient(toolConfig)
+	tokenInfo, err := githubapi.GetTokenInfo(client)
+	if err != nil {
+		// GitHub App installation tokens can't read the authenticated user
+		log.Printf("WARN  Token: could not get the authenticated user (expected for GitHub App tokens): %v", err)
+	} else {
+		log.Printf("INFO  Token: authenticated as %v, connected to %v", tokenInfo.Login, client.BaseURL)
+	}
+	if tokenInfo.HasScopes {
+		// classic tokens need the repo scope for pushing branches and creating PRs, and admin:org for reading org secrets
+		hasScope := func(scopes ...string) bool {
+			for _, scope := range scopes {
+				if util.Contains(tokenInfo.Scopes, scope) {
+					return true
+				}
+			}
+			return false
+		}
+		if !hasScope("repo", "public_repo") {
+			fail("Token: scope repo missing (has %v), required for pushing branches and creating pull requests.", tokenInfo.Scopes)
+		}
+		if toolConfig.CheckSecretAccess && !hasScope("admin:org", "write:org", "read:org") {
+			fail("Token: scope admin:org missing (has %v), required for check-secret-access.", tokenInfo.Scopes)
+		}
+		if toolConfig.GrantSecretAccess && !hasScope("admin:org") {
+			fail("Token: scope admin:org missing (has %v), required for grant-secret-access.", tokenInfo.Scopes)
+		}
+	}
+
+	// rate limit
+	rate, err := githubapi.GetRateLimit(client)
+	if err != nil {
+		fail("Rate limit: could not be read: %v", err)
+	} else {
+		log.Printf("INFO  Rate limit: %v of %v requests remaining, reset at %v", rate.Remaining, rate.Limit, rate.Reset.Format(time.RFC3339))
+		if toolConfig.RateLimitBuffer > 0 && rate.Remaining < toolConfig.RateLimitBuffer {
+			log.Printf("WARN  Rate limit: less than rate-limit-buffer requests remaining, the run will wait for the reset first.")
+		}
+	}
+
+	// repo permissions
+	if org == "" || repo == "" {
+		log.Printf("INFO  Repo permissions: skipped, use -org and -repo to check the permissions for a repo.")
+		return passed
+	}
+	permissions, err := githubapi.GetRepoPermissions(client, org, repo)
+	if err != nil {
+		fail("Repo permissions: could not read repo %v/%v (metadata permission missing?): %v", org, repo, err)
+		return passed
+	}
+	if !permissions["push"] {
+		fail("Repo permissions: no write access to %v/%v, contents and pull requests must be writable.", org, repo)
+	} else {
+		log.Printf("INFO  Repo permissions: write access to %v/%v", org, repo)
+	}
+	if toolConfig.EnsureSecurityUpdates && !permissions["admin"] {
+		fail("Repo permissions: no admin access to %v/%v, required for ensure-security-updates.", org, repo)
+	}
+	return passed
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/state"
+	"github.com/google/go-github/v50/github"
+)
+
+// followUpPullRequest records the outcome of the pending PR of dependabutler, if any, and the config merged with it.
+func followUpPullRequest(client *github.Client, store *state.Store, org string, repo string) {
+	repoState := store.Get(org, repo)
+	if repoState.PendingPR == 0 {
+		return
+	}
+	defer func() { store.Set(org, repo, repoState) }()
+
+	pr, err := githubapi.GetPullRequest(client, org, repo, repoState.PendingPR)
+	switch {
+	case err != nil:
+		log.Printf("WARN  Could not get PR %v of repo %v: %v", repoState.PendingPR, repo, err)
+	case pr.GetMerged():
+		content, err := githubapi.GetFileContent(client, org, repo, ".github/dependabot.yml", pr.GetMergeCommitSHA())
+		if err != nil {
+			log.Printf("WARN  Could not get config merged with PR %v: %v", pr.GetHTMLURL(), err)
+			break
+		}
+		mergedAt := pr.GetMergedAt().Time
+		repoState.ResolvePullRequest(pr.GetNumber(), state.OutcomeMerged, pr.GetCreatedAt().Time, mergedAt)
+		repoState.PendingPR, repoState.DriftDetectedAt = 0, nil
+		repoState.Fingerprint, repoState.Content, repoState.MergedAt = state.Fingerprint(content), string(content), &mergedAt
+	case pr.GetState() == "closed":
+		repoState.ResolvePullRequest(pr.GetNumber(), state.OutcomeClosed, pr.GetCreatedAt().Time, pr.GetClosedAt().Time)
+		repoState.PendingPR = 0
+	}
+}
+
+// checkConfigDrift returns the manual changes made to the config since the PR of dependabutler has been merged.
+// Nothing is reverted, the drift is only reported.
+func checkConfigDrift(client *github.Client, store *state.Store, org string, repo string) *report.ConfigDrift {
+	repoState := store.Get(org, repo)
+	defer func() { store.Set(org, repo, repoState) }()
+
+	if repoState.Fingerprint == "" {
+		return nil
+	}
+
+	currentConfig, err := githubapi.GetFileContent(client, org, repo, ".github/dependabot.yml", "")
+	if err != nil {
+		log.Printf("WARN  Could not read config of repo %v for checking drift: %v", repo, err)
+		return nil
+	}
+	if state.Fingerprint(currentConfig) == repoState.Fingerprint {
+		repoState.DriftDetectedAt = nil
+		return nil
+	}
+	if repoState.DriftDetectedAt == nil {
+		now := time.Now()
+		repoState.DriftDetectedAt = &now
+	}
+	drift := &report.ConfigDrift{MergedAt: repoState.MergedAt, DetectedAt: repoState.DriftDetectedAt}
+	if commit, err := githubapi.GetLastCommit(client, org, repo, ".github/dependabot.yml"); err != nil {
+		log.Printf("WARN  Could not get last commit of config of repo %v: %v", repo, err)
+	} else if commit != nil {
+		changedAt := commit.GetCommit().GetAuthor().GetDate().Time
+		drift.ChangedAt = &changedAt
+		drift.ChangedBy = commit.GetAuthor().GetLogin()
+		if drift.ChangedBy == "" {
+			drift.ChangedBy = commit.GetCommit().GetAuthor().GetName()
+		}
+		drift.Commit = commit.GetSHA()
+	}
+	recordedConfig, errRecorded := config.ParseDependabotConfig([]byte(repoState.Content))
+	parsedConfig, errCurrent := config.ParseDependabotConfig(currentConfig)
+	if errRecorded == nil && errCurrent == nil {
+		drift.Changes = config.CompareConfigs(recordedConfig, parsedConfig)
+	}
+	log.Printf("INFO  Config of repo %v has been changed manually since the PR of dependabutler was merged.", repo)
+	return drift
+}
+
+// recordPullRequest remembers the PR of dependabutler, so its outcome and the config are recorded once it's merged.
+func recordPullRequest(store *state.Store, org string, repo string, number int) {
+	repoState := store.Get(org, repo)
+	repoState.PendingPR = number
+	repoState.RecordPullRequest(number, time.Now())
+	store.Set(org, repo, repoState)
+}
+
+// adoptionOf returns the outcomes of the PRs of dependabutler for the repos of an org in the state file, by owning team.
+func adoptionOf(store *state.Store, org string, teamOf func(repo string) string) *report.Adoption {
+	repos := make([]report.RepoAdoption, 0)
+	for key, repoState := range store.Repos {
+		repo, found := strings.CutPrefix(key, org+"/")
+		if !found {
+			continue
+		}
+		outcomes := repoState.Outcomes()
+		repos = append(repos, report.RepoAdoption{Repo: repo, Team: teamOf(repo), Outcomes: report.Outcomes{
+			Open: outcomes[state.OutcomeOpen], Merged: outcomes[state.OutcomeMerged], Closed: outcomes[state.OutcomeClosed],
+			Superseded: outcomes[state.OutcomeSuperseded],
+		}})
+	}
+	return report.NewAdoption(repos)
+}
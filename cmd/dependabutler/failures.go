@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/state"
+	"github.com/google/go-github/v50/github"
+)
+
+// trackFailures records the failures of a repo in consecutive runs, and opens an issue once there are as many as the
+// threshold of failure-issues. A successful run clears them.
+func trackFailures(client *github.Client, store *state.Store, toolConfig config.ToolConfig, execute bool, org string, result report.RepoResult) {
+	repoState := store.Get(org, result.Repo)
+	defer func() { store.Set(org, result.Repo, repoState) }()
+
+	if result.Status != report.StatusError {
+		repoState.Failures, repoState.FailureIssue = nil, 0
+		return
+	}
+	repoState.RecordFailure(time.Now(), result.Message)
+	settings := toolConfig.FailureIssues
+	if settings.Repo == "" || repoState.FailureIssue > 0 || len(repoState.Failures) < max(settings.Threshold, 1) {
+		return
+	}
+	title := fmt.Sprintf("dependabutler fails for %v/%v", org, result.Repo)
+	body := failureIssueBody(org, result.Repo, repoState.Failures)
+	if !execute {
+		log.Printf("INFO  log-only mode, would open issue in %v for %v failed runs of %v:\n----------\n%v\n----------", settings.Repo, len(repoState.Failures), result.Repo, body)
+		return
+	}
+	issue, err := githubapi.CreateIssue(client, org, settings.Repo, title, body, settings.Labels)
+	if err != nil {
+		log.Printf("WARN  Could not open issue in %v for the failures of %v: %v", settings.Repo, result.Repo, err)
+		return
+	}
+	log.Printf("INFO  Opened issue %v for %v failed runs of %v.", issue.GetHTMLURL(), len(repoState.Failures), result.Repo)
+	repoState.FailureIssue = issue.GetNumber()
+}
+
+// failureIssueBody returns the description of the issue for the failures of a repo.
+func failureIssueBody(org string, repo string, failures []state.Failure) string {
+	lines := []string{
+		fmt.Sprintf("dependabutler failed for `%v/%v` in the last %v runs.", org, repo, len(failures)),
+		"",
+		"| run | error |",
+		"| - | - |",
+	}
+	for _, failure := range failures {
+		message := strings.ReplaceAll(strings.ReplaceAll(failure.Message, "|", "\\|"), "\n", " ")
+		lines = append(lines, fmt.Sprintf("| %v | %v |", failure.At.UTC().Format(time.RFC3339), message))
+	}
+	lines = append(lines, "", "Once a run succeeds again, a new issue is opened for further failures.")
+	return strings.Join(lines, "\n")
+}
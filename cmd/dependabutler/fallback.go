@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"github.com/google/go-github/v50/github"
+)
+
+// fallbackIssueTitle is the title of the issue opened instead of the PR, updated by later runs.
+const fallbackIssueTitle = "dependabutler: proposed changes of .github/dependabot.yml"
+
+// openFallbackIssue opens an issue with the proposed changes of the config, for a repo the PR couldn't be created for,
+// due to missing permissions or branch protections. The open issue is updated by later runs.
+func openFallbackIssue(client *github.Client, toolConfig config.ToolConfig, org string, repo string, currentConfig []byte,
+	yamlContent []byte, prDesc string, changeInfo config.ChangeInfo, prErr error,
+) report.RepoResult {
+	body := fallbackIssueBody(currentConfig, yamlContent, prDesc, prErr)
+	issue, opened, err := githubapi.CreateOrUpdateIssue(client, org, repo, fallbackIssueTitle, body, toolConfig.IssueFallback.Labels)
+	if err != nil {
+		log.Printf("ERROR Could not create PR: %v, nor open an issue instead: %v", prErr, err)
+		result := report.NewRepoResult(repo, report.StatusError, changeInfo)
+		result.Message = prErr.Error()
+		return result
+	}
+	if opened {
+		log.Printf("WARN  Could not create PR for %v: %v. Opened issue %v instead.", repo, prErr, issue.GetHTMLURL())
+	} else {
+		log.Printf("WARN  Could not create PR for %v: %v. Updated issue %v instead.", repo, prErr, issue.GetHTMLURL())
+	}
+	result := report.NewRepoResult(repo, report.StatusChanged, changeInfo)
+	result.Message = "issue instead of PR: " + prErr.Error()
+	result.IssueURL = issue.GetHTMLURL()
+	return result
+}
+
+// fallbackIssueBody returns the description of the issue opened instead of the PR, with the diff of the config.
+func fallbackIssueBody(currentConfig []byte, yamlContent []byte, prDesc string, prErr error) string {
+	diff := util.UnifiedDiff(string(currentConfig), string(yamlContent), "a/.github/dependabot.yml", "b/.github/dependabot.yml")
+	lines := []string{
+		"dependabutler could not create a pull request for the changes of `.github/dependabot.yml`, " +
+			"e.g. due to missing permissions or branch protections:",
+		"",
+		fmt.Sprintf("> %v", strings.ReplaceAll(prErr.Error(), "\n", " ")),
+		"",
+		"Please apply the changes below, or allow dependabutler to push its branch. " +
+			"This issue is updated by later runs, as long as the changes are pending.",
+		"",
+		"```diff",
+		strings.TrimSuffix(diff, "\n"),
+		"```",
+		"",
+		prDesc,
+	}
+	return strings.Join(lines, "\n")
+}
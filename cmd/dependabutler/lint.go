@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+// runLintConfig lints a tool config, logs the problems and warnings found, and returns if no problems were found.
+func runLintConfig(content []byte, source string) bool {
+	problems, warnings, err := config.LintToolConfig(content)
+	if err != nil {
+		log.Printf("ERROR Could not parse tool config %v: %v", source, err)
+		return false
+	}
+	for _, problem := range problems {
+		log.Printf("ERROR Tool config: %v", problem)
+	}
+	for _, warning := range warnings {
+		log.Printf("WARN  Tool config: %v", warning)
+	}
+	if len(problems) == 0 {
+		log.Printf("INFO  Tool config %v: OK, %v warnings", source, len(warnings))
+	}
+	return len(problems) == 0
+}
@@ -1,21 +1,33 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/getyourguide/dependabutler/internal/pkg/config"
 	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/metrics"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/secrets"
+	"github.com/getyourguide/dependabutler/internal/pkg/state"
 	"github.com/getyourguide/dependabutler/internal/pkg/util"
 	"github.com/google/go-github/v50/github"
 )
 
 // LoadRemoteFileContent is the implementation of LoadFileContent, for remote files (GitHub).
 func LoadRemoteFileContent(file string, params config.LoadFileContentParameters) string {
-	content, err := githubapi.GetFileContent(params.GitHubClient, params.Org, params.Repo, file, "")
+	content, err := githubapi.GetFileContent(params.GitHubClient, params.Org, params.Repo, file, params.Ref)
+	if err == nil {
+		err = util.CheckTextContent(content, params.FileSizeLimit())
+	}
 	if err != nil {
 		log.Printf("WARN  Could not get content of remote file %v: %v", file, err)
 		return ""
@@ -26,7 +38,7 @@ func LoadRemoteFileContent(file string, params config.LoadFileContentParameters)
 // LoadLocalFileContent is the implementation of LoadFileContent, for local files (file system).
 func LoadLocalFileContent(file string, params config.LoadFileContentParameters) string {
 	fullPath := filepath.Join(params.Directory, file)
-	content, err := util.ReadFile(fullPath)
+	content, err := util.ReadTextFile(fullPath, params.FileSizeLimit())
 	if err != nil {
 		log.Printf("WARN  Could not get content of local file %v: %v", fullPath, err)
 		return ""
@@ -34,93 +46,370 @@ func LoadLocalFileContent(file string, params config.LoadFileContentParameters)
 	return string(content)
 }
 
+// CheckRemoteDirectoryExists is the implementation of CheckDirectoryExists, for remote directories (GitHub).
+// The repo's file list, fetched already for finding the manifests, is used instead of further API calls. For other
+// branches, their file list is fetched, cached for the run. Truncated file lists fail the check, as directories
+// missing in them may exist.
+func CheckRemoteDirectoryExists(directory string, params config.LoadFileContentParameters) (bool, error) {
+	fileList, truncated := params.FileList, params.FileListTruncated
+	if fileList == nil && params.GitHubClient != nil && params.Ref != "" {
+		fileList, truncated = githubapi.GetRepoFileList(params.GitHubClient, params.Org, params.Repo, params.Ref)
+	}
+	if fileList == nil {
+		return false, errors.New("file list of repo not available")
+	}
+	if truncated {
+		return false, errors.New("file list of repo is truncated")
+	}
+	return config.DirectoryInFileList(directory, fileList), nil
+}
+
+// CheckLocalDirectoryExists is the implementation of CheckDirectoryExists, for local directories (file system).
+func CheckLocalDirectoryExists(directory string, params config.LoadFileContentParameters) (bool, error) {
+	fileInfo, err := os.Stat(filepath.Join(params.Directory, directory))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return fileInfo.IsDir(), nil
+}
+
 func showUsageAndExit() {
 	flag.Usage()
 	os.Exit(1)
 }
 
-func getParameters() (string, string, bool, string, string, string, string) {
-	var mode, dir, repo, repoFile, org, configFile string
-	var execute bool
-	flag.StringVar(&mode, "mode", "local", "local or remote")
-	flag.StringVar(&configFile, "configFile", "dependabutler.yml", "location of tool config file")
-	flag.BoolVar(&execute, "execute", false, "true: write file/create PR; false: log-only mode")
-	flag.StringVar(&dir, "dir", "./", "local directory containing the project, for mode=local")
-	flag.StringVar(&org, "org", "", "org/owner name, required for mode=remote")
-	flag.StringVar(&repo, "repo", "", "repository name, for mode=remote")
-	flag.StringVar(&repoFile, "repoFile", "", "file containing repo list (one per line), for mode=remote")
+// parameters holds the command line parameters.
+type parameters struct {
+	mode           string
+	configFile     string
+	configFileSet  bool
+	configInline   string
+	execute        bool
+	dir            string
+	org            string
+	repo           string
+	repoFile       string
+	repoQuery      string
+	report         string
+	reportFile     string
+	path           string
+	trace          bool
+	annotations    bool
+	rolloutPlan    string
+	check          bool
+	ref            string
+	cacheDir       string
+	prefetch       bool
+	listen         string
+	output         string
+	confirm        bool
+	proposed       string
+	deleteRenovate bool
+	force          bool
+	onlyNew        bool
+	metrics        string
+	metricsURL     string
+}
+
+func getParameters() parameters {
+	var params parameters
+	flag.StringVar(&params.mode, "mode", "local", "local, remote, doctor, server, what-if, migrate-renovate or lint-config")
+	flag.StringVar(&params.configFile, "configFile", "dependabutler.yml", "location of tool config file")
+	flag.StringVar(&params.configInline, "configInline", "", "tool config as YAML, instead of configFile and the "+inlineConfigVariable+" environment variable")
+	flag.BoolVar(&params.execute, "execute", false, "true: write file/create PR; false: log-only mode")
+	flag.StringVar(&params.dir, "dir", "./", "local directory containing the project, for mode=local")
+	flag.StringVar(&params.org, "org", "", "org/owner name, required for mode=remote")
+	flag.StringVar(&params.repo, "repo", "", "repository name, for mode=remote")
+	flag.StringVar(&params.repoFile, "repoFile", "", "file containing repo list (one per line), for mode=remote")
+	flag.StringVar(&params.repoQuery, "repoQuery", "", "GitHub search query selecting the repos, e.g. \"org:acme topic:backend archived:false\", for mode=remote")
+	flag.StringVar(&params.report, "report", "", "json or markdown: write a report of all changes to reportFile")
+	flag.StringVar(&params.reportFile, "reportFile", "", "file to write the report to, required for report")
+	flag.StringVar(&params.path, "path", "", "only process manifests in this directory and its subdirectories")
+	flag.BoolVar(&params.trace, "trace", false, "include a trace of the decisions taken for each manifest in the json report")
+	flag.BoolVar(&params.annotations, "annotations", false, "print the findings as GitHub Actions annotations, for mode=local")
+	flag.StringVar(&params.rolloutPlan, "rolloutPlan", "", "file to write the pending changes grouped by owning team to, as json")
+	flag.BoolVar(&params.check, "check", false, "log-only mode, exit with 2 if any repo requires changes, 1 on errors and 0 if all are up to date")
+	flag.StringVar(&params.ref, "ref", "", "branch or commit to scan instead of the default branch, and base branch of the PR, for mode=remote with -repo")
+	flag.StringVar(&params.cacheDir, "cacheDir", "", "directory to persist the repo trees and file contents read in, for later runs")
+	flag.BoolVar(&params.prefetch, "prefetch", false, "fetch the repo metadata and configs in batched GraphQL queries, for mode=remote")
+	flag.StringVar(&params.listen, "listen", ":8080", "address to listen for webhooks on, for mode=server")
+	flag.StringVar(&params.output, "output", "", "github-actions: write the report to the job summary, and set the step outputs changed, pr-url and pr-urls")
+	flag.BoolVar(&params.confirm, "confirmRemovals", false, "apply removals of updates and registries exceeding removal-limits")
+	flag.StringVar(&params.proposed, "proposedConfigFile", "", "tool config file to compare the generated configs with, for mode=what-if")
+	flag.BoolVar(&params.force, "force", false, "process repos unchanged since their last run too, for mode=remote with state-file")
+	flag.BoolVar(&params.onlyNew, "onlyNewRepos", false, "only process repos without a dependabot config, for mode=remote")
+	flag.StringVar(&params.metrics, "metrics", "", "pushgateway or otlp: push the metrics of the run to metricsURL")
+	flag.StringVar(&params.metricsURL, "metricsURL", "", "Prometheus Pushgateway or OTLP/HTTP endpoint, required for metrics")
+	flag.BoolVar(&params.deleteRenovate, "deleteRenovateConfig", false, "delete the renovate config in the migration PR, for mode=migrate-renovate")
 	flag.Parse()
-	switch mode {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "configFile" {
+			params.configFileSet = true
+		}
+	})
+	switch params.mode {
 	case "local":
 		break
 	case "remote":
-		if (repo == "" && repoFile == "") || org == "" {
+		if (params.repo == "" && params.repoFile == "" && params.repoQuery == "") || params.org == "" {
 			showUsageAndExit()
 		}
+	case "migrate-renovate":
+		if (params.repo == "" && params.repoFile == "" && params.repoQuery == "") || params.org == "" {
+			showUsageAndExit()
+		}
+	case "what-if":
+		if (params.repo == "" && params.repoFile == "" && params.repoQuery == "") || params.org == "" || params.proposed == "" || params.execute {
+			showUsageAndExit()
+		}
+	case "doctor", "server", "lint-config":
+		break
 	default:
 		showUsageAndExit()
 	}
-	return mode, configFile, execute, dir, org, repo, repoFile
+	if params.report != "" && (params.reportFile == "" || (params.report != "json" && params.report != "markdown")) {
+		showUsageAndExit()
+	}
+	if params.metrics != "" && (params.metricsURL == "" || (params.metrics != metrics.FormatPushgateway && params.metrics != metrics.FormatOTLP)) {
+		showUsageAndExit()
+	}
+	if params.output != "" && params.output != "github-actions" {
+		showUsageAndExit()
+	}
+	if params.trace && params.report != "json" {
+		showUsageAndExit()
+	}
+	if (params.force || params.onlyNew) && params.mode != "remote" {
+		showUsageAndExit()
+	}
+	if params.deleteRenovate && params.mode != "migrate-renovate" {
+		showUsageAndExit()
+	}
+	if params.annotations && params.mode != "local" {
+		showUsageAndExit()
+	}
+	if params.check && (params.execute || params.mode == "doctor" || params.mode == "server") {
+		showUsageAndExit()
+	}
+	if (params.ref != "" && (params.mode != "remote" || params.repo == "")) || (params.prefetch && params.mode != "remote") {
+		showUsageAndExit()
+	}
+	return params
 }
 
-func getGitHubClient() *github.Client {
-	gitHubToken := util.GetEnvParameter("GITHUB_TOKEN", true)
+func getGitHubClient(toolConfig config.ToolConfig) *github.Client {
+	gitHubToken := toolConfig.Credentials.GitHubToken
+	if gitHubToken == "" {
+		gitHubToken = util.GetEnvParameter("GITHUB_TOKEN", true)
+	}
 	if gitHubToken == "" {
 		log.Printf("ERROR Missing GITHUB_TOKEN environment variable, quitting.")
 		os.Exit(1)
 	}
-	return githubapi.GetGitHubClient(gitHubToken)
+	apiURL := toolConfig.GitHubAPIURL
+	if apiURL == "" {
+		apiURL = githubapi.APIURLFromEnvironment(util.GetEnvParameter("GITHUB_API_URL", false), util.GetEnvParameter("GITHUB_SERVER_URL", false))
+	}
+	client, err := githubapi.GetGitHubClient(gitHubToken, apiURL, toolConfig.GitHubUploadURL, toolConfig.RateLimitBuffer)
+	if err != nil {
+		log.Printf("ERROR Invalid GitHub API URL %v: %v, quitting.", apiURL, err)
+		os.Exit(1)
+	}
+	return client
 }
 
-func processRemoteRepo(toolConfig config.ToolConfig, execute bool, org string, repo string) {
+func processRemoteRepo(toolConfig config.ToolConfig, execute bool, org string, repo string, ref string, removalsThisRun *int) report.RepoResult {
 	// find manifests
 	manifests := map[string]string{}
 
 	// get the current config and file list, from GitHub, via API
-	gitHubClient := getGitHubClient()
+	gitHubClient := getGitHubClient(toolConfig)
 	gitHubRepo, err := githubapi.GetRepository(gitHubClient, org, repo)
 	if err != nil {
-		return
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
 	}
 	if *gitHubRepo.Archived {
 		log.Printf("INFO  Repository %v is archived. Nothing to do.", repo)
-		return
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "archived"}
+	}
+	baseBranch := *gitHubRepo.DefaultBranch
+	if ref != "" {
+		if execute && isCommitSHA(ref) {
+			log.Printf("ERROR A pull request can't target commit %v, use a branch for -ref.", ref)
+			return report.RepoResult{Repo: repo, Status: report.StatusError, Message: "pull requests can only target branches"}
+		}
+		baseBranch = ref
+	}
+	toolConfig.RepoFacts = repoFacts(repo, gitHubRepo)
+	if reason := toolConfig.RepoFilter.SkipReason(toolConfig.RepoFacts, time.Now()); reason != "" {
+		log.Printf("INFO  Repository %v is filtered out (%v). Nothing to do.", repo, reason)
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "filtered: " + reason}
 	}
-	currentConfig, err := githubapi.GetFileContent(gitHubClient, org, repo, ".github/dependabot.yml", "")
+	if toolConfig.UsesRepoProperties() {
+		if toolConfig.RepoFacts.Properties, err = githubapi.GetCustomProperties(gitHubClient, org, repo); err != nil {
+			log.Printf("WARN  Could not get custom properties of repo %v: %v", repo, err)
+		}
+	}
+	currentConfig, err := githubapi.GetFileContent(gitHubClient, org, repo, ".github/dependabot.yml", ref)
 	if err != nil {
 		if strings.Contains(err.Error(), "This repository is empty") {
 			log.Printf("INFO  Repository %v is empty. Nothing to do.", repo)
-		} else {
-			log.Printf("ERROR Could not read config of repo %v: %v", repo, err)
+			return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "empty"}
 		}
-		return
+		log.Printf("ERROR Could not read config of repo %v: %v", repo, err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
 	}
-	baseBranch := *gitHubRepo.DefaultBranch
-	fileList := githubapi.GetRepoFileList(gitHubClient, org, repo, baseBranch)
+	if currentConfig != nil && toolConfig.OnlyNewRepos {
+		log.Printf("INFO  Repository %v has a config already. Nothing to do.", repo)
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "config exists"}
+	}
+	fileList, truncated := githubapi.GetRepoFileList(gitHubClient, org, repo, baseBranch)
 	config.ScanFileList(fileList, manifests)
+	recordManifests(manifests)
+	if len(toolConfig.Cleanup.CheckBranches) > 0 && toolConfig.MissingDirectoriesCleanup() == config.CleanupRemove {
+		if branches, err := githubapi.GetBranches(gitHubClient, org, repo); err != nil {
+			// without the branches, directories only existing on them would be removed
+			log.Printf("WARN  Could not list branches of repo %v, only warning about missing directories: %v", repo, err)
+			toolConfig.Cleanup.MissingDirectories = config.CleanupWarnOnly
+		} else {
+			toolConfig.CheckedBranches = toolConfig.Cleanup.MatchingBranches(branches)
+		}
+	}
 	// update the configuration and create a PR
-	loadFileParameters := config.LoadFileContentParameters{GitHubClient: gitHubClient, Org: org, Repo: repo}
-	yamlContent, changeInfo := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, repo, LoadRemoteFileContent, loadFileParameters)
+	loadFileParameters := config.LoadFileContentParameters{
+		GitHubClient: gitHubClient, Org: org, Repo: repo, FileList: fileList, FileListTruncated: truncated, Ref: baseBranch,
+		MaxFileSize: toolConfig.MaxFileSize,
+	}
+	yamlContent, changeInfo, err := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, repo, LoadRemoteFileContent, CheckRemoteDirectoryExists, loadFileParameters,
+		removalsThisRun)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	companionFiles, err := companionFileChanges(gitHubClient, org, repo, ref, toolConfig)
+	if err != nil {
+		log.Printf("ERROR Could not render companion files for %v: %v", repo, err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	for _, file := range companionFiles {
+		changeInfo.UpdatedFiles = append(changeInfo.UpdatedFiles, file.Path)
+	}
+	if yamlContent == nil && len(companionFiles) == 0 {
+		return report.NewRepoResult(repo, report.StatusUnchanged, changeInfo)
+	}
+	if yamlContent == nil {
+		// only companion files have changed, the config is kept on the PR branch as it is
+		yamlContent = currentConfig
+	}
+	files := make([]githubapi.FileChange, 0, len(companionFiles)+1)
 	if yamlContent != nil {
-		prDesc := githubapi.CreatePRDescription(changeInfo)
-		if execute {
-			if err := githubapi.CreateOrUpdatePullRequest(gitHubClient, org, repo, baseBranch, prDesc, string(yamlContent), toolConfig); err != nil {
-				if strings.Contains(err.Error(), "pull request already exists") {
-					log.Printf("WARN  There's an open pull request already on repo %v. Close or merge it first.", repo)
-				} else {
-					log.Printf("ERROR Could not create PR: %v", err)
-				}
-			}
+		files = append(files, githubapi.FileChange{Path: githubapi.ConfigPath, Content: string(yamlContent)})
+	}
+	files = append(files, companionFiles...)
+	if toolConfig.CheckSecretAccess && len(changeInfo.NewRegistries) > 0 {
+		// verify that the repo can access the org-level secrets of the registries it receives
+		if newConfig, err := config.ParseDependabotConfig(yamlContent); err != nil {
+			log.Printf("WARN  Could not parse new config of repo %v, skipping the secret access check: %v", repo, err)
 		} else {
-			log.Printf("INFO  log-only mode, would create PR for %v:\n----------\n%v\n----------\n%v\n----------\nuse -execute=true to apply", repo, prDesc, string(yamlContent))
+			newRegistries := map[string]config.Registry{}
+			for _, registry := range changeInfo.NewRegistries {
+				newRegistries[registry.Name] = newConfig.Registries[registry.Name]
+			}
+			changeInfo.Warnings = append(changeInfo.Warnings, githubapi.CheckRegistrySecretAccess(gitHubClient, org, gitHubRepo, newRegistries,
+				toolConfig.GrantSecretAccess && execute)...)
+		}
+	}
+	prDesc := githubapi.CreatePRDescription(changeInfo)
+	templateData := config.TemplateData{Org: org, Repo: repo, Date: time.Now(), ChangeCount: changeInfo.ChangeCount()}
+	if toolConfig.PullRequestParameters, err = toolConfig.PullRequestParameters.Render(templateData); err != nil {
+		log.Printf("ERROR Invalid pull request parameters: %v", err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	if ref != "" {
+		// the PR for a branch other than the default one gets a branch of its own
+		toolConfig.PullRequestParameters.BranchName += "-" + ref
+		toolConfig.PullRequestParameters.BranchNamePattern += regexp.QuoteMeta("-" + ref)
+	}
+	prBody := prDesc
+	if toolConfig.PullRequestParameters.IncludeDiff {
+		// the fallback issue shows the diff already, and gets the description without it
+		prBody += githubapi.PRDescriptionDiff(currentConfig, yamlContent)
+	}
+	prNumber := 0
+	if execute {
+		if prNumber, err = githubapi.CreateOrUpdatePullRequest(gitHubClient, org, repo, baseBranch, prBody, files, toolConfig); err != nil {
+			if strings.Contains(err.Error(), "pull request already exists") {
+				log.Printf("WARN  There's an open pull request already on repo %v. Close or merge it first.", repo)
+			} else if toolConfig.IssueFallback.Enabled && githubapi.IsPermissionError(err) {
+				return openFallbackIssue(gitHubClient, toolConfig, org, repo, currentConfig, yamlContent, prDesc, changeInfo, err)
+			} else {
+				log.Printf("ERROR Could not create PR: %v", err)
+			}
+			result := report.NewRepoResult(repo, report.StatusError, changeInfo)
+			result.Message = err.Error()
+			return result
+		}
+	} else {
+		log.Printf("INFO  log-only mode, would create PR for %v:\n----------\n%v\n----------\n%v\n----------\nuse -execute=true to apply", repo, prBody, string(yamlContent))
+	}
+	result := report.NewRepoResult(repo, report.StatusChanged, changeInfo)
+	result.PullRequest = prNumber
+	if prNumber > 0 {
+		result.PullRequestURL = fmt.Sprintf("%v/pull/%v", gitHubRepo.GetHTMLURL(), prNumber)
+	}
+	return result
+}
+
+// repoFacts returns what the repo filter and the schedule policies check of a repo.
+func repoFacts(repo string, gitHubRepo *github.Repository) config.RepoFacts {
+	return config.RepoFacts{
+		Name: repo, Size: gitHubRepo.GetSize(), Topics: gitHubRepo.Topics, Language: gitHubRepo.GetLanguage(),
+		Visibility: repoVisibility(gitHubRepo), PushedAt: gitHubRepo.GetPushedAt().Time,
+	}
+}
+
+// repoVisibility returns the visibility of a repo. Older versions of GitHub Enterprise Server only report if it's
+// private.
+func repoVisibility(gitHubRepo *github.Repository) string {
+	if visibility := gitHubRepo.GetVisibility(); visibility != "" {
+		return visibility
+	}
+	if gitHubRepo.GetPrivate() {
+		return "private"
+	}
+	return "public"
+}
+
+// companionFileChanges returns the companion files whose rendered content differs from the one in the repo.
+func companionFileChanges(gitHubClient *github.Client, org string, repo string, ref string, toolConfig config.ToolConfig) ([]githubapi.FileChange, error) {
+	contents, err := toolConfig.RenderCompanionFiles(config.TemplateData{Org: org, Repo: repo, Version: version()})
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]githubapi.FileChange, 0)
+	for _, file := range toolConfig.CompanionFiles {
+		current, err := githubapi.GetFileContent(gitHubClient, org, repo, file.Path, ref)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || string(current) != contents[file.Path] {
+			changes = append(changes, githubapi.FileChange{Path: file.Path, Content: contents[file.Path]})
 		}
 	}
+	return changes, nil
 }
 
-func processLocalRepo(toolConfig config.ToolConfig, execute bool, dir string) {
+func processLocalRepo(toolConfig config.ToolConfig, execute bool, annotations bool, dir string) report.RepoResult {
 	// find manifests
 	manifests := map[string]string{}
 
+	// sizes and custom properties are only known for remote repos
+	if absDir, err := filepath.Abs(dir); err == nil {
+		toolConfig.RepoFacts = config.RepoFacts{Name: filepath.Base(absDir)}
+	}
+
 	// get the current config and file list, from local file system
 	dirPath := filepath.Join(dir, ".github/")
 	fullPath := filepath.Join(dirPath, "dependabot.yml")
@@ -131,77 +420,481 @@ func processLocalRepo(toolConfig config.ToolConfig, execute bool, dir string) {
 			currentConfig = []byte("version: 2")
 		} else {
 			log.Printf("ERROR Could not read config from file %v: %v", fullPath, err)
-			return
+			return report.RepoResult{Repo: dir, Status: report.StatusError, Message: err.Error()}
 		}
 	}
 	config.ScanLocalDirectory(dir, "", manifests)
+	recordManifests(manifests)
 	// update the configuration and save it back
-	loadFileParameters := config.LoadFileContentParameters{Directory: dir}
-	yamlContent, _ := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, dir, LoadLocalFileContent, loadFileParameters)
-	if yamlContent != nil {
-		if execute {
-			if err := util.MakeDirIfNotExists(dirPath); err != nil {
-				log.Printf("ERROR Could not create directory %v : %v\n", dirPath, err)
-				return
-			}
-			if err := util.SaveFile(fullPath, yamlContent); err != nil {
-				log.Printf("ERROR Could not save file %v : %v\n", fullPath, err)
-				return
-			}
-			log.Printf("INFO  File %v written.", fullPath)
-		} else {
-			log.Printf("INFO  log-only mode, would write file %v:\n----------\n%v\n----------\nuse -execute=true to apply", fullPath, string(yamlContent))
+	loadFileParameters := config.LoadFileContentParameters{Directory: dir, MaxFileSize: toolConfig.MaxFileSize}
+	yamlContent, changeInfo, err := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, dir, LoadLocalFileContent, CheckLocalDirectoryExists, loadFileParameters,
+		new(int))
+	if err != nil {
+		if annotations {
+			fmt.Printf("::error file=.github/dependabot.yml::%v\n", err)
+		}
+		return report.RepoResult{Repo: dir, Status: report.StatusError, Message: err.Error()}
+	}
+	if annotations {
+		// workflow commands are read from stdout, the log is written to stderr
+		for _, command := range report.Annotations(".github/dependabot.yml", currentConfig, changeInfo) {
+			fmt.Println(command)
 		}
 	}
+	if yamlContent == nil {
+		return report.NewRepoResult(dir, report.StatusUnchanged, changeInfo)
+	}
+	if execute {
+		if err := util.MakeDirIfNotExists(dirPath); err != nil {
+			log.Printf("ERROR Could not create directory %v : %v\n", dirPath, err)
+			return report.RepoResult{Repo: dir, Status: report.StatusError, Message: err.Error()}
+		}
+		if err := util.SaveFile(fullPath, yamlContent); err != nil {
+			log.Printf("ERROR Could not save file %v : %v\n", fullPath, err)
+			return report.RepoResult{Repo: dir, Status: report.StatusError, Message: err.Error()}
+		}
+		log.Printf("INFO  File %v written.", fullPath)
+	} else {
+		log.Printf("INFO  log-only mode, would write file %v:\n----------\n%v\n----------\nuse -execute=true to apply", fullPath, string(yamlContent))
+	}
+	return report.NewRepoResult(dir, report.StatusChanged, changeInfo)
 }
 
 func main() {
 	// get parameters
-	mode, configFile, execute, dir, org, repo, repoFile := getParameters()
+	params := getParameters()
 
-	// read and parse config file, and initialize the patterns
-	fileContent, err := util.ReadFile(configFile)
-	if err != nil {
-		log.Printf("ERROR Could not read tool config file %v.", configFile)
+	// lint the tool config instead of processing repos
+	if params.mode == "lint-config" {
+		inline, source := inlineToolConfig(params)
+		content := []byte(inline)
+		if len(content) == 0 {
+			var err error
+			if content, err = util.ReadFile(params.configFile); err != nil {
+				log.Printf("ERROR Could not read tool config file %v.", params.configFile)
+				os.Exit(exitErrors)
+			}
+			source = params.configFile
+		}
+		if !runLintConfig(content, source) {
+			os.Exit(exitErrors)
+		}
 		return
 	}
-	toolConfig, err := config.ParseToolConfig(fileContent)
-	if err != nil {
-		log.Printf("ERROR Could not parse tool config: %v", err)
+
+	// read and parse the tool config, given inline or as file
+	var toolConfig *config.ToolConfig
+	if inline, source := inlineToolConfig(params); inline != "" {
+		log.Printf("INFO  Using tool config %v.", source)
+		toolConfig = parseToolConfig([]byte(inline), source)
+	} else {
+		log.Printf("INFO  Using tool config file %v.", params.configFile)
+		toolConfig = readToolConfig(params.configFile)
+	}
+
+	// fetch the credentials referenced in secret stores
+	if err := toolConfig.ResolveSecrets(secrets.Resolve); err != nil {
+		log.Printf("ERROR Could not resolve secrets of tool config: %v", err)
+		os.Exit(exitErrors)
+	}
+
+	// check the environment instead of processing repos
+	if params.mode == "doctor" {
+		repo := params.repo
+		if repo == "" && params.repoFile != "" {
+			if repos := util.ReadLinesFromFile(params.repoFile); len(repos) > 0 {
+				repo = repos[0]
+			}
+		}
+		if !runDoctor(toolConfig.ForRepo(repo), params.org, repo) {
+			log.Printf("ERROR Some checks failed, see above.")
+			os.Exit(1)
+		}
+		log.Printf("INFO  All checks passed.")
 		return
 	}
 
 	// initialize / precompile the patterns
 	toolConfig.InitializePatterns()
+	toolConfig.PathFilter = params.path
+	toolConfig.Trace = params.trace
+	toolConfig.ConfirmRemovals = params.confirm
+	toolConfig.OnlyNewRepos = params.onlyNew
+	if params.cacheDir != "" {
+		githubapi.EnableDiskCache(params.cacheDir)
+	}
+
+	// process the repos of webhooks, until stopped
+	if params.mode == "server" {
+		if toolConfig.Credentials.WebhookSecret == "" {
+			log.Printf("ERROR Missing credentials.webhook-secret, required for mode=server, quitting.")
+			os.Exit(exitErrors)
+		}
+		if err := runServer(toolConfig, params.execute, params.org, params.listen); err != nil {
+			log.Printf("ERROR Server stopped: %v", err)
+			os.Exit(exitErrors)
+		}
+		return
+	}
 
 	// process
-	if mode == "local" {
-		processLocalRepo(*toolConfig, execute, dir)
-	} else if mode == "remote" {
-		if repo != "" {
-			processRemoteRepo(*toolConfig, execute, org, repo)
-		} else if repoFile != "" {
-			for _, repo := range util.ReadLinesFromFile(repoFile) {
-				processRemoteRepo(*toolConfig, execute, org, repo)
+	runReport := report.Report{}
+	start := time.Now()
+	if params.mode == "local" {
+		absDir, _ := filepath.Abs(params.dir)
+		runReport.Add(processLocalRepo(toolConfig.ForRepo(filepath.Base(absDir)), params.execute, params.annotations, params.dir))
+		recordRepoDuration(start)
+	} else if params.mode == "what-if" {
+		proposedConfig := readToolConfig(params.proposed)
+		proposedConfig.PathFilter = params.path
+		// the proposed tool config is used with the credentials of the current one
+		proposedConfig.Credentials = toolConfig.Credentials
+		for _, repo := range listRepos(params, toolConfig) {
+			runReport.Add(whatIfRepo(toolConfig.ForRepo(repo), proposedConfig.ForRepo(repo), params.org, repo))
+		}
+	} else if params.mode == "migrate-renovate" {
+		removalsThisRun := 0
+		for i, repo := range listRepos(params, toolConfig) {
+			if i > 0 {
+				githubapi.ThrottleBetweenRepos()
+			}
+			runReport.Add(migrateRenovateRepo(toolConfig.ForRepo(repo), params.execute, params.org, repo, params.deleteRenovate, &removalsThisRun))
+		}
+	} else if params.mode == "remote" {
+		repos := listRepos(params, toolConfig)
+		removalsThisRun := 0
+		// the state of the repos is kept between runs, for tracking config drift
+		var store *state.Store
+		if toolConfig.StateFile != "" {
+			var err error
+			if store, err = state.Load(toolConfig.StateFile); err != nil {
+				log.Printf("ERROR Could not read state file %v: %v", toolConfig.StateFile, err)
+				os.Exit(exitErrors)
+			}
+		}
+		if params.prefetch {
+			prefetched := githubapi.PrefetchRepos(getGitHubClient(*toolConfig), params.org, repos)
+			runReport.Prefetch = prefetchSummary(prefetched)
+			log.Printf("INFO  Prefetched %v repos: %v missing, %v archived, %v empty, %v with config and %v without config (%v KB).",
+				runReport.Prefetch.Repos, runReport.Prefetch.Missing, runReport.Prefetch.Archived, runReport.Prefetch.Empty,
+				runReport.Prefetch.WithConfig, runReport.Prefetch.WithoutConfig, runReport.Prefetch.SizeKB)
+			// cheap skips first, the most expensive repos last; -ref applies to the single repo given
+			if params.ref == "" {
+				repos = githubapi.OrderByCost(prefetched)
+			}
+		}
+		queued := map[string]bool{}
+		for _, repo := range repos {
+			queued[strings.ToLower(repo)] = true
+		}
+		// repos referenced as submodules are added to the queue, if enabled
+		enqueue := func(submodules []string, repo string) {
+			for _, submodule := range submodules {
+				if !queued[strings.ToLower(submodule)] {
+					log.Printf("INFO  Adding submodule repo %v of %v to the queue.", submodule, repo)
+					queued[strings.ToLower(submodule)] = true
+					repos = append(repos, submodule)
+				}
+			}
+		}
+		fingerprint := toolConfigFingerprint(toolConfig)
+		for i := 0; i < len(repos); i++ {
+			// -ref applies to the repo given, not to its submodules
+			ref := ""
+			if i == 0 {
+				ref = params.ref
+			}
+			// the outcome of the pending PR is followed up on, even if the repo is skipped
+			if store != nil {
+				followUpPullRequest(getGitHubClient(*toolConfig), store, params.org, repos[i])
+			}
+			// repos unchanged since their last successful run are skipped, unless forced
+			headCommit, changed := "", true
+			if store != nil && ref == "" {
+				headCommit, changed = headCommitIfChanged(getGitHubClient(*toolConfig), store, fingerprint, params.org, repos[i])
 			}
+			if !changed && !params.force {
+				if toolConfig.ProcessSubmodules {
+					enqueue(store.Get(params.org, repos[i]).Submodules, repos[i])
+				}
+				runReport.Add(report.RepoResult{Repo: repos[i], Status: report.StatusSkipped, Message: "unchanged since the last run"})
+				continue
+			}
+			if i > 0 {
+				githubapi.ThrottleBetweenRepos()
+			}
+			var drift *report.ConfigDrift
+			if store != nil {
+				drift = checkConfigDrift(getGitHubClient(*toolConfig), store, params.org, repos[i])
+			}
+			repoStart := time.Now()
+			repoConfig := toolConfig.ForRepo(repos[i])
+			result := processRemoteRepo(repoConfig, params.execute, params.org, repos[i], ref, &removalsThisRun)
+			if repoConfig.EnsureSecurityUpdates && (result.Status == report.StatusChanged || result.Status == report.StatusUnchanged) {
+				result.SecurityFeaturesEnabled = ensureSecurityFeatures(getGitHubClient(*toolConfig), params.execute, params.org, repos[i])
+			}
+			recordRepoDuration(repoStart)
+			result.ConfigDrift = drift
+			if store != nil && result.PullRequest > 0 {
+				recordPullRequest(store, params.org, repos[i], result.PullRequest)
+			}
+			if store != nil {
+				trackFailures(getGitHubClient(*toolConfig), store, *toolConfig, params.execute, params.org, result)
+			}
+			if toolConfig.ProcessSubmodules && (result.Status == report.StatusChanged || result.Status == report.StatusUnchanged) {
+				submodules, err := githubapi.GetSubmoduleRepos(getGitHubClient(*toolConfig), params.org, repos[i])
+				if err != nil {
+					log.Printf("WARN  Could not get submodules of repo %v: %v", repos[i], err)
+				}
+				result.Submodules = submodules
+				enqueue(submodules, repos[i])
+			}
+			if store != nil && headCommit != "" {
+				recordProcessedCommit(store, fingerprint, params.execute, params.org, headCommit, result)
+			}
+			runReport.Add(result)
+		}
+		if store != nil {
+			runReport.Adoption = adoptionOf(store, params.org, toolConfig.TeamOf)
+			if err := store.Save(); err != nil {
+				log.Printf("ERROR Could not write state file %v: %v", toolConfig.StateFile, err)
+			}
+		}
+	}
+
+	// summarize the time spent waiting for rate limits
+	if stats := githubapi.GetRateLimitStats(); stats.Waits > 0 {
+		log.Printf("INFO  Waited %v for rate limits (%v times): %v primary, %v secondary, %v after PR actions, %v throttled between repos.",
+			stats.Total().Round(time.Second), stats.Waits, stats.PrimaryWait.Round(time.Second),
+			stats.SecondaryWait.Round(time.Second), stats.PRActionWait.Round(time.Second), stats.AdaptiveWait.Round(time.Second))
+		runReport.RateLimits = &report.RateLimitWaits{
+			Waits:            stats.Waits,
+			PrimarySeconds:   int(stats.PrimaryWait.Seconds()),
+			SecondarySeconds: int(stats.SecondaryWait.Seconds()),
+			PRActionSeconds:  int(stats.PRActionWait.Seconds()),
+			AdaptiveSeconds:  int(stats.AdaptiveWait.Seconds()),
+		}
+	}
+
+	// summarize the file reads saved by the cache
+	if stats := githubapi.GetCacheStats(); stats.Hits > 0 {
+		log.Printf("INFO  File cache: %v of %v file reads served from the cache.", stats.Hits, stats.Hits+stats.Misses)
+	}
+
+	// write the report
+	if params.report != "" {
+		if err := runReport.Write(params.report, params.reportFile); err != nil {
+			log.Printf("ERROR Could not write report to %v: %v", params.reportFile, err)
+		} else {
+			log.Printf("INFO  Report written to %v.", params.reportFile)
+		}
+	}
+
+	// write the job summary and the step outputs
+	if params.output == "github-actions" {
+		if err := runReport.WriteGitHubActions(util.GetEnvParameter("GITHUB_STEP_SUMMARY", true), util.GetEnvParameter("GITHUB_OUTPUT", true)); err != nil {
+			log.Printf("ERROR Could not write GitHub Actions summary and outputs: %v", err)
+		}
+	}
+
+	// write the rollout plan
+	if params.rolloutPlan != "" {
+		if err := runReport.RolloutPlan(toolConfig.TeamOf).Write(params.rolloutPlan); err != nil {
+			log.Printf("ERROR Could not write rollout plan to %v: %v", params.rolloutPlan, err)
+		} else {
+			log.Printf("INFO  Rollout plan written to %v.", params.rolloutPlan)
+		}
+	}
+
+	// post the summary of the run
+	if toolConfig.Notification.URL != "" {
+		title := "dependabutler run, mode=" + params.mode
+		if params.org != "" {
+			title += " for " + params.org
+		}
+		if !params.execute {
+			title += " (log-only)"
+		}
+		if err := runReport.Notify(toolConfig.Notification.URL, toolConfig.Notification.Format, title); err != nil {
+			log.Printf("ERROR Could not post the run summary: %v", err)
+		} else {
+			log.Printf("INFO  Run summary posted.")
+		}
+	}
+
+	// push the metrics of the run
+	if params.metrics != "" {
+		recordRun(runReport, start)
+		if err := metrics.Push(params.metricsURL, params.metrics); err != nil {
+			log.Printf("ERROR Could not push the metrics: %v", err)
+		} else {
+			log.Printf("INFO  Metrics pushed.")
+		}
+	}
+
+	// exit with a code telling the result of the check
+	if params.check {
+		code := checkExitCode(runReport)
+		log.Printf("INFO  Check: %v changed, %v errors, exit code %v.", runReport.CountByStatus(report.StatusChanged), runReport.CountByStatus(report.StatusError), code)
+		os.Exit(code)
+	}
+}
+
+// prefetchSummary returns the breakdown of the prefetched repos, for the report.
+func prefetchSummary(prefetched []githubapi.PrefetchedRepo) *report.PrefetchSummary {
+	summary := &report.PrefetchSummary{Repos: len(prefetched)}
+	for _, repo := range prefetched {
+		switch {
+		case !repo.Found:
+			summary.Missing++
+		case repo.Archived:
+			summary.Archived++
+		case repo.Empty:
+			summary.Empty++
+		case repo.HasConfig:
+			summary.WithConfig++
+			summary.SizeKB += repo.Size
+		default:
+			summary.WithoutConfig++
+			summary.SizeKB += repo.Size
+		}
+	}
+	return summary
+}
+
+// inlineConfigVariable is the environment variable holding the tool config as YAML, e.g. for containers without
+// a config file mounted.
+const inlineConfigVariable = "DEPENDABUTLER_CONFIG"
+
+// inlineToolConfig returns the tool config given inline, and where it's given, "" if none. The parameter takes
+// precedence; the environment variable is only used if no config file is given on the command line either.
+func inlineToolConfig(params parameters) (string, string) {
+	if params.configInline != "" {
+		return params.configInline, "given by -configInline"
+	}
+	if params.configFileSet {
+		return "", ""
+	}
+	return os.Getenv(inlineConfigVariable), "given by environment variable " + inlineConfigVariable
+}
+
+// readToolConfig reads and parses a tool config file, and quits on errors.
+func readToolConfig(file string) *config.ToolConfig {
+	fileContent, err := util.ReadFile(file)
+	if err != nil {
+		log.Printf("ERROR Could not read tool config file %v.", file)
+		os.Exit(exitErrors)
+	}
+	return parseToolConfig(fileContent, file)
+}
+
+// parseToolConfig parses a tool config, and quits on errors.
+func parseToolConfig(content []byte, source string) *config.ToolConfig {
+	toolConfig, err := config.ParseToolConfig(content)
+	if err != nil {
+		log.Printf("ERROR Could not parse tool config %v: %v", source, err)
+		os.Exit(exitErrors)
+	}
+	return toolConfig
+}
+
+// listRepos returns the repos to process in remote mode: the one given, the ones of the file, or the search results.
+func listRepos(params parameters, toolConfig *config.ToolConfig) []string {
+	if params.repo != "" {
+		return []string{params.repo}
+	}
+	if params.repoFile != "" {
+		return util.ReadLinesFromFile(params.repoFile)
+	}
+	repos, err := githubapi.SearchRepos(getGitHubClient(*toolConfig), params.org, params.repoQuery)
+	if err != nil {
+		log.Printf("ERROR Could not search repos for %v: %v", params.repoQuery, err)
+		os.Exit(exitErrors)
+	}
+	log.Printf("INFO  Found %v repos for %v.", len(repos), params.repoQuery)
+	return repos
+}
+
+// Exit codes of -check.
+const (
+	exitUpToDate = 0
+	exitErrors   = 1
+	exitDrift    = 2
+)
+
+// checkExitCode returns the exit code of -check for a report. Errors take precedence, as the result is incomplete then.
+func checkExitCode(runReport report.Report) int {
+	switch {
+	case runReport.CountByStatus(report.StatusError) > 0:
+		return exitErrors
+	case runReport.CountByStatus(report.StatusChanged) > 0:
+		return exitDrift
+	default:
+		return exitUpToDate
+	}
+}
+
+// isCommitSHA returns if a ref is a (full or abbreviated) commit SHA rather than a branch name.
+func isCommitSHA(ref string) bool {
+	if len(ref) < 7 || len(ref) > 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
 		}
 	}
+	return true
+}
+
+// version returns the version of dependabutler, as recorded in the build info by go install.
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
 }
 
 // GetUpdatedConfigYaml returns the new .dependabot.yml file content, based on the current content and the manifests found.
+// removalsThisRun counts the removals of updates and registries of the run, for removal-limits.max-per-run, and is
+// increased by the removals applied.
 func GetUpdatedConfigYaml(currentConfig []byte, manifests map[string]string, toolConfig config.ToolConfig, repo string,
-	loadFileFn config.LoadFileContent, loadFileParams config.LoadFileContentParameters,
-) ([]byte, config.ChangeInfo) {
+	loadFileFn config.LoadFileContent, checkDirectoryFn config.CheckDirectoryExists, loadFileParams config.LoadFileContentParameters,
+	removalsThisRun *int,
+) ([]byte, config.ChangeInfo, error) {
 	dependabotConfig, err := config.ParseDependabotConfig(currentConfig)
 	if err != nil {
 		log.Printf("ERROR Could not parse current config for %v: %v", repo, err)
-		return nil, config.ChangeInfo{}
+		return nil, config.ChangeInfo{}, err
+	}
+	entries := len(dependabotConfig.Updates) + len(dependabotConfig.Registries)
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, loadFileFn, checkDirectoryFn, loadFileParams)
+	if removals := changeInfo.RemovalCount(); removals > 0 && !toolConfig.ConfirmRemovals &&
+		toolConfig.RemovalLimits.Exceeded(removals, entries, *removalsThisRun) {
+		// update the config again, without removals, and report them for confirmation
+		log.Printf("WARN  %v removals for %v exceed the removal limits, pausing them. Use -confirmRemovals to apply them.", removals, repo)
+		paused := changeInfo
+		dependabotConfig, _ = config.ParseDependabotConfig(currentConfig)
+		withoutRemovals := toolConfig
+		withoutRemovals.Cleanup = config.Cleanup{
+			MissingDirectories: config.CleanupOff, UnusedRegistries: config.CleanupOff, MissingManifests: config.CleanupOff,
+		}
+		changeInfo = dependabotConfig.UpdateConfig(manifests, withoutRemovals, loadFileFn, checkDirectoryFn, loadFileParams)
+		changeInfo.PausedRemovedUpdates = paused.RemovedUpdates
+		changeInfo.PausedRemovedRegistries = paused.RemovedRegistries
 	}
-	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, loadFileFn, loadFileParams)
-	if len(changeInfo.NewRegistries) > 0 || len(changeInfo.NewUpdates) > 0 {
+	*removalsThisRun += changeInfo.RemovalCount()
+	if changeInfo.HasChanges() {
+		comment, err := toolConfig.RenderGeneratedComment(config.TemplateData{Repo: repo, Date: time.Now(), Version: version()})
+		if err != nil {
+			log.Printf("WARN  Could not render generated-comment: %v", err)
+		}
 		// at least one item in the update block is needed
-		return dependabotConfig.ToYaml(), changeInfo
+		if toolConfig.MinimalDiff {
+			return dependabotConfig.ToYamlMinimalDiff(currentConfig, changeInfo.NewUpdates, comment), changeInfo, nil
+		}
+		return dependabotConfig.ToYamlWithComments(currentConfig, changeInfo.NewUpdates, comment), changeInfo, nil
 	}
 	log.Printf("INFO  No update needed.")
-	return nil, config.ChangeInfo{}
+	return nil, changeInfo, nil
 }
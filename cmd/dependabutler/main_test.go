@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/google/go-github/v50/github"
+)
+
+func TestGetUpdatedConfigYamlRemovalLimits(t *testing.T) {
+	currentConfig := []byte(`version: 2
+updates:
+  - package-ecosystem: npm
+    directory: /app
+  - package-ecosystem: npm
+    directory: /gone1
+  - package-ecosystem: npm
+    directory: /gone2
+`)
+	manifests := map[string]string{"app/package.json": "npm"}
+	loadFileFn := func(_ string, _ config.LoadFileContentParameters) string { return "" }
+	checkDirectoryFn := func(directory string, _ config.LoadFileContentParameters) (bool, error) {
+		return directory == "/app", nil
+	}
+	removed := []config.UpdateInfo{{Type: "npm", Directory: "/gone1"}, {Type: "npm", Directory: "/gone2"}}
+	for _, tt := range []struct {
+		name                string
+		limits              config.RemovalLimits
+		confirmRemovals     bool
+		runRemovals         int
+		expectedRemoved     []config.UpdateInfo
+		expectedPaused      []config.UpdateInfo
+		expectedRunRemovals int
+	}{
+		{"no limits", config.RemovalLimits{}, false, 0, removed, nil, 2},
+		{"run limit not exceeded", config.RemovalLimits{MaxPerRun: 5}, false, 3, removed, nil, 5},
+		{"run limit exceeded", config.RemovalLimits{MaxPerRun: 5}, false, 4, []config.UpdateInfo{}, removed, 4},
+		{"repo limit exceeded", config.RemovalLimits{MaxPercentPerRepo: 50}, false, 0, []config.UpdateInfo{}, removed, 0},
+		{"limit exceeded, removals confirmed", config.RemovalLimits{MaxPerRun: 1}, true, 0, removed, nil, 2},
+	} {
+		toolConfig := config.ToolConfig{RemoveMissingDirectories: true, RemovalLimits: tt.limits, ConfirmRemovals: tt.confirmRemovals}
+		runRemovals := tt.runRemovals
+		yamlContent, changeInfo, err := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, "service", loadFileFn,
+			checkDirectoryFn, config.LoadFileContentParameters{}, &runRemovals)
+		if err != nil {
+			t.Fatalf("GetUpdatedConfigYaml() with %v failed; unexpected error %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(changeInfo.RemovedUpdates, tt.expectedRemoved) {
+			t.Errorf("GetUpdatedConfigYaml() with %v failed; expected removed updates %v got %v", tt.name, tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+		if !reflect.DeepEqual(changeInfo.PausedRemovedUpdates, tt.expectedPaused) {
+			t.Errorf("GetUpdatedConfigYaml() with %v failed; expected paused removals %v got %v", tt.name, tt.expectedPaused, changeInfo.PausedRemovedUpdates)
+		}
+		if runRemovals != tt.expectedRunRemovals {
+			t.Errorf("GetUpdatedConfigYaml() with %v failed; expected %v removals of the run got %v", tt.name, tt.expectedRunRemovals, runRemovals)
+		}
+		// paused removals leave the config as it is
+		if len(tt.expectedPaused) > 0 && yamlContent != nil {
+			t.Errorf("GetUpdatedConfigYaml() with %v failed; expected no new config, got %v", tt.name, string(yamlContent))
+		}
+		if len(tt.expectedRemoved) > 0 && (yamlContent == nil || strings.Contains(string(yamlContent), "/gone1")) {
+			t.Errorf("GetUpdatedConfigYaml() with %v failed; expected a config without /gone1, got %v", tt.name, string(yamlContent))
+		}
+	}
+}
+
+func TestInlineToolConfig(t *testing.T) {
+	t.Setenv(inlineConfigVariable, "from-env")
+	for _, tt := range []struct {
+		params         parameters
+		expected       string
+		expectedSource string
+	}{
+		{parameters{configInline: "from-param"}, "from-param", "given by -configInline"},
+		{parameters{configInline: "from-param", configFileSet: true}, "from-param", "given by -configInline"},
+		{parameters{}, "from-env", "given by environment variable " + inlineConfigVariable},
+		{parameters{configFileSet: true}, "", ""},
+	} {
+		if got, source := inlineToolConfig(tt.params); got != tt.expected || source != tt.expectedSource {
+			t.Errorf("inlineToolConfig(%+v) failed; expected %v (%v) got %v (%v)", tt.params, tt.expected, tt.expectedSource, got, source)
+		}
+	}
+}
+
+func TestToolConfigFingerprint(t *testing.T) {
+	fingerprint := toolConfigFingerprint(&config.ToolConfig{Credentials: config.Credentials{GitHubToken: "token-1"}})
+	if got := toolConfigFingerprint(&config.ToolConfig{Credentials: config.Credentials{GitHubToken: "token-2"}}); got != fingerprint {
+		t.Errorf("toolConfigFingerprint() failed; expected %v for other credentials, got %v", fingerprint, got)
+	}
+	if got := toolConfigFingerprint(&config.ToolConfig{RemoveUnusedRegistries: true}); got == fingerprint {
+		t.Errorf("toolConfigFingerprint() failed; expected another fingerprint for another config, got %v", got)
+	}
+}
+
+func TestRepoFactsPrefetched(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			t.Errorf("repoFacts() failed; unexpected REST request %v", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"r0": {"name": "service", "isArchived": false, "isPrivate": true, "visibility": "INTERNAL",
+  "pushedAt": "2024-03-01T12:00:00Z", "repositoryTopics": {"nodes": [{"topic": {"name": "backend"}}, {"topic": {"name": "payments"}}]},
+  "primaryLanguage": {"name": "Go"}, "defaultBranchRef": {"name": "main", "target": {"oid": "c1"}}, "config": null, "gitmodules": null}}}`))
+	}))
+	defer server.Close()
+	githubapi.ClearCache()
+	defer githubapi.ClearCache()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	githubapi.PrefetchRepos(client, "acme", []string{"service"})
+	gitHubRepo, err := githubapi.GetRepository(client, "acme", "service")
+	if err != nil {
+		t.Fatalf("GetRepository() failed; unexpected error %v", err)
+	}
+	facts := repoFacts("service", gitHubRepo)
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		filter   config.RepoFilter
+		expected string
+	}{
+		{config.RepoFilter{Topics: []string{"payments"}}, ""},
+		{config.RepoFilter{SkipTopics: []string{"backend"}}, "topic backend"},
+		{config.RepoFilter{Languages: []string{"go"}}, ""},
+		{config.RepoFilter{Visibilities: []string{"internal"}}, ""},
+		{config.RepoFilter{Visibilities: []string{"private"}}, "visibility internal"},
+		{config.RepoFilter{MaxPushAge: 30}, ""},
+		{config.RepoFilter{MaxPushAge: 7}, "no push for more than 7 days"},
+	} {
+		if got := tt.filter.SkipReason(facts, now); got != tt.expected {
+			t.Errorf("SkipReason(%+v) of prefetched repo failed; expected %q got %q", tt.filter, tt.expected, got)
+		}
+	}
+}
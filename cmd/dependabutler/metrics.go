@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/metrics"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+)
+
+// repoDurationBuckets holds the bounds of the buckets of the processing time per repo, in seconds.
+var repoDurationBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+
+// recordManifests counts the manifests found in a repo, by type.
+func recordManifests(manifests map[string]string) {
+	for _, manifestType := range manifests {
+		metrics.Add("dependabutler_manifests_found_total", "Manifests found, by type.", metrics.Labels{"ecosystem": manifestType}, 1)
+	}
+}
+
+// recordRepoDuration observes the time spent processing a repo.
+func recordRepoDuration(start time.Time) {
+	metrics.Observe("dependabutler_repo_duration_seconds", "Time spent processing a repo.", repoDurationBuckets, nil,
+		time.Since(start).Seconds())
+}
+
+// recordRun records the results of the repos, the API requests and the rate limit waits of the run.
+func recordRun(runReport report.Report, start time.Time) {
+	for _, status := range []string{report.StatusChanged, report.StatusUnchanged, report.StatusSkipped, report.StatusError} {
+		metrics.Add("dependabutler_repos_processed_total", "Repos processed, by result.", metrics.Labels{"status": status},
+			float64(runReport.CountByStatus(status)))
+	}
+	pullRequests := 0
+	for _, result := range runReport.Repos {
+		if result.PullRequest > 0 {
+			pullRequests++
+		}
+	}
+	metrics.Add("dependabutler_pull_requests_total", "Pull requests created or updated.", nil, float64(pullRequests))
+
+	stats := githubapi.GetRateLimitStats()
+	metrics.Add("dependabutler_api_requests_total", "GitHub API requests sent, retries included.", nil, float64(stats.Requests))
+	for kind, wait := range map[string]time.Duration{
+		"primary": stats.PrimaryWait, "secondary": stats.SecondaryWait, "pr-action": stats.PRActionWait, "adaptive": stats.AdaptiveWait,
+	} {
+		metrics.Add("dependabutler_rate_limit_wait_seconds_total", "Time spent waiting for rate limits, by kind.",
+			metrics.Labels{"kind": kind}, wait.Seconds())
+	}
+	metrics.Set("dependabutler_run_duration_seconds", "Duration of the run.", nil, time.Since(start).Seconds())
+	metrics.Set("dependabutler_run_finished_timestamp_seconds", "Time the run finished, as Unix time.", nil, float64(time.Now().Unix()))
+}
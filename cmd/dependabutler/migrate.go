@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+)
+
+// migrateRenovateRepo creates a PR migrating the renovate config of a repo to dependabot: the config is generated as
+// usual, with the schedule, package rules and host rules of the renovate config applied. The renovate config is
+// deleted in the same PR, if enabled.
+func migrateRenovateRepo(toolConfig config.ToolConfig, execute bool, org string, repo string, deleteRenovateConfig bool,
+	removalsThisRun *int,
+) report.RepoResult {
+	gitHubClient := getGitHubClient(toolConfig)
+	gitHubRepo, err := githubapi.GetRepository(gitHubClient, org, repo)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	if gitHubRepo.GetArchived() {
+		log.Printf("INFO  Repository %v is archived. Nothing to do.", repo)
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "archived"}
+	}
+	baseBranch := gitHubRepo.GetDefaultBranch()
+	fileList, truncated := githubapi.GetRepoFileList(gitHubClient, org, repo, baseBranch)
+	renovateFile := config.FindRenovateConfig(fileList)
+	if renovateFile == "" {
+		log.Printf("INFO  Repository %v has no renovate config. Nothing to do.", repo)
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "no renovate config"}
+	}
+	renovateContent, err := githubapi.GetFileContent(gitHubClient, org, repo, renovateFile, "")
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	renovateConfig, err := config.ParseRenovateConfig(renovateContent)
+	if err != nil {
+		log.Printf("ERROR Could not parse %v of repo %v: %v", renovateFile, repo, err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	currentConfig, err := githubapi.GetFileContent(gitHubClient, org, repo, ".github/dependabot.yml", "")
+	if err != nil {
+		log.Printf("ERROR Could not read config of repo %v: %v", repo, err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+
+	// generate the config as usual, then apply the renovate settings
+	toolConfig.RepoFacts = config.RepoFacts{Name: repo, Size: gitHubRepo.GetSize()}
+	manifests := map[string]string{}
+	config.ScanFileList(fileList, manifests)
+	loadFileParameters := config.LoadFileContentParameters{
+		GitHubClient: gitHubClient, Org: org, Repo: repo, FileList: fileList, FileListTruncated: truncated, Ref: baseBranch,
+		MaxFileSize: toolConfig.MaxFileSize,
+	}
+	yamlContent, changeInfo, err := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, repo, LoadRemoteFileContent, CheckRemoteDirectoryExists, loadFileParameters,
+		removalsThisRun)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	if yamlContent == nil {
+		yamlContent = currentConfig
+	}
+	dependabotConfig, err := config.ParseDependabotConfig(yamlContent)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	notes := renovateConfig.ApplyTo(dependabotConfig)
+	yamlContent = dependabotConfig.ToYamlWithComments(yamlContent, nil, "")
+	changeInfo.Warnings = append(changeInfo.Warnings, notes...)
+
+	files := []githubapi.FileChange{{Path: githubapi.ConfigPath, Content: string(yamlContent)}}
+	intro := fmt.Sprintf("Migrates the renovate config `%v` to dependabot.", renovateFile)
+	if deleteRenovateConfig {
+		files = append(files, githubapi.FileChange{Path: renovateFile, Delete: true})
+		intro = fmt.Sprintf("Migrates the renovate config `%v` to dependabot, and deletes it.", renovateFile)
+	}
+	prDesc := intro + "\n\n" + githubapi.CreatePRDescription(changeInfo)
+	templateData := config.TemplateData{Org: org, Repo: repo, Date: time.Now(), ChangeCount: changeInfo.ChangeCount()}
+	if toolConfig.PullRequestParameters, err = toolConfig.PullRequestParameters.Render(templateData); err != nil {
+		log.Printf("ERROR Invalid pull request parameters: %v", err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	// the migration gets a PR of its own, next to the regular one
+	toolConfig.PullRequestParameters.BranchName += "-renovate-migration"
+	toolConfig.PullRequestParameters.PRTitle = "Migrate from renovate to dependabot"
+	prNumber := 0
+	if execute {
+		if prNumber, err = githubapi.CreateOrUpdatePullRequest(gitHubClient, org, repo, baseBranch, prDesc, files, toolConfig); err != nil {
+			log.Printf("ERROR Could not create PR: %v", err)
+			result := report.NewRepoResult(repo, report.StatusError, changeInfo)
+			result.Message = err.Error()
+			return result
+		}
+	} else {
+		deleted := ""
+		if deleteRenovateConfig {
+			deleted = fmt.Sprintf(", deleting %v", renovateFile)
+		}
+		log.Printf("INFO  log-only mode, would create PR for %v%v:\n----------\n%v\n----------\n%v\n----------\nuse -execute=true to apply", repo, deleted, prDesc, string(yamlContent))
+	}
+	result := report.NewRepoResult(repo, report.StatusChanged, changeInfo)
+	result.PullRequest = prNumber
+	if prNumber > 0 {
+		result.PullRequestURL = fmt.Sprintf("%v/pull/%v", gitHubRepo.GetHTMLURL(), prNumber)
+	}
+	return result
+}
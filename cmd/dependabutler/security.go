@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/google/go-github/v50/github"
+)
+
+// ensureSecurityFeatures enables the dependabot alerts and security updates of a repo, if disabled, and returns the
+// features enabled. In log-only mode, the features which would be enabled are returned.
+func ensureSecurityFeatures(client *github.Client, execute bool, org string, repo string) []string {
+	enabled, err := githubapi.EnsureSecurityFeatures(client, org, repo, execute)
+	if err != nil {
+		log.Printf("WARN  Could not ensure the security features of repo %v: %v", repo, err)
+	}
+	if len(enabled) == 0 {
+		return enabled
+	}
+	if execute {
+		log.Printf("INFO  Enabled %v for repo %v.", strings.Join(enabled, " and "), repo)
+	} else {
+		log.Printf("INFO  log-only mode, would enable %v for repo %v.", strings.Join(enabled, " and "), repo)
+	}
+	return enabled
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+)
+
+// serverQueueSize is the number of repos waiting for processing, further webhooks are rejected.
+const serverQueueSize = 100
+
+// runServer listens for GitHub webhooks, and processes the repos affected by them one after the other.
+// Only repos of the org are processed, if an org is given.
+func runServer(toolConfig *config.ToolConfig, execute bool, org string, listen string) error {
+	queue := make(chan githubapi.WebhookRepo, serverQueueSize)
+	go func() {
+		for repo := range queue {
+			// the repo may have changed since it has been read last
+			githubapi.ClearCache()
+			repoConfig := toolConfig.ForRepo(repo.Repo)
+			// each event is a run of its own, for removal-limits.max-per-run
+			result := processRemoteRepo(repoConfig, execute, repo.Org, repo.Repo, "", new(int))
+			if repoConfig.EnsureSecurityUpdates && (result.Status == report.StatusChanged || result.Status == report.StatusUnchanged) {
+				ensureSecurityFeatures(getGitHubClient(*toolConfig), execute, repo.Org, repo.Repo)
+			}
+			log.Printf("INFO  Processed repo %v/%v (%v): %v %v", repo.Org, repo.Repo, repo.Reason, result.Status, result.Message)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		repo, err := githubapi.ParseWebhook(r, toolConfig.Credentials.WebhookSecret)
+		if err != nil {
+			log.Printf("WARN  Rejected webhook: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if repo == nil || (org != "" && !strings.EqualFold(repo.Org, org)) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		select {
+		case queue <- *repo:
+			log.Printf("INFO  Queued repo %v/%v: %v", repo.Org, repo.Repo, repo.Reason)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			log.Printf("WARN  Queue full, dropped repo %v/%v: %v", repo.Org, repo.Repo, repo.Reason)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	log.Printf("INFO  Listening for webhooks on %v.", listen)
+	server := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return server.ListenAndServe()
+}
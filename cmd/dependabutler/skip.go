@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/state"
+	"github.com/google/go-github/v50/github"
+)
+
+// toolConfigFingerprint returns the fingerprint of the tool config and the version of dependabutler, as repos need to
+// be processed again if either changes. The credentials are left out, as rotating them doesn't change the results.
+func toolConfigFingerprint(toolConfig *config.ToolConfig) string {
+	withoutCredentials := *toolConfig
+	withoutCredentials.Credentials = config.Credentials{}
+	content, _ := json.Marshal(withoutCredentials)
+	return state.Fingerprint(append(content, version()...))
+}
+
+// headCommitIfChanged returns the head commit of the default branch of a repo, and false if it has been processed
+// successfully at this commit with the same tool config already.
+func headCommitIfChanged(client *github.Client, store *state.Store, fingerprint string, org string, repo string) (string, bool) {
+	gitHubRepo, err := githubapi.GetRepository(client, org, repo)
+	if err != nil {
+		return "", true
+	}
+	commit, err := githubapi.GetHeadCommit(client, org, repo, gitHubRepo.GetDefaultBranch())
+	if err != nil {
+		return "", true
+	}
+	if store.Get(org, repo).Unchanged(commit, fingerprint) {
+		log.Printf("INFO  Repository %v is unchanged since the last run. Nothing to do.", repo)
+		return commit, false
+	}
+	return commit, true
+}
+
+// recordProcessedCommit remembers the commit a repo has been processed at, if it succeeded: the config is up to date,
+// or a PR has been created. Log-only runs with changes are processed again.
+func recordProcessedCommit(store *state.Store, fingerprint string, execute bool, org string, commit string, result report.RepoResult) {
+	repoState := store.Get(org, result.Repo)
+	repoState.Submodules = result.Submodules
+	if result.Status == report.StatusUnchanged || result.Status == report.StatusChanged && execute {
+		repoState.ProcessedCommit, repoState.ToolConfigFingerprint = commit, fingerprint
+	} else {
+		repoState.ProcessedCommit, repoState.ToolConfigFingerprint = "", ""
+	}
+	store.Set(org, result.Repo, repoState)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/githubapi"
+	"github.com/getyourguide/dependabutler/internal/pkg/report"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// whatIfRepo compares the configs generated for a repo with the current and with the proposed tool config.
+// Nothing is changed. Removals are not limited, to show the full effect of both tool configs.
+func whatIfRepo(current config.ToolConfig, proposed config.ToolConfig, org string, repo string) report.RepoResult {
+	gitHubClient := getGitHubClient(current)
+	gitHubRepo, err := githubapi.GetRepository(gitHubClient, org, repo)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	if gitHubRepo.GetArchived() {
+		log.Printf("INFO  Repository %v is archived. Nothing to do.", repo)
+		return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "archived"}
+	}
+	currentConfig, err := githubapi.GetFileContent(gitHubClient, org, repo, ".github/dependabot.yml", "")
+	if err != nil {
+		if strings.Contains(err.Error(), "This repository is empty") {
+			log.Printf("INFO  Repository %v is empty. Nothing to do.", repo)
+			return report.RepoResult{Repo: repo, Status: report.StatusSkipped, Message: "empty"}
+		}
+		log.Printf("ERROR Could not read config of repo %v: %v", repo, err)
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	fileList, truncated := githubapi.GetRepoFileList(gitHubClient, org, repo, gitHubRepo.GetDefaultBranch())
+	loadFileParameters := config.LoadFileContentParameters{
+		GitHubClient: gitHubClient, Org: org, Repo: repo, FileList: fileList, FileListTruncated: truncated,
+		Ref:         gitHubRepo.GetDefaultBranch(),
+		MaxFileSize: current.MaxFileSize,
+	}
+	var properties map[string]string
+	if current.UsesRepoProperties() || proposed.UsesRepoProperties() {
+		if properties, err = githubapi.GetCustomProperties(gitHubClient, org, repo); err != nil {
+			log.Printf("WARN  Could not get custom properties of repo %v: %v", repo, err)
+		}
+	}
+
+	// the manifest patterns may differ, so the file list is scanned with each tool config
+	generate := func(toolConfig config.ToolConfig) ([]byte, config.ChangeInfo, error) {
+		toolConfig.InitializePatterns()
+		toolConfig.ConfirmRemovals = true
+		toolConfig.RepoFacts = config.RepoFacts{Name: repo, Size: gitHubRepo.GetSize(), Properties: properties}
+		manifests := map[string]string{}
+		config.ScanFileList(fileList, manifests)
+		yamlContent, changeInfo, err := GetUpdatedConfigYaml(currentConfig, manifests, toolConfig, repo, LoadRemoteFileContent, CheckRemoteDirectoryExists, loadFileParameters,
+			new(int))
+		if err == nil && yamlContent == nil {
+			// written the same way as updated configs, so formatting doesn't show in the diff
+			unchanged, _ := config.ParseDependabotConfig(currentConfig)
+			yamlContent = unchanged.ToYamlWithComments(currentConfig, nil, "")
+		}
+		return yamlContent, changeInfo, err
+	}
+	currentContent, currentChangeInfo, err := generate(current)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	proposedContent, proposedChangeInfo, err := generate(proposed)
+	if err != nil {
+		return report.RepoResult{Repo: repo, Status: report.StatusError, Message: err.Error()}
+	}
+	currentParsed, _ := config.ParseDependabotConfig(currentContent)
+	proposedParsed, _ := config.ParseDependabotConfig(proposedContent)
+	whatIf := &report.WhatIf{
+		CurrentChanges:  currentChangeInfo.ChangeCount(),
+		ProposedChanges: proposedChangeInfo.ChangeCount(),
+		Changes:         config.CompareConfigs(currentParsed, proposedParsed),
+		Diff:            util.UnifiedDiff(string(currentContent), string(proposedContent), "current tool config", "proposed tool config"),
+	}
+	status := report.StatusUnchanged
+	if whatIf.Diff != "" {
+		status = report.StatusChanged
+		log.Printf("INFO  The proposed tool config changes the generated config of %v:\n----------\n%v----------", repo, whatIf.Diff)
+	} else {
+		log.Printf("INFO  The proposed tool config generates the same config for %v.", repo)
+	}
+	result := report.NewRepoResult(repo, status, config.ChangeInfo{})
+	result.WhatIf = whatIf
+	return result
+}
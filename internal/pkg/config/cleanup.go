@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// Cleanup modes, for the sections of the cleanup policy.
+const (
+	CleanupRemove   = "remove"
+	CleanupWarnOnly = "warn-only"
+	CleanupOff      = "off"
+)
+
+// cleanupModes holds the modes accepted by the sections of the cleanup policy.
+var cleanupModes = []string{CleanupRemove, CleanupWarnOnly, CleanupOff}
+
+// Cleanup holds the policy for entries of existing configs which don't apply anymore: remove them, only list them
+// as warnings, or leave them as they are. Unset sections fall back to remove-missing-directories and
+// remove-unused-registries, except for missing-manifests (updates whose directory exists, but contains no manifests
+// of their ecosystem), which is off unless set. Before removing an update whose directory is missing on the base branch, the
+// directory can be looked up on further branches (check-branches, names or patterns like release/*), and on the
+// update's target-branch (check-target-branch).
+type Cleanup struct {
+	MissingDirectories string   `yaml:"missing-directories"`
+	UnusedRegistries   string   `yaml:"unused-registries"`
+	MissingManifests   string   `yaml:"missing-manifests"`
+	CheckBranches      []string `yaml:"check-branches"`
+	CheckTargetBranch  bool     `yaml:"check-target-branch"`
+}
+
+// MatchingBranches returns the branches of a repo matching check-branches, in the given order.
+func (cleanup Cleanup) MatchingBranches(branches []string) []string {
+	matching := make([]string, 0)
+	for _, branch := range branches {
+		for _, pattern := range cleanup.CheckBranches {
+			if matched, _ := path.Match(pattern, branch); matched {
+				matching = append(matching, branch)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// cleanupBranches returns the branches other than the base branch to look up the directories of an update on,
+// before removing it.
+func (config *ToolConfig) cleanupBranches(update Update, baseBranch string) []string {
+	branches := make([]string, 0)
+	candidates := config.CheckedBranches
+	if config.Cleanup.CheckTargetBranch && update.TargetBranch != "" {
+		candidates = append([]string{update.TargetBranch}, candidates...)
+	}
+	for _, branch := range candidates {
+		if branch != baseBranch && !util.Contains(branches, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+// MissingDirectoriesCleanup returns the cleanup mode for updates whose directory is missing.
+func (config *ToolConfig) MissingDirectoriesCleanup() string {
+	return cleanupMode(config.Cleanup.MissingDirectories, config.RemoveMissingDirectories)
+}
+
+// MissingManifestsCleanup returns the cleanup mode for updates whose directory contains no manifests of their ecosystem.
+func (config *ToolConfig) MissingManifestsCleanup() string {
+	return cleanupMode(config.Cleanup.MissingManifests, false)
+}
+
+// UnusedRegistriesCleanup returns the cleanup mode for registries not referenced by any update.
+func (config *ToolConfig) UnusedRegistriesCleanup() string {
+	return cleanupMode(config.Cleanup.UnusedRegistries, config.RemoveUnusedRegistries)
+}
+
+// cleanupMode returns the mode of a cleanup section, or the one of the legacy flag if unset.
+func cleanupMode(mode string, remove bool) string {
+	if mode != "" {
+		return mode
+	}
+	if remove {
+		return CleanupRemove
+	}
+	return CleanupOff
+}
+
+// validateCleanup returns the problems of the cleanup policy.
+func (config *ToolConfig) validateCleanup() []string {
+	problems := make([]string, 0)
+	for _, section := range []struct {
+		key  string
+		mode string
+	}{
+		{"missing-directories", config.Cleanup.MissingDirectories},
+		{"unused-registries", config.Cleanup.UnusedRegistries},
+		{"missing-manifests", config.Cleanup.MissingManifests},
+	} {
+		if section.mode != "" && !util.Contains(cleanupModes, section.mode) {
+			problems = append(problems, fmt.Sprintf("cleanup.%v: %v is none of %v", section.key, section.mode, cleanupModes))
+		}
+	}
+	for _, pattern := range config.Cleanup.CheckBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("cleanup.check-branches: invalid pattern %v: %v", pattern, err))
+		}
+	}
+	return problems
+}
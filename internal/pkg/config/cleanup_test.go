@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestUpdateConfigCleanup(t *testing.T) {
+	checkDirectoryFn := func(directory string, _ LoadFileContentParameters) (bool, error) {
+		return directory != "/gone", nil
+	}
+	for _, tt := range []struct {
+		toolConfig         ToolConfig
+		expectedUpdates    int
+		expectedRegistries []string
+		expectedRemoved    int
+		expectedWarnings   []string
+	}{
+		{ToolConfig{}, 2, []string{"npm-reg", "unused-reg"}, 0, []string{}},
+		{ToolConfig{RemoveMissingDirectories: true, RemoveUnusedRegistries: true}, 1, []string{"npm-reg"}, 2, []string{}},
+		{
+			ToolConfig{Cleanup: Cleanup{MissingDirectories: CleanupRemove, UnusedRegistries: CleanupRemove}},
+			1,
+			[]string{"npm-reg"},
+			2,
+			[]string{},
+		},
+		{
+			ToolConfig{RemoveMissingDirectories: true, RemoveUnusedRegistries: true, Cleanup: Cleanup{MissingDirectories: CleanupOff, UnusedRegistries: CleanupOff}},
+			2,
+			[]string{"npm-reg", "unused-reg"},
+			0,
+			[]string{},
+		},
+		{
+			ToolConfig{Cleanup: Cleanup{MissingDirectories: CleanupWarnOnly, UnusedRegistries: CleanupWarnOnly}},
+			2,
+			[]string{"npm-reg", "unused-reg"},
+			0,
+			[]string{"npm update for /gone kept, although the directory does not exist", "registry unused-reg kept, although not referenced by any update"},
+		},
+	} {
+		config := DependabotConfig{
+			Registries: map[string]Registry{
+				"npm-reg":    {Type: "npm-registry"},
+				"unused-reg": {Type: "docker-registry"},
+			},
+			Updates: []Update{
+				{PackageEcosystem: "npm", Directory: "/app", Registries: []string{"npm-reg"}},
+				{PackageEcosystem: "npm", Directory: "/gone"},
+			},
+		}
+		changeInfo := config.UpdateConfig(map[string]string{}, tt.toolConfig, LoadFileContentDummy, checkDirectoryFn, LoadFileContentParameters{})
+		if len(config.Updates) != tt.expectedUpdates {
+			t.Errorf("UpdateConfig() failed; expected %v updates got %v", tt.expectedUpdates, len(config.Updates))
+		}
+		if got := sortedRegistryNames(config.Registries); !reflect.DeepEqual(tt.expectedRegistries, got) {
+			t.Errorf("UpdateConfig() failed; expected registries %v got %v", tt.expectedRegistries, got)
+		}
+		if got := changeInfo.RemovalCount(); got != tt.expectedRemoved {
+			t.Errorf("UpdateConfig() failed; expected %v removals got %v", tt.expectedRemoved, got)
+		}
+		if len(tt.expectedWarnings)+len(changeInfo.Warnings) > 0 && !reflect.DeepEqual(tt.expectedWarnings, changeInfo.Warnings) {
+			t.Errorf("UpdateConfig() failed; expected warnings %v got %v", tt.expectedWarnings, changeInfo.Warnings)
+		}
+	}
+}
+
+func TestUpdateConfigCleanupBranches(t *testing.T) {
+	branchFiles := map[string][]string{
+		"":            {"app/package.json"},
+		"develop":     {"app/package.json", "next/package.json"},
+		"release/1.0": {"app/package.json", "legacy/package.json"},
+	}
+	checkDirectoryFn := func(directory string, params LoadFileContentParameters) (bool, error) {
+		files, found := branchFiles[params.Ref]
+		if !found {
+			return false, errors.New("404 Branch not found")
+		}
+		return DirectoryInFileList(directory, files), nil
+	}
+	for _, tt := range []struct {
+		cleanup         Cleanup
+		checkedBranches []string
+		expectedRemoved []UpdateInfo
+	}{
+		{Cleanup{}, nil, []UpdateInfo{{Type: "npm", Directory: "/next"}, {Type: "npm", Directory: "/legacy"}}},
+		{Cleanup{CheckTargetBranch: true}, nil, []UpdateInfo{{Type: "npm", Directory: "/legacy"}}},
+		{Cleanup{CheckTargetBranch: true}, []string{"release/1.0"}, []UpdateInfo{}},
+		{Cleanup{}, []string{"develop", "release/1.0"}, []UpdateInfo{}},
+	} {
+		config := DependabotConfig{
+			Updates: []Update{
+				{PackageEcosystem: "npm", Directory: "/app"},
+				{PackageEcosystem: "npm", Directory: "/next", TargetBranch: "develop"},
+				{PackageEcosystem: "npm", Directory: "/legacy"},
+			},
+		}
+		toolConfig := ToolConfig{RemoveMissingDirectories: true, Cleanup: tt.cleanup, CheckedBranches: tt.checkedBranches}
+		changeInfo := config.UpdateConfig(map[string]string{}, toolConfig, LoadFileContentDummy, checkDirectoryFn, LoadFileContentParameters{})
+		if !reflect.DeepEqual(tt.expectedRemoved, changeInfo.RemovedUpdates) {
+			t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+	}
+}
+
+func TestMatchingBranches(t *testing.T) {
+	cleanup := Cleanup{CheckBranches: []string{"develop", "release/*"}}
+	got := cleanup.MatchingBranches([]string{"main", "develop", "release/1.0", "release/1.0/hotfix", "feature/x"})
+	if expected := []string{"develop", "release/1.0"}; !reflect.DeepEqual(expected, got) {
+		t.Errorf("MatchingBranches() failed; expected %v got %v", expected, got)
+	}
+}
+
+func TestValidateCleanup(t *testing.T) {
+	config := ToolConfig{Cleanup: Cleanup{MissingDirectories: "keep", UnusedRegistries: CleanupWarnOnly, CheckBranches: []string{"release/["}}}
+	problems := config.Validate()
+	if !util.Contains(problems, "cleanup.missing-directories: keep is none of [remove warn-only off]") {
+		t.Errorf("Validate() failed; expected a problem for the cleanup mode, got %v", problems)
+	}
+	if !util.Contains(problems, "cleanup.check-branches: invalid pattern release/[: syntax error in pattern") {
+		t.Errorf("Validate() failed; expected a problem for the branch pattern, got %v", problems)
+	}
+	for _, problem := range problems {
+		if problem == "cleanup.unused-registries: warn-only is none of [remove warn-only off]" {
+			t.Errorf("Validate() failed; unexpected problem %v", problem)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CompanionFile holds a file managed next to the config, e.g. a workflow auto-merging the PRs of dependabot.
+// Its content is rendered from the template, with the placeholders {{org}}, {{repo}} and {{version}}.
+type CompanionFile struct {
+	Path     string `yaml:"path"`
+	Template string `yaml:"template"`
+}
+
+// RenderCompanionFiles returns the content of the companion files, by path.
+func (config *ToolConfig) RenderCompanionFiles(data TemplateData) (map[string]string, error) {
+	contents := make(map[string]string, len(config.CompanionFiles))
+	for _, file := range config.CompanionFiles {
+		content, err := renderTemplate(file.Template, data)
+		if err != nil {
+			return nil, fmt.Errorf("companion file %v: %w", file.Path, err)
+		}
+		contents[file.Path] = content
+	}
+	return contents, nil
+}
+
+// validateCompanionFiles returns the problems of the companion files.
+func (config *ToolConfig) validateCompanionFiles() []string {
+	problems := make([]string, 0)
+	paths := map[string]bool{}
+	for i, file := range config.CompanionFiles {
+		key := fmt.Sprintf("companion-files[%v]", i)
+		cleaned := path.Clean(file.Path)
+		switch {
+		case file.Path == "":
+			problems = append(problems, key+".path: not set")
+		case path.IsAbs(file.Path) || cleaned != file.Path || strings.HasPrefix(cleaned, "../"):
+			problems = append(problems, fmt.Sprintf("%v.path: %v is no relative path within the repo", key, file.Path))
+		case cleaned == ".github/dependabot.yml":
+			problems = append(problems, key+".path: the config is managed by dependabutler already")
+		case paths[cleaned]:
+			problems = append(problems, fmt.Sprintf("%v.path: %v is listed twice", key, file.Path))
+		}
+		paths[cleaned] = true
+		if _, err := renderTemplate(file.Template, TemplateData{}); err != nil {
+			problems = append(problems, fmt.Sprintf("%v.template: %v", key, err))
+		}
+	}
+	return problems
+}
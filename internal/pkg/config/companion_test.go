@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderCompanionFiles(t *testing.T) {
+	config := ToolConfig{CompanionFiles: []CompanionFile{
+		{Path: ".github/workflows/dependabot-auto-merge.yml", Template: "name: auto-merge {{repo}}\n"},
+		{Path: ".github/dependabot-auto-merge.yml", Template: "org: {{org}}\n"},
+	}}
+	got, err := config.RenderCompanionFiles(TemplateData{Org: "acme", Repo: "service"})
+	expected := map[string]string{
+		".github/workflows/dependabot-auto-merge.yml": "name: auto-merge service\n",
+		".github/dependabot-auto-merge.yml":           "org: acme\n",
+	}
+	if err != nil || !reflect.DeepEqual(expected, got) {
+		t.Errorf("RenderCompanionFiles() failed; expected %v got %v, error %v", expected, got, err)
+	}
+	config.CompanionFiles = append(config.CompanionFiles, CompanionFile{Path: "broken.yml", Template: "{{unknown}}"})
+	if _, err := config.RenderCompanionFiles(TemplateData{}); err == nil {
+		t.Errorf("RenderCompanionFiles() failed; expected an error for an unknown placeholder")
+	}
+}
+
+func TestValidateCompanionFiles(t *testing.T) {
+	config := ToolConfig{CompanionFiles: []CompanionFile{
+		{Path: ".github/auto-merge.yml", Template: "repo: {{repo}}"},
+		{Path: "", Template: "x"},
+		{Path: "../outside.yml", Template: "x"},
+		{Path: ".github/dependabot.yml", Template: "x"},
+		{Path: ".github/auto-merge.yml", Template: "{{unknown}}"},
+	}}
+	expected := []string{
+		"companion-files[1].path: not set",
+		"companion-files[2].path: ../outside.yml is no relative path within the repo",
+		"companion-files[3].path: the config is managed by dependabutler already",
+		"companion-files[4].path: .github/auto-merge.yml is listed twice",
+		`companion-files[4].template: template: :1: function "unknown" not defined`,
+	}
+	if got := config.validateCompanionFiles(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("validateCompanionFiles() failed; expected %v got %v", expected, got)
+	}
+}
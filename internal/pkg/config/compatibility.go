@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ghesMinimumVersions holds the first GitHub Enterprise Server versions supporting the fields and package ecosystems
+// generated by dependabutler, which are not supported by all versions. GitHub.com supports all of them.
+var ghesMinimumVersions = map[string]string{
+	"groups":         "3.10",
+	"applies-to":     "3.13",
+	"devcontainers":  "3.14",
+	"directories":    "3.15",
+	"bun":            "3.16",
+	"docker-compose": "3.17",
+	"dotnet-sdk":     "3.17",
+	"helm":           "3.17",
+	"uv":             "3.17",
+	"cooldown":       "3.18",
+}
+
+// targetPrefix is the prefix of targets naming a GHES version, e.g. ghes-3.12.
+const targetPrefix = "ghes-"
+
+// TargetSupports returns if the target of the generated configs supports a field or package ecosystem.
+// Without target, or with github.com, everything is supported.
+func (config *ToolConfig) TargetSupports(feature string) bool {
+	if !strings.HasPrefix(config.Target, targetPrefix) {
+		return true
+	}
+	minimum, restricted := ghesMinimumVersions[feature]
+	if !restricted {
+		return true
+	}
+	return versionAtLeast(strings.TrimPrefix(config.Target, targetPrefix), minimum)
+}
+
+// applyTarget removes the fields of a new update the target doesn't support.
+func (config *ToolConfig) applyTarget(update *Update) {
+	if !config.TargetSupports("cooldown") {
+		update.Cooldown = nil
+	}
+	if !config.TargetSupports("groups") {
+		update.Groups = nil
+	}
+	if !config.TargetSupports("applies-to") && len(update.Groups) > 0 {
+		// groups for security updates aren't possible yet, the other ones apply to version updates anyway
+		groups := make(map[string]Group, len(update.Groups))
+		for name, group := range update.Groups {
+			if group.AppliesTo != groupSecurityUpdates {
+				group.AppliesTo = ""
+				groups[name] = group
+			}
+		}
+		update.Groups = groups
+	}
+}
+
+// validateTarget returns the problems of the target.
+func (config *ToolConfig) validateTarget() []string {
+	if config.Target == "" || config.Target == "github.com" {
+		return nil
+	}
+	if version, found := strings.CutPrefix(config.Target, targetPrefix); found && parseVersion(version) != nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("target: %v is neither github.com nor ghes-<major>.<minor>", config.Target)}
+}
+
+// versionAtLeast returns if a major.minor version is the minimum version or a later one. Invalid versions are not.
+func versionAtLeast(version string, minimum string) bool {
+	v, m := parseVersion(version), parseVersion(minimum)
+	if v == nil || m == nil {
+		return false
+	}
+	return v[0] > m[0] || v[0] == m[0] && v[1] >= m[1]
+}
+
+// parseVersion returns the major and minor numbers of a version, or nil if it's invalid.
+func parseVersion(version string) []int {
+	major, minor, found := strings.Cut(version, ".")
+	if !found {
+		return nil
+	}
+	majorNumber, errMajor := strconv.Atoi(major)
+	minorNumber, errMinor := strconv.Atoi(minor)
+	if errMajor != nil || errMinor != nil {
+		return nil
+	}
+	return []int{majorNumber, minorNumber}
+}
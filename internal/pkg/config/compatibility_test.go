@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestTargetSupports(t *testing.T) {
+	for _, tt := range []struct {
+		target   string
+		feature  string
+		expected bool
+	}{
+		{"", "cooldown", true},
+		{"github.com", "uv", true},
+		{"ghes-3.12", "groups", true},
+		{"ghes-3.12", "cooldown", false},
+		{"ghes-3.12", "uv", false},
+		{"ghes-3.12", "npm", true},
+		{"ghes-3.18", "cooldown", true},
+		{"ghes-4.0", "cooldown", true},
+	} {
+		toolConfig := ToolConfig{Target: tt.target}
+		if got := toolConfig.TargetSupports(tt.feature); got != tt.expected {
+			t.Errorf("TargetSupports(%v, %v) failed; expected %v got %v", tt.target, tt.feature, tt.expected, got)
+		}
+	}
+}
+
+func TestUpdateConfigTarget(t *testing.T) {
+	toolConfig := ToolConfig{
+		Target: "ghes-3.12",
+		UpdateDefaults: UpdateDefaults{
+			Cooldown: &Cooldown{DefaultDays: 3},
+			Groups: map[string]Group{
+				"all":      {Patterns: []string{"*"}},
+				"security": {AppliesTo: "security-updates", Patterns: []string{"*"}},
+			},
+		},
+	}
+	dependabotConfig := DependabotConfig{}
+	manifests := map[string]string{"package.json": "npm", "pyproject.toml": "uv"}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expected := []Update{{PackageEcosystem: "npm", Directory: "/", Groups: map[string]Group{"all": {Patterns: []string{"*"}}}}}
+	if !reflect.DeepEqual(dependabotConfig.Updates, expected) {
+		t.Errorf("UpdateConfig() failed; expected updates %v got %v", expected, dependabotConfig.Updates)
+	}
+	expectedSkipped := []SkippedInfo{{Type: "uv", File: "pyproject.toml", Reason: "not supported by ghes-3.12"}}
+	if !reflect.DeepEqual(changeInfo.SkippedManifests, expectedSkipped) {
+		t.Errorf("UpdateConfig() failed; expected skipped manifests %v got %v", expectedSkipped, changeInfo.SkippedManifests)
+	}
+
+	// from ghes-3.13 on, groups for security updates are kept
+	toolConfig.Target = "ghes-3.13"
+	dependabotConfig = DependabotConfig{}
+	dependabotConfig.UpdateConfig(map[string]string{"package.json": "npm"}, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	if groups := dependabotConfig.Updates[0].Groups; len(groups) != 2 || groups["security"].AppliesTo != "security-updates" {
+		t.Errorf("UpdateConfig() failed; expected the group for security updates, got %v", groups)
+	}
+	problems := (&ToolConfig{Target: "ghes-3"}).Validate()
+	if !util.Contains(problems, "target: ghes-3 is neither github.com nor ghes-<major>.<minor>") {
+		t.Errorf("Validate() failed; expected a problem for an invalid target, got %v", problems)
+	}
+}
@@ -3,13 +3,18 @@ package config
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/getyourguide/dependabutler/internal/pkg/util"
 	"github.com/google/go-github/v50/github"
@@ -19,6 +24,33 @@ import (
 var (
 	manifestFilePatterns      map[string]*regexp.Regexp
 	manifestIgnoreFilePattern *regexp.Regexp
+	manifestIgnoreTypePattern map[string]*regexp.Regexp
+	manifestDirectoryRules    map[string]DirectoryRule
+	secretReferencePattern    = regexp.MustCompile(`\$\{\{\s*secrets\.([A-Za-z0-9_]+)\s*\}\}`)
+	templateActionPattern     = regexp.MustCompile(`\{\{[^}]*\}\}`)
+	// supportedEcosystems holds the package ecosystems supported by dependabot.
+	supportedEcosystems = []string{
+		"bun", "bundler", "cargo", "composer", "devcontainers", "docker", "docker-compose", "dotnet-sdk", "elm",
+		"gitsubmodule", "github-actions", "gomod", "gradle", "helm", "maven", "mix", "npm", "nuget", "pip", "pub",
+		"swift", "terraform", "uv",
+	}
+	// defaultBetaEcosystems holds the package ecosystems dependabot only supports with enable-beta-ecosystems, unless
+	// given by beta-ecosystems.
+	defaultBetaEcosystems = []string{"bun"}
+	// exactDirectoryEcosystems holds the package ecosystems, for which an update only covers its directory itself.
+	// Terraform modules are independent from the root module in the parent directory.
+	exactDirectoryEcosystems = []string{"terraform"}
+	// groupVersionUpdates and groupSecurityUpdates are the kinds of updates a group applies to, version updates by default.
+	groupVersionUpdates  = "version-updates"
+	groupSecurityUpdates = "security-updates"
+	// hexOrganization is the registry type of hex organizations, identified by name instead of URL.
+	hexOrganization = "hex-organization"
+	// projectFiles holds the build tool files in subdirectories of the project directory they belong to, relative to it:
+	// wrappers, version catalogs and buildSrc.
+	projectFiles = []string{
+		"gradle/wrapper/gradle-wrapper.properties", ".mvn/wrapper/maven-wrapper.properties", "gradle/libs.versions.toml",
+		"buildSrc/build.gradle", "buildSrc/build.gradle.kts", "buildSrc/settings.gradle", "buildSrc/settings.gradle.kts",
+	}
 )
 
 // InitializePatterns pre-compiles manifest file name patterns
@@ -31,16 +63,133 @@ func (config *ToolConfig) InitializePatterns() {
 	if config.ManifestIgnorePattern != "" {
 		manifestIgnoreFilePattern = util.CompileRePattern(config.ManifestIgnorePattern)
 	}
+	manifestIgnoreTypePattern = map[string]*regexp.Regexp{}
+	for manifestType, pattern := range config.ManifestIgnorePatterns {
+		manifestIgnoreTypePattern[manifestType] = util.CompileRePattern(pattern)
+	}
+	localScan = localScanOptions{
+		skipDirectories: config.ScanSkipDirectories,
+		submodules:      config.ScanSubmodules,
+		symlinks:        config.ScanSymlinks,
+		maxDepth:        config.ScanMaxDepth,
+	}
+	manifestDirectoryRules = map[string]DirectoryRule{}
+	for manifestType, rule := range config.ManifestDirectories {
+		if directoryRule, err := ParseDirectoryRule(rule); err == nil {
+			manifestDirectoryRules[manifestType] = directoryRule
+		}
+	}
 }
 
 // ToolConfig holds the tool's configuration defined in config.yml
 type ToolConfig struct {
-	UpdateDefaults        UpdateDefaults               `yaml:"update-defaults"`
-	UpdateOverrides       map[string]UpdateDefaults    `yaml:"update-overrides"`
-	Registries            map[string]DefaultRegistries `yaml:"registries"`
-	ManifestPatterns      map[string]string            `yaml:"manifest-patterns"`
-	ManifestIgnorePattern string                       `yaml:"manifest-ignore-pattern"`
-	PullRequestParameters PullRequestParameters        `yaml:"pull-request-parameters"`
+	UpdateDefaults           UpdateDefaults               `yaml:"update-defaults"`
+	UpdateOverrides          map[string]UpdateDefaults    `yaml:"update-overrides"`
+	Registries               map[string]DefaultRegistries `yaml:"registries"`
+	ManifestPatterns         map[string]string            `yaml:"manifest-patterns"`
+	ManifestIgnorePattern    string                       `yaml:"manifest-ignore-pattern"`
+	ManifestIgnorePatterns   map[string]string            `yaml:"manifest-ignore-patterns"`
+	ScanSkipDirectories      []string                     `yaml:"scan-skip-directories"`
+	ScanSubmodules           bool                         `yaml:"scan-submodules"`
+	ScanSymlinks             bool                         `yaml:"scan-symlinks"`
+	ScanMaxDepth             int                          `yaml:"scan-max-depth"`
+	PullRequestParameters    PullRequestParameters        `yaml:"pull-request-parameters"`
+	CheckSecretAccess        bool                         `yaml:"check-secret-access"`
+	GrantSecretAccess        bool                         `yaml:"grant-secret-access"`
+	EnsureSecurityUpdates    bool                         `yaml:"ensure-security-updates"`
+	RemoveUnusedRegistries   bool                         `yaml:"remove-unused-registries"`
+	RemoveMissingDirectories bool                         `yaml:"remove-missing-directories"`
+	Cleanup                  Cleanup                      `yaml:"cleanup"`
+	GitHubAPIURL             string                       `yaml:"github-api-url"`
+	GitHubUploadURL          string                       `yaml:"github-upload-url"`
+	RepoOverrides            map[string]RepoOverride      `yaml:"repo-overrides"`
+	RegistryReferences       string                       `yaml:"registry-references"`
+	SeparateWorkspaceUpdates bool                         `yaml:"separate-workspace-updates"`
+	ConsolidateDirectories   bool                         `yaml:"consolidate-directories"`
+	MergeDuplicateUpdates    bool                         `yaml:"merge-duplicate-updates"`
+	RateLimitBuffer          int                          `yaml:"rate-limit-buffer"`
+	Credentials              Credentials                  `yaml:"credentials"`
+	ProcessSubmodules        bool                         `yaml:"process-submodules"`
+	TeamOwnership            map[string][]string          `yaml:"team-ownership"`
+	StateFile                string                       `yaml:"state-file"`
+	ManifestEcosystems       map[string]string            `yaml:"manifest-ecosystems"`
+	ManifestDirectories      map[string]string            `yaml:"manifest-directories"`
+	EnabledEcosystems        []string                     `yaml:"enabled-ecosystems"`
+	DisabledEcosystems       []string                     `yaml:"disabled-ecosystems"`
+	EnableBetaEcosystems     *bool                        `yaml:"enable-beta-ecosystems"`
+	BetaEcosystems           []string                     `yaml:"beta-ecosystems"`
+	RemovalLimits            RemovalLimits                `yaml:"removal-limits"`
+	GeneratedComment         string                       `yaml:"generated-comment"`
+	MinimalDiff              bool                         `yaml:"minimal-diff"`
+	MaxFileSize              int64                        `yaml:"max-file-size"`
+	SchedulePolicies         []SchedulePolicy             `yaml:"schedule-policies"`
+	Enforce                  []string                     `yaml:"enforce"`
+	Target                   string                       `yaml:"target"`
+	FailureIssues            FailureIssues                `yaml:"failure-issues"`
+	IssueFallback            IssueFallback                `yaml:"issue-fallback"`
+	Notification             Notification                 `yaml:"notification"`
+	CompanionFiles           []CompanionFile              `yaml:"companion-files"`
+	RepoFilter               RepoFilter                   `yaml:"repo-filter"`
+	// PathFilter restricts the processing to a directory and its subdirectories, set by the -path parameter.
+	PathFilter string `yaml:"-"`
+	// Trace enables recording the decisions taken for each manifest, set by the -trace parameter.
+	Trace bool `yaml:"-"`
+	// RepoFacts holds the attributes of the repo processed, for the schedule policies.
+	RepoFacts RepoFacts `yaml:"-"`
+	// ConfirmRemovals applies removals exceeding the removal limits, set by the -confirmRemovals parameter.
+	ConfirmRemovals bool `yaml:"-"`
+	// OnlyNewRepos restricts the processing to repos without a config, set by the -onlyNewRepos parameter.
+	OnlyNewRepos bool `yaml:"-"`
+	// CheckedBranches holds the branches of the repo processed matching cleanup.check-branches.
+	CheckedBranches []string `yaml:"-"`
+}
+
+// RemovalLimits holds the limits for removing updates and registries, protecting against mass deletions caused by
+// mistakes in patterns or in the tool config. Removals exceeding them are paused, and reported for confirmation.
+type RemovalLimits struct {
+	MaxPerRun         int `yaml:"max-per-run"`
+	MaxPercentPerRepo int `yaml:"max-percent-per-repo"`
+}
+
+// Exceeded returns if the removals of a repo exceed the limits, given the number of updates and registries
+// of its config before, and the number of removals of the run so far.
+func (limits RemovalLimits) Exceeded(removals int, entries int, runRemovals int) bool {
+	if limits.MaxPerRun > 0 && runRemovals+removals > limits.MaxPerRun {
+		return true
+	}
+	return limits.MaxPercentPerRepo > 0 && removals*100 > limits.MaxPercentPerRepo*entries
+}
+
+// FailureIssues holds the settings for opening an issue for repos failing in consecutive runs, tracked in the state file.
+type FailureIssues struct {
+	// Repo is the repository of the org the issues are opened in, e.g. an ops repository.
+	Repo string `yaml:"repo"`
+	// Threshold is the number of consecutive failed runs after which an issue is opened.
+	Threshold int      `yaml:"threshold"`
+	Labels    []string `yaml:"labels"`
+}
+
+// IssueFallback holds the settings for opening an issue in repos the PR can't be created for, due to missing
+// permissions or branch protections, with the proposed changes of the config.
+type IssueFallback struct {
+	Enabled bool     `yaml:"enabled"`
+	Labels  []string `yaml:"labels"`
+}
+
+// Notification holds the endpoint the summary of a run is posted to when it finishes.
+type Notification struct {
+	// URL is the Slack incoming webhook or HTTP endpoint, or a secret store reference to it.
+	URL string `yaml:"url"`
+	// Format is slack (default) for a message, or json for the totals of the run.
+	Format string `yaml:"format"`
+}
+
+// Credentials holds the credentials used by dependabutler, or references to them in a secret store.
+type Credentials struct {
+	GitHubToken   string `yaml:"github-token"`
+	GPGPrivateKey string `yaml:"gpg-private-key"`
+	GPGPassphrase string `yaml:"gpg-passphrase"`
+	WebhookSecret string `yaml:"webhook-secret"`
 }
 
 // DefaultRegistries holds the default registries for new update definitions
@@ -48,13 +197,107 @@ type DefaultRegistries map[string]DefaultRegistry
 
 // PullRequestParameters holds the parameters for PRs created by dependabutler
 type PullRequestParameters struct {
-	AuthorName             string `yaml:"author-name"`
-	AuthorEmail            string `yaml:"author-email"`
-	CommitMessage          string `yaml:"commit-message"`
-	PRTitle                string `yaml:"pr-title"`
-	BranchName             string `yaml:"branch-name"`
-	BranchNameRandomSuffix bool   `yaml:"branch-name-random-suffix"`
-	SleepAfterPRAction     int    `yaml:"sleep-after-pr-action"`
+	AuthorName              string   `yaml:"author-name"`
+	AuthorEmail             string   `yaml:"author-email"`
+	CommitMessage           string   `yaml:"commit-message"`
+	PRTitle                 string   `yaml:"pr-title"`
+	BranchName              string   `yaml:"branch-name"`
+	BranchNameRandomSuffix  bool     `yaml:"branch-name-random-suffix"`
+	SleepAfterPRAction      int      `yaml:"sleep-after-pr-action"`
+	AutoMerge               string   `yaml:"auto-merge"`
+	ReviewersFromCodeowners bool     `yaml:"reviewers-from-codeowners"`
+	AssigneesFromCodeowners bool     `yaml:"assignees-from-codeowners"`
+	CodeownersPath          string   `yaml:"codeowners-path"`
+	Labels                  []string `yaml:"labels"`
+	CommitSigning           string   `yaml:"commit-signing"`
+	IncludeDiff             bool     `yaml:"include-diff"`
+	GitHubAppSlug           string   `yaml:"github-app-slug"`
+	// BranchNamePattern is the regular expression matching the branch names of all runs, set by Render. Placeholders
+	// changing between runs, like {{date}}, match any text.
+	BranchNamePattern string `yaml:"-"`
+}
+
+// TemplateData holds the values available as placeholders in the pull request parameters.
+type TemplateData struct {
+	Org         string
+	Repo        string
+	Date        time.Time
+	ChangeCount int
+	Version     string
+}
+
+// Render returns the pull request parameters, with the placeholders in commit message, PR title and branch name
+// replaced: {{org}}, {{repo}}, {{date}} (YYYY-MM-DD) and {{change-count}}.
+func (prParams PullRequestParameters) Render(data TemplateData) (PullRequestParameters, error) {
+	pattern, err := branchNamePattern(prParams.BranchName, data)
+	if err != nil {
+		return prParams, err
+	}
+	prParams.BranchNamePattern = pattern
+	for _, field := range []*string{&prParams.CommitMessage, &prParams.PRTitle, &prParams.BranchName} {
+		rendered, err := renderTemplate(*field, data)
+		if err != nil {
+			return prParams, err
+		}
+		*field = rendered
+	}
+	return prParams, nil
+}
+
+// branchNamePattern returns the regular expression matching a branch name template rendered on any run: the
+// placeholders whose value for the repo is the same on another day and with another change count are rendered, the
+// others match any text.
+func branchNamePattern(branchName string, data TemplateData) (string, error) {
+	other := data
+	other.Date, other.ChangeCount = data.Date.AddDate(0, 0, 1), data.ChangeCount+1
+	var pattern strings.Builder
+	last := 0
+	for _, match := range templateActionPattern.FindAllStringIndex(branchName, -1) {
+		pattern.WriteString(regexp.QuoteMeta(branchName[last:match[0]]))
+		action := branchName[match[0]:match[1]]
+		rendered, err := renderTemplate(action, data)
+		if err != nil {
+			return "", err
+		}
+		if renderedOther, _ := renderTemplate(action, other); renderedOther != rendered {
+			pattern.WriteString(".+")
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(rendered))
+		}
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(branchName[last:]))
+	return pattern.String(), nil
+}
+
+// RenderGeneratedComment returns the comment for new updates, with the placeholders replaced, as for the pull request
+// parameters. {{version}} is the version of dependabutler.
+func (config *ToolConfig) RenderGeneratedComment(data TemplateData) (string, error) {
+	return renderTemplate(config.GeneratedComment, data)
+}
+
+// renderTemplate replaces the placeholders in a text.
+func renderTemplate(text string, data TemplateData) (string, error) {
+	funcs := template.FuncMap{
+		"org":         func() string { return data.Org },
+		"repo":        func() string { return data.Repo },
+		"date":        func() string { return data.Date.Format("2006-01-02") },
+		"changeCount": func() int { return data.ChangeCount },
+		"version":     func() string { return data.Version },
+	}
+	// template function names can't contain a dash
+	text = templateActionPattern.ReplaceAllStringFunc(text, func(action string) string {
+		return strings.ReplaceAll(action, "change-count", "changeCount")
+	})
+	tmpl, err := template.New("").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
 }
 
 // DefaultRegistry holds the config items of a default registry
@@ -63,17 +306,37 @@ type DefaultRegistry struct {
 	URL                     string   `yaml:"url"`
 	Username                string   `yaml:"username,omitempty"`
 	Password                string   `yaml:"password,omitempty"`
+	Key                     string   `yaml:"key,omitempty"`
+	Token                   string   `yaml:"token,omitempty"`
+	Organization            string   `yaml:"organization,omitempty"`
+	ReplacesBase            bool     `yaml:"replaces-base,omitempty"`
 	URLMatchRequired        bool     `yaml:"url-match-required,omitempty"`
 	URLMatchAdditionalFiles []string `yaml:"url-match-additional-files,omitempty"`
+	ManifestPaths           []string `yaml:"manifest-paths,omitempty"`
 }
 
 // UpdateDefaults holds the default config for new update definitions
 type UpdateDefaults struct {
-	Schedule                      Schedule      `yaml:"schedule"`
-	CommitMessage                 CommitMessage `yaml:"commit-message"`
-	OpenPullRequestsLimit         int           `yaml:"open-pull-requests-limit"`
-	InsecureExternalCodeExecution string        `yaml:"insecure-external-code-execution"`
-	RebaseStrategy                string        `yaml:"rebase-strategy"`
+	Schedule                      Schedule         `yaml:"schedule"`
+	CommitMessage                 CommitMessage    `yaml:"commit-message"`
+	OpenPullRequestsLimit         int              `yaml:"open-pull-requests-limit"`
+	InsecureExternalCodeExecution string           `yaml:"insecure-external-code-execution"`
+	RebaseStrategy                string           `yaml:"rebase-strategy"`
+	TargetBranch                  string           `yaml:"target-branch"`
+	Labels                        []string         `yaml:"labels"`
+	Reviewers                     []string         `yaml:"reviewers"`
+	Assignees                     []string         `yaml:"assignees"`
+	Allow                         []Allow          `yaml:"allow"`
+	Ignore                        []Ignore         `yaml:"ignore"`
+	Cooldown                      *Cooldown        `yaml:"cooldown"`
+	Groups                        map[string]Group `yaml:"groups"`
+}
+
+// RepoOverride holds the config overriding the defaults, for matching repositories
+type RepoOverride struct {
+	UpdateDefaults  UpdateDefaults               `yaml:"update-defaults"`
+	UpdateOverrides map[string]UpdateDefaults    `yaml:"update-overrides"`
+	Registries      map[string]DefaultRegistries `yaml:"registries"`
 }
 
 // DependabotConfig holds the configuration defined in dependabot.yml
@@ -82,6 +345,7 @@ type DependabotConfig struct {
 	Registries           map[string]Registry `yaml:"registries,omitempty"`
 	Updates              []Update            `yaml:"updates"`
 	EnableBetaEcoSystems bool                `yaml:"enable-beta-ecosystems,omitempty"`
+	usesAnchors          bool
 }
 
 // Allow holds the config items of an allow definition
@@ -100,7 +364,8 @@ type Ignore struct {
 // Update holds the config items of an update definition
 type Update struct {
 	PackageEcosystem              string           `yaml:"package-ecosystem"`
-	Directory                     string           `yaml:"directory"`
+	Directory                     string           `yaml:"directory,omitempty"`
+	Directories                   []string         `yaml:"directories,omitempty"`
 	Schedule                      Schedule         `yaml:"schedule,omitempty"`
 	Registries                    []string         `yaml:"registries,omitempty"`
 	CommitMessage                 CommitMessage    `yaml:"commit-message,omitempty"`
@@ -108,6 +373,7 @@ type Update struct {
 	Assignees                     []string         `yaml:"assignees,omitempty"`
 	Allow                         []Allow          `yaml:"allow,omitempty"`
 	Ignore                        []Ignore         `yaml:"ignore,omitempty"`
+	Cooldown                      *Cooldown        `yaml:"cooldown,omitempty"`
 	Groups                        map[string]Group `yaml:"groups,omitempty"`
 	InsecureExternalCodeExecution string           `yaml:"insecure-external-code-execution,omitempty"`
 	Labels                        []string         `yaml:"labels,omitempty"`
@@ -120,11 +386,24 @@ type Update struct {
 	TargetBranch       string   `yaml:"target-branch,omitempty"`
 	Vendor             bool     `yaml:"vendor,omitempty"`
 	VersioningStrategy string   `yaml:"versioning-strategy,omitempty"`
+	// protected is set for updates opted out of being modified or removed by dependabutler, with a comment.
+	protected bool
+}
+
+// Cooldown holds the config items of a cooldown definition
+type Cooldown struct {
+	DefaultDays     int      `yaml:"default-days,omitempty"`
+	SemverMajorDays int      `yaml:"semver-major-days,omitempty"`
+	SemverMinorDays int      `yaml:"semver-minor-days,omitempty"`
+	SemverPatchDays int      `yaml:"semver-patch-days,omitempty"`
+	Include         []string `yaml:"include,omitempty"`
+	Exclude         []string `yaml:"exclude,omitempty"`
 }
 
 // Group holds the config items of a group definition
 type Group struct {
-	Separator       string   `yaml:"dependency-type,omitempty"`
+	AppliesTo       string   `yaml:"applies-to,omitempty"`
+	DependencyType  string   `yaml:"dependency-type,omitempty"`
 	Patterns        []string `yaml:"patterns,omitempty"`
 	ExcludePatterns []string `yaml:"exclude-patterns,omitempty"`
 	UpdateTypes     []string `yaml:"update-types,omitempty"`
@@ -138,7 +417,8 @@ type Registry struct {
 	Password     string `yaml:"password,omitempty"`
 	Key          string `yaml:"key,omitempty"`
 	Token        string `yaml:"token,omitempty"`
-	ReplacesBase string `yaml:"replaces-base,omitempty"`
+	Organization string `yaml:"organization,omitempty"`
+	ReplacesBase bool   `yaml:"replaces-base,omitempty"`
 }
 
 // Schedule holds the config items of a schedule
@@ -158,21 +438,82 @@ type CommitMessage struct {
 
 // ChangeInfo holds the changes applied to a config.
 type ChangeInfo struct {
-	NewRegistries []RegistryInfo
-	NewUpdates    []UpdateInfo
+	NewRegistries       []RegistryInfo
+	NewUpdates          []UpdateInfo
+	Warnings            []string
+	RemovedRegistries   []RegistryInfo
+	RemovedUpdates      []UpdateInfo
+	AddedRegistryRefs   []RegistryRefInfo
+	MissingRegistryRefs []RegistryRefInfo
+	FixedUpdates        []FixInfo
+	SkippedManifests    []SkippedInfo
+	// UpdatedFiles holds the companion files created or updated next to the config.
+	UpdatedFiles []string
+	// PausedRemovedUpdates and PausedRemovedRegistries hold the removals not applied, as they exceeded the removal limits.
+	PausedRemovedUpdates    []UpdateInfo
+	PausedRemovedRegistries []RegistryInfo
+	// Trace holds the decisions taken for the manifests, if enabled by the -trace parameter.
+	Trace []TraceEntry
+}
+
+// HasChanges returns if any change has been applied to the config.
+// Missing registry references are reported only, they don't count as a change.
+func (changeInfo ChangeInfo) HasChanges() bool {
+	return changeInfo.ChangeCount() > 0
+}
+
+// ChangeCount returns the number of changes applied to the config.
+func (changeInfo ChangeInfo) ChangeCount() int {
+	return len(changeInfo.NewRegistries) + len(changeInfo.NewUpdates) +
+		len(changeInfo.RemovedRegistries) + len(changeInfo.RemovedUpdates) + len(changeInfo.AddedRegistryRefs) +
+		len(changeInfo.FixedUpdates) + len(changeInfo.UpdatedFiles)
+}
+
+// RemovalCount returns the number of updates and registries removed from the config.
+func (changeInfo ChangeInfo) RemovalCount() int {
+	return len(changeInfo.RemovedUpdates) + len(changeInfo.RemovedRegistries)
+}
+
+// SkippedInfo holds a manifest file which has been skipped, for the change message.
+type SkippedInfo struct {
+	Type   string `json:"type"`
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// FixInfo holds a fix applied to an existing update, for the change message.
+type FixInfo struct {
+	Type      string `json:"type"`
+	Directory string `json:"directory"`
+	Change    string `json:"change"`
+}
+
+// RegistryRefInfo holds a registry used by the manifests of an existing update, for the change message.
+type RegistryRefInfo struct {
+	Registry  string `json:"registry"`
+	Type      string `json:"type"`
+	Directory string `json:"directory"`
 }
 
 // RegistryInfo holds the properties of a registry, for the change message.
 type RegistryInfo struct {
-	Type string
-	Name string
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 // UpdateInfo holds the properties of an update, for the change message.
 type UpdateInfo struct {
-	Type      string
-	Directory string
-	File      string
+	Type      string `json:"type"`
+	Directory string `json:"directory"`
+	File      string `json:"file,omitempty"`
+}
+
+// ConfigDiff holds the differences between two revisions of a config.
+type ConfigDiff struct {
+	AddedRegistries   []RegistryInfo `json:"added-registries"`
+	RemovedRegistries []RegistryInfo `json:"removed-registries"`
+	AddedUpdates      []UpdateInfo   `json:"added-updates"`
+	RemovedUpdates    []UpdateInfo   `json:"removed-updates"`
 }
 
 // LoadFileContentParameters holds all parameters needed for the LoadFileContent function implementations.
@@ -181,6 +522,24 @@ type LoadFileContentParameters struct {
 	Org          string
 	Repo         string
 	Directory    string
+	FileList     []string
+	// FileListTruncated is set if FileList is incomplete, so it can't tell that a directory doesn't exist.
+	FileListTruncated bool
+	// Ref is the branch or commit of a remote repo to read files from, its default branch if empty.
+	Ref string
+	// MaxFileSize is the size limit of files read in bytes, DefaultMaxFileSize if 0.
+	MaxFileSize int64
+}
+
+// DefaultMaxFileSize is the size limit of files read for matching registries, if max-file-size is not set.
+const DefaultMaxFileSize = 1 << 20
+
+// FileSizeLimit returns the size limit of files read in bytes.
+func (params LoadFileContentParameters) FileSizeLimit() int64 {
+	if params.MaxFileSize > 0 {
+		return params.MaxFileSize
+	}
+	return DefaultMaxFileSize
 }
 
 // KeyValue holds a key/value pair of strings. Used as a sortable key/value map.
@@ -189,9 +548,291 @@ type KeyValue struct {
 	Value string
 }
 
+// SecretReferences returns the names of the secrets referenced by a registry, like MY_SECRET in ${{secrets.MY_SECRET}}.
+func (registry Registry) SecretReferences() []string {
+	names := make([]string, 0)
+	for _, value := range []string{registry.URL, registry.Username, registry.Password, registry.Key, registry.Token} {
+		for _, match := range secretReferencePattern.FindAllStringSubmatch(value, -1) {
+			if !util.Contains(names, match[1]) {
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names
+}
+
 // LoadFileContent is a function type for loading the content of a file.
 type LoadFileContent func(file string, params LoadFileContentParameters) string
 
+// CheckDirectoryExists is a function type for checking if a directory exists.
+// An error is returned if the check itself failed, i.e. the result is unknown.
+type CheckDirectoryExists func(directory string, params LoadFileContentParameters) (bool, error)
+
+// ForRepo returns the tool config to be used for a repository, with the matching repo overrides applied.
+// The keys of repo-overrides are repository names without the organization, or regular expressions matching the whole
+// name. The update-defaults of a repo override take precedence over the global update-overrides, and its
+// update-overrides over both.
+func (config *ToolConfig) ForRepo(repo string) ToolConfig {
+	repoConfig := *config
+	if len(config.RepoOverrides) == 0 {
+		return repoConfig
+	}
+	keys := make([]string, 0, len(config.RepoOverrides))
+	for key := range config.RepoOverrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key != repo {
+			re := util.CompileRePattern("^(" + key + ")$")
+			if re == nil || !re.MatchString(repo) {
+				continue
+			}
+		}
+		override := config.RepoOverrides[key]
+		repoConfig.UpdateDefaults = mergeUpdateDefaults(repoConfig.UpdateDefaults, override.UpdateDefaults)
+		updateOverrides := map[string]UpdateDefaults{}
+		for manifestType, overrides := range repoConfig.UpdateOverrides {
+			updateOverrides[manifestType] = mergeUpdateDefaults(overrides, override.UpdateDefaults)
+		}
+		for manifestType, overrides := range override.UpdateOverrides {
+			updateOverrides[manifestType] = mergeUpdateDefaults(updateOverrides[manifestType], overrides)
+		}
+		repoConfig.UpdateOverrides = updateOverrides
+		registries := map[string]DefaultRegistries{}
+		for manifestType, defaultRegistries := range repoConfig.Registries {
+			registries[manifestType] = defaultRegistries
+		}
+		for manifestType, defaultRegistries := range override.Registries {
+			mergedRegistries := DefaultRegistries{}
+			for name, registry := range registries[manifestType] {
+				mergedRegistries[name] = registry
+			}
+			for name, registry := range defaultRegistries {
+				mergedRegistries[name] = registry
+			}
+			registries[manifestType] = mergedRegistries
+		}
+		repoConfig.Registries = registries
+	}
+	return repoConfig
+}
+
+// TeamOf returns the team owning a repository according to team-ownership, or "" if none does.
+// The repos of a team are repository names or regular expressions matching the whole name, teams are checked in order of name.
+func (config *ToolConfig) TeamOf(repo string) string {
+	for _, team := range util.SortedKeys(config.TeamOwnership) {
+		for _, key := range config.TeamOwnership[team] {
+			if key == repo {
+				return team
+			}
+			if re := util.CompileRePattern("^(" + key + ")$"); re != nil && re.MatchString(repo) {
+				return team
+			}
+		}
+	}
+	return ""
+}
+
+// ResolveSecrets replaces the secret store references in the credentials, the notification URL, and in the usernames
+// and URLs of the default registries by their values. Passwords, keys and tokens of registries are written to
+// dependabot.yml, so these must remain references to Dependabot secrets.
+func (config *ToolConfig) ResolveSecrets(resolve func(value string) (string, error)) error {
+	gitHubToken, err := resolve(config.Credentials.GitHubToken)
+	if err != nil {
+		return err
+	}
+	config.Credentials.GitHubToken = gitHubToken
+	if config.Credentials.GPGPrivateKey, err = resolve(config.Credentials.GPGPrivateKey); err != nil {
+		return err
+	}
+	if config.Credentials.GPGPassphrase, err = resolve(config.Credentials.GPGPassphrase); err != nil {
+		return err
+	}
+	if config.Credentials.WebhookSecret, err = resolve(config.Credentials.WebhookSecret); err != nil {
+		return err
+	}
+	if config.Notification.URL, err = resolve(config.Notification.URL); err != nil {
+		return err
+	}
+	registries := []map[string]DefaultRegistries{config.Registries}
+	for _, override := range config.RepoOverrides {
+		registries = append(registries, override.Registries)
+	}
+	for _, registriesByType := range registries {
+		for _, defaultRegistries := range registriesByType {
+			for name, registry := range defaultRegistries {
+				if registry.Username, err = resolve(registry.Username); err != nil {
+					return err
+				}
+				if registry.URL, err = resolve(registry.URL); err != nil {
+					return err
+				}
+				defaultRegistries[name] = registry
+			}
+		}
+	}
+	return nil
+}
+
+// Validate returns the problems of the tool config, like invalid patterns or secrets given as plain text.
+func (config *ToolConfig) Validate() []string {
+	problems := make([]string, 0)
+	for _, manifestType := range util.SortedKeys(config.ManifestPatterns) {
+		if _, err := regexp.Compile(config.ManifestPatterns[manifestType]); err != nil {
+			problems = append(problems, fmt.Sprintf("manifest-patterns: invalid pattern for %v: %v", manifestType, err))
+		}
+	}
+	for _, manifestType := range util.SortedKeys(config.ManifestEcosystems) {
+		if ecosystem := config.ManifestEcosystems[manifestType]; !util.Contains(supportedEcosystems, ecosystem) {
+			problems = append(problems, fmt.Sprintf("manifest-ecosystems: %v of %v is not supported by dependabot", ecosystem, manifestType))
+		}
+	}
+	for _, manifestType := range util.SortedKeys(config.ManifestDirectories) {
+		if _, err := ParseDirectoryRule(config.ManifestDirectories[manifestType]); err != nil {
+			problems = append(problems, fmt.Sprintf("manifest-directories: invalid rule for %v: %v", manifestType, err))
+		}
+	}
+	for _, ecosystem := range config.EnabledEcosystems {
+		if !util.Contains(supportedEcosystems, ecosystem) {
+			problems = append(problems, fmt.Sprintf("enabled-ecosystems: %v is not supported by dependabot", ecosystem))
+		}
+	}
+	for _, ecosystem := range config.DisabledEcosystems {
+		if !util.Contains(supportedEcosystems, ecosystem) {
+			problems = append(problems, fmt.Sprintf("disabled-ecosystems: %v is not supported by dependabot", ecosystem))
+		}
+	}
+	for _, ecosystem := range config.BetaEcosystems {
+		if !util.Contains(supportedEcosystems, ecosystem) {
+			problems = append(problems, fmt.Sprintf("beta-ecosystems: %v is not supported by dependabot", ecosystem))
+		}
+	}
+	if config.MaxFileSize < 0 {
+		problems = append(problems, "max-file-size: must not be negative")
+	}
+	if config.ScanMaxDepth < 0 {
+		problems = append(problems, "scan-max-depth: must not be negative")
+	}
+	if _, err := regexp.Compile(config.ManifestIgnorePattern); err != nil {
+		problems = append(problems, fmt.Sprintf("manifest-ignore-pattern: invalid pattern: %v", err))
+	}
+	for _, manifestType := range util.SortedKeys(config.ManifestIgnorePatterns) {
+		if _, err := regexp.Compile(config.ManifestIgnorePatterns[manifestType]); err != nil {
+			problems = append(problems, fmt.Sprintf("manifest-ignore-patterns: invalid pattern for %v: %v", manifestType, err))
+		}
+	}
+	for _, key := range util.SortedKeys(config.RepoOverrides) {
+		if _, err := regexp.Compile("^(" + key + ")$"); err != nil {
+			problems = append(problems, fmt.Sprintf("repo-overrides: invalid pattern %v: %v", key, err))
+		}
+	}
+	registries := map[string]map[string]DefaultRegistries{"registries": config.Registries}
+	for key, override := range config.RepoOverrides {
+		registries["repo-overrides."+key+".registries"] = override.Registries
+	}
+	for _, section := range util.SortedKeys(registries) {
+		for _, manifestType := range util.SortedKeys(registries[section]) {
+			defaultRegistries := registries[section][manifestType]
+			for _, name := range util.SortedKeys(defaultRegistries) {
+				problems = append(problems, defaultRegistries[name].validate(fmt.Sprintf("%v.%v.%v", section, manifestType, name))...)
+			}
+		}
+	}
+	if !util.Contains(registryReferencesModes, config.RegistryReferences) {
+		problems = append(problems, fmt.Sprintf("registry-references: %v is none of %v", config.RegistryReferences, registryReferencesModes[1:]))
+	}
+	problems = append(problems, config.validateGroups()...)
+	problems = append(problems, config.validateSchedulePolicies()...)
+	problems = append(problems, config.validateEnforce()...)
+	problems = append(problems, config.validateCleanup()...)
+	problems = append(problems, config.validateCompanionFiles()...)
+	problems = append(problems, config.validateRepoFilter()...)
+	problems = append(problems, config.validateTarget()...)
+	if !util.Contains([]string{"", "slack", "json"}, config.Notification.Format) {
+		problems = append(problems, fmt.Sprintf("notification.format: %v is none of slack and json", config.Notification.Format))
+	}
+	if config.FailureIssues.Repo != "" && config.StateFile == "" {
+		problems = append(problems, "failure-issues: requires state-file, tracking the failures")
+	}
+	if config.GrantSecretAccess && !config.CheckSecretAccess {
+		problems = append(problems, "grant-secret-access: requires check-secret-access")
+	}
+	prParams := config.PullRequestParameters
+	if _, err := prParams.Render(TemplateData{}); err != nil {
+		problems = append(problems, fmt.Sprintf("pull-request-parameters: %v", err))
+	}
+	if _, err := config.RenderGeneratedComment(TemplateData{}); err != nil {
+		problems = append(problems, fmt.Sprintf("generated-comment: %v", err))
+	}
+	if prParams.AutoMerge != "" && !util.Contains([]string{"merge", "squash", "rebase"}, prParams.AutoMerge) {
+		problems = append(problems, fmt.Sprintf("pull-request-parameters.auto-merge: %v is none of merge, squash and rebase", prParams.AutoMerge))
+	}
+	if !util.Contains([]string{"", "gpg", "api"}, prParams.CommitSigning) {
+		problems = append(problems, fmt.Sprintf("pull-request-parameters.commit-signing: %v is none of gpg and api", prParams.CommitSigning))
+	}
+	if prParams.CommitSigning == "gpg" && config.Credentials.GPGPrivateKey == "" {
+		problems = append(problems, "pull-request-parameters.commit-signing: gpg requires credentials.gpg-private-key")
+	}
+	if prParams.BranchName == "" {
+		problems = append(problems, "pull-request-parameters.branch-name: not set")
+	}
+	return problems
+}
+
+// validateGroups returns the problems of the groups of the update defaults and overrides.
+func (config *ToolConfig) validateGroups() []string {
+	defaults := map[string]UpdateDefaults{"update-defaults": config.UpdateDefaults}
+	for manifestType, overrides := range config.UpdateOverrides {
+		defaults["update-overrides."+manifestType] = overrides
+	}
+	for key, override := range config.RepoOverrides {
+		defaults["repo-overrides."+key+".update-defaults"] = override.UpdateDefaults
+		for manifestType, overrides := range override.UpdateOverrides {
+			defaults["repo-overrides."+key+".update-overrides."+manifestType] = overrides
+		}
+	}
+	problems := make([]string, 0)
+	for _, section := range util.SortedKeys(defaults) {
+		groups := defaults[section].Groups
+		for _, name := range util.SortedKeys(groups) {
+			if appliesTo := groups[name].AppliesTo; !util.Contains([]string{"", groupVersionUpdates, groupSecurityUpdates}, appliesTo) {
+				problems = append(problems, fmt.Sprintf("%v.groups.%v.applies-to: %v is none of %v and %v", section, name, appliesTo,
+					groupVersionUpdates, groupSecurityUpdates))
+			}
+		}
+	}
+	return problems
+}
+
+// validate returns the problems of a default registry, prefixed by its path in the tool config.
+func (registry DefaultRegistry) validate(path string) []string {
+	problems := make([]string, 0)
+	if registry.Type == "" {
+		problems = append(problems, path+": type not set")
+	}
+	if registry.Type == hexOrganization {
+		if registry.Organization == "" {
+			problems = append(problems, path+": organization not set")
+		}
+		if registry.URLMatchRequired {
+			problems = append(problems, path+": url-match-required needs a url, hex organizations have none")
+		}
+	} else if registryURL, err := url.Parse(registry.URL); err != nil || registryURL.Hostname() == "" {
+		problems = append(problems, fmt.Sprintf("%v: invalid url %v", path, registry.URL))
+	}
+	// these values are written to dependabot.yml, and must not be committed as plain text
+	for _, secret := range []struct {
+		key   string
+		value string
+	}{{"password", registry.Password}, {"key", registry.Key}, {"token", registry.Token}} {
+		if secret.value != "" && !secretReferencePattern.MatchString(secret.value) {
+			problems = append(problems, fmt.Sprintf("%v: %v is no Dependabot secret reference (${{secrets.NAME}})", path, secret.key))
+		}
+	}
+	return problems
+}
+
 // Parse parses the config.yml format
 func (config *ToolConfig) Parse(data []byte) error {
 	return yaml.Unmarshal(data, config)
@@ -202,6 +843,11 @@ func (config *DependabotConfig) Parse(data []byte) error {
 	if err := yaml.Unmarshal(data, config); err != nil {
 		return err
 	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err == nil {
+		config.usesAnchors = usesAnchors(&document)
+		config.markProtectedUpdates(&document)
+	}
 	for i, update := range config.Updates {
 		if update.Directory != "/" && strings.HasSuffix(update.Directory, "/") {
 			config.Updates[i].Directory = strings.TrimSuffix(update.Directory, "/")
@@ -210,6 +856,19 @@ func (config *DependabotConfig) Parse(data []byte) error {
 	return nil
 }
 
+// usesAnchors returns if a YAML node or any of its children uses anchors, aliases or merge keys.
+func usesAnchors(node *yaml.Node) bool {
+	if node.Anchor != "" || node.Kind == yaml.AliasNode || node.Tag == "!!merge" {
+		return true
+	}
+	for _, child := range node.Content {
+		if usesAnchors(child) {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseToolConfig parses the config file
 func ParseToolConfig(fileContent []byte) (*ToolConfig, error) {
 	if fileContent == nil {
@@ -233,37 +892,84 @@ func ParseDependabotConfig(fileContent []byte) (*DependabotConfig, error) {
 	return &config, nil
 }
 
-// IsManifestCovered returns if a manifest file is covered within a dependabot.yml config
-func (config *DependabotConfig) IsManifestCovered(manifestFile string, manifestType string, updateRegistries []string) bool {
-	if len(config.Updates) == 0 {
-		return false
-	}
+// coveringUpdateIndex returns the index of the update entry covering a manifest file, or -1 if there is none.
+func (config *DependabotConfig) coveringUpdateIndex(manifestFile string, manifestType string) int {
+	manifestPath := PathWithEndingSlash(GetManifestPath(manifestFile, manifestType))
 	for i, update := range config.Updates {
 		ecosystem := update.PackageEcosystem
-		directory := update.Directory
-		if ecosystem == "" || directory == "" {
+		directories := update.DirectoryList()
+		if ecosystem == "" || len(directories) == 0 {
 			log.Printf("WARN  Invalid dependabot config: %v", update)
-			return false
+			return -1
 		}
-		directory = PathWithEndingSlash(directory)
-		manifestPath := PathWithEndingSlash(GetManifestPath(manifestFile, manifestType))
-		if ecosystem == manifestType && strings.HasPrefix(manifestPath, directory) {
-			// update entry is covering the one being checked
-			// in case the latter is using registries, these must be referenced by this entry
-			for _, name := range updateRegistries {
-				// check if name in update -> []registries
-				if !util.Contains(update.Registries, name) {
-					config.Updates[i].Registries = append(config.Updates[i].Registries, name)
+		if ecosystem != manifestType {
+			continue
+		}
+		for _, directory := range directories {
+			if util.Contains(exactDirectoryEcosystems, manifestType) {
+				if isDirectoryMatching(manifestPath, directory) {
+					return i
 				}
+			} else if isPathCovered(manifestPath, directory) {
+				return i
 			}
+		}
+	}
+	return -1
+}
+
+// isDirectoryMatching returns if a manifest path is the directory of an update, or matches it if it has wildcards.
+func isDirectoryMatching(manifestPath string, directory string) bool {
+	manifestPath = strings.TrimSuffix(manifestPath, "/")
+	directory = strings.TrimSuffix(directory, "/")
+	if manifestPath == "" {
+		manifestPath = "/"
+	}
+	if directory == "" {
+		directory = "/"
+	}
+	if strings.ContainsAny(directory, "*?") {
+		return util.MatchPathPattern(directory, manifestPath)
+	}
+	return manifestPath == directory
+}
+
+// isPathCovered returns if a manifest path is covered by the directory of an update.
+// A directory with wildcards covers the paths for which the path itself or one of its parents matches.
+func isPathCovered(manifestPath string, directory string) bool {
+	if !strings.ContainsAny(directory, "*?") {
+		return strings.HasPrefix(manifestPath, PathWithEndingSlash(directory))
+	}
+	pattern := strings.TrimSuffix(directory, "/")
+	for candidate := strings.TrimSuffix(manifestPath, "/"); candidate != ""; candidate = path.Dir(candidate) {
+		if util.MatchPathPattern(pattern, candidate) {
 			return true
 		}
+		if candidate == "/" {
+			break
+		}
 	}
 	return false
 }
 
+// DirectoryList returns the directories of an update, defined either by directory or directories.
+func (update Update) DirectoryList() []string {
+	if update.Directory != "" {
+		return []string{update.Directory}
+	}
+	return update.Directories
+}
+
+// NormalizeDirectory returns a directory with a leading slash, without duplicate slashes and ./ or ../ elements.
+func NormalizeDirectory(directory string) string {
+	if directory == "" {
+		return ""
+	}
+	return path.Clean("/" + directory)
+}
+
 // IsRegistryUsed returns if a registry is used by a manifest file
-func IsRegistryUsed(manifestFile string, manifestPath string, defaultRegistry DefaultRegistry,
+func IsRegistryUsed(manifestFile string, manifestType string, manifestPath string, defaultRegistry DefaultRegistry,
 	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
 ) bool {
 	// check if registry is used for this manifest file - only add it if so
@@ -273,7 +979,14 @@ func IsRegistryUsed(manifestFile string, manifestPath string, defaultRegistry De
 		return false
 	}
 	// search the manifest file itself and - if defined - additional files
+	// for manifest types with a detector, the hosts it finds replace the search in the manifest file
 	searchFiles := []string{manifestFile}
+	if detector, hasDetector := registryHostDetectors[manifestType]; hasDetector {
+		if util.Contains(detector(manifestFile, manifestPath, loadFileFn, loadFileParams), strings.ToLower(registryURL.Hostname())) {
+			return true
+		}
+		searchFiles = []string{}
+	}
 	for _, additionalFile := range defaultRegistry.URLMatchAdditionalFiles {
 		searchFiles = append(searchFiles, filepath.Join(manifestPath, additionalFile))
 	}
@@ -286,6 +999,19 @@ func IsRegistryUsed(manifestFile string, manifestPath string, defaultRegistry De
 	return false
 }
 
+// AppliesToPath returns if a registry may be used for manifests in a path, according to its manifest path patterns
+func (registry DefaultRegistry) AppliesToPath(manifestPath string) bool {
+	if len(registry.ManifestPaths) == 0 {
+		return true
+	}
+	for _, pattern := range registry.ManifestPaths {
+		if util.MatchPathPattern(pattern, manifestPath) {
+			return true
+		}
+	}
+	return false
+}
+
 // PathWithEndingSlash returns a path with an added slash, if needed
 func PathWithEndingSlash(path string) string {
 	if path == "/" || path == "" {
@@ -299,15 +1025,27 @@ func PathWithEndingSlash(path string) string {
 
 // GetManifestPath returns the path of the absolute path of a manifest file
 func GetManifestPath(manifestFile string, manifestType string) string {
-	if manifestType == "github-actions" {
-		// special case for GitHub Actions
+	if manifestType == "github-actions" || manifestType == "devcontainers" {
+		// special case for GitHub Actions and dev containers, dependabot finds their files from the root directory
 		return "/"
 	}
-	manifestPath, _ := filepath.Split("/" + manifestFile)
-	if manifestPath == "/" {
-		return "/"
+	projectFile := manifestFile
+	for _, file := range projectFiles {
+		// e.g. build tool wrappers belong to the project directory, not to the directory of their properties file
+		if strings.HasSuffix("/"+manifestFile, "/"+file) {
+			projectFile = strings.TrimSuffix(manifestFile, file) + filepath.Base(file)
+			break
+		}
 	}
-	return strings.TrimSuffix(manifestPath, "/")
+	manifestPath, _ := filepath.Split("/" + projectFile)
+	if manifestPath != "/" {
+		manifestPath = strings.TrimSuffix(manifestPath, "/")
+	}
+	// unconventional layouts, e.g. lock files generated by a build system, may belong to another directory
+	if rule, found := manifestDirectoryRule(manifestFile, manifestType); found {
+		return rule.Apply(manifestPath)
+	}
+	return manifestPath
 }
 
 // ProcessManifest adds config for a new manifest file to dependabot.yml if necessary
@@ -317,6 +1055,40 @@ func (config *DependabotConfig) ProcessManifest(manifestFile string, manifestTyp
 	if manifestFile == "" || manifestType == "" {
 		return
 	}
+	// skip manifests of ecosystems not to be configured, by the first check that applies
+	for _, skip := range []struct {
+		applies bool
+		reason  string
+		trace   string
+		warning string
+	}{
+		{!util.Contains(supportedEcosystems, manifestType), "unsupported", "not supported by dependabot, skipped", ""},
+		{
+			len(toolConfig.EnabledEcosystems) > 0 && !util.Contains(toolConfig.EnabledEcosystems, manifestType),
+			"ecosystem not enabled", "not in enabled-ecosystems, skipped", "",
+		},
+		{util.Contains(toolConfig.DisabledEcosystems, manifestType), "ecosystem disabled", "in disabled-ecosystems, skipped", ""},
+		{
+			!toolConfig.TargetSupports(manifestType),
+			"not supported by " + toolConfig.Target, "not supported by target " + toolConfig.Target + ", skipped", "",
+		},
+		{
+			!config.EnableBetaEcoSystems && util.Contains(toolConfig.betaEcosystems(), manifestType),
+			"beta ecosystem", "beta ecosystem, enable-beta-ecosystems not set, skipped",
+			fmt.Sprintf("manifests of the beta ecosystem %v are skipped, as enable-beta-ecosystems is not set", manifestType),
+		},
+	} {
+		if !skip.applies {
+			continue
+		}
+		changeInfo.SkippedManifests = append(changeInfo.SkippedManifests, SkippedInfo{Type: manifestType, File: manifestFile, Reason: skip.reason})
+		changeInfo.addTrace(toolConfig.Trace, TraceEntry{Manifest: manifestFile, Type: manifestType, Check: TraceEcosystem, Result: skip.trace})
+		if skip.warning != "" && !util.Contains(changeInfo.Warnings, skip.warning) {
+			log.Printf("WARN  %v", skip.warning)
+			changeInfo.Warnings = append(changeInfo.Warnings, skip.warning)
+		}
+		return
+	}
 	if config.Updates == nil {
 		config.Updates = []Update{}
 	}
@@ -326,68 +1098,204 @@ func (config *DependabotConfig) ProcessManifest(manifestFile string, manifestTyp
 	manifestPath := GetManifestPath(manifestFile, manifestType)
 	updateRegistries := make([]string, 0)
 
-	// check if the default registries of the manifest's type are covered, and add them if necessary
-	if defaultRegistries, containsRegistry := toolConfig.Registries[manifestType]; containsRegistry {
-		for name, defaultRegistry := range defaultRegistries {
-			if defaultRegistry.URLMatchRequired {
-				// check if registry is used for this manifest file - only add it if so
-				found := IsRegistryUsed(manifestFile, manifestPath, defaultRegistry, loadFileFn, loadFileParams)
-				if !found {
-					continue
-				}
-			}
-			updateRegistries = append(updateRegistries, name)
-			if _, contains := config.Registries[name]; !contains {
-				// registry not yet in config -> add it
-				config.Registries[name] = Registry{
-					Type:     defaultRegistry.Type,
-					URL:      defaultRegistry.URL,
-					Username: defaultRegistry.Username,
-					Password: defaultRegistry.Password,
-				}
-				changeInfo.NewRegistries = append(changeInfo.NewRegistries, RegistryInfo{Type: defaultRegistry.Type, Name: name})
+	// collect the default registries of the manifest's type used by the manifest, by their name in dependabot.yml
+	defaultRegistries := DefaultRegistries{}
+	registryNames := make([]string, 0, len(toolConfig.Registries[manifestType]))
+	for name, defaultRegistry := range toolConfig.Registries[manifestType] {
+		registryName := toolConfig.RegistryName(manifestType, name)
+		defaultRegistries[registryName] = defaultRegistry
+		registryNames = append(registryNames, registryName)
+	}
+	sort.Strings(registryNames)
+	traceRegistry := func(name string, result string) {
+		changeInfo.addTrace(toolConfig.Trace, TraceEntry{Manifest: manifestFile, Type: manifestType, Check: TraceRegistry, Subject: name, Result: result})
+	}
+	for _, name := range registryNames {
+		defaultRegistry := defaultRegistries[name]
+		if !defaultRegistry.AppliesToPath(manifestPath) {
+			traceRegistry(name, "not applicable, directory doesn't match manifest-paths")
+			continue
+		}
+		if defaultRegistry.URLMatchRequired {
+			// check if registry is used for this manifest file - only add it if so
+			found := IsRegistryUsed(manifestFile, manifestType, manifestPath, defaultRegistry, loadFileFn, loadFileParams)
+			if !found {
+				traceRegistry(name, "not used, URL not found")
+				continue
 			}
+			traceRegistry(name, "used, URL found")
+		} else {
+			traceRegistry(name, "used, no URL match required")
 		}
+		updateRegistries = append(updateRegistries, name)
 	}
 
 	// check if the manifest itself is covered, and add it if necessary
-	if !config.IsManifestCovered(manifestFile, manifestType, updateRegistries) {
+	index := config.coveringUpdateIndex(manifestFile, manifestType)
+	if index < 0 {
 		// create the new update section using the default properties
 		update := createUpdateEntry(manifestType, manifestPath, toolConfig)
 		// add new registries if required
 		if len(updateRegistries) > 0 {
 			update.Registries = updateRegistries
+			for _, name := range updateRegistries {
+				config.addRegistry(name, defaultRegistries[name], changeInfo)
+				traceRegistry(name, "referenced by new update")
+			}
 		}
 		// add the update block, to the config
 		config.Updates = append(config.Updates, update)
 		changeInfo.NewUpdates = append(changeInfo.NewUpdates, UpdateInfo{Type: manifestType, Directory: manifestPath, File: manifestFile})
+		changeInfo.addTrace(toolConfig.Trace, TraceEntry{Manifest: manifestFile, Type: manifestType, Check: TraceCoverage, Subject: manifestPath, Result: "not covered, update added"})
+		return
+	}
+
+	// the covering update entry must reference the registries used by the manifest
+	update := &config.Updates[index]
+	// several manifests in the same directory (e.g. Dockerfile and Dockerfile.dev) share the update created for the first one
+	isNewUpdate := containsUpdateInfo(changeInfo.NewUpdates, manifestType, update.Directory)
+	changeInfo.addTrace(toolConfig.Trace, TraceEntry{Manifest: manifestFile, Type: manifestType, Check: TraceCoverage, Subject: strings.Join(update.DirectoryList(), ","), Result: "covered by update"})
+	for _, name := range updateRegistries {
+		if util.Contains(update.Registries, name) {
+			config.addRegistry(name, defaultRegistries[name], changeInfo)
+			traceRegistry(name, "referenced by update")
+			continue
+		}
+		ref := RegistryRefInfo{Registry: name, Type: manifestType, Directory: update.Directory}
+		if isNewUpdate {
+			config.addRegistry(name, defaultRegistries[name], changeInfo)
+			update.Registries = append(update.Registries, name)
+			traceRegistry(name, "reference added to new update")
+		} else if config.isRegistryReferenceAdded(name, update, toolConfig.RegistryReferences, changeInfo) {
+			config.addRegistry(name, defaultRegistries[name], changeInfo)
+			update.Registries = append(update.Registries, name)
+			changeInfo.AddedRegistryRefs = append(changeInfo.AddedRegistryRefs, ref)
+			traceRegistry(name, "reference added")
+		} else {
+			traceRegistry(name, "reference missing")
+			if !containsRegistryRef(changeInfo.MissingRegistryRefs, ref) {
+				log.Printf("WARN  Update %v %v doesn't reference registry %v, used by %v", manifestType, update.Directory, name, manifestFile)
+				changeInfo.MissingRegistryRefs = append(changeInfo.MissingRegistryRefs, ref)
+			}
+		}
+	}
+}
+
+// addRegistry adds a default registry to the config, unless it's there already.
+func (config *DependabotConfig) addRegistry(name string, defaultRegistry DefaultRegistry, changeInfo *ChangeInfo) {
+	if _, contains := config.Registries[name]; contains {
+		return
+	}
+	config.Registries[name] = Registry{
+		Type:         defaultRegistry.Type,
+		URL:          defaultRegistry.URL,
+		Username:     defaultRegistry.Username,
+		Password:     defaultRegistry.Password,
+		Key:          defaultRegistry.Key,
+		Token:        defaultRegistry.Token,
+		Organization: defaultRegistry.Organization,
+		ReplacesBase: defaultRegistry.ReplacesBase,
 	}
+	changeInfo.NewRegistries = append(changeInfo.NewRegistries, RegistryInfo{Type: defaultRegistry.Type, Name: name})
+}
+
+// isRegistryReferenceAdded returns if a missing reference of an existing update to a registry is added, by the mode of
+// registry-references: always with add, and with inherit if the registry is missing in the current config, so the
+// reference wasn't left out on purpose. References of protected updates are never added.
+func (config *DependabotConfig) isRegistryReferenceAdded(name string, update *Update, mode string, changeInfo *ChangeInfo) bool {
+	if update.IsProtected() {
+		return false
+	}
+	switch mode {
+	case "", RegistryReferencesAdd:
+		return true
+	case RegistryReferencesInherit:
+		return config.isRegistryMissing(name, changeInfo)
+	}
+	return false
+}
+
+// isRegistryMissing returns if a registry is missing in the current config, i.e. it's not defined or has been added
+// by this run only.
+func (config *DependabotConfig) isRegistryMissing(name string, changeInfo *ChangeInfo) bool {
+	if _, contains := config.Registries[name]; !contains {
+		return true
+	}
+	for _, registry := range changeInfo.NewRegistries {
+		if registry.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsUpdateInfo returns if an update of a package ecosystem and directory is part of a list.
+func containsUpdateInfo(updates []UpdateInfo, manifestType string, directory string) bool {
+	for _, u := range updates {
+		if u.Type == manifestType && u.Directory == directory {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRegistryRef returns if a registry reference is part of a list.
+func containsRegistryRef(refs []RegistryRefInfo, ref RegistryRefInfo) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
 }
 
 func createUpdateEntry(manifestType string, manifestPath string, toolConfig ToolConfig) Update {
+	defaults := toolConfig.UpdateDefaults
+	// apply override properties, if defined
+	if overrides, hasOverrides := toolConfig.UpdateOverrides[manifestType]; hasOverrides {
+		defaults = mergeUpdateDefaults(defaults, overrides)
+	}
+	// a matching schedule policy takes precedence
+	if schedule, found := toolConfig.scheduleFor(manifestType); found {
+		defaults.Schedule = schedule
+	}
 	update := Update{
 		PackageEcosystem:              manifestType,
 		Directory:                     manifestPath,
-		Schedule:                      toolConfig.UpdateDefaults.Schedule,
-		CommitMessage:                 toolConfig.UpdateDefaults.CommitMessage,
-		OpenPullRequestsLimit:         toolConfig.UpdateDefaults.OpenPullRequestsLimit,
-		RebaseStrategy:                toolConfig.UpdateDefaults.RebaseStrategy,
-		InsecureExternalCodeExecution: toolConfig.UpdateDefaults.InsecureExternalCodeExecution,
+		Schedule:                      defaults.Schedule,
+		CommitMessage:                 defaults.CommitMessage,
+		OpenPullRequestsLimit:         defaults.OpenPullRequestsLimit,
+		RebaseStrategy:                defaults.RebaseStrategy,
+		TargetBranch:                  defaults.TargetBranch,
+		InsecureExternalCodeExecution: defaults.InsecureExternalCodeExecution,
+		Labels:                        defaults.Labels,
+		Reviewers:                     defaults.Reviewers,
+		Assignees:                     defaults.Assignees,
+		Allow:                         defaults.Allow,
+		Ignore:                        defaults.Ignore,
+		Cooldown:                      defaults.Cooldown,
 	}
-	// apply override properties, if defined
-	if overrides, hasOverrides := toolConfig.UpdateOverrides[manifestType]; hasOverrides {
-		applyOverrides(&update, overrides)
+	if len(defaults.Groups) > 0 {
+		update.Groups = make(map[string]Group, len(defaults.Groups))
+		for name, group := range defaults.Groups {
+			update.Groups[name] = group
+		}
 	}
 	fixUpdateConfig(&update, manifestType)
+	toolConfig.applyTarget(&update)
 	return update
 }
 
-// GetManifestType returns the type of manifest file, if any.
+// GetManifestType returns the type of manifest file, if any. Paths matching the global ignore pattern are no
+// manifests, the ones matching the ignore pattern of a manifest type are no manifests of that type only.
 func GetManifestType(fullPath string) string {
 	if manifestIgnoreFilePattern != nil && manifestIgnoreFilePattern.MatchString(fullPath) {
 		return ""
 	}
 	for manifestType, re := range manifestFilePatterns {
+		if ignore := manifestIgnoreTypePattern[manifestType]; ignore != nil && ignore.MatchString(fullPath) {
+			continue
+		}
 		if re.MatchString(fullPath) {
 			return manifestType
 		}
@@ -395,6 +1303,20 @@ func GetManifestType(fullPath string) string {
 	return ""
 }
 
+// DirectoryInFileList returns if a directory is part of a list of file names (incl. path)
+func DirectoryInFileList(directory string, files []string) bool {
+	prefix := strings.TrimPrefix(PathWithEndingSlash(directory), "/")
+	if prefix == "" {
+		return true
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file, prefix) || file == strings.TrimSuffix(prefix, "/") {
+			return true
+		}
+	}
+	return false
+}
+
 // ScanFileList looks for manifest files, in a list of file names (incl. path)
 func ScanFileList(files []string, manifests map[string]string) {
 	for _, fullPath := range files {
@@ -405,17 +1327,42 @@ func ScanFileList(files []string, manifests map[string]string) {
 	}
 }
 
-// ScanLocalDirectory lists all files in a directory, recursively
+// ScanLocalDirectory lists all files in a directory, recursively. Like git, it skips the files and directories
+// ignored by .gitignore files, and it skips the directories of scan-skip-directories. Git submodules and symlinked
+// directories are skipped, unless enabled by scan-submodules and scan-symlinks, and directories deeper than
+// scan-max-depth are not scanned.
 func ScanLocalDirectory(baseDirectory string, directory string, manifests map[string]string) {
+	scanLocalDirectory(baseDirectory, directory, nil, map[string]bool{}, 0, manifests)
+}
+
+// scanLocalDirectory lists all files in a directory, recursively, applying the rules of the .gitignore files found
+// on the way. Directories already visited, e.g. by a symlink loop, are not scanned again, and symlinks are followed
+// after the other entries of a directory, to list manifests by their actual path where possible.
+func scanLocalDirectory(baseDirectory string, directory string, rules []ignoreRule, visited map[string]bool, depth int,
+	manifests map[string]string,
+) {
+	if !localScan.enter(baseDirectory, directory, visited, depth) {
+		return
+	}
 	files, err := os.ReadDir(filepath.Join(baseDirectory, directory))
 	if err != nil {
 		log.Printf("ERROR Could not read directory %v: %v\n", directory, err)
 		return
 	}
+	rules = append(rules[:len(rules):len(rules)], readGitignore(baseDirectory, directory)...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Type()&os.ModeSymlink == 0 && files[j].Type()&os.ModeSymlink != 0
+	})
 	for _, file := range files {
 		fullPath := filepath.Join(directory, file.Name())
-		if file.IsDir() {
-			ScanLocalDirectory(baseDirectory, fullPath, manifests)
+		isDir, follow := localScan.directoryEntry(baseDirectory, fullPath, file)
+		if isIgnored(rules, fullPath, isDir) {
+			continue
+		}
+		if isDir {
+			if follow {
+				scanLocalDirectory(baseDirectory, fullPath, rules, visited, depth+1, manifests)
+			}
 		} else {
 			manifestType := GetManifestType(fullPath)
 			if manifestType != "" {
@@ -427,8 +1374,13 @@ func ScanLocalDirectory(baseDirectory string, directory string, manifests map[st
 
 // ToYaml returns a YAML representation of a dependabot config.
 func (config *DependabotConfig) ToYaml() []byte {
-	// sort entries in update list, to avoid commits due to changed order only
-	// nothing to be done for registries, as yaml v3 marshals maps sorted by key
+	config.sortUpdates()
+	return encodeYaml(config)
+}
+
+// sortUpdates sorts the entries in the update list, to avoid commits due to changed order only.
+// Nothing to be done for registries, as yaml v3 marshals maps sorted by key.
+func (config *DependabotConfig) sortUpdates() {
 	if len(config.Updates) > 1 {
 		sort.Slice(config.Updates, func(i, j int) bool {
 			a := config.Updates[i]
@@ -437,10 +1389,14 @@ func (config *DependabotConfig) ToYaml() []byte {
 				(a.PackageEcosystem == b.PackageEcosystem && a.Directory < b.Directory)
 		})
 	}
+}
+
+// encodeYaml returns the YAML representation of a value, with an indentation of 2 and secret expressions quoted.
+func encodeYaml(value interface{}) []byte {
 	buf := new(bytes.Buffer)
 	encoder := yaml.NewEncoder(buf)
 	encoder.SetIndent(2)
-	err := encoder.Encode(config)
+	err := encoder.Encode(value)
 	if err != nil {
 		log.Printf("ERROR Could not encode yml: %v", err)
 	}
@@ -453,12 +1409,75 @@ func (config *DependabotConfig) ToYaml() []byte {
 
 // UpdateConfig updates a dependabot config with a list of manifests found and the tool's config.
 func (config *DependabotConfig) UpdateConfig(manifests map[string]string, toolConfig ToolConfig,
-	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+	loadFileFn LoadFileContent, checkDirectoryFn CheckDirectoryExists, loadFileParams LoadFileContentParameters,
 ) ChangeInfo {
 	changeInfo := ChangeInfo{
-		NewRegistries: []RegistryInfo{},
-		NewUpdates:    []UpdateInfo{},
+		NewRegistries:     []RegistryInfo{},
+		NewUpdates:        []UpdateInfo{},
+		RemovedRegistries: []RegistryInfo{},
+		RemovedUpdates:    []UpdateInfo{},
+	}
+
+	if toolConfig.Trace {
+		changeInfo.traceManifests(manifests, toolConfig.ManifestPatterns)
+	}
+
+	// Beta ecosystems are only processed if enabled, as set or unset by the tool config.
+	config.applyBetaEcosystems(toolConfig, &changeInfo)
+
+	// Manifests handled by the update of another package ecosystem, e.g. build tool wrappers, are processed as such.
+	if len(toolConfig.ManifestEcosystems) > 0 {
+		mapped := make(map[string]string, len(manifests))
+		for manifestFile, manifestType := range manifests {
+			if ecosystem := toolConfig.ManifestEcosystems[manifestType]; ecosystem != "" {
+				manifestType = ecosystem
+			}
+			mapped[manifestFile] = manifestType
+		}
+		manifests = mapped
+	}
+
+	// Only the manifests and updates within the path filter are considered.
+	if toolConfig.PathFilter != "" {
+		pathFilter := NormalizeDirectory(toolConfig.PathFilter)
+		filteredManifests := make(map[string]string, len(manifests))
+		for manifestFile, manifestType := range manifests {
+			if isPathCovered(PathWithEndingSlash(GetManifestPath(manifestFile, manifestType)), pathFilter) {
+				filteredManifests[manifestFile] = manifestType
+			}
+		}
+		if toolConfig.Trace {
+			changeInfo.traceDropped(manifests, filteredManifests, TracePathFilter, pathFilter, "outside of path filter, skipped")
+		}
+		manifests = filteredManifests
+	}
+
+	// Dependabot doesn't match malformed directories, fix them first.
+	config.normalizeDirectories(&changeInfo)
+
+	// Dependabot rejects configs with several updates for the same ecosystem, directory and target branch.
+	config.handleDuplicateUpdates(&changeInfo, toolConfig.MergeDuplicateUpdates)
+
+	// Remove the updates for directories which don't exist anymore, or don't contain manifests anymore.
+	if toolConfig.MissingDirectoriesCleanup() != CleanupOff || toolConfig.MissingManifestsCleanup() != CleanupOff {
+		config.removeMissingDirectories(&changeInfo, checkDirectoryFn, loadFileParams, manifests, toolConfig)
+	}
+
+	// Workspace members are updated from their workspace root.
+	if !toolConfig.SeparateWorkspaceUpdates {
+		collapsed := CollapseWorkspaces(manifests, loadFileFn, loadFileParams)
+		if toolConfig.Trace {
+			changeInfo.traceDropped(manifests, collapsed, TraceWorkspace, "", "workspace member, covered by the workspace root")
+		}
+		manifests = collapsed
+	}
+
+	// The modules of Maven multi-module projects are updated from their root project.
+	collapsed := CollapseMavenModules(manifests, loadFileFn, loadFileParams)
+	if toolConfig.Trace {
+		changeInfo.traceDropped(manifests, collapsed, TraceWorkspace, "", "module of a Maven multi-module project, covered by the root project")
 	}
+	manifests = collapsed
 
 	// Base directories must be processed before subdirectories (/ before /app).
 	// Sort by length of path we must.
@@ -469,32 +1488,466 @@ func (config *DependabotConfig) UpdateConfig(manifests map[string]string, toolCo
 	sort.SliceStable(manifestsSorted, func(i, j int) bool {
 		path1, _ := filepath.Split("/" + manifestsSorted[i].Key)
 		path2, _ := filepath.Split("/" + manifestsSorted[j].Key)
-		return len(path1) < len(path2) || len(path1) == len(path2) && path1 < path2
+		if path1 != path2 {
+			return len(path1) < len(path2) || len(path1) == len(path2) && path1 < path2
+		}
+		// manifests in the same directory share an update, which is reported with the first one
+		return manifestsSorted[i].Key < manifestsSorted[j].Key
 	})
 	// Iterate manifest files and check if they are covered by the current config file
 	for _, manifest := range manifestsSorted {
 		config.ProcessManifest(manifest.Key, manifest.Value, toolConfig, &changeInfo, loadFileFn, loadFileParams)
 	}
+	// Existing updates are reconciled towards the defaults, if enforced.
+	if len(toolConfig.Enforce) > 0 {
+		config.enforceDefaults(&changeInfo, toolConfig)
+	}
+	if toolConfig.ConsolidateDirectories && toolConfig.TargetSupports("directories") {
+		config.consolidateDirectories(&changeInfo)
+	}
+	if cleanup := toolConfig.UnusedRegistriesCleanup(); cleanup != CleanupOff {
+		config.removeUnusedRegistries(&changeInfo, cleanup == CleanupWarnOnly)
+	}
+	if config.usesAnchors && changeInfo.HasChanges() {
+		log.Printf("WARN  The current config uses YAML anchors, aliases or merge keys, these are expanded in the updated config.")
+		changeInfo.Warnings = append([]string{"the current config uses YAML anchors, aliases or merge keys: " +
+			"these are expanded in the updated config, check if the result is as intended"}, changeInfo.Warnings...)
+	}
 	return changeInfo
 }
 
-// applyOverrides updates a config for an Update, using overridden values
-func applyOverrides(update *Update, overrides UpdateDefaults) {
+// betaEcosystems returns the package ecosystems requiring enable-beta-ecosystems.
+func (config *ToolConfig) betaEcosystems() []string {
+	if config.BetaEcosystems != nil {
+		return config.BetaEcosystems
+	}
+	return defaultBetaEcosystems
+}
+
+// applyBetaEcosystems sets or unsets enable-beta-ecosystems, if given by the tool config. It's kept as long as
+// updates of beta ecosystems exist, dependabot would reject the config otherwise.
+func (config *DependabotConfig) applyBetaEcosystems(toolConfig ToolConfig, changeInfo *ChangeInfo) {
+	if toolConfig.EnableBetaEcosystems == nil || *toolConfig.EnableBetaEcosystems == config.EnableBetaEcoSystems {
+		return
+	}
+	if !*toolConfig.EnableBetaEcosystems {
+		for _, update := range config.Updates {
+			if util.Contains(toolConfig.betaEcosystems(), update.PackageEcosystem) {
+				changeInfo.Warnings = append(changeInfo.Warnings, fmt.Sprintf(
+					"enable-beta-ecosystems is kept, as the config has updates of the beta ecosystem %v", update.PackageEcosystem))
+				return
+			}
+		}
+	}
+	config.EnableBetaEcoSystems = *toolConfig.EnableBetaEcosystems
+	change := "enable-beta-ecosystems set"
+	if !config.EnableBetaEcoSystems {
+		change = "enable-beta-ecosystems unset"
+	}
+	log.Printf("INFO  Config: %v.", change)
+	changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{Change: change})
+}
+
+// normalizeDirectories normalizes the directory and directories values of the updates.
+func (config *DependabotConfig) normalizeDirectories(changeInfo *ChangeInfo) {
+	for i, update := range config.Updates {
+		if update.IsProtected() {
+			continue
+		}
+		if normalized := NormalizeDirectory(update.Directory); normalized != update.Directory {
+			log.Printf("INFO  Normalizing directory %v of %v update to %v.", update.Directory, update.PackageEcosystem, normalized)
+			config.Updates[i].Directory = normalized
+			changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{
+				Type: update.PackageEcosystem, Directory: normalized, Change: fmt.Sprintf("directory %v normalized", update.Directory),
+			})
+		}
+		for j, directory := range update.Directories {
+			if normalized := NormalizeDirectory(directory); normalized != directory {
+				log.Printf("INFO  Normalizing directory %v of %v update to %v.", directory, update.PackageEcosystem, normalized)
+				config.Updates[i].Directories[j] = normalized
+				changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{
+					Type: update.PackageEcosystem, Directory: normalized, Change: fmt.Sprintf("directories entry %v normalized", directory),
+				})
+			}
+		}
+	}
+}
+
+// handleDuplicateUpdates reports the updates sharing ecosystem, directory and target branch with a previous update.
+// With merge, the directory is removed from the later update, and its registries are added to the first one.
+func (config *DependabotConfig) handleDuplicateUpdates(changeInfo *ChangeInfo, merge bool) {
+	updates := make([]Update, 0, len(config.Updates))
+	seen := map[string]int{}
+	for _, update := range config.Updates {
+		remaining := make([]string, 0)
+		for _, directory := range update.DirectoryList() {
+			key := update.PackageEcosystem + "|" + directory + "|" + update.TargetBranch
+			index, isDuplicate := seen[key]
+			if !isDuplicate || !merge || update.IsProtected() || updates[index].IsProtected() {
+				remaining = append(remaining, directory)
+			}
+			if !isDuplicate {
+				seen[key] = len(updates)
+				continue
+			}
+			if !merge || update.IsProtected() || updates[index].IsProtected() {
+				log.Printf("WARN  Duplicate %v update for directory %v.", update.PackageEcosystem, directory)
+				changeInfo.Warnings = append(changeInfo.Warnings,
+					fmt.Sprintf("duplicate %v update for directory %v, dependabot rejects this config", update.PackageEcosystem, directory))
+				continue
+			}
+			log.Printf("INFO  Merging duplicate %v update for directory %v.", update.PackageEcosystem, directory)
+			for _, name := range update.Registries {
+				if !util.Contains(updates[index].Registries, name) {
+					updates[index].Registries = append(updates[index].Registries, name)
+				}
+			}
+			changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{
+				Type: update.PackageEcosystem, Directory: directory, Change: "duplicate update merged, settings of the first update kept",
+			})
+		}
+		if len(remaining) == 0 {
+			continue
+		}
+		if update.Directory == "" {
+			update.Directories = remaining
+		}
+		updates = append(updates, update)
+	}
+	config.Updates = updates
+}
+
+// consolidateDirectories merges the updates of an ecosystem with identical settings into one update,
+// listing their directories in "directories".
+func (config *DependabotConfig) consolidateDirectories(changeInfo *ChangeInfo) {
+	updates := make([]Update, 0, len(config.Updates))
+	merged := map[int]int{}
+	for _, update := range config.Updates {
+		settings := update
+		settings.Directory, settings.Directories = "", nil
+		index := -1
+		for i, candidate := range updates {
+			candidate.Directory, candidate.Directories = "", nil
+			if !update.IsProtected() && !candidate.IsProtected() && reflect.DeepEqual(settings, candidate) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			updates = append(updates, update)
+			continue
+		}
+		directories := updates[index].DirectoryList()
+		for _, directory := range update.DirectoryList() {
+			if !util.Contains(directories, directory) {
+				directories = append(directories, directory)
+			}
+		}
+		sort.Strings(directories)
+		updates[index].Directory, updates[index].Directories = "", directories
+		merged[index]++
+	}
+	for i, update := range updates {
+		if count, isMerged := merged[i]; isMerged {
+			log.Printf("INFO  Consolidating %v %v updates into one update for directories %v.", count+1, update.PackageEcosystem, update.Directories)
+			changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{
+				Type: update.PackageEcosystem, Directory: strings.Join(update.Directories, ", "), Change: fmt.Sprintf("%v updates consolidated", count+1),
+			})
+		}
+	}
+	config.Updates = updates
+}
+
+// removeMissingDirectories removes the updates whose directory doesn't exist, by the cleanup mode of
+// missing-directories, and the ones whose directories exist but don't contain any manifest of the update's ecosystem
+// anymore, by the cleanup mode of missing-manifests. The latter is only checked for ecosystems with a manifest
+// pattern, and not for truncated file lists. Updates whose directories exist on any of the cleanup branches are kept.
+// Of updates with a list of directories, only the missing directories are removed, and the update if none is left.
+// If any of the directory checks fails, nothing is removed, as the results are not reliable. With warn-only, the
+// updates are kept, and listed as warnings.
+func (config *DependabotConfig) removeMissingDirectories(changeInfo *ChangeInfo, checkDirectoryFn CheckDirectoryExists,
+	loadFileParams LoadFileContentParameters, manifests map[string]string, toolConfig ToolConfig,
+) {
+	directoriesMode := toolConfig.MissingDirectoriesCleanup()
+	manifestsMode := toolConfig.MissingManifestsCleanup()
+	if manifestsMode != CleanupOff && loadFileParams.FileListTruncated {
+		log.Printf("WARN  File list of repo is truncated, not checking the directories of updates for manifests.")
+		manifestsMode = CleanupOff
+	}
+	pathFilter := NormalizeDirectory(toolConfig.PathFilter)
+	updates := make([]Update, 0, len(config.Updates))
+	removedUpdates := make([]UpdateInfo, 0)
+	reasons := make([]string, 0)
+	warnings := make([]string, 0)
+	// check returns the update, of a single directory, to be removed, and why
+	check := func(update Update) (*UpdateInfo, string, error) {
+		removed, reason, mode, err := update.missingOnBase(checkDirectoryFn, loadFileParams, manifests, toolConfig.ManifestPatterns,
+			directoriesMode, manifestsMode)
+		if err == nil && removed != nil {
+			var branch string
+			branch, err = update.existingBranch(checkDirectoryFn, loadFileParams, toolConfig.cleanupBranches(update, loadFileParams.Ref))
+			if branch != "" {
+				log.Printf("INFO  Directory %v %v, keeping %v update as it exists on branch %v.", removed.Directory, reason, update.PackageEcosystem, branch)
+				removed = nil
+			}
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if removed != nil && mode == CleanupWarnOnly {
+			log.Printf("WARN  Directory %v %v, keeping %v update as cleanup is warn-only.", removed.Directory, reason, removed.Type)
+			warnings = append(warnings, fmt.Sprintf("%v update for %v kept, although the directory %v", removed.Type, removed.Directory, reason))
+			removed = nil
+		}
+		return removed, reason, nil
+	}
+	for _, update := range config.Updates {
+		// updates outside the path filter, and protected ones, are kept as they are
+		if (pathFilter != "" && !update.isWithinPath(pathFilter)) || update.IsProtected() {
+			updates = append(updates, update)
+			continue
+		}
+		// the directories of a list are checked one by one, wildcard ones are kept; the missing ones are dropped from
+		// the list, and the update is removed only if none is left
+		singleUpdates := []Update{update}
+		if len(update.Directories) > 0 {
+			singleUpdates = make([]Update, 0, len(update.Directories))
+			for _, directory := range update.Directories {
+				singleUpdate := update
+				singleUpdate.Directory, singleUpdate.Directories = directory, nil
+				singleUpdates = append(singleUpdates, singleUpdate)
+			}
+		}
+		keptDirectories := make([]string, 0, len(update.Directories))
+		for _, singleUpdate := range singleUpdates {
+			if len(update.Directories) > 0 && strings.ContainsAny(singleUpdate.Directory, "*?") {
+				keptDirectories = append(keptDirectories, singleUpdate.Directory)
+				continue
+			}
+			removed, reason, err := check(singleUpdate)
+			if err != nil {
+				log.Printf("WARN  Could not check if directory of %v update exists, skipping removal of updates: %v", update.PackageEcosystem, err)
+				changeInfo.Warnings = append(changeInfo.Warnings, "removal of updates for missing directories skipped, as directories could not be checked")
+				return
+			}
+			if removed != nil {
+				removedUpdates = append(removedUpdates, *removed)
+				reasons = append(reasons, reason)
+				continue
+			}
+			keptDirectories = append(keptDirectories, singleUpdate.Directory)
+		}
+		if len(keptDirectories) == 0 {
+			continue
+		}
+		if len(update.Directories) > 0 {
+			update.Directories = keptDirectories
+		}
+		updates = append(updates, update)
+	}
+	changeInfo.Warnings = append(changeInfo.Warnings, warnings...)
+	for i, update := range removedUpdates {
+		log.Printf("INFO  Directory %v %v, removing %v update.", update.Directory, reasons[i], update.Type)
+	}
+	changeInfo.RemovedUpdates = append(changeInfo.RemovedUpdates, removedUpdates...)
+	config.Updates = updates
+}
+
+// missingOnBase returns the update to be removed, the reason and the cleanup mode applying, if its directory doesn't
+// exist on the base branch, or doesn't contain any manifest of the update's ecosystem. Checks whose cleanup mode is
+// off are skipped. Otherwise, nil is returned.
+func (update Update) missingOnBase(checkDirectoryFn CheckDirectoryExists, loadFileParams LoadFileContentParameters,
+	manifests map[string]string, manifestPatterns map[string]string, directoriesMode string, manifestsMode string,
+) (*UpdateInfo, string, string, error) {
+	// wildcard directories can't be checked
+	if directoriesMode != CleanupOff && update.Directory != "/" && update.Directory != "" && !strings.ContainsAny(update.Directory, "*?") {
+		exists, err := checkDirectoryFn(update.Directory, loadFileParams)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("directory %v: %w", update.Directory, err)
+		}
+		if !exists {
+			return &UpdateInfo{Type: update.PackageEcosystem, Directory: update.Directory}, "does not exist", directoriesMode, nil
+		}
+	}
+	if manifestsMode != CleanupOff && manifestPatterns[update.PackageEcosystem] != "" && !update.coversManifest(manifests) {
+		return &UpdateInfo{Type: update.PackageEcosystem, Directory: strings.Join(update.DirectoryList(), ", ")},
+			"contains no " + update.PackageEcosystem + " manifests", manifestsMode, nil
+	}
+	return nil, "", "", nil
+}
+
+// existingBranch returns the first of the branches any of the update's directories exists on, if any.
+// Manifests are only matched on the base branch, on the other branches the directories are only looked up.
+func (update Update) existingBranch(checkDirectoryFn CheckDirectoryExists, loadFileParams LoadFileContentParameters,
+	branches []string,
+) (string, error) {
+	for _, branch := range branches {
+		branchParams := loadFileParams
+		branchParams.Ref, branchParams.FileList, branchParams.FileListTruncated = branch, nil, false
+		for _, directory := range update.DirectoryList() {
+			if directory == "/" || strings.ContainsAny(directory, "*?") {
+				continue
+			}
+			exists, err := checkDirectoryFn(directory, branchParams)
+			if err != nil {
+				return "", fmt.Errorf("directory %v on branch %v: %w", directory, branch, err)
+			}
+			if exists {
+				return branch, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// isWithinPath returns if all directories of the update are within a path.
+func (update Update) isWithinPath(path string) bool {
+	for _, directory := range update.DirectoryList() {
+		if !isPathCovered(PathWithEndingSlash(directory), path) {
+			return false
+		}
+	}
+	return len(update.DirectoryList()) > 0
+}
+
+// coversManifest returns if any of the manifests of the update's ecosystem is covered by the update.
+func (update Update) coversManifest(manifests map[string]string) bool {
+	for manifestFile, manifestType := range manifests {
+		if manifestType != update.PackageEcosystem {
+			continue
+		}
+		manifestPath := PathWithEndingSlash(GetManifestPath(manifestFile, manifestType))
+		for _, directory := range update.DirectoryList() {
+			if util.Contains(exactDirectoryEcosystems, manifestType) {
+				if isDirectoryMatching(manifestPath, directory) {
+					return true
+				}
+			} else if isPathCovered(manifestPath, directory) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeUnusedRegistries removes the registries not referenced by any update. With warnOnly, the registries are kept,
+// and listed as warnings.
+func (config *DependabotConfig) removeUnusedRegistries(changeInfo *ChangeInfo, warnOnly bool) {
+	for _, name := range sortedRegistryNames(config.Registries) {
+		used := false
+		for _, update := range config.Updates {
+			if util.Contains(update.Registries, name) {
+				used = true
+				break
+			}
+		}
+		if !used && warnOnly {
+			changeInfo.Warnings = append(changeInfo.Warnings, fmt.Sprintf("registry %v kept, although not referenced by any update", name))
+		} else if !used {
+			changeInfo.RemovedRegistries = append(changeInfo.RemovedRegistries, RegistryInfo{Type: config.Registries[name].Type, Name: name})
+			delete(config.Registries, name)
+		}
+	}
+}
+
+// CompareConfigs returns the registries and updates added and removed between two revisions of a config.
+func CompareConfigs(previous *DependabotConfig, current *DependabotConfig) ConfigDiff {
+	diff := ConfigDiff{
+		AddedRegistries:   []RegistryInfo{},
+		RemovedRegistries: []RegistryInfo{},
+		AddedUpdates:      []UpdateInfo{},
+		RemovedUpdates:    []UpdateInfo{},
+	}
+	for _, name := range sortedRegistryNames(current.Registries) {
+		if _, found := previous.Registries[name]; !found {
+			diff.AddedRegistries = append(diff.AddedRegistries, RegistryInfo{Type: current.Registries[name].Type, Name: name})
+		}
+	}
+	for _, name := range sortedRegistryNames(previous.Registries) {
+		if _, found := current.Registries[name]; !found {
+			diff.RemovedRegistries = append(diff.RemovedRegistries, RegistryInfo{Type: previous.Registries[name].Type, Name: name})
+		}
+	}
+	for _, update := range current.Updates {
+		if !containsUpdate(previous.Updates, update) {
+			diff.AddedUpdates = append(diff.AddedUpdates, UpdateInfo{Type: update.PackageEcosystem, Directory: update.Directory})
+		}
+	}
+	for _, update := range previous.Updates {
+		if !containsUpdate(current.Updates, update) {
+			diff.RemovedUpdates = append(diff.RemovedUpdates, UpdateInfo{Type: update.PackageEcosystem, Directory: update.Directory})
+		}
+	}
+	return diff
+}
+
+// IsEmpty returns if no registries or updates have been added or removed.
+func (diff ConfigDiff) IsEmpty() bool {
+	return len(diff.AddedRegistries) == 0 && len(diff.RemovedRegistries) == 0 &&
+		len(diff.AddedUpdates) == 0 && len(diff.RemovedUpdates) == 0
+}
+
+func containsUpdate(updates []Update, update Update) bool {
+	for _, candidate := range updates {
+		if candidate.PackageEcosystem == update.PackageEcosystem && candidate.Directory == update.Directory &&
+			candidate.TargetBranch == update.TargetBranch {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedRegistryNames(registries map[string]Registry) []string {
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergeUpdateDefaults returns the default config for updates, with the overridden values applied
+func mergeUpdateDefaults(defaults UpdateDefaults, overrides UpdateDefaults) UpdateDefaults {
 	if overrides.Schedule != (Schedule{}) {
-		update.Schedule = overrides.Schedule
+		defaults.Schedule = overrides.Schedule
 	}
 	if overrides.CommitMessage != (CommitMessage{}) {
-		update.CommitMessage = overrides.CommitMessage
+		defaults.CommitMessage = overrides.CommitMessage
 	}
 	if overrides.OpenPullRequestsLimit != 0 {
-		update.OpenPullRequestsLimit = overrides.OpenPullRequestsLimit
+		defaults.OpenPullRequestsLimit = overrides.OpenPullRequestsLimit
 	}
 	if overrides.RebaseStrategy != "" {
-		update.RebaseStrategy = overrides.RebaseStrategy
+		defaults.RebaseStrategy = overrides.RebaseStrategy
+	}
+	if overrides.TargetBranch != "" {
+		defaults.TargetBranch = overrides.TargetBranch
 	}
 	if overrides.InsecureExternalCodeExecution != "" {
-		update.InsecureExternalCodeExecution = overrides.InsecureExternalCodeExecution
+		defaults.InsecureExternalCodeExecution = overrides.InsecureExternalCodeExecution
+	}
+	if len(overrides.Labels) > 0 {
+		defaults.Labels = overrides.Labels
+	}
+	if len(overrides.Reviewers) > 0 {
+		defaults.Reviewers = overrides.Reviewers
+	}
+	if len(overrides.Assignees) > 0 {
+		defaults.Assignees = overrides.Assignees
+	}
+	if len(overrides.Allow) > 0 {
+		defaults.Allow = overrides.Allow
+	}
+	if len(overrides.Ignore) > 0 {
+		defaults.Ignore = overrides.Ignore
+	}
+	if overrides.Cooldown != nil {
+		defaults.Cooldown = overrides.Cooldown
+	}
+	if len(overrides.Groups) > 0 {
+		defaults.Groups = overrides.Groups
 	}
+	return defaults
 }
 
 // fixUpdateConfig fixes the config for an Update, if necessary
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"errors"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseToolConfig(t *testing.T) {
@@ -139,7 +146,7 @@ updates:
 	}
 }
 
-func TestIsManifestCovered(t *testing.T) {
+func TestCoveringUpdateIndex(t *testing.T) {
 	config := DependabotConfig{
 		Updates: []Update{
 			{PackageEcosystem: "docker", Directory: "/"},
@@ -175,9 +182,9 @@ func TestIsManifestCovered(t *testing.T) {
 		{"npm", "npm/stuff/not_here/package.json", false},
 		{"github-actions", ".github/workflows/action.yml", true},
 	} {
-		got := config.IsManifestCovered(tt.manifestFile, tt.manifestType, []string{})
+		got := config.coveringUpdateIndex(tt.manifestFile, tt.manifestType) >= 0
 		if tt.expected != got {
-			t.Errorf("IsManifestCovered(%v, %v) failed; expected %t got %t", tt.manifestType, tt.manifestFile, tt.expected, got)
+			t.Errorf("coveringUpdateIndex(%v, %v) failed; expected covered %t got %t", tt.manifestType, tt.manifestFile, tt.expected, got)
 		}
 	}
 }
@@ -186,6 +193,10 @@ func LoadFileContentDummy(_ string, _ LoadFileContentParameters) string {
 	return "dummy"
 }
 
+func CheckDirectoryExistsDummy(_ string, _ LoadFileContentParameters) (bool, error) {
+	return true, nil
+}
+
 func TestAddManifest(t *testing.T) {
 	config := DependabotConfig{}
 	toolConfig := ToolConfig{
@@ -292,3 +303,1365 @@ func TestGetManifestType(t *testing.T) {
 		}
 	}
 }
+
+func TestGetManifestTypeIgnorePatterns(t *testing.T) {
+	config := ToolConfig{
+		ManifestPatterns: map[string]string{
+			"npm":    "(.*/)?package\\.json",
+			"docker": "(.*/)?Dockerfile",
+		},
+		ManifestIgnorePattern:  "^vendor/",
+		ManifestIgnorePatterns: map[string]string{"npm": "^test/fixtures/"},
+	}
+	config.InitializePatterns()
+	defer (&ToolConfig{}).InitializePatterns()
+
+	for _, tt := range []struct {
+		fullPath string
+		expected string
+	}{
+		{"package.json", "npm"},
+		{"test/fixtures/package.json", ""},
+		{"test/fixtures/Dockerfile", "docker"},
+		{"vendor/package.json", ""},
+		{"vendor/Dockerfile", ""},
+	} {
+		got := GetManifestType(tt.fullPath)
+		if tt.expected != got {
+			t.Errorf("GetManifestType() failed for %v : expected '%v', got '%v'", tt.fullPath, tt.expected, got)
+		}
+	}
+}
+
+func TestProcessManifestRegistryManifestPaths(t *testing.T) {
+	toolConfig := ToolConfig{
+		UpdateDefaults: UpdateDefaults{Schedule: Schedule{Interval: "daily"}},
+		Registries: map[string]DefaultRegistries{
+			"npm": map[string]DefaultRegistry{
+				"npm-frontend": {Type: "npm-registry", URL: "https://npm.foo.bar", ManifestPaths: []string{"/frontend/**"}},
+				"npm-global":   {Type: "npm-registry", URL: "https://npm.bar.foo"},
+			},
+		},
+	}
+	for _, tt := range []struct {
+		manifestFile       string
+		expectedRegistries []string
+	}{
+		{"package.json", []string{"npm-global"}},
+		{"frontend/package.json", []string{"npm-frontend", "npm-global"}},
+		{"frontend/app/package.json", []string{"npm-frontend", "npm-global"}},
+		{"backend/package.json", []string{"npm-global"}},
+	} {
+		config := DependabotConfig{}
+		config.ProcessManifest(tt.manifestFile, "npm", toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+		got := config.Updates[0].Registries
+		sort.Strings(got)
+		if !reflect.DeepEqual(tt.expectedRegistries, got) {
+			t.Errorf("ProcessManifest(%v) failed; expected registries %v got %v", tt.manifestFile, tt.expectedRegistries, got)
+		}
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	for _, tt := range []struct {
+		registry Registry
+		expected []string
+	}{
+		{Registry{Type: "npm-registry", URL: "https://npm.foo.bar"}, []string{}},
+		{Registry{Username: "usr", Password: "${{secrets.PASSWORD}}"}, []string{"PASSWORD"}},
+		{Registry{Username: "${{ secrets.USER }}", Password: "${{secrets.PASSWORD}}", Token: "${{secrets.PASSWORD}}"}, []string{"USER", "PASSWORD"}},
+	} {
+		got := tt.registry.SecretReferences()
+		if !reflect.DeepEqual(tt.expected, got) {
+			t.Errorf("SecretReferences() failed; expected %v got %v", tt.expected, got)
+		}
+	}
+}
+
+func TestCompareConfigs(t *testing.T) {
+	previous := &DependabotConfig{
+		Registries: map[string]Registry{
+			"npm-reg":    {Type: "npm-registry"},
+			"docker-reg": {Type: "docker-registry"},
+		},
+		Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "/"},
+			{PackageEcosystem: "docker", Directory: "/app"},
+		},
+	}
+	current := &DependabotConfig{
+		Registries: map[string]Registry{
+			"npm-reg": {Type: "npm-registry"},
+			"pip-reg": {Type: "python-index"},
+		},
+		Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "/", OpenPullRequestsLimit: 5},
+			{PackageEcosystem: "pip", Directory: "/"},
+		},
+	}
+	expected := ConfigDiff{
+		AddedRegistries:   []RegistryInfo{{Type: "python-index", Name: "pip-reg"}},
+		RemovedRegistries: []RegistryInfo{{Type: "docker-registry", Name: "docker-reg"}},
+		AddedUpdates:      []UpdateInfo{{Type: "pip", Directory: "/"}},
+		RemovedUpdates:    []UpdateInfo{{Type: "docker", Directory: "/app"}},
+	}
+	got := CompareConfigs(previous, current)
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("CompareConfigs() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+	if !CompareConfigs(current, current).IsEmpty() {
+		t.Errorf("CompareConfigs() failed; expected no differences for identical configs")
+	}
+}
+
+func TestUpdateConfigRemoveUnusedRegistries(t *testing.T) {
+	for _, tt := range []struct {
+		removeUnusedRegistries bool
+		expectedRegistries     []string
+		expectedRemoved        []RegistryInfo
+	}{
+		{false, []string{"npm-reg", "unused-reg"}, []RegistryInfo{}},
+		{true, []string{"npm-reg"}, []RegistryInfo{{Type: "docker-registry", Name: "unused-reg"}}},
+	} {
+		config := DependabotConfig{
+			Registries: map[string]Registry{
+				"npm-reg":    {Type: "npm-registry"},
+				"unused-reg": {Type: "docker-registry"},
+			},
+			Updates: []Update{
+				{PackageEcosystem: "npm", Directory: "/", Registries: []string{"npm-reg"}},
+			},
+		}
+		toolConfig := ToolConfig{RemoveUnusedRegistries: tt.removeUnusedRegistries}
+		changeInfo := config.UpdateConfig(map[string]string{}, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		if got := sortedRegistryNames(config.Registries); !reflect.DeepEqual(tt.expectedRegistries, got) {
+			t.Errorf("UpdateConfig() failed; expected registries %v got %v", tt.expectedRegistries, got)
+		}
+		if !reflect.DeepEqual(tt.expectedRemoved, changeInfo.RemovedRegistries) {
+			t.Errorf("UpdateConfig() failed; expected removed registries %v got %v", tt.expectedRemoved, changeInfo.RemovedRegistries)
+		}
+		if changeInfo.HasChanges() != tt.removeUnusedRegistries {
+			t.Errorf("UpdateConfig() failed; expected changes %t", tt.removeUnusedRegistries)
+		}
+	}
+}
+
+func TestUpdateConfigRemoveMissingDirectories(t *testing.T) {
+	checkDirectoryFn := func(directory string, _ LoadFileContentParameters) (bool, error) {
+		if directory == "/unknown" {
+			return false, errors.New("502 Bad Gateway")
+		}
+		return directory != "/gone", nil
+	}
+	for _, tt := range []struct {
+		removeMissingDirectories bool
+		withUnknown              bool
+		expectedCount            int
+		expectedRemoved          []UpdateInfo
+	}{
+		{false, false, 3, []UpdateInfo{}},
+		{true, false, 2, []UpdateInfo{{Type: "npm", Directory: "/gone"}}},
+		{true, true, 4, []UpdateInfo{}},
+	} {
+		config := DependabotConfig{
+			Updates: []Update{
+				{PackageEcosystem: "github-actions", Directory: "/"},
+				{PackageEcosystem: "npm", Directory: "/app"},
+				{PackageEcosystem: "npm", Directory: "/gone"},
+			},
+		}
+		if tt.withUnknown {
+			config.Updates = append(config.Updates, Update{PackageEcosystem: "npm", Directory: "/unknown"})
+		}
+		toolConfig := ToolConfig{RemoveMissingDirectories: tt.removeMissingDirectories}
+		changeInfo := config.UpdateConfig(map[string]string{}, toolConfig, LoadFileContentDummy, checkDirectoryFn, LoadFileContentParameters{})
+		if len(config.Updates) != tt.expectedCount {
+			t.Errorf("UpdateConfig() failed; expected %v updates got %v", tt.expectedCount, len(config.Updates))
+		}
+		if !reflect.DeepEqual(tt.expectedRemoved, changeInfo.RemovedUpdates) {
+			t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+	}
+}
+
+func TestDirectoryInFileList(t *testing.T) {
+	files := []string{"README.md", "app", "app/package.json", "lib/sub/go.mod"}
+	for _, tt := range []struct {
+		directory string
+		expected  bool
+	}{
+		{"/", true},
+		{"/app", true},
+		{"/app/", true},
+		{"/ap", false},
+		{"/lib", true},
+		{"/lib/sub", true},
+		{"/lib/other", false},
+	} {
+		got := DirectoryInFileList(tt.directory, files)
+		if tt.expected != got {
+			t.Errorf("DirectoryInFileList(%v) failed; expected %t got %t", tt.directory, tt.expected, got)
+		}
+	}
+}
+
+func TestForRepo(t *testing.T) {
+	toolConfig := ToolConfig{
+		UpdateDefaults: UpdateDefaults{
+			Schedule:              Schedule{Interval: "daily"},
+			OpenPullRequestsLimit: 5,
+		},
+		UpdateOverrides: map[string]UpdateDefaults{
+			"docker": {Schedule: Schedule{Interval: "weekly"}},
+			"pip":    {OpenPullRequestsLimit: 2},
+		},
+		Registries: map[string]DefaultRegistries{
+			"npm": {"npm-reg": {Type: "npm-registry", URL: "https://npm.foo.bar"}},
+		},
+		RepoOverrides: map[string]RepoOverride{
+			"monorepo": {
+				UpdateDefaults: UpdateDefaults{OpenPullRequestsLimit: 20, Labels: []string{"monorepo"}},
+				UpdateOverrides: map[string]UpdateDefaults{
+					"npm": {Schedule: Schedule{Interval: "weekly", Day: "monday"}},
+				},
+				Registries: map[string]DefaultRegistries{
+					"npm": {"npm-mono": {Type: "npm-registry", URL: "https://mono.foo.bar"}},
+				},
+			},
+			"service-.*": {
+				UpdateDefaults: UpdateDefaults{Schedule: Schedule{Interval: "monthly"}},
+			},
+		},
+	}
+	for _, tt := range []struct {
+		repo                   string
+		expectedInterval       string
+		expectedLimit          int
+		expectedNpmDay         string
+		expectedDockerInterval string
+		expectedPipLimit       int
+		expectedRegistries     int
+	}{
+		{"other", "daily", 5, "", "weekly", 2, 1},
+		// the update-defaults of repo overrides take precedence over the global update-overrides
+		{"monorepo", "daily", 20, "monday", "weekly", 20, 2},
+		{"service-payments", "monthly", 5, "", "monthly", 2, 1},
+		{"my-service-payments", "daily", 5, "", "weekly", 2, 1},
+	} {
+		got := toolConfig.ForRepo(tt.repo)
+		if got.UpdateDefaults.Schedule.Interval != tt.expectedInterval || got.UpdateDefaults.OpenPullRequestsLimit != tt.expectedLimit {
+			t.Errorf("ForRepo(%v) failed; got update defaults %v", tt.repo, got.UpdateDefaults)
+		}
+		if got.UpdateOverrides["npm"].Schedule.Day != tt.expectedNpmDay {
+			t.Errorf("ForRepo(%v) failed; expected npm day %v got %v", tt.repo, tt.expectedNpmDay, got.UpdateOverrides["npm"].Schedule.Day)
+		}
+		if got.UpdateOverrides["docker"].Schedule.Interval != tt.expectedDockerInterval {
+			t.Errorf("ForRepo(%v) failed; expected docker interval %v got %v", tt.repo, tt.expectedDockerInterval, got.UpdateOverrides["docker"].Schedule.Interval)
+		}
+		if got.UpdateOverrides["pip"].OpenPullRequestsLimit != tt.expectedPipLimit {
+			t.Errorf("ForRepo(%v) failed; expected pip limit %v got %v", tt.repo, tt.expectedPipLimit, got.UpdateOverrides["pip"].OpenPullRequestsLimit)
+		}
+		if len(got.Registries["npm"]) != tt.expectedRegistries {
+			t.Errorf("ForRepo(%v) failed; expected %v npm registries got %v", tt.repo, tt.expectedRegistries, len(got.Registries["npm"]))
+		}
+	}
+	// the original config must not be modified
+	if len(toolConfig.Registries["npm"]) != 1 || toolConfig.UpdateDefaults.OpenPullRequestsLimit != 5 {
+		t.Errorf("ForRepo() failed; original config modified")
+	}
+}
+
+func TestPullRequestParametersRender(t *testing.T) {
+	data := TemplateData{Org: "foo", Repo: "payments-service", Date: time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC), ChangeCount: 3}
+	prParams := PullRequestParameters{
+		CommitMessage: "update config of {{org}}/{{repo}}",
+		PRTitle:       "chore: update dependabot config for {{repo}} ({{ change-count }} new updates)",
+		BranchName:    "dependabutler-{{date}}",
+		AuthorName:    "{{repo}}",
+	}
+	got, err := prParams.Render(data)
+	if err != nil {
+		t.Fatalf("Render() failed; unexpected error %v", err)
+	}
+	expected := PullRequestParameters{
+		CommitMessage: "update config of foo/payments-service",
+		PRTitle:       "chore: update dependabot config for payments-service (3 new updates)",
+		BranchName:    "dependabutler-2024-03-07",
+		AuthorName:    "{{repo}}",
+		// the date changes between runs, and matches any text
+		BranchNamePattern: "dependabutler-.+",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Render() failed; expected %v got %v", expected, got)
+	}
+	if got, _ := (PullRequestParameters{BranchName: "dependabutler/{{repo}}.{{ change-count }}"}).Render(data); got.BranchNamePattern != `dependabutler/payments-service\..+` {
+		t.Errorf("Render() failed; expected branch name pattern dependabutler/payments-service\\..+ got %v", got.BranchNamePattern)
+	}
+	if _, err := (PullRequestParameters{PRTitle: "{{unknown}}"}).Render(data); err == nil {
+		t.Errorf("Render() failed; expected an error for an unknown placeholder")
+	}
+}
+
+func TestProcessManifestRegistryReferences(t *testing.T) {
+	toolConfig := ToolConfig{
+		Registries: map[string]DefaultRegistries{
+			"npm": {
+				"npm-reg":   {Type: "npm-registry", URL: "https://npm.foo.bar"},
+				"other-reg": {Type: "npm-registry", URL: "https://other.foo.bar", URLMatchRequired: true},
+			},
+		},
+	}
+	for _, tt := range []struct {
+		mode               string
+		expectedRegistries []string
+		expectedBlocks     int
+		expectedAdded      []RegistryRefInfo
+		expectedMissing    []RegistryRefInfo
+	}{
+		{RegistryReferencesReport, nil, 0, nil, []RegistryRefInfo{{Registry: "npm-reg", Type: "npm", Directory: "/"}}},
+		{"", []string{"npm-reg"}, 1, []RegistryRefInfo{{Registry: "npm-reg", Type: "npm", Directory: "/"}}, nil},
+		{RegistryReferencesAdd, []string{"npm-reg"}, 1, []RegistryRefInfo{{Registry: "npm-reg", Type: "npm", Directory: "/"}}, nil},
+	} {
+		toolConfig.RegistryReferences = tt.mode
+		fix := tt.mode != RegistryReferencesReport
+		dependabotConfig := DependabotConfig{Updates: []Update{{PackageEcosystem: "npm", Directory: "/"}}}
+		changeInfo := ChangeInfo{}
+		for _, manifestFile := range []string{"package.json", "app/package.json"} {
+			dependabotConfig.ProcessManifest(manifestFile, "npm", toolConfig, &changeInfo, LoadFileContentDummy, LoadFileContentParameters{})
+		}
+		if !reflect.DeepEqual(dependabotConfig.Updates[0].Registries, tt.expectedRegistries) {
+			t.Errorf("ProcessManifest() with %v failed; expected registries %v got %v", tt.mode, tt.expectedRegistries, dependabotConfig.Updates[0].Registries)
+		}
+		if len(dependabotConfig.Registries) != tt.expectedBlocks {
+			t.Errorf("ProcessManifest() with %v failed; expected %v registry blocks got %v", tt.mode, tt.expectedBlocks, len(dependabotConfig.Registries))
+		}
+		if !reflect.DeepEqual(changeInfo.AddedRegistryRefs, tt.expectedAdded) || !reflect.DeepEqual(changeInfo.MissingRegistryRefs, tt.expectedMissing) {
+			t.Errorf("ProcessManifest() with %v failed; got added %v missing %v", tt.mode, changeInfo.AddedRegistryRefs, changeInfo.MissingRegistryRefs)
+		}
+		if changeInfo.HasChanges() != fix {
+			t.Errorf("ProcessManifest() with %v failed; unexpected HasChanges() %t", tt.mode, changeInfo.HasChanges())
+		}
+	}
+}
+
+func TestProcessManifestInheritRegistries(t *testing.T) {
+	toolConfig := ToolConfig{
+		RegistryReferences: RegistryReferencesInherit,
+		Registries: map[string]DefaultRegistries{
+			"npm": {
+				"npm-reg":   {Type: "npm-registry", URL: "https://npm.foo.bar"},
+				"other-reg": {Type: "npm-registry", URL: "https://other.foo.bar", URLMatchRequired: true},
+				"third-reg": {Type: "npm-registry", URL: "https://third.foo.bar", URLMatchRequired: true},
+			},
+		},
+	}
+	loadFile := func(file string, params LoadFileContentParameters) string {
+		return `{"publishConfig": {"registry": "https://other.foo.bar/"}}`
+	}
+	// npm-reg is part of the config already, the reference being left out on purpose
+	dependabotConfig := DependabotConfig{
+		Registries: map[string]Registry{"npm-reg": {Type: "npm-registry", URL: "https://npm.foo.bar"}},
+		Updates:    []Update{{PackageEcosystem: "npm", Directory: "/"}},
+	}
+	changeInfo := ChangeInfo{}
+	dependabotConfig.ProcessManifest("package.json", "npm", toolConfig, &changeInfo, loadFile, LoadFileContentParameters{})
+	if !reflect.DeepEqual(dependabotConfig.Updates[0].Registries, []string{"other-reg"}) {
+		t.Errorf("ProcessManifest() failed; expected registries [other-reg] got %v", dependabotConfig.Updates[0].Registries)
+	}
+	if _, found := dependabotConfig.Registries["other-reg"]; !found || len(dependabotConfig.Registries) != 2 {
+		t.Errorf("ProcessManifest() failed; expected the registry block of other-reg, got %v", dependabotConfig.Registries)
+	}
+	expectedAdded := []RegistryRefInfo{{Registry: "other-reg", Type: "npm", Directory: "/"}}
+	expectedMissing := []RegistryRefInfo{{Registry: "npm-reg", Type: "npm", Directory: "/"}}
+	if !reflect.DeepEqual(changeInfo.AddedRegistryRefs, expectedAdded) || !reflect.DeepEqual(changeInfo.MissingRegistryRefs, expectedMissing) {
+		t.Errorf("ProcessManifest() failed; got added %v missing %v", changeInfo.AddedRegistryRefs, changeInfo.MissingRegistryRefs)
+	}
+
+	// protected updates are left as they are
+	dependabotConfig = DependabotConfig{Updates: []Update{{PackageEcosystem: "npm", Directory: "/", Labels: []string{ProtectedMarker}}}}
+	changeInfo = ChangeInfo{}
+	dependabotConfig.ProcessManifest("package.json", "npm", toolConfig, &changeInfo, loadFile, LoadFileContentParameters{})
+	if len(dependabotConfig.Updates[0].Registries) > 0 || len(changeInfo.AddedRegistryRefs) > 0 {
+		t.Errorf("ProcessManifest() failed; expected no registries for a protected update, got %v", dependabotConfig.Updates[0].Registries)
+	}
+}
+
+func TestNormalizeDirectory(t *testing.T) {
+	for _, tt := range []struct {
+		directory string
+		expected  string
+	}{
+		{"", ""},
+		{"/", "/"},
+		{".", "/"},
+		{"./", "/"},
+		{"app", "/app"},
+		{"./app", "/app"},
+		{"/app/", "/app"},
+		{"//app//sub", "/app/sub"},
+		{"/app/./sub/../other", "/app/other"},
+		{"/apps/*", "/apps/*"},
+	} {
+		if got := NormalizeDirectory(tt.directory); got != tt.expected {
+			t.Errorf("NormalizeDirectory(%v) failed; expected %v got %v", tt.directory, tt.expected, got)
+		}
+	}
+}
+
+func TestUpdateConfigNormalizeDirectories(t *testing.T) {
+	dependabotConfig := DependabotConfig{
+		Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "./app"},
+			{PackageEcosystem: "docker", Directories: []string{"/", "svc//a", "/svc/b"}},
+			{PackageEcosystem: "pip", Directory: "/"},
+		},
+	}
+	manifests := map[string]string{"app/package.json": "npm", "svc/a/Dockerfile": "docker"}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, ToolConfig{}, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedFixes := []FixInfo{
+		{Type: "npm", Directory: "/app", Change: "directory ./app normalized"},
+		{Type: "docker", Directory: "/svc/a", Change: "directories entry svc//a normalized"},
+	}
+	if !reflect.DeepEqual(changeInfo.FixedUpdates, expectedFixes) {
+		t.Errorf("UpdateConfig() failed; expected fixes %v got %v", expectedFixes, changeInfo.FixedUpdates)
+	}
+	if len(changeInfo.NewUpdates) != 0 {
+		t.Errorf("UpdateConfig() failed; expected the manifests to be covered, got new updates %v", changeInfo.NewUpdates)
+	}
+	if dependabotConfig.Updates[0].Directory != "/app" || dependabotConfig.Updates[1].Directories[1] != "/svc/a" {
+		t.Errorf("UpdateConfig() failed; directories not normalized: %v", dependabotConfig.Updates)
+	}
+}
+
+func TestUpdateConfigConsolidateDirectories(t *testing.T) {
+	weekly := Schedule{Interval: "weekly"}
+	for _, tt := range []struct {
+		consolidate     bool
+		expectedUpdates []Update
+		expectedFixes   int
+	}{
+		{false, []Update{
+			{PackageEcosystem: "npm", Directory: "/packages/a", Schedule: weekly},
+			{PackageEcosystem: "npm", Directories: []string{"/apps/*"}, Schedule: weekly},
+			{PackageEcosystem: "npm", Directory: "/legacy", Schedule: Schedule{Interval: "daily"}},
+			{PackageEcosystem: "npm", Directory: "/packages/b", Schedule: weekly},
+			{PackageEcosystem: "docker", Directory: "/packages/a", Schedule: weekly},
+		}, 0},
+		{true, []Update{
+			{PackageEcosystem: "npm", Directories: []string{"/apps/*", "/packages/a", "/packages/b"}, Schedule: weekly},
+			{PackageEcosystem: "npm", Directory: "/legacy", Schedule: Schedule{Interval: "daily"}},
+			{PackageEcosystem: "docker", Directory: "/packages/a", Schedule: weekly},
+		}, 1},
+	} {
+		dependabotConfig := DependabotConfig{
+			Updates: []Update{
+				{PackageEcosystem: "npm", Directory: "/packages/a", Schedule: weekly},
+				{PackageEcosystem: "npm", Directories: []string{"/apps/*"}, Schedule: weekly},
+				{PackageEcosystem: "npm", Directory: "/legacy", Schedule: Schedule{Interval: "daily"}},
+				{PackageEcosystem: "npm", Directory: "/packages/b", Schedule: weekly},
+				{PackageEcosystem: "docker", Directory: "/packages/a", Schedule: weekly},
+			},
+		}
+		toolConfig := ToolConfig{ConsolidateDirectories: tt.consolidate}
+		changeInfo := dependabotConfig.UpdateConfig(map[string]string{}, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		if !reflect.DeepEqual(dependabotConfig.Updates, tt.expectedUpdates) {
+			t.Errorf("UpdateConfig() with consolidate %t failed;\n  expected %v\n  got      %v", tt.consolidate, tt.expectedUpdates, dependabotConfig.Updates)
+		}
+		if len(changeInfo.FixedUpdates) != tt.expectedFixes {
+			t.Errorf("UpdateConfig() with consolidate %t failed; expected %v fixes got %v", tt.consolidate, tt.expectedFixes, changeInfo.FixedUpdates)
+		}
+	}
+}
+
+func TestUpdateConfigDuplicateUpdates(t *testing.T) {
+	for _, tt := range []struct {
+		merge            bool
+		expectedUpdates  []Update
+		expectedWarnings int
+		expectedFixes    int
+	}{
+		{false, []Update{
+			{PackageEcosystem: "npm", Directory: "/app", Registries: []string{"npm-reg"}},
+			{PackageEcosystem: "npm", Directory: "/app", Registries: []string{"other-reg"}, Schedule: Schedule{Interval: "weekly"}},
+			{PackageEcosystem: "npm", Directory: "/app", TargetBranch: "develop"},
+			{PackageEcosystem: "npm", Directories: []string{"/app", "/lib"}},
+			{PackageEcosystem: "docker", Directory: "/app"},
+		}, 2, 0},
+		{true, []Update{
+			{PackageEcosystem: "npm", Directory: "/app", Registries: []string{"npm-reg", "other-reg"}},
+			{PackageEcosystem: "npm", Directory: "/app", TargetBranch: "develop"},
+			{PackageEcosystem: "npm", Directories: []string{"/lib"}},
+			{PackageEcosystem: "docker", Directory: "/app"},
+		}, 0, 2},
+	} {
+		dependabotConfig := DependabotConfig{
+			Updates: []Update{
+				{PackageEcosystem: "npm", Directory: "/app", Registries: []string{"npm-reg"}},
+				{PackageEcosystem: "npm", Directory: "app/", Registries: []string{"other-reg"}, Schedule: Schedule{Interval: "weekly"}},
+				{PackageEcosystem: "npm", Directory: "/app", TargetBranch: "develop"},
+				{PackageEcosystem: "npm", Directories: []string{"/app", "/lib"}},
+				{PackageEcosystem: "docker", Directory: "/app"},
+			},
+		}
+		toolConfig := ToolConfig{MergeDuplicateUpdates: tt.merge}
+		changeInfo := dependabotConfig.UpdateConfig(map[string]string{}, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		if !reflect.DeepEqual(dependabotConfig.Updates, tt.expectedUpdates) {
+			t.Errorf("UpdateConfig() with merge %t failed;\n  expected %v\n  got      %v", tt.merge, tt.expectedUpdates, dependabotConfig.Updates)
+		}
+		if len(changeInfo.Warnings) != tt.expectedWarnings {
+			t.Errorf("UpdateConfig() with merge %t failed; expected %v warnings got %v", tt.merge, tt.expectedWarnings, changeInfo.Warnings)
+		}
+		// the normalization of app/ is a fix as well
+		if len(changeInfo.FixedUpdates) != tt.expectedFixes+1 {
+			t.Errorf("UpdateConfig() with merge %t failed; expected %v fixes got %v", tt.merge, tt.expectedFixes+1, changeInfo.FixedUpdates)
+		}
+	}
+}
+
+func TestCoveringUpdateIndexGlob(t *testing.T) {
+	config := DependabotConfig{
+		Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "/apps/*"},
+			{PackageEcosystem: "npm", Directories: []string{"/lib", "/services/**"}},
+			{PackageEcosystem: "docker", Directories: []string{"/images/base-?"}},
+		},
+	}
+	for _, tt := range []struct {
+		manifestType string
+		manifestFile string
+		expected     bool
+	}{
+		{"npm", "apps/web/package.json", true},
+		{"npm", "apps/web/sub/package.json", true},
+		{"npm", "apps/package.json", false},
+		{"npm", "package.json", false},
+		{"npm", "lib/package.json", true},
+		{"npm", "services/package.json", true},
+		{"npm", "services/a/b/package.json", true},
+		{"npm", "other/services/a/package.json", false},
+		{"docker", "images/base-1/Dockerfile", true},
+		{"docker", "images/base-10/Dockerfile", false},
+		{"docker", "apps/web/Dockerfile", false},
+	} {
+		got := config.coveringUpdateIndex(tt.manifestFile, tt.manifestType) >= 0
+		if tt.expected != got {
+			t.Errorf("coveringUpdateIndex(%v, %v) failed; expected covered %t got %t", tt.manifestType, tt.manifestFile, tt.expected, got)
+		}
+	}
+}
+
+func TestUpdateConfigAnchors(t *testing.T) {
+	for _, tt := range []struct {
+		content          string
+		manifests        map[string]string
+		expectedWarnings int
+	}{
+		{"version: 2\nupdates:\n  - package-ecosystem: npm\n    directory: /\n", map[string]string{"app/Dockerfile": "docker"}, 0},
+		{"version: 2\nupdates:\n  - &npm\n    package-ecosystem: npm\n    directory: /\n", map[string]string{}, 0},
+		{"version: 2\nupdates:\n  - &npm\n    package-ecosystem: npm\n    directory: /\n", map[string]string{"app/Dockerfile": "docker"}, 1},
+	} {
+		dependabotConfig, err := ParseDependabotConfig([]byte(tt.content))
+		if err != nil {
+			t.Fatalf("ParseDependabotConfig() failed; unexpected error %v", err)
+		}
+		changeInfo := dependabotConfig.UpdateConfig(tt.manifests, ToolConfig{}, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		if len(changeInfo.Warnings) != tt.expectedWarnings {
+			t.Errorf("UpdateConfig() failed; expected %v warnings got %v", tt.expectedWarnings, changeInfo.Warnings)
+		}
+	}
+}
+
+func TestParseDependabotConfigMergeKeys(t *testing.T) {
+	content := `version: 2
+x-defaults: &defaults
+  schedule:
+    interval: weekly
+  open-pull-requests-limit: 5
+updates:
+  - <<: *defaults
+    package-ecosystem: npm
+    directory: /
+  - <<: *defaults
+    package-ecosystem: docker
+    directory: /app
+    open-pull-requests-limit: 2
+`
+	got, err := ParseDependabotConfig([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseDependabotConfig() failed; unexpected error %v", err)
+	}
+	expected := []Update{
+		{PackageEcosystem: "npm", Directory: "/", Schedule: Schedule{Interval: "weekly"}, OpenPullRequestsLimit: 5},
+		{PackageEcosystem: "docker", Directory: "/app", Schedule: Schedule{Interval: "weekly"}, OpenPullRequestsLimit: 2},
+	}
+	if !reflect.DeepEqual(got.Updates, expected) {
+		t.Errorf("ParseDependabotConfig() failed;\n  expected %v\n  got      %v", expected, got.Updates)
+	}
+	if !got.usesAnchors {
+		t.Errorf("ParseDependabotConfig() failed; expected anchors to be detected")
+	}
+}
+
+func TestProcessManifestGroups(t *testing.T) {
+	minorAndPatch := Group{Patterns: []string{"*"}, UpdateTypes: []string{"minor", "patch"}}
+	toolConfig := ToolConfig{
+		UpdateDefaults: UpdateDefaults{
+			Groups: map[string]Group{"minor-and-patch": minorAndPatch},
+		},
+		UpdateOverrides: map[string]UpdateDefaults{
+			"npm": {Groups: map[string]Group{
+				"dev-dependencies": {DependencyType: "development", ExcludePatterns: []string{"eslint*"}},
+			}},
+		},
+	}
+	for _, tt := range []struct {
+		manifestFile   string
+		manifestType   string
+		expectedGroups map[string]Group
+	}{
+		{"Dockerfile", "docker", map[string]Group{"minor-and-patch": minorAndPatch}},
+		{"package.json", "npm", map[string]Group{
+			"dev-dependencies": {DependencyType: "development", ExcludePatterns: []string{"eslint*"}},
+		}},
+	} {
+		dependabotConfig := DependabotConfig{}
+		dependabotConfig.ProcessManifest(tt.manifestFile, tt.manifestType, toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+		if !reflect.DeepEqual(dependabotConfig.Updates[0].Groups, tt.expectedGroups) {
+			t.Errorf("ProcessManifest(%v) failed; expected groups %v got %v", tt.manifestFile, tt.expectedGroups, dependabotConfig.Updates[0].Groups)
+		}
+	}
+}
+
+func TestProcessManifestUnsupportedEcosystem(t *testing.T) {
+	dependabotConfig := DependabotConfig{}
+	changeInfo := ChangeInfo{}
+	dependabotConfig.ProcessManifest("MODULE.bazel", "bazel", ToolConfig{}, &changeInfo, LoadFileContentDummy, LoadFileContentParameters{})
+	dependabotConfig.ProcessManifest("app/Dockerfile", "docker", ToolConfig{}, &changeInfo, LoadFileContentDummy, LoadFileContentParameters{})
+	expected := []SkippedInfo{{Type: "bazel", File: "MODULE.bazel", Reason: "unsupported"}}
+	if !reflect.DeepEqual(changeInfo.SkippedManifests, expected) {
+		t.Errorf("ProcessManifest() failed; expected skipped %v got %v", expected, changeInfo.SkippedManifests)
+	}
+	if len(dependabotConfig.Updates) != 1 || dependabotConfig.Updates[0].PackageEcosystem != "docker" {
+		t.Errorf("ProcessManifest() failed; unexpected updates %v", dependabotConfig.Updates)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	toolConfig := ToolConfig{
+		Credentials: Credentials{GitHubToken: "vault://github#token"},
+		Registries: map[string]DefaultRegistries{
+			"npm": {"npm-reg": {Type: "npm-registry", URL: "vault://npm#url", Username: "vault://npm#username", Password: "${{secrets.NPM_PASSWORD}}"}},
+		},
+	}
+	resolve := func(value string) (string, error) {
+		values := map[string]string{"vault://github#token": "ghp_token", "vault://npm#url": "https://npm.foo.bar", "vault://npm#username": "npm-user"}
+		if resolved, found := values[value]; found {
+			return resolved, nil
+		}
+		if strings.HasPrefix(value, "vault://") {
+			return "", errors.New("unknown secret")
+		}
+		return value, nil
+	}
+	if err := toolConfig.ResolveSecrets(resolve); err != nil {
+		t.Fatalf("ResolveSecrets() failed; unexpected error %v", err)
+	}
+	expected := DefaultRegistry{Type: "npm-registry", URL: "https://npm.foo.bar", Username: "npm-user", Password: "${{secrets.NPM_PASSWORD}}"}
+	if toolConfig.Credentials.GitHubToken != "ghp_token" || !reflect.DeepEqual(toolConfig.Registries["npm"]["npm-reg"], expected) {
+		t.Errorf("ResolveSecrets() failed; got %v %v", toolConfig.Credentials, toolConfig.Registries["npm"]["npm-reg"])
+	}
+	toolConfig.Credentials.GitHubToken = "vault://unknown#token"
+	if err := toolConfig.ResolveSecrets(resolve); err == nil {
+		t.Errorf("ResolveSecrets() failed; expected an error for an unknown secret")
+	}
+}
+
+func TestUpdateConfigRemoveDirectoriesWithoutManifests(t *testing.T) {
+	dependabotConfig := DependabotConfig{
+		Updates: []Update{
+			{PackageEcosystem: "github-actions", Directory: "/"},
+			{PackageEcosystem: "npm", Directory: "/app"},
+			{PackageEcosystem: "npm", Directory: "/stale"},
+			{PackageEcosystem: "npm", Directories: []string{"/libs/*"}},
+			{PackageEcosystem: "docker", Directory: "/stale"},
+			{PackageEcosystem: "terraform", Directory: "/infra"},
+		},
+	}
+	manifests := map[string]string{
+		"app/package.json":         "npm",
+		"libs/a/package.json":      "npm",
+		"stale/README.md":          "",
+		".github/workflows/ci.yml": "github-actions",
+	}
+	manifestPatterns := map[string]string{"npm": "package\\.json$", "docker": "Dockerfile$", "github-actions": "\\.yml$"}
+	for _, tt := range []struct {
+		toolConfig      ToolConfig
+		params          LoadFileContentParameters
+		expectedRemoved []UpdateInfo
+	}{
+		// remove-missing-directories alone doesn't check for manifests
+		{ToolConfig{RemoveMissingDirectories: true}, LoadFileContentParameters{}, []UpdateInfo{}},
+		{
+			ToolConfig{Cleanup: Cleanup{MissingManifests: CleanupRemove}},
+			LoadFileContentParameters{},
+			[]UpdateInfo{{Type: "npm", Directory: "/stale"}, {Type: "docker", Directory: "/stale"}},
+		},
+		// truncated file lists may lack manifests
+		{ToolConfig{Cleanup: Cleanup{MissingManifests: CleanupRemove}}, LoadFileContentParameters{FileListTruncated: true}, []UpdateInfo{}},
+	} {
+		config := DependabotConfig{Updates: append([]Update{}, dependabotConfig.Updates...)}
+		tt.toolConfig.ManifestPatterns = manifestPatterns
+		changeInfo := config.UpdateConfig(manifests, tt.toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, tt.params)
+		if !reflect.DeepEqual(changeInfo.RemovedUpdates, tt.expectedRemoved) {
+			t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+		// terraform has no manifest pattern, so its update is kept
+		if expected := 6 - len(tt.expectedRemoved); len(config.Updates) != expected {
+			t.Errorf("UpdateConfig() failed; expected %v updates got %v", expected, config.Updates)
+		}
+	}
+}
+
+func TestUpdateConfigRemoveMissingDirectoriesOfList(t *testing.T) {
+	manifests := map[string]string{"app/package.json": "npm", "libs/a/package.json": "npm", "docs/README.md": ""}
+	checkDirectoryFn := func(directory string, _ LoadFileContentParameters) (bool, error) {
+		return directory == "/app" || directory == "/docs", nil
+	}
+	for _, tt := range []struct {
+		cleanup             Cleanup
+		expectedRemoved     []UpdateInfo
+		expectedDirectories []string
+	}{
+		{
+			Cleanup{MissingDirectories: CleanupRemove},
+			[]UpdateInfo{{Type: "npm", Directory: "/gone"}, {Type: "npm", Directory: "/old"}},
+			[]string{"/app", "/docs", "/libs/*"},
+		},
+		{
+			Cleanup{MissingDirectories: CleanupRemove, MissingManifests: CleanupRemove},
+			[]UpdateInfo{{Type: "npm", Directory: "/docs"}, {Type: "npm", Directory: "/gone"}, {Type: "npm", Directory: "/old"}},
+			[]string{"/app", "/libs/*"},
+		},
+		{Cleanup{MissingDirectories: CleanupWarnOnly}, []UpdateInfo{}, []string{"/app", "/docs", "/gone", "/libs/*"}},
+	} {
+		// consolidated updates, the second one with missing directories only
+		config := DependabotConfig{Updates: []Update{
+			{PackageEcosystem: "npm", Directories: []string{"/app", "/docs", "/gone", "/libs/*"}},
+			{PackageEcosystem: "npm", Directories: []string{"/old"}, Schedule: Schedule{Interval: "weekly"}},
+		}}
+		toolConfig := ToolConfig{Cleanup: tt.cleanup, ManifestPatterns: map[string]string{"npm": "(.*/)?package\\.json"}}
+		changeInfo := config.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, checkDirectoryFn, LoadFileContentParameters{})
+		if !reflect.DeepEqual(changeInfo.RemovedUpdates, tt.expectedRemoved) {
+			t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+		expectedUpdates := 1
+		if len(tt.expectedRemoved) == 0 {
+			expectedUpdates = 2
+		}
+		if len(config.Updates) != expectedUpdates || !reflect.DeepEqual(config.Updates[0].Directories, tt.expectedDirectories) {
+			t.Errorf("UpdateConfig() failed; expected %v updates, the first for %v, got %v", expectedUpdates, tt.expectedDirectories, config.Updates)
+		}
+	}
+}
+
+func TestUpdateConfigMissingManifestsIgnorePattern(t *testing.T) {
+	toolConfig := ToolConfig{
+		ManifestPatterns:       map[string]string{"npm": "(.*/)?package\\.json"},
+		ManifestIgnorePatterns: map[string]string{"npm": "^test/fixtures/"},
+	}
+	toolConfig.InitializePatterns()
+	defer (&ToolConfig{}).InitializePatterns()
+	manifests := map[string]string{}
+	ScanFileList([]string{"package.json", "test/fixtures/package.json"}, manifests)
+	for _, tt := range []struct {
+		cleanup         Cleanup
+		expectedRemoved []UpdateInfo
+		expectedWarning string
+	}{
+		{Cleanup{MissingDirectories: CleanupRemove}, []UpdateInfo{}, ""},
+		{Cleanup{MissingManifests: CleanupWarnOnly}, []UpdateInfo{}, "npm update for /test/fixtures kept, although the directory contains no npm manifests"},
+		{Cleanup{MissingManifests: CleanupRemove}, []UpdateInfo{{Type: "npm", Directory: "/test/fixtures"}}, ""},
+	} {
+		config := DependabotConfig{Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "/"},
+			{PackageEcosystem: "npm", Directory: "/test/fixtures"},
+		}}
+		toolConfig.Cleanup = tt.cleanup
+		changeInfo := config.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		if !reflect.DeepEqual(changeInfo.RemovedUpdates, tt.expectedRemoved) {
+			t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", tt.expectedRemoved, changeInfo.RemovedUpdates)
+		}
+		if tt.expectedWarning != "" && !util.Contains(changeInfo.Warnings, tt.expectedWarning) {
+			t.Errorf("UpdateConfig() failed; expected warning %v got %v", tt.expectedWarning, changeInfo.Warnings)
+		}
+	}
+}
+
+func TestUpdateConfigPathFilter(t *testing.T) {
+	dependabotConfig := DependabotConfig{
+		Updates: []Update{
+			{PackageEcosystem: "npm", Directory: "/legacy"},
+			{PackageEcosystem: "npm", Directory: "/services/payments/stale"},
+		},
+	}
+	manifests := map[string]string{
+		"package.json":                      "npm",
+		"frontend/package.json":             "npm",
+		"services/payments/package.json":    "npm",
+		"services/payments/api/Dockerfile":  "docker",
+		"services/payments-v2/package.json": "npm",
+	}
+	toolConfig := ToolConfig{
+		RemoveMissingDirectories: true,
+		Cleanup:                  Cleanup{MissingManifests: CleanupRemove},
+		ManifestPatterns:         map[string]string{"npm": "package\\.json$"},
+		PathFilter:               "services/payments/",
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{
+		{Type: "npm", Directory: "/services/payments", File: "services/payments/package.json"},
+		{Type: "docker", Directory: "/services/payments/api", File: "services/payments/api/Dockerfile"},
+	}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+	expectedRemoved := []UpdateInfo{{Type: "npm", Directory: "/services/payments/stale"}}
+	if !reflect.DeepEqual(changeInfo.RemovedUpdates, expectedRemoved) {
+		t.Errorf("UpdateConfig() failed; expected removed updates %v got %v", expectedRemoved, changeInfo.RemovedUpdates)
+	}
+}
+
+func TestUpdateConfigTerraform(t *testing.T) {
+	dependabotConfig := DependabotConfig{
+		Updates: []Update{{PackageEcosystem: "terraform", Directory: "/infra"}},
+	}
+	manifests := map[string]string{
+		"infra/main.tf":                    "terraform",
+		"infra/variables.tf":               "terraform",
+		"infra/modules/network/main.tf":    "terraform",
+		"infra/modules/network/outputs.tf": "terraform",
+		"infra/modules/dns/main.tf":        "terraform",
+	}
+	toolConfig := ToolConfig{
+		Registries: map[string]DefaultRegistries{
+			"terraform": {"tf-reg": {Type: "terraform-registry", URL: "https://app.terraform.io", Token: "${{secrets.TF_TOKEN}}"}},
+		},
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{
+		{Type: "terraform", Directory: "/infra/modules/dns", File: "infra/modules/dns/main.tf"},
+		{Type: "terraform", Directory: "/infra/modules/network", File: "infra/modules/network/main.tf"},
+	}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+	expectedRegistry := Registry{Type: "terraform-registry", URL: "https://app.terraform.io", Token: "${{secrets.TF_TOKEN}}"}
+	if !reflect.DeepEqual(dependabotConfig.Registries["tf-reg"], expectedRegistry) {
+		t.Errorf("UpdateConfig() failed; expected registry %v got %v", expectedRegistry, dependabotConfig.Registries["tf-reg"])
+	}
+}
+
+func TestUpdateConfigRegistryAuthModes(t *testing.T) {
+	dependabotConfig := DependabotConfig{Version: 2}
+	manifests := map[string]string{"mix.exs": "mix", "requirements.txt": "pip"}
+	toolConfig := ToolConfig{
+		Registries: map[string]DefaultRegistries{
+			"mix": {"hex-org": {Type: "hex-organization", Organization: "acme", Key: "${{secrets.HEX_KEY}}"}},
+			"pip": {"pypi-mirror": {Type: "python-index", URL: "https://pypi.foo.bar/simple", Token: "${{secrets.PYPI_TOKEN}}", ReplacesBase: true}},
+		},
+	}
+	dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expected := map[string]Registry{
+		"hex-org":     {Type: "hex-organization", Organization: "acme", Key: "${{secrets.HEX_KEY}}"},
+		"pypi-mirror": {Type: "python-index", URL: "https://pypi.foo.bar/simple", Token: "${{secrets.PYPI_TOKEN}}", ReplacesBase: true},
+	}
+	if !reflect.DeepEqual(dependabotConfig.Registries, expected) {
+		t.Errorf("UpdateConfig() failed; expected registries %v got %v", expected, dependabotConfig.Registries)
+	}
+	content, err := yaml.Marshal(dependabotConfig.Registries["pypi-mirror"])
+	if err != nil || !strings.Contains(string(content), "replaces-base: true\n") {
+		t.Errorf("Marshal() failed; expected replaces-base as boolean, got %v", string(content))
+	}
+
+	problems := DefaultRegistry{Type: "hex-organization", Key: "plain-key", URLMatchRequired: true}.validate("registries.mix.hex-org")
+	expectedProblems := []string{
+		"registries.mix.hex-org: organization not set",
+		"registries.mix.hex-org: url-match-required needs a url, hex organizations have none",
+		"registries.mix.hex-org: key is no Dependabot secret reference (${{secrets.NAME}})",
+	}
+	if !reflect.DeepEqual(problems, expectedProblems) {
+		t.Errorf("validate() failed; expected %v got %v", expectedProblems, problems)
+	}
+}
+
+func TestUpdateConfigDockerVariants(t *testing.T) {
+	toolConfig := ToolConfig{
+		ManifestPatterns: map[string]string{
+			"docker": "^(.*/)?(([^/.]+\\.)?(Dockerfile|Containerfile)(\\.[^/.]+)?|[^/]+\\.[Dd]ockerfile)$",
+		},
+		Registries: map[string]DefaultRegistries{
+			"docker": {"docker-reg": {Type: "docker-registry", URL: "https://docker.foo.bar", URLMatchRequired: true}},
+		},
+	}
+	toolConfig.InitializePatterns()
+	for _, tt := range []struct {
+		fullPath string
+		expected string
+	}{
+		{"Dockerfile", "docker"},
+		{"app/Dockerfile.dev", "docker"},
+		{"app/api.Dockerfile", "docker"},
+		{"app/api.dockerfile", "docker"},
+		{"app/Containerfile", "docker"},
+		{"app/Dockerfile.dev.bak", ""},
+		{"app/dockerfile.go", ""},
+	} {
+		got := GetManifestType(tt.fullPath)
+		if tt.expected != got {
+			t.Errorf("GetManifestType() failed for %v : expected '%v', got '%v'", tt.fullPath, tt.expected, got)
+		}
+	}
+
+	dependabotConfig := DependabotConfig{}
+	manifests := map[string]string{
+		"app/Dockerfile":     "docker",
+		"app/Dockerfile.dev": "docker",
+		"app/Containerfile":  "docker",
+	}
+	loadFileFn := func(file string, _ LoadFileContentParameters) string {
+		if file == "app/Dockerfile.dev" {
+			return "FROM docker.foo.bar/base:1.0"
+		}
+		return "FROM alpine:3"
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, loadFileFn, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{{Type: "docker", Directory: "/app", File: "app/Containerfile"}}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+	if len(dependabotConfig.Updates) != 1 || !reflect.DeepEqual(dependabotConfig.Updates[0].Registries, []string{"docker-reg"}) {
+		t.Errorf("UpdateConfig() failed; expected one update referencing docker-reg, got %v", dependabotConfig.Updates)
+	}
+	if len(changeInfo.MissingRegistryRefs) > 0 {
+		t.Errorf("UpdateConfig() failed; expected no missing registry references, got %v", changeInfo.MissingRegistryRefs)
+	}
+}
+
+func TestUpdateConfigTrace(t *testing.T) {
+	dependabotConfig := DependabotConfig{
+		Updates: []Update{{PackageEcosystem: "npm", Directory: "/"}},
+	}
+	manifests := map[string]string{
+		"package.json":     "npm",
+		"app/Dockerfile":   "docker",
+		"other/Dockerfile": "docker",
+		"WORKSPACE":        "bazel",
+	}
+	toolConfig := ToolConfig{
+		ManifestPatterns: map[string]string{"npm": "^(.*/)?package\\.json$", "docker": "^(.*/)?Dockerfile$"},
+		Registries: map[string]DefaultRegistries{
+			"npm": {"npm-reg": {Type: "npm-registry", URL: "https://npm.foo.bar"}},
+			"docker": {
+				"docker-reg":   {Type: "docker-registry", URL: "https://docker.foo.bar", URLMatchRequired: true},
+				"frontend-reg": {Type: "docker-registry", URL: "https://frontend.foo.bar", ManifestPaths: []string{"/frontend/**"}},
+			},
+		},
+		PathFilter: "/app",
+		Trace:      true,
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expected := []TraceEntry{
+		{Manifest: "WORKSPACE", Type: "bazel", Check: TracePattern, Result: "matched"},
+		{Manifest: "app/Dockerfile", Type: "docker", Check: TracePattern, Subject: "^(.*/)?Dockerfile$", Result: "matched"},
+		{Manifest: "other/Dockerfile", Type: "docker", Check: TracePattern, Subject: "^(.*/)?Dockerfile$", Result: "matched"},
+		{Manifest: "package.json", Type: "npm", Check: TracePattern, Subject: "^(.*/)?package\\.json$", Result: "matched"},
+		{Manifest: "WORKSPACE", Type: "bazel", Check: TracePathFilter, Subject: "/app", Result: "outside of path filter, skipped"},
+		{Manifest: "other/Dockerfile", Type: "docker", Check: TracePathFilter, Subject: "/app", Result: "outside of path filter, skipped"},
+		{Manifest: "package.json", Type: "npm", Check: TracePathFilter, Subject: "/app", Result: "outside of path filter, skipped"},
+		{Manifest: "app/Dockerfile", Type: "docker", Check: TraceRegistry, Subject: "docker-reg", Result: "not used, URL not found"},
+		{Manifest: "app/Dockerfile", Type: "docker", Check: TraceRegistry, Subject: "frontend-reg", Result: "not applicable, directory doesn't match manifest-paths"},
+		{Manifest: "app/Dockerfile", Type: "docker", Check: TraceCoverage, Subject: "/app", Result: "not covered, update added"},
+	}
+	if !reflect.DeepEqual(changeInfo.Trace, expected) {
+		t.Errorf("UpdateConfig() failed; expected trace\n%v\ngot\n%v", expected, changeInfo.Trace)
+	}
+
+	toolConfig.Trace = false
+	changeInfo = dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	if changeInfo.Trace != nil {
+		t.Errorf("UpdateConfig() failed; expected no trace if disabled, got %v", changeInfo.Trace)
+	}
+}
+
+func TestUpdateConfigDevcontainersAndCompose(t *testing.T) {
+	toolConfig := ToolConfig{
+		ManifestPatterns: map[string]string{
+			"devcontainers":  "^(\\.devcontainer\\.json|\\.devcontainer/([^/]+/)?devcontainer\\.json)$",
+			"docker-compose": "^(.*/)?(docker-compose[^/]*|compose)\\.ya?ml$",
+		},
+	}
+	toolConfig.InitializePatterns()
+	for _, tt := range []struct {
+		fullPath string
+		expected string
+	}{
+		{".devcontainer/devcontainer.json", "devcontainers"},
+		{".devcontainer/python/devcontainer.json", "devcontainers"},
+		{".devcontainer.json", "devcontainers"},
+		{"app/.devcontainer/devcontainer.json", ""},
+		{"docker-compose.yml", "docker-compose"},
+		{"app/docker-compose.dev.yaml", "docker-compose"},
+		{"app/compose.yml", "docker-compose"},
+		{"app/compose-notes.txt", ""},
+	} {
+		got := GetManifestType(tt.fullPath)
+		if tt.expected != got {
+			t.Errorf("GetManifestType() failed for %v : expected '%v', got '%v'", tt.fullPath, tt.expected, got)
+		}
+	}
+
+	dependabotConfig := DependabotConfig{}
+	manifests := map[string]string{
+		".devcontainer/devcontainer.json":        "devcontainers",
+		".devcontainer/python/devcontainer.json": "devcontainers",
+		"app/docker-compose.yml":                 "docker-compose",
+		"app/docker-compose.dev.yml":             "docker-compose",
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{
+		{Type: "docker-compose", Directory: "/app", File: "app/docker-compose.dev.yml"},
+		{Type: "devcontainers", Directory: "/", File: ".devcontainer/devcontainer.json"},
+	}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	toolConfig := ToolConfig{
+		ManifestPatterns:       map[string]string{"npm": "^(.*/)?package\\.json$", "pip": "requirements(\\.txt"},
+		ManifestIgnorePattern:  "^.*[$][{].*$",
+		ManifestIgnorePatterns: map[string]string{"npm": "^test/fixtures/", "docker": "vendor/["},
+		RepoOverrides:          map[string]RepoOverride{"service-[": {}},
+		Registries: map[string]DefaultRegistries{
+			"npm": {
+				"ok":     {Type: "npm-registry", URL: "https://npm.foo.bar", Password: "${{secrets.NPM_PASSWORD}}"},
+				"plain":  {Type: "npm-registry", URL: "https://npm.foo.bar", Token: "secret-token"},
+				"no-url": {Type: "npm-registry"},
+			},
+		},
+		UpdateOverrides: map[string]UpdateDefaults{
+			"npm": {Groups: map[string]Group{"security": {AppliesTo: "security"}, "all": {AppliesTo: "version-updates"}}},
+		},
+		GrantSecretAccess:     true,
+		PullRequestParameters: PullRequestParameters{BranchName: "update-{{repo}", PRTitle: "{{unknown}}", AutoMerge: "fast-forward"},
+	}
+	expected := []string{
+		"manifest-patterns: invalid pattern for pip: error parsing regexp: missing closing ): `requirements(\\.txt`",
+		"manifest-ignore-patterns: invalid pattern for docker: error parsing regexp: missing closing ]: `[`",
+		"repo-overrides: invalid pattern service-[: error parsing regexp: missing closing ]: `[)$`",
+		"registries.npm.no-url: invalid url ",
+		"registries.npm.plain: token is no Dependabot secret reference (${{secrets.NAME}})",
+		"update-overrides.npm.groups.security.applies-to: security is none of version-updates and security-updates",
+		"grant-secret-access: requires check-secret-access",
+		"pull-request-parameters.auto-merge: fast-forward is none of merge, squash and rebase",
+	}
+	got := toolConfig.Validate()
+	if len(got) != len(expected)+1 || !strings.HasPrefix(got[7], "pull-request-parameters: ") {
+		t.Fatalf("Validate() failed; expected %v and a pull-request-parameters problem, got %v", expected, got)
+	}
+	got = append(got[:7], got[8:]...)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Validate() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+
+	valid := ToolConfig{PullRequestParameters: PullRequestParameters{BranchName: "dependabutler-update"}}
+	if problems := valid.Validate(); len(problems) > 0 {
+		t.Errorf("Validate() failed; expected no problems, got %v", problems)
+	}
+}
+
+func TestTeamOf(t *testing.T) {
+	toolConfig := ToolConfig{
+		TeamOwnership: map[string][]string{
+			"payments": {"payments-.*", "checkout"},
+			"platform": {"checkout", "infra"},
+		},
+	}
+	for _, tt := range []struct {
+		repo     string
+		expected string
+	}{
+		{"payments-api", "payments"},
+		{"checkout", "payments"},
+		{"infra", "platform"},
+		{"infra-tools", ""},
+	} {
+		if got := toolConfig.TeamOf(tt.repo); got != tt.expected {
+			t.Errorf("TeamOf(%v) failed; expected %v got %v", tt.repo, tt.expected, got)
+		}
+	}
+}
+
+func TestUpdateConfigGradleBuild(t *testing.T) {
+	for manifestFile, expected := range map[string]string{
+		"gradle/libs.versions.toml":          "/",
+		"buildSrc/build.gradle.kts":          "/",
+		"buildSrc/settings.gradle":           "/",
+		"included/gradle/libs.versions.toml": "/included",
+		"mybuildSrc/build.gradle.kts":        "/mybuildSrc",
+		"app/build.gradle.kts":               "/app",
+	} {
+		if got := GetManifestPath(manifestFile, "gradle"); got != expected {
+			t.Errorf("GetManifestPath(%v) failed; expected %v, got %v", manifestFile, expected, got)
+		}
+	}
+
+	// all projects of a multi-project build are covered by a single update for the root project
+	manifests := map[string]string{
+		"settings.gradle.kts":       "gradle",
+		"gradle/libs.versions.toml": "gradle",
+		"buildSrc/build.gradle.kts": "gradle",
+		"app/build.gradle.kts":      "gradle",
+		"lib/core/build.gradle.kts": "gradle",
+	}
+	dependabotConfig := DependabotConfig{}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, ToolConfig{}, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	if len(changeInfo.NewUpdates) != 1 || changeInfo.NewUpdates[0].Directory != "/" {
+		t.Errorf("UpdateConfig() failed; expected a single update for /, got %v", changeInfo.NewUpdates)
+	}
+}
+
+func TestUpdateConfigBetaEcosystems(t *testing.T) {
+	enabled, disabled := true, false
+	manifests := map[string]string{"package.json": "npm", "bun.lock": "bun"}
+	for _, tt := range []struct {
+		name            string
+		current         DependabotConfig
+		enable          *bool
+		expectedEnabled bool
+		expectedTypes   []string
+		expectedFixed   []FixInfo
+		expectedWarning bool
+	}{
+		{"not enabled", DependabotConfig{}, nil, false, []string{"npm"}, nil, true},
+		{"enabled by config", DependabotConfig{EnableBetaEcoSystems: true}, nil, true, []string{"bun", "npm"}, nil, false},
+		{"set", DependabotConfig{}, &enabled, true, []string{"bun", "npm"}, []FixInfo{{Change: "enable-beta-ecosystems set"}}, false},
+		{"unset", DependabotConfig{EnableBetaEcoSystems: true}, &disabled, false, []string{"npm"}, []FixInfo{{Change: "enable-beta-ecosystems unset"}}, true},
+		{
+			"kept for beta updates",
+			DependabotConfig{EnableBetaEcoSystems: true, Updates: []Update{{PackageEcosystem: "bun", Directory: "/"}}},
+			&disabled, true,
+			[]string{"npm"},
+			nil, true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dependabotConfig := tt.current
+			toolConfig := ToolConfig{EnableBetaEcosystems: tt.enable}
+			changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+			if dependabotConfig.EnableBetaEcoSystems != tt.expectedEnabled {
+				t.Errorf("UpdateConfig() failed; expected enable-beta-ecosystems %t", tt.expectedEnabled)
+			}
+			types := []string{}
+			for _, update := range changeInfo.NewUpdates {
+				types = append(types, update.Type)
+			}
+			if !reflect.DeepEqual(types, tt.expectedTypes) || !reflect.DeepEqual(changeInfo.FixedUpdates, tt.expectedFixed) {
+				t.Errorf("UpdateConfig() failed; expected new updates %v and fixes %v, got %v and %v", tt.expectedTypes, tt.expectedFixed, types, changeInfo.FixedUpdates)
+			}
+			if (len(changeInfo.Warnings) > 0) != tt.expectedWarning {
+				t.Errorf("UpdateConfig() failed; unexpected warnings %v", changeInfo.Warnings)
+			}
+		})
+	}
+
+	toolConfig := ToolConfig{BetaEcosystems: []string{"uv", "yarn"}}
+	if !util.Contains(toolConfig.Validate(), "beta-ecosystems: yarn is not supported by dependabot") {
+		t.Errorf("Validate() failed; expected a problem for an unsupported beta ecosystem")
+	}
+}
+
+func TestUpdateConfigBuildToolWrappers(t *testing.T) {
+	manifests := map[string]string{
+		"gradle/wrapper/gradle-wrapper.properties":  "gradle-wrapper",
+		"app/.mvn/wrapper/maven-wrapper.properties": "maven-wrapper",
+		"app/pom.xml": "maven",
+		"tools/cli/gradle/wrapper/gradle-wrapper.properties": "gradle-wrapper",
+	}
+	if got := GetManifestPath("tools/cli/gradle/wrapper/gradle-wrapper.properties", "gradle"); got != "/tools/cli" {
+		t.Errorf("GetManifestPath() failed; expected /tools/cli, got %v", got)
+	}
+
+	// not mapped: listed as skipped
+	dependabotConfig := DependabotConfig{}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, ToolConfig{}, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedSkipped := []SkippedInfo{
+		{Type: "gradle-wrapper", File: "gradle/wrapper/gradle-wrapper.properties", Reason: "unsupported"},
+		{Type: "maven-wrapper", File: "app/.mvn/wrapper/maven-wrapper.properties", Reason: "unsupported"},
+		{Type: "gradle-wrapper", File: "tools/cli/gradle/wrapper/gradle-wrapper.properties", Reason: "unsupported"},
+	}
+	if !reflect.DeepEqual(changeInfo.SkippedManifests, expectedSkipped) {
+		t.Errorf("UpdateConfig() failed; expected skipped manifests %v got %v", expectedSkipped, changeInfo.SkippedManifests)
+	}
+
+	// mapped: updates for the project directories, the maven wrapper is covered by the update for its pom.xml,
+	// the gradle wrapper of tools/cli by the one for the root directory
+	toolConfig := ToolConfig{ManifestEcosystems: map[string]string{"gradle-wrapper": "gradle", "maven-wrapper": "maven"}}
+	dependabotConfig = DependabotConfig{}
+	changeInfo = dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{
+		{Type: "maven", Directory: "/app", File: "app/pom.xml"},
+		{Type: "gradle", Directory: "/", File: "gradle/wrapper/gradle-wrapper.properties"},
+	}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) || len(changeInfo.SkippedManifests) != 0 {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v, skipped %v", expectedNew, changeInfo.NewUpdates, changeInfo.SkippedManifests)
+	}
+	problems := (&ToolConfig{ManifestEcosystems: map[string]string{"gradle-wrapper": "gradle-wrapper"}}).Validate()
+	if !util.Contains(problems, "manifest-ecosystems: gradle-wrapper of gradle-wrapper is not supported by dependabot") {
+		t.Errorf("Validate() failed; expected a problem for an unsupported ecosystem, got %v", problems)
+	}
+}
+
+func TestUpdateConfigEnabledEcosystems(t *testing.T) {
+	toolConfig := ToolConfig{EnabledEcosystems: []string{"npm"}}
+	dependabotConfig := DependabotConfig{Updates: []Update{{PackageEcosystem: "docker", Directory: "/"}}}
+	manifests := map[string]string{
+		"package.json":   "npm",
+		"app/go.mod":     "gomod",
+		"app/Dockerfile": "docker",
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{{Type: "npm", Directory: "/", File: "package.json"}}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+	expectedSkipped := []SkippedInfo{
+		{Type: "docker", File: "app/Dockerfile", Reason: "ecosystem not enabled"},
+		{Type: "gomod", File: "app/go.mod", Reason: "ecosystem not enabled"},
+	}
+	if !reflect.DeepEqual(changeInfo.SkippedManifests, expectedSkipped) {
+		t.Errorf("UpdateConfig() failed; expected skipped manifests %v got %v", expectedSkipped, changeInfo.SkippedManifests)
+	}
+	if len(dependabotConfig.Updates) != 2 {
+		t.Errorf("UpdateConfig() failed; expected the existing docker update to be kept, got %v", dependabotConfig.Updates)
+	}
+	problems := (&ToolConfig{EnabledEcosystems: []string{"npm", "bazel"}}).Validate()
+	if !util.Contains(problems, "enabled-ecosystems: bazel is not supported by dependabot") {
+		t.Errorf("Validate() failed; expected a problem for an unsupported ecosystem, got %v", problems)
+	}
+}
+
+func TestUpdateConfigDisabledEcosystems(t *testing.T) {
+	toolConfig := ToolConfig{DisabledEcosystems: []string{"docker"}}
+	dependabotConfig := DependabotConfig{Updates: []Update{{PackageEcosystem: "docker", Directory: "/"}}}
+	manifests := map[string]string{
+		"package.json":          "npm",
+		"Dockerfile":            "docker",
+		"vendor/img/Dockerfile": "docker",
+	}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{{Type: "npm", Directory: "/", File: "package.json"}}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+	expectedSkipped := []SkippedInfo{
+		{Type: "docker", File: "Dockerfile", Reason: "ecosystem disabled"},
+		{Type: "docker", File: "vendor/img/Dockerfile", Reason: "ecosystem disabled"},
+	}
+	if !reflect.DeepEqual(changeInfo.SkippedManifests, expectedSkipped) {
+		t.Errorf("UpdateConfig() failed; expected skipped manifests %v got %v", expectedSkipped, changeInfo.SkippedManifests)
+	}
+	if len(dependabotConfig.Updates) != 2 {
+		t.Errorf("UpdateConfig() failed; expected the existing docker update to be kept, got %v", dependabotConfig.Updates)
+	}
+	problems := (&ToolConfig{DisabledEcosystems: []string{"bazel"}}).Validate()
+	if !util.Contains(problems, "disabled-ecosystems: bazel is not supported by dependabot") {
+		t.Errorf("Validate() failed; expected a problem for an unsupported ecosystem, got %v", problems)
+	}
+}
+
+func TestRemovalLimitsExceeded(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      RemovalLimits
+		removals    int
+		entries     int
+		runRemovals int
+		want        bool
+	}{
+		{"no limits", RemovalLimits{}, 10, 10, 100, false},
+		{"within run limit", RemovalLimits{MaxPerRun: 5}, 2, 10, 3, false},
+		{"exceeding run limit", RemovalLimits{MaxPerRun: 5}, 2, 10, 4, true},
+		{"within repo limit", RemovalLimits{MaxPercentPerRepo: 50}, 2, 4, 0, false},
+		{"exceeding repo limit", RemovalLimits{MaxPercentPerRepo: 50}, 3, 4, 0, true},
+	}
+	for _, test := range tests {
+		if got := test.limits.Exceeded(test.removals, test.entries, test.runRemovals); got != test.want {
+			t.Errorf("Exceeded() %v: expected %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestProcessManifestTargetBranch(t *testing.T) {
+	toolConfig := ToolConfig{
+		UpdateDefaults:  UpdateDefaults{TargetBranch: "develop"},
+		UpdateOverrides: map[string]UpdateDefaults{"github-actions": {TargetBranch: "main"}},
+	}
+	for _, tt := range []struct {
+		manifestFile string
+		manifestType string
+		expected     string
+	}{
+		{"package.json", "npm", "develop"},
+		{".github/workflows/build.yml", "github-actions", "main"},
+	} {
+		dependabotConfig := DependabotConfig{}
+		dependabotConfig.ProcessManifest(tt.manifestFile, tt.manifestType, toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+		if dependabotConfig.Updates[0].TargetBranch != tt.expected {
+			t.Errorf("ProcessManifest(%v) failed; expected target branch %v got %v", tt.manifestFile, tt.expected, dependabotConfig.Updates[0].TargetBranch)
+		}
+	}
+}
+
+func TestProcessManifestReviewersAssigneesLabels(t *testing.T) {
+	toolConfig := ToolConfig{
+		UpdateDefaults: UpdateDefaults{Labels: []string{"dependencies"}, Assignees: []string{"octocat"}},
+		UpdateOverrides: map[string]UpdateDefaults{
+			"npm": {Labels: []string{"dependencies", "frontend"}, Reviewers: []string{"acme/frontend"}},
+		},
+	}
+	dependabotConfig := DependabotConfig{}
+	dependabotConfig.ProcessManifest("package.json", "npm", toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+	dependabotConfig.ProcessManifest("Dockerfile", "docker", toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+	expected := []Update{
+		{PackageEcosystem: "npm", Directory: "/", Labels: []string{"dependencies", "frontend"}, Reviewers: []string{"acme/frontend"}, Assignees: []string{"octocat"}},
+		{PackageEcosystem: "docker", Directory: "/", Labels: []string{"dependencies"}, Assignees: []string{"octocat"}},
+	}
+	for i, update := range dependabotConfig.Updates {
+		if !reflect.DeepEqual(update.Labels, expected[i].Labels) || !reflect.DeepEqual(update.Reviewers, expected[i].Reviewers) ||
+			!reflect.DeepEqual(update.Assignees, expected[i].Assignees) {
+			t.Errorf("ProcessManifest() failed; expected %v got %v", expected[i], update)
+		}
+	}
+}
+
+func TestProcessManifestAllowIgnore(t *testing.T) {
+	ignoreMajor := []Ignore{{DependencyName: "*", UpdateTypes: []string{"version-update:semver-major"}}}
+	allowDirect := []Allow{{DependencyType: "direct"}}
+	toolConfig := ToolConfig{
+		UpdateOverrides: map[string]UpdateDefaults{
+			"docker": {Ignore: ignoreMajor},
+			"gomod":  {Allow: allowDirect},
+		},
+	}
+	dependabotConfig := DependabotConfig{}
+	dependabotConfig.ProcessManifest("Dockerfile", "docker", toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+	dependabotConfig.ProcessManifest("go.mod", "gomod", toolConfig, &ChangeInfo{}, LoadFileContentDummy, LoadFileContentParameters{})
+	if !reflect.DeepEqual(dependabotConfig.Updates[0].Ignore, ignoreMajor) || dependabotConfig.Updates[0].Allow != nil {
+		t.Errorf("ProcessManifest() failed; unexpected docker update %v", dependabotConfig.Updates[0])
+	}
+	if !reflect.DeepEqual(dependabotConfig.Updates[1].Allow, allowDirect) || dependabotConfig.Updates[1].Ignore != nil {
+		t.Errorf("ProcessManifest() failed; unexpected gomod update %v", dependabotConfig.Updates[1])
+	}
+}
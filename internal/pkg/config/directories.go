@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// directoryRulePattern matches the directory rules for manifest types, see ParseDirectoryRule.
+var directoryRulePattern = regexp.MustCompile(`^(file|root|strip-([1-9][0-9]*))$`)
+
+// DirectoryRule holds how the directory of an update is derived from the path of a manifest file.
+type DirectoryRule struct {
+	// Root puts the update into the root directory, e.g. for lock files generated by a build system.
+	Root bool
+	// Strip is the number of trailing components removed from the directory of the manifest file.
+	Strip int
+}
+
+// ParseDirectoryRule parses a directory rule: "file" for the directory of the manifest file, "root" for the root
+// directory, or "strip-N" for the directory N levels above the one of the manifest file.
+func ParseDirectoryRule(rule string) (DirectoryRule, error) {
+	match := directoryRulePattern.FindStringSubmatch(rule)
+	if match == nil {
+		return DirectoryRule{}, fmt.Errorf("%q is not one of file, root or strip-N", rule)
+	}
+	if match[1] == "root" {
+		return DirectoryRule{Root: true}, nil
+	}
+	strip, _ := strconv.Atoi(match[2])
+	return DirectoryRule{Strip: strip}, nil
+}
+
+// Apply returns the directory of an update, given the directory of its manifest file.
+func (rule DirectoryRule) Apply(directory string) string {
+	if rule.Root {
+		return "/"
+	}
+	for i := 0; i < rule.Strip && directory != "/"; i++ {
+		directory = path.Dir(directory)
+	}
+	return directory
+}
+
+// manifestDirectoryRule returns the directory rule for a manifest file: the one of its type, or else the one of the
+// manifest type whose pattern it matches, as manifests may have been mapped to other ecosystems.
+func manifestDirectoryRule(manifestFile string, manifestType string) (DirectoryRule, bool) {
+	if rule, found := manifestDirectoryRules[manifestType]; found {
+		return rule, true
+	}
+	for _, ruleType := range util.SortedKeys(manifestDirectoryRules) {
+		if re := manifestFilePatterns[ruleType]; re != nil && re.MatchString(strings.TrimPrefix(manifestFile, "/")) {
+			return manifestDirectoryRules[ruleType], true
+		}
+	}
+	return DirectoryRule{}, false
+}
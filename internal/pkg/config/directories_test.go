@@ -0,0 +1,74 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestParseDirectoryRule(t *testing.T) {
+	tests := []struct {
+		rule      string
+		directory string
+		expected  string
+		wantErr   bool
+	}{
+		{"file", "/python/deps", "/python/deps", false},
+		{"root", "/python/deps", "/", false},
+		{"strip-1", "/python/deps", "/python", false},
+		{"strip-2", "/python/deps", "/", false},
+		{"strip-5", "/python/deps", "/", false},
+		{"strip-1", "/", "/", false},
+		{"strip-0", "/python", "", true},
+		{"parent", "/python", "", true},
+	}
+	for _, tt := range tests {
+		rule, err := ParseDirectoryRule(tt.rule)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDirectoryRule(%v) failed; expected error %v, got %v", tt.rule, tt.wantErr, err)
+			continue
+		}
+		if err == nil {
+			if got := rule.Apply(tt.directory); got != tt.expected {
+				t.Errorf("Apply(%v) of %v failed; expected %v got %v", tt.directory, tt.rule, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestUpdateConfigManifestDirectories(t *testing.T) {
+	defer (&ToolConfig{}).InitializePatterns()
+	toolConfig := ToolConfig{
+		ManifestPatterns: map[string]string{
+			"pip":      "^(.*/)?requirements\\.txt$",
+			"pip-lock": "^(.*/)?requirements_lock\\.txt$",
+			"vendored": "^third_party/.*/go\\.mod$",
+		},
+		ManifestEcosystems:  map[string]string{"pip-lock": "pip", "vendored": "gomod"},
+		ManifestDirectories: map[string]string{"pip-lock": "strip-1", "vendored": "root"},
+	}
+	toolConfig.InitializePatterns()
+	manifests := map[string]string{}
+	ScanFileList([]string{
+		"tools/requirements.txt",
+		"python/bazel/requirements_lock.txt",
+		"third_party/github.com/lib/go.mod",
+	}, manifests)
+
+	dependabotConfig := DependabotConfig{}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expected := []UpdateInfo{
+		{Type: "pip", Directory: "/tools", File: "tools/requirements.txt"},
+		{Type: "pip", Directory: "/python", File: "python/bazel/requirements_lock.txt"},
+		{Type: "gomod", Directory: "/", File: "third_party/github.com/lib/go.mod"},
+	}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expected) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expected, changeInfo.NewUpdates)
+	}
+
+	problems := (&ToolConfig{ManifestDirectories: map[string]string{"pip-lock": "up"}}).Validate()
+	if !util.Contains(problems, `manifest-directories: invalid rule for pip-lock: "up" is not one of file, root or strip-N`) {
+		t.Errorf("Validate() failed; expected a problem for an invalid rule, got %v", problems)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// enforceableFields holds the fields of existing updates which can be reconciled towards the defaults.
+var enforceableFields = []string{"schedule", "open-pull-requests-limit", "cooldown", "groups"}
+
+// enforceDefaults rewrites the enforced fields of the existing updates which differ from the values new updates would
+// get, for the same ecosystem. Fields without a default value are left as they are.
+func (config *DependabotConfig) enforceDefaults(changeInfo *ChangeInfo, toolConfig ToolConfig) {
+	pathFilter := NormalizeDirectory(toolConfig.PathFilter)
+	for i := range config.Updates {
+		update := &config.Updates[i]
+		if (pathFilter != "" && !update.isWithinPath(pathFilter)) || update.IsProtected() {
+			continue
+		}
+		standard := createUpdateEntry(update.PackageEcosystem, update.Directory, toolConfig)
+		changes := make([]string, 0)
+		for _, field := range toolConfig.Enforce {
+			switch field {
+			case "schedule":
+				if standard.Schedule != (Schedule{}) && update.Schedule != standard.Schedule {
+					update.Schedule = standard.Schedule
+					changes = append(changes, field)
+				}
+			case "open-pull-requests-limit":
+				if standard.OpenPullRequestsLimit != 0 && update.OpenPullRequestsLimit != standard.OpenPullRequestsLimit {
+					update.OpenPullRequestsLimit = standard.OpenPullRequestsLimit
+					changes = append(changes, field)
+				}
+			case "cooldown":
+				if standard.Cooldown != nil && !reflect.DeepEqual(update.Cooldown, standard.Cooldown) {
+					update.Cooldown = standard.Cooldown
+					changes = append(changes, field)
+				}
+			case "groups":
+				if len(standard.Groups) > 0 && !reflect.DeepEqual(update.Groups, standard.Groups) {
+					update.Groups = standard.Groups
+					changes = append(changes, field)
+				}
+			}
+		}
+		for _, field := range changes {
+			changeInfo.FixedUpdates = append(changeInfo.FixedUpdates, FixInfo{
+				Type: update.PackageEcosystem, Directory: update.Directory, Change: fmt.Sprintf("%v set to the default", field),
+			})
+		}
+	}
+}
+
+// validateEnforce returns the problems of the enforced fields.
+func (config *ToolConfig) validateEnforce() []string {
+	problems := make([]string, 0)
+	for _, field := range config.Enforce {
+		if !util.Contains(enforceableFields, field) {
+			problems = append(problems, fmt.Sprintf("enforce: %v is none of %v", field, enforceableFields))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestUpdateConfigEnforce(t *testing.T) {
+	weekly := Schedule{Interval: "weekly"}
+	cooldown := &Cooldown{DefaultDays: 3}
+	toolConfig := ToolConfig{
+		UpdateDefaults:  UpdateDefaults{Schedule: weekly, OpenPullRequestsLimit: 5, Cooldown: cooldown},
+		UpdateOverrides: map[string]UpdateDefaults{"docker": {OpenPullRequestsLimit: 2}},
+		Enforce:         []string{"schedule", "open-pull-requests-limit", "cooldown", "groups"},
+	}
+	dependabotConfig := DependabotConfig{Updates: []Update{
+		{PackageEcosystem: "npm", Directory: "/", Schedule: Schedule{Interval: "daily"}, OpenPullRequestsLimit: 5, Labels: []string{"custom"}},
+		{PackageEcosystem: "docker", Directory: "/app", Schedule: weekly, OpenPullRequestsLimit: 10, Cooldown: cooldown},
+	}}
+	manifests := map[string]string{"package.json": "npm", "app/Dockerfile": "docker"}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedFixed := []FixInfo{
+		{Type: "npm", Directory: "/", Change: "schedule set to the default"},
+		{Type: "npm", Directory: "/", Change: "cooldown set to the default"},
+		{Type: "docker", Directory: "/app", Change: "open-pull-requests-limit set to the default"},
+	}
+	if !reflect.DeepEqual(changeInfo.FixedUpdates, expectedFixed) {
+		t.Errorf("UpdateConfig() failed; expected fixed updates %v got %v", expectedFixed, changeInfo.FixedUpdates)
+	}
+	expectedUpdates := []Update{
+		{PackageEcosystem: "npm", Directory: "/", Schedule: weekly, OpenPullRequestsLimit: 5, Labels: []string{"custom"}, Cooldown: cooldown},
+		{PackageEcosystem: "docker", Directory: "/app", Schedule: weekly, OpenPullRequestsLimit: 2, Cooldown: cooldown},
+	}
+	if !reflect.DeepEqual(dependabotConfig.Updates, expectedUpdates) {
+		t.Errorf("UpdateConfig() failed; expected updates %v got %v", expectedUpdates, dependabotConfig.Updates)
+	}
+	problems := (&ToolConfig{Enforce: []string{"labels"}}).Validate()
+	if !util.Contains(problems, "enforce: labels is none of [schedule open-pull-requests-limit cooldown groups]") {
+		t.Errorf("Validate() failed; expected a problem for an unknown field, got %v", problems)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule holds a rule of a .gitignore file, matched against paths relative to the directory of the file.
+type ignoreRule struct {
+	directory string
+	pattern   *regexp.Regexp
+	anchored  bool
+	dirOnly   bool
+	negate    bool
+}
+
+// parseGitignore returns the rules of a .gitignore file in a directory, relative to the base directory.
+func parseGitignore(content string, directory string) []ignoreRule {
+	rules := make([]ignoreRule, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{directory: filepath.ToSlash(directory)}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globToRegexp returns the regular expression of a .gitignore pattern.
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
+
+// isIgnored returns if a path, relative to the base directory, is ignored by the rules. The last matching rule wins.
+func isIgnored(rules []ignoreRule, fullPath string, isDir bool) bool {
+	fullPath = filepath.ToSlash(fullPath)
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		relative := fullPath
+		if rule.directory != "" && rule.directory != "." {
+			if !strings.HasPrefix(fullPath, rule.directory+"/") {
+				continue
+			}
+			relative = strings.TrimPrefix(fullPath, rule.directory+"/")
+		}
+		subject := relative
+		if !rule.anchored {
+			subject = path.Base(relative)
+		}
+		if rule.pattern.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// readGitignore returns the rules of the .gitignore file in a directory, if any.
+func readGitignore(baseDirectory string, directory string) []ignoreRule {
+	content, err := os.ReadFile(filepath.Join(baseDirectory, directory, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	return parseGitignore(string(content), directory)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsIgnored(t *testing.T) {
+	rules := parseGitignore("# build output\n/build\n*.log\ndist/\n!keep.log\ndocs/**/package.json\n", "")
+	rules = append(rules, parseGitignore("fixtures\n", "app")...)
+	for _, tt := range []struct {
+		fullPath string
+		isDir    bool
+		expected bool
+	}{
+		{"build", true, true},
+		{"app/build", true, false},
+		{"app/debug.log", false, true},
+		{"app/keep.log", false, false},
+		{"app/dist", true, true},
+		{"app/dist", false, false},
+		{"docs/package.json", false, true},
+		{"docs/site/v1/package.json", false, true},
+		{"app/package.json", false, false},
+		{"app/test/fixtures", true, true},
+		{"fixtures", true, false},
+	} {
+		if got := isIgnored(rules, tt.fullPath, tt.isDir); got != tt.expected {
+			t.Errorf("isIgnored(%v) failed; expected %t got %t", tt.fullPath, tt.expected, got)
+		}
+	}
+}
+
+func TestScanLocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		".gitignore":                          "/tmp\n",
+		"package.json":                        "{}",
+		"node_modules/lib/package.json":       "{}",
+		"tmp/package.json":                    "{}",
+		"app/.gitignore":                      "fixtures/\n",
+		"app/package.json":                    "{}",
+		"app/fixtures/package.json":           "{}",
+		"vendor/github.com/lib/go.mod":        "",
+		"services/api/target/classes/pom.xml": "",
+		"services/api/pom.xml":                "",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		skipDirectories []string
+		expected        map[string]string
+	}{
+		{nil, map[string]string{"package.json": "npm", "app/package.json": "npm", "services/api/pom.xml": "maven"}},
+		{[]string{}, map[string]string{
+			"package.json": "npm", "app/package.json": "npm", "services/api/pom.xml": "maven",
+			"node_modules/lib/package.json": "npm", "vendor/github.com/lib/go.mod": "gomod",
+			"services/api/target/classes/pom.xml": "maven",
+		}},
+	} {
+		toolConfig := ToolConfig{
+			ManifestPatterns: map[string]string{
+				"npm":   "(.*/)?package\\.json",
+				"maven": "(.*/)?pom\\.xml",
+				"gomod": "(.*/)?go\\.mod",
+			},
+			ScanSkipDirectories: tt.skipDirectories,
+		}
+		toolConfig.InitializePatterns()
+		manifests := map[string]string{}
+		ScanLocalDirectory(dir, "", manifests)
+		if !reflect.DeepEqual(tt.expected, manifests) {
+			t.Errorf("ScanLocalDirectory() failed; expected %v got %v", tt.expected, manifests)
+		}
+	}
+	(&ToolConfig{}).InitializePatterns()
+}
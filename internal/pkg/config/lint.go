@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// supportedRegistryTypes holds the registry types supported by dependabot.
+	supportedRegistryTypes = []string{
+		"cargo-registry", "composer-repository", "docker-registry", "git", "goproxy-server", "helm-registry",
+		"hex-organization", "hex-repository", "maven-repository", "npm-registry", "nuget-feed", "pub-repository",
+		"python-index", "rubygems-server", "terraform-registry",
+	}
+	// scheduleIntervals and scheduleDays hold the schedule values accepted by dependabot.
+	scheduleIntervals = []string{"daily", "weekly", "monthly", "quarterly", "semiannually", "yearly", "cron"}
+	scheduleDays      = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	scheduleTime      = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+	// unknownFieldError matches the errors of keys unknown when decoding strictly.
+	unknownFieldError = regexp.MustCompile(`^line (\d+): field (\S+) not found in type`)
+)
+
+// LintToolConfig checks a tool config file beyond Validate: keys unknown to dependabutler (e.g. typos), manifest
+// types and registry types unknown to dependabot, schedules dependabot rejects, and missing mandatory defaults.
+// It returns the problems, and the warnings for settings which are valid but likely unintended. Invalid YAML
+// results in an error.
+func LintToolConfig(content []byte) ([]string, []string, error) {
+	problems := make([]string, 0)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var strict ToolConfig
+	if err := decoder.Decode(&strict); err != nil {
+		var typeError *yaml.TypeError
+		if !errors.As(err, &typeError) {
+			return nil, nil, err
+		}
+		for _, message := range typeError.Errors {
+			if match := unknownFieldError.FindStringSubmatch(message); match != nil {
+				message = fmt.Sprintf("line %v: unknown key %v", match[1], match[2])
+			}
+			problems = append(problems, message)
+		}
+	}
+	var config ToolConfig
+	if err := config.Parse(content); err != nil {
+		// the errors of the strict decoding cover the values of wrong types already
+		return problems, nil, nil
+	}
+	problems = append(problems, config.Validate()...)
+	lintProblems, warnings := config.lint()
+	return append(problems, lintProblems...), warnings, nil
+}
+
+// lint returns the problems and warnings of a parsed tool config not covered by Validate.
+func (config *ToolConfig) lint() ([]string, []string) {
+	problems, warnings := make([]string, 0), make([]string, 0)
+	if len(config.ManifestPatterns) == 0 {
+		problems = append(problems, "manifest-patterns: not set, no manifests are found")
+	}
+	missingInterval := make([]string, 0)
+	for _, manifestType := range util.SortedKeys(config.ManifestPatterns) {
+		ecosystem := manifestType
+		if mapped := config.ManifestEcosystems[manifestType]; mapped != "" {
+			ecosystem = mapped
+		}
+		if !util.Contains(supportedEcosystems, ecosystem) {
+			warnings = append(warnings, fmt.Sprintf("manifest-patterns: %v is not supported by dependabot, its manifests are only listed as skipped", manifestType))
+			continue
+		}
+		if mergeUpdateDefaults(config.UpdateDefaults, config.UpdateOverrides[ecosystem]).Schedule.Interval == "" {
+			missingInterval = append(missingInterval, manifestType)
+		}
+	}
+	if len(missingInterval) > 0 {
+		problems = append(problems, fmt.Sprintf("update-defaults.schedule.interval: not set, required by dependabot for %v",
+			strings.Join(missingInterval, ", ")))
+	}
+
+	// the sections keyed by manifest type
+	manifestTypes := map[string]bool{}
+	for manifestType := range config.ManifestPatterns {
+		manifestTypes[manifestType] = true
+	}
+	for _, ecosystem := range supportedEcosystems {
+		manifestTypes[ecosystem] = true
+	}
+	keyed := map[string][]string{"update-overrides": util.SortedKeys(config.UpdateOverrides), "registries": util.SortedKeys(config.Registries)}
+	for key, override := range config.RepoOverrides {
+		keyed["repo-overrides."+key+".update-overrides"] = util.SortedKeys(override.UpdateOverrides)
+		keyed["repo-overrides."+key+".registries"] = util.SortedKeys(override.Registries)
+	}
+	for _, section := range util.SortedKeys(keyed) {
+		for _, manifestType := range keyed[section] {
+			if !manifestTypes[manifestType] {
+				problems = append(problems, fmt.Sprintf("%v.%v: neither a manifest type nor a package ecosystem", section, manifestType))
+			}
+		}
+	}
+
+	// registry types
+	registries := map[string]map[string]DefaultRegistries{"registries": config.Registries}
+	for key, override := range config.RepoOverrides {
+		registries["repo-overrides."+key+".registries"] = override.Registries
+	}
+	for _, section := range util.SortedKeys(registries) {
+		for _, manifestType := range util.SortedKeys(registries[section]) {
+			defaultRegistries := registries[section][manifestType]
+			for _, name := range util.SortedKeys(defaultRegistries) {
+				if registryType := defaultRegistries[name].Type; registryType != "" && !util.Contains(supportedRegistryTypes, registryType) {
+					problems = append(problems, fmt.Sprintf("%v.%v.%v: type %v is not supported by dependabot", section, manifestType, name, registryType))
+				}
+			}
+		}
+	}
+
+	// schedules
+	schedules := map[string]Schedule{"update-defaults": config.UpdateDefaults.Schedule}
+	for manifestType, overrides := range config.UpdateOverrides {
+		schedules["update-overrides."+manifestType] = overrides.Schedule
+	}
+	for key, override := range config.RepoOverrides {
+		schedules["repo-overrides."+key+".update-defaults"] = override.UpdateDefaults.Schedule
+		for manifestType, overrides := range override.UpdateOverrides {
+			schedules["repo-overrides."+key+".update-overrides."+manifestType] = overrides.Schedule
+		}
+	}
+	for i, policy := range config.SchedulePolicies {
+		schedules[fmt.Sprintf("schedule-policies[%v]", i)] = policy.Schedule
+	}
+	for _, section := range util.SortedKeys(schedules) {
+		problems = append(problems, schedules[section].lint(section+".schedule")...)
+	}
+	return problems, warnings
+}
+
+// lint returns the problems of a schedule, as values dependabot rejects.
+func (schedule Schedule) lint(path string) []string {
+	problems := make([]string, 0)
+	if schedule.Interval != "" && !util.Contains(scheduleIntervals, schedule.Interval) {
+		problems = append(problems, fmt.Sprintf("%v.interval: %v is none of %v", path, schedule.Interval, strings.Join(scheduleIntervals, ", ")))
+	}
+	if schedule.Day != "" && !util.Contains(scheduleDays, schedule.Day) {
+		problems = append(problems, fmt.Sprintf("%v.day: %v is no day of the week", path, schedule.Day))
+	}
+	if schedule.Time != "" && !scheduleTime.MatchString(schedule.Time) {
+		problems = append(problems, fmt.Sprintf("%v.time: %v is not in the format hh:mm", path, schedule.Time))
+	}
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			problems = append(problems, fmt.Sprintf("%v.timezone: %v is no IANA time zone", path, schedule.Timezone))
+		}
+	}
+	return problems
+}
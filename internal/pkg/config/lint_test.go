@@ -0,0 +1,150 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintToolConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedProblems []string
+		expectedWarnings []string
+		expectedError    bool
+	}{
+		{
+			name: "valid",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+update-defaults:
+  schedule:
+    interval: weekly
+    day: monday
+    time: "04:30"
+    timezone: Europe/Berlin
+manifest-patterns:
+  gomod: "(^|/)go\\.mod$"
+  gradle-wrapper: "(^|/)gradle/wrapper/gradle-wrapper\\.properties$"
+`,
+			expectedProblems: []string{},
+			expectedWarnings: []string{"manifest-patterns: gradle-wrapper is not supported by dependabot, its manifests are only listed as skipped"},
+		},
+		{
+			name: "mapped ecosystem",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+update-defaults:
+  schedule:
+    interval: weekly
+manifest-patterns:
+  gradle-wrapper: "(^|/)gradle/wrapper/gradle-wrapper\\.properties$"
+manifest-ecosystems:
+  gradle-wrapper: gradle
+`,
+			expectedProblems: []string{},
+			expectedWarnings: []string{},
+		},
+		{
+			name: "unknown keys",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+update-defaults:
+  schedule:
+    interval: weekly
+  commit-mesage:
+    prefix: deps
+manifest-patterns:
+  gomod: "(^|/)go\\.mod$"
+`,
+			expectedProblems: []string{"line 7: unknown key commit-mesage"},
+			expectedWarnings: []string{},
+		},
+		{
+			name: "invalid values",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+update-defaults:
+  schedule:
+    interval: fortnightly
+    day: mon
+    time: "4:30"
+    timezone: Mars/Olympus
+manifest-patterns:
+  gomod: "(^|/)go\\.mod$"
+update-overrides:
+  golang:
+    open-pull-requests-limit: 5
+registries:
+  gomod:
+    proxy:
+      type: artifactory
+      url: https://proxy.example.com
+`,
+			expectedProblems: []string{
+				"update-overrides.golang: neither a manifest type nor a package ecosystem",
+				"registries.gomod.proxy: type artifactory is not supported by dependabot",
+				"update-defaults.schedule.interval: fortnightly is none of daily, weekly, monthly, quarterly, semiannually, yearly, cron",
+				"update-defaults.schedule.day: mon is no day of the week",
+				"update-defaults.schedule.time: 4:30 is not in the format hh:mm",
+				"update-defaults.schedule.timezone: Mars/Olympus is no IANA time zone",
+			},
+			expectedWarnings: []string{},
+		},
+		{
+			name: "missing defaults",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+update-overrides:
+  npm:
+    schedule:
+      interval: daily
+`,
+			expectedProblems: []string{"manifest-patterns: not set, no manifests are found"},
+			expectedWarnings: []string{},
+		},
+		{
+			name: "missing interval",
+			content: `
+pull-request-parameters:
+  branch-name: dependabutler
+manifest-patterns:
+  gomod: "(^|/)go\\.mod$"
+  npm: "(^|/)package\\.json$"
+update-overrides:
+  npm:
+    schedule:
+      interval: daily
+`,
+			expectedProblems: []string{"update-defaults.schedule.interval: not set, required by dependabot for gomod"},
+			expectedWarnings: []string{},
+		},
+		{
+			name:          "invalid YAML",
+			content:       "manifest-patterns: [",
+			expectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, warnings, err := LintToolConfig([]byte(test.content))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("LintToolConfig() failed; unexpected error %v", err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("LintToolConfig() failed; expected problems %q, got %q", test.expectedProblems, problems)
+			}
+			if !reflect.DeepEqual(warnings, test.expectedWarnings) {
+				t.Errorf("LintToolConfig() failed; expected warnings %q, got %q", test.expectedWarnings, warnings)
+			}
+		})
+	}
+}
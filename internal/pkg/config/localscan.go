@@ -0,0 +1,88 @@
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// defaultScanSkipDirectories holds the directories skipped when scanning a local directory, unless given by
+// scan-skip-directories: VCS metadata, installed packages and build output, which contain manifests of dependencies.
+var defaultScanSkipDirectories = []string{".git", "node_modules", "vendor", ".venv", "target"}
+
+// DefaultScanMaxDepth is the depth limit of directories scanned locally, if scan-max-depth is not set.
+const DefaultScanMaxDepth = 32
+
+// localScan holds the options of local scans, set by InitializePatterns.
+var localScan localScanOptions
+
+// localScanOptions holds which directories are scanned locally. skipDirectories is nil for the defaults.
+type localScanOptions struct {
+	skipDirectories []string
+	submodules      bool
+	symlinks        bool
+	maxDepth        int
+}
+
+// enter returns if a directory is scanned: it's within the depth limit, and not visited before under another path.
+func (options localScanOptions) enter(baseDirectory string, directory string, visited map[string]bool, depth int) bool {
+	maxDepth := options.maxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultScanMaxDepth
+	}
+	if depth > maxDepth {
+		log.Printf("WARN  Directory %v not scanned, deeper than %v levels.", directory, maxDepth)
+		return false
+	}
+	realPath, err := filepath.EvalSymlinks(filepath.Join(baseDirectory, directory))
+	if err != nil {
+		log.Printf("ERROR Could not resolve directory %v: %v", directory, err)
+		return false
+	}
+	if visited[realPath] {
+		log.Printf("WARN  Directory %v not scanned, already scanned as %v.", directory, realPath)
+		return false
+	}
+	visited[realPath] = true
+	return true
+}
+
+// directoryEntry returns if an entry of a scanned directory is a directory, or a symlink to one, and if it's scanned:
+// it's not skipped by name, and it's neither a submodule nor a symlink, unless enabled.
+func (options localScanOptions) directoryEntry(baseDirectory string, fullPath string, entry os.DirEntry) (bool, bool) {
+	if entry.Type()&os.ModeSymlink != 0 {
+		target, err := os.Stat(filepath.Join(baseDirectory, fullPath))
+		if err != nil || !target.IsDir() {
+			return false, false
+		}
+		if !options.symlinks {
+			return true, false
+		}
+	} else if !entry.IsDir() {
+		return false, false
+	}
+	if options.skipDirectory(entry.Name()) {
+		return true, false
+	}
+	if !options.submodules && isSubmodule(filepath.Join(baseDirectory, fullPath)) {
+		return true, false
+	}
+	return true, true
+}
+
+// skipDirectory returns if a directory found when scanning a local directory is skipped by name.
+func (options localScanOptions) skipDirectory(name string) bool {
+	if options.skipDirectories == nil {
+		return util.Contains(defaultScanSkipDirectories, name)
+	}
+	return util.Contains(options.skipDirectories, name)
+}
+
+// isSubmodule returns if a directory is the working tree of a git submodule, or of another nested repo: it has a
+// .git file (pointing to the git directory of the submodule) or directory.
+func isSubmodule(directory string) bool {
+	_, err := os.Lstat(filepath.Join(directory, ".git"))
+	return err == nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestScanLocalDirectorySubmodulesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"package.json":            "{}",
+		"lib/.git":                "gitdir: ../.git/modules/lib",
+		"lib/package.json":        "{}",
+		"shared/app/package.json": "{}",
+		"a/b/c/package.json":      "{}",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"linked":      "shared",
+		"shared/loop": "..",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	for _, tt := range []struct {
+		toolConfig ToolConfig
+		expected   []string
+	}{
+		{ToolConfig{}, []string{"a/b/c/package.json", "package.json", "shared/app/package.json"}},
+		{ToolConfig{ScanSubmodules: true}, []string{"a/b/c/package.json", "lib/package.json", "package.json", "shared/app/package.json"}},
+		// symlinks are followed, but directories already scanned (shared, and the base directory by shared/loop) are not
+		// scanned again
+		{ToolConfig{ScanSymlinks: true}, []string{"a/b/c/package.json", "package.json", "shared/app/package.json"}},
+		{ToolConfig{ScanMaxDepth: 2}, []string{"package.json", "shared/app/package.json"}},
+	} {
+		tt.toolConfig.ManifestPatterns = map[string]string{"npm": "(.*/)?package\\.json"}
+		tt.toolConfig.InitializePatterns()
+		manifests := map[string]string{}
+		ScanLocalDirectory(dir, "", manifests)
+		if got := util.SortedKeys(manifests); !reflect.DeepEqual(tt.expected, got) {
+			t.Errorf("ScanLocalDirectory() failed; expected %v got %v", tt.expected, got)
+		}
+	}
+	(&ToolConfig{}).InitializePatterns()
+}
+
+func TestScanLocalDirectoryFollowedSymlink(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "package.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "linked")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	for _, tt := range []struct {
+		symlinks bool
+		expected map[string]string
+	}{
+		{false, map[string]string{}},
+		{true, map[string]string{"linked/package.json": "npm"}},
+	} {
+		toolConfig := ToolConfig{ManifestPatterns: map[string]string{"npm": "(.*/)?package\\.json"}, ScanSymlinks: tt.symlinks}
+		toolConfig.InitializePatterns()
+		manifests := map[string]string{}
+		ScanLocalDirectory(dir, "", manifests)
+		if !reflect.DeepEqual(tt.expected, manifests) {
+			t.Errorf("ScanLocalDirectory() failed; expected %v got %v", tt.expected, manifests)
+		}
+	}
+	(&ToolConfig{}).InitializePatterns()
+}
+
+func TestValidateScanMaxDepth(t *testing.T) {
+	problems := (&ToolConfig{ScanMaxDepth: -1}).Validate()
+	if !util.Contains(problems, "scan-max-depth: must not be negative") {
+		t.Errorf("Validate() failed; expected a problem for scan-max-depth, got %v", problems)
+	}
+}
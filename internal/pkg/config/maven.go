@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/xml"
+	"path"
+	"sort"
+	"strings"
+)
+
+// mavenProject holds the modules of a pom.xml, the ones of its profiles included.
+type mavenProject struct {
+	Modules  []string `xml:"modules>module"`
+	Profiles []struct {
+		Modules []string `xml:"modules>module"`
+	} `xml:"profiles>profile"`
+}
+
+// CollapseMavenModules returns the manifests, with the pom.xml files of the modules of Maven multi-module projects
+// replaced by the one of their aggregator, the root project. Dependabot updates the modules from the root project,
+// also the ones outside of its directory (e.g. <module>../shared</module>).
+func CollapseMavenModules(manifests map[string]string, loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters) map[string]string {
+	poms := make([]string, 0)
+	for manifestFile, manifestType := range manifests {
+		if manifestType == "maven" && path.Base(manifestFile) == "pom.xml" {
+			poms = append(poms, manifestFile)
+		}
+	}
+	if len(poms) < 2 {
+		return manifests
+	}
+	sort.Strings(poms)
+
+	aggregators := map[string]string{}
+	for _, pom := range poms {
+		for _, module := range mavenModules(loadFileFn(pom, loadFileParams)) {
+			modulePom := mavenModuleFile(pom, module)
+			if _, known := aggregators[modulePom]; !known && modulePom != pom && manifests[modulePom] == "maven" {
+				aggregators[modulePom] = pom
+			}
+		}
+	}
+	collapsed := make(map[string]string, len(manifests))
+	for manifestFile, manifestType := range manifests {
+		if manifestType == "maven" {
+			collapsed[mavenRootOf(manifestFile, aggregators)] = manifestType
+			continue
+		}
+		collapsed[manifestFile] = manifestType
+	}
+	return collapsed
+}
+
+// mavenModules returns the modules of a pom.xml.
+func mavenModules(pomXML string) []string {
+	var project mavenProject
+	if err := xml.Unmarshal([]byte(pomXML), &project); err != nil {
+		return nil
+	}
+	modules := project.Modules
+	for _, profile := range project.Profiles {
+		modules = append(modules, profile.Modules...)
+	}
+	return modules
+}
+
+// mavenModuleFile returns the pom.xml of a module, given as directory or as pom file relative to the aggregator's one.
+func mavenModuleFile(aggregatorPom string, module string) string {
+	moduleFile := path.Join(path.Dir("/"+aggregatorPom), strings.TrimSpace(module))
+	if !strings.HasSuffix(moduleFile, ".xml") {
+		moduleFile = path.Join(moduleFile, "pom.xml")
+	}
+	return strings.TrimPrefix(moduleFile, "/")
+}
+
+// mavenRootOf returns the pom.xml of the root project a pom.xml belongs to, following the aggregators of nested
+// multi-module projects. A pom.xml which is no module of another one is a root project itself.
+func mavenRootOf(pom string, aggregators map[string]string) string {
+	visited := map[string]bool{pom: true}
+	for {
+		aggregator, found := aggregators[pom]
+		if !found || visited[aggregator] {
+			return pom
+		}
+		visited[aggregator] = true
+		pom = aggregator
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseMavenModules(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"build/pom.xml": `<project><modules><module>../service</module><module>../shared/</module></modules>
+  <profiles><profile><id>it</id><modules><module>../integration-tests/pom-it.xml</module></modules></profile></profiles></project>`,
+		"service/pom.xml": `<project><modules><module>api</module></modules></project>`,
+		"plain/pom.xml":   `<project><artifactId>plain</artifactId></project>`,
+		"plain/a/pom.xml": `<project><artifactId>a</artifactId></project>`,
+		"cycle/pom.xml":   `<project><modules><module>b</module></modules></project>`,
+		"cycle/b/pom.xml": `<project><modules><module>..</module></modules></project>`,
+		"invalid/pom.xml": `<project><modules>`,
+	})
+	manifests := map[string]string{
+		"build/pom.xml":                "maven",
+		"service/pom.xml":              "maven",
+		"service/api/pom.xml":          "maven",
+		"shared/pom.xml":               "maven",
+		"integration-tests/pom-it.xml": "maven",
+		"plain/pom.xml":                "maven",
+		"plain/a/pom.xml":              "maven",
+		"cycle/pom.xml":                "maven",
+		"cycle/b/pom.xml":              "maven",
+		"invalid/pom.xml":              "maven",
+		"service/Dockerfile":           "docker",
+	}
+	expected := map[string]string{
+		"build/pom.xml":      "maven",
+		"plain/pom.xml":      "maven",
+		"plain/a/pom.xml":    "maven",
+		"cycle/pom.xml":      "maven",
+		"cycle/b/pom.xml":    "maven",
+		"invalid/pom.xml":    "maven",
+		"service/Dockerfile": "docker",
+	}
+	got := CollapseMavenModules(manifests, loadFileFn, LoadFileContentParameters{})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("CollapseMavenModules() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+}
+
+func TestUpdateConfigMavenModules(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"build/pom.xml": `<project><modules><module>../service</module><module>../shared</module></modules></project>`,
+	})
+	manifests := map[string]string{"build/pom.xml": "maven", "service/pom.xml": "maven", "shared/pom.xml": "maven"}
+	dependabotConfig := DependabotConfig{}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, ToolConfig{}, loadFileFn, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedNew := []UpdateInfo{{Type: "maven", Directory: "/build", File: "build/pom.xml"}}
+	if !reflect.DeepEqual(changeInfo.NewUpdates, expectedNew) {
+		t.Errorf("UpdateConfig() failed; expected new updates %v got %v", expectedNew, changeInfo.NewUpdates)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ProtectedMarker opts an update out of being modified or removed by dependabutler, as a comment on the update
+// (e.g. "# dependabutler:ignore" above or next to its package-ecosystem) or as one of its labels.
+const ProtectedMarker = "dependabutler:ignore"
+
+// IsProtected returns if an update is opted out of being modified or removed by dependabutler.
+func (update Update) IsProtected() bool {
+	return update.protected || util.Contains(update.Labels, ProtectedMarker)
+}
+
+// markProtectedUpdates marks the updates with the protected marker in a comment of their entry.
+func (config *DependabotConfig) markProtectedUpdates(document *yaml.Node) {
+	if len(document.Content) == 0 {
+		return
+	}
+	_, updates := mappingEntry(document.Content[0], "updates")
+	if updates == nil || updates.Kind != yaml.SequenceNode || len(updates.Content) != len(config.Updates) {
+		return
+	}
+	for i, update := range updates.Content {
+		config.Updates[i].protected = hasProtectedComment(update)
+	}
+}
+
+// hasProtectedComment returns if a node or any of its children has a comment with the protected marker.
+func hasProtectedComment(node *yaml.Node) bool {
+	for _, comment := range []string{node.HeadComment, node.LineComment, node.FootComment} {
+		if strings.Contains(comment, ProtectedMarker) {
+			return true
+		}
+	}
+	for _, child := range node.Content {
+		if hasProtectedComment(child) {
+			return true
+		}
+	}
+	return false
+}
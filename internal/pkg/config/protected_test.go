@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProtectedUpdates(t *testing.T) {
+	current := []byte(`version: 2
+updates:
+  - package-ecosystem: npm
+    directory: /
+    schedule:
+      interval: daily
+  # dependabutler:ignore - intentionally monthly
+  - package-ecosystem: docker
+    directory: /
+    schedule:
+      interval: monthly
+  - package-ecosystem: gomod
+    directory: /legacy # dependabutler:ignore
+    schedule:
+      interval: daily
+  - package-ecosystem: pip
+    directory: /tools
+    schedule:
+      interval: monthly
+    labels:
+      - dependabutler:ignore
+`)
+	dependabotConfig, err := ParseDependabotConfig(current)
+	if err != nil {
+		t.Fatalf("ParseDependabotConfig() failed; unexpected error %v", err)
+	}
+	protected := make([]bool, 0)
+	for _, update := range dependabotConfig.Updates {
+		protected = append(protected, update.IsProtected())
+	}
+	if expected := []bool{false, true, true, true}; !reflect.DeepEqual(protected, expected) {
+		t.Fatalf("IsProtected() failed; expected %v got %v", expected, protected)
+	}
+	toolConfig := ToolConfig{
+		UpdateDefaults:           UpdateDefaults{Schedule: Schedule{Interval: "weekly"}},
+		Enforce:                  []string{"schedule"},
+		RemoveMissingDirectories: true,
+		ManifestPatterns:         map[string]string{"gomod": "(^|/)go\\.mod$", "pip": "(^|/)requirements\\.txt$"},
+	}
+	manifests := map[string]string{"package.json": "npm", "Dockerfile": "docker"}
+	changeInfo := dependabotConfig.UpdateConfig(manifests, toolConfig, LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+	expectedFixed := []FixInfo{{Type: "npm", Directory: "/", Change: "schedule set to the default"}}
+	if !reflect.DeepEqual(changeInfo.FixedUpdates, expectedFixed) || len(changeInfo.RemovedUpdates) != 0 {
+		t.Errorf("UpdateConfig() failed; expected fixed updates %v and no removals, got %v and %v", expectedFixed, changeInfo.FixedUpdates, changeInfo.RemovedUpdates)
+	}
+	if len(dependabotConfig.Updates) != 4 || dependabotConfig.Updates[1].Schedule.Interval != "monthly" {
+		t.Errorf("UpdateConfig() failed; expected the protected updates to be kept as they are, got %v", dependabotConfig.Updates)
+	}
+}
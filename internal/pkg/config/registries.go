@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// Modes of registry-references, for existing updates whose manifests use default registries they don't reference:
+// add the references, and the registries to the config, add them only for registries missing in the config
+// (inherit), or only report them. Protected updates are only reported.
+const (
+	RegistryReferencesAdd     = "add"
+	RegistryReferencesInherit = "inherit"
+	RegistryReferencesReport  = "report"
+)
+
+// registryReferencesModes holds the values accepted by registry-references, add if empty.
+var registryReferencesModes = []string{"", RegistryReferencesAdd, RegistryReferencesInherit, RegistryReferencesReport}
+
+// registryHostDetector returns the registry hosts a manifest file actually refers to.
+type registryHostDetector func(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters) []string
+
+// registryHostDetectors holds the manifest type specific detectors, used instead of a plain search in the manifest file.
+var registryHostDetectors = map[string]registryHostDetector{
+	"composer": detectComposerRegistryHosts,
+	"pip":      detectPipRegistryHosts,
+	"nuget":    detectNugetRegistryHosts,
+	"npm":      detectNpmRegistryHosts,
+	"maven":    detectMavenRegistryHosts,
+	"gradle":   detectGradleRegistryHosts,
+}
+
+// pipURLPattern matches http(s) URLs in a requirements file line.
+var pipURLPattern = regexp.MustCompile(`https?://[^\s#'"]+`)
+
+// urlPattern matches http(s) URLs in config files and lockfiles of any format, e.g. JSON, YAML, XML or Groovy.
+var urlPattern = regexp.MustCompile(`https?://[^\s#'"<>()\[\]{},;]+`)
+
+// npmLockfiles holds the files of npm, yarn and pnpm listing the URLs packages are resolved from.
+var npmLockfiles = []string{"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml"}
+
+// detectComposerRegistryHosts returns the hosts of the repositories in composer.json and the hosts in auth.json.
+func detectComposerRegistryHosts(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := make([]string, 0)
+
+	// composer.json: "repositories" is either a list or an object of repository definitions
+	var composerJSON struct {
+		Repositories json.RawMessage `json:"repositories"`
+	}
+	if err := json.Unmarshal([]byte(loadFileFn(manifestFile, loadFileParams)), &composerJSON); err == nil && composerJSON.Repositories != nil {
+		var repositoryList []json.RawMessage
+		if err := json.Unmarshal(composerJSON.Repositories, &repositoryList); err != nil {
+			repositoryMap := map[string]json.RawMessage{}
+			if err := json.Unmarshal(composerJSON.Repositories, &repositoryMap); err == nil {
+				for _, repository := range repositoryMap {
+					repositoryList = append(repositoryList, repository)
+				}
+			}
+		}
+		for _, repository := range repositoryList {
+			var definition struct {
+				URL string `json:"url"`
+			}
+			// entries like "packagist.org": false are no objects and are skipped
+			if err := json.Unmarshal(repository, &definition); err == nil && definition.URL != "" {
+				hosts = append(hosts, hostFromURL(definition.URL))
+			}
+		}
+	}
+
+	// auth.json: every auth section (http-basic, bearer, gitlab-token, ...) is an object keyed by host
+	authJSON := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(loadFileFn(filepath.Join(manifestPath, "auth.json"), loadFileParams)), &authJSON); err == nil {
+		for _, section := range authJSON {
+			sectionHosts := map[string]json.RawMessage{}
+			if err := json.Unmarshal(section, &sectionHosts); err == nil {
+				for host := range sectionHosts {
+					hosts = append(hosts, hostFromURL(host))
+				}
+			}
+		}
+	}
+	return hosts
+}
+
+// detectPipRegistryHosts returns the hosts of the package indexes in requirements files, pip.conf, Pipfile and
+// pyproject.toml.
+func detectPipRegistryHosts(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := make([]string, 0)
+	pyprojectFile := filepath.Join(manifestPath, "pyproject.toml")
+	if filepath.Base(manifestFile) != "pyproject.toml" {
+		// requirements files: --index-url, --extra-index-url, -i and direct URL references
+		for _, line := range strings.Split(loadFileFn(manifestFile, loadFileParams), "\n") {
+			for _, match := range pipURLPattern.FindAllString(line, -1) {
+				hosts = append(hosts, hostFromURL(match))
+			}
+		}
+	} else {
+		pyprojectFile = manifestFile
+	}
+	// pip.conf: index-url and extra-index-url, the latter may hold several URLs on indented continuation lines
+	inIndexValue := false
+	for _, line := range strings.Split(loadFileFn(filepath.Join(manifestPath, "pip.conf"), loadFileParams), "\n") {
+		value := line
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			var key string
+			key, value, inIndexValue = strings.Cut(line, "=")
+			inIndexValue = inIndexValue && (strings.TrimSpace(key) == "index-url" || strings.TrimSpace(key) == "extra-index-url")
+		}
+		if inIndexValue {
+			for _, indexURL := range strings.Fields(value) {
+				hosts = append(hosts, hostFromURL(indexURL))
+			}
+		}
+	}
+	// Pipfile and Pipfile.lock: the url entries of the [[source]] tables, and the sources of the lockfile
+	for _, pipenvFile := range []string{"Pipfile", "Pipfile.lock"} {
+		if pipenvFile != filepath.Base(manifestFile) {
+			hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(manifestPath, pipenvFile), loadFileParams))...)
+		}
+	}
+	// pyproject.toml: url entries of [[tool.poetry.source]] and [[tool.uv.index]] tables
+	inSourceTable := false
+	for _, line := range strings.Split(loadFileFn(pyprojectFile, loadFileParams), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			table := strings.Trim(trimmed, "[] ")
+			inSourceTable = table == "tool.poetry.source" || table == "tool.uv.index"
+			continue
+		}
+		if key, value, found := strings.Cut(trimmed, "="); inSourceTable && found && strings.TrimSpace(key) == "url" {
+			hosts = append(hosts, hostFromURL(strings.Trim(strings.TrimSpace(value), `"'`)))
+		}
+	}
+	return hosts
+}
+
+// detectNugetRegistryHosts returns the hosts of the package sources in NuGet.config files.
+// NuGet merges the config files of the project directory and all parent directories, so all of them are searched.
+func detectNugetRegistryHosts(_ string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := make([]string, 0)
+	directory := manifestPath
+	for {
+		for _, fileName := range []string{"NuGet.config", "nuget.config", "NuGet.Config"} {
+			var nugetConfig struct {
+				PackageSources struct {
+					Add []struct {
+						Value string `xml:"value,attr"`
+					} `xml:"add"`
+				} `xml:"packageSources"`
+			}
+			if err := xml.Unmarshal([]byte(loadFileFn(filepath.Join(directory, fileName), loadFileParams)), &nugetConfig); err == nil {
+				for _, source := range nugetConfig.PackageSources.Add {
+					if source.Value != "" {
+						hosts = append(hosts, hostFromURL(source.Value))
+					}
+				}
+			}
+		}
+		if directory == "/" || directory == "." || directory == "" {
+			return hosts
+		}
+		directory = filepath.Dir(directory)
+	}
+}
+
+// detectNpmRegistryHosts returns the hosts in package.json, the registries of .npmrc and .yarnrc.yml, and the hosts
+// packages are resolved from according to the lockfiles of the project directory.
+func detectNpmRegistryHosts(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := urlHosts(loadFileFn(manifestFile, loadFileParams))
+	// .npmrc: registry and @scope:registry settings, and auth settings like //npm.foo.bar/:_authToken=...
+	for _, line := range strings.Split(loadFileFn(filepath.Join(manifestPath, ".npmrc"), loadFileParams), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") {
+			hosts = appendHosts(hosts, hostFromURL(strings.TrimPrefix(line, "//")))
+			continue
+		}
+		hosts = appendHosts(hosts, urlHosts(line)...)
+	}
+	// .yarnrc.yml: npmRegistryServer and the ones of npmScopes
+	hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(manifestPath, ".yarnrc.yml"), loadFileParams))...)
+	for _, lockfile := range npmLockfiles {
+		if lockfile != filepath.Base(manifestFile) {
+			hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(manifestPath, lockfile), loadFileParams))...)
+		}
+	}
+	return hosts
+}
+
+// detectMavenRegistryHosts returns the hosts of the repositories in pom.xml, and the mirrors, repositories and servers
+// of the Maven settings of the project directory (settings.xml or .mvn/settings.xml).
+func detectMavenRegistryHosts(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := urlHosts(loadFileFn(manifestFile, loadFileParams))
+	for _, settingsFile := range []string{"settings.xml", ".mvn/settings.xml"} {
+		hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(manifestPath, settingsFile), loadFileParams))...)
+	}
+	return hosts
+}
+
+// detectGradleRegistryHosts returns the hosts of the repositories in the build scripts, gradle.properties, and the
+// settings scripts of the project directory and its parents, declaring the repositories of multi-project builds.
+func detectGradleRegistryHosts(manifestFile string, manifestPath string,
+	loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters,
+) []string {
+	hosts := urlHosts(loadFileFn(manifestFile, loadFileParams))
+	for _, file := range []string{"build.gradle", "build.gradle.kts", "gradle.properties"} {
+		if file != filepath.Base(manifestFile) {
+			hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(manifestPath, file), loadFileParams))...)
+		}
+	}
+	directory := manifestPath
+	for {
+		for _, settingsFile := range []string{"settings.gradle", "settings.gradle.kts"} {
+			hosts = appendHosts(hosts, urlHosts(loadFileFn(filepath.Join(directory, settingsFile), loadFileParams))...)
+		}
+		if directory == "/" || directory == "." || directory == "" {
+			return hosts
+		}
+		directory = filepath.Dir(directory)
+	}
+}
+
+// urlHosts returns the distinct hosts of the http(s) URLs in a file's content.
+func urlHosts(content string) []string {
+	hosts := make([]string, 0)
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		hosts = appendHosts(hosts, hostFromURL(match))
+	}
+	return hosts
+}
+
+// appendHosts appends the hosts not contained yet to a list of hosts.
+func appendHosts(hosts []string, newHosts ...string) []string {
+	for _, host := range newHosts {
+		if host != "" && !util.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hostFromURL returns the lower-case host name of a URL, which may be given without a scheme.
+func hostFromURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// registryNameData holds the values available in templated registry names, e.g. "npm-internal-{{.Ecosystem}}".
+type registryNameData struct {
+	Ecosystem string
+	Type      string
+}
+
+// RegistryName returns the name of a default registry in dependabot.yml.
+// Templated names are rendered. If another package ecosystem defines a different registry with the same name,
+// the names get the package ecosystem as suffix, so that none of them is overwritten.
+func (config *ToolConfig) RegistryName(manifestType string, name string) string {
+	defaultRegistry := config.Registries[manifestType][name]
+	registryName := renderRegistryName(name, manifestType, defaultRegistry)
+	otherTypes := make([]string, 0, len(config.Registries))
+	for otherType := range config.Registries {
+		if otherType != manifestType {
+			otherTypes = append(otherTypes, otherType)
+		}
+	}
+	sort.Strings(otherTypes)
+	for _, otherType := range otherTypes {
+		for otherName, otherRegistry := range config.Registries[otherType] {
+			if renderRegistryName(otherName, otherType, otherRegistry) != registryName {
+				continue
+			}
+			if otherRegistry.Type != defaultRegistry.Type || otherRegistry.URL != defaultRegistry.URL ||
+				otherRegistry.Organization != defaultRegistry.Organization {
+				log.Printf("WARN  Registry name %v is used by %v and %v, adding the package ecosystem as suffix.",
+					registryName, manifestType, otherType)
+				return registryName + "-" + manifestType
+			}
+		}
+	}
+	return registryName
+}
+
+// renderRegistryName renders a templated registry name, the name is used as is if it's no valid template.
+func renderRegistryName(name string, manifestType string, defaultRegistry DefaultRegistry) string {
+	if !strings.Contains(name, "{{") {
+		return name
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(name)
+	if err != nil {
+		log.Printf("WARN  Invalid registry name template %v: %v", name, err)
+		return name
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, registryNameData{Ecosystem: manifestType, Type: defaultRegistry.Type}); err != nil {
+		log.Printf("WARN  Invalid registry name template %v: %v", name, err)
+		return name
+	}
+	return rendered.String()
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"testing"
+)
+
+func LoadFileContentFromMap(files map[string]string) LoadFileContent {
+	return func(file string, _ LoadFileContentParameters) string {
+		return files[file]
+	}
+}
+
+func TestIsRegistryUsedComposer(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"list/composer.json": `{"repositories": [{"type": "composer", "url": "https://composer.foo.bar/repo"}]}`,
+		"map/composer.json":  `{"repositories": {"private": {"type": "vcs", "url": "https://vcs.foo.bar"}, "packagist.org": false}}`,
+		"auth/composer.json": `{"require": {"foo/bar": "^1.0"}}`,
+		"/auth/auth.json":    `{"http-basic": {"composer.foo.bar": {"username": "usr", "password": "pwd"}}}`,
+		"text/composer.json": `{"description": "see https://composer.foo.bar for details"}`,
+	})
+	for _, tt := range []struct {
+		manifestFile string
+		registryURL  string
+		expected     bool
+	}{
+		{"list/composer.json", "https://composer.foo.bar", true},
+		{"list/composer.json", "https://vcs.foo.bar", false},
+		{"map/composer.json", "https://vcs.foo.bar", true},
+		{"map/composer.json", "https://composer.foo.bar", false},
+		{"auth/composer.json", "https://composer.foo.bar", true},
+		{"text/composer.json", "https://composer.foo.bar", false},
+	} {
+		manifestPath := GetManifestPath(tt.manifestFile, "composer")
+		registry := DefaultRegistry{Type: "composer-repository", URL: tt.registryURL, URLMatchRequired: true}
+		got := IsRegistryUsed(tt.manifestFile, "composer", manifestPath, registry, loadFileFn, LoadFileContentParameters{})
+		if tt.expected != got {
+			t.Errorf("IsRegistryUsed(%v, %v) failed; expected %t got %t", tt.manifestFile, tt.registryURL, tt.expected, got)
+		}
+	}
+}
+
+func TestIsRegistryUsedPip(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"index/requirements.txt": "--index-url https://pypi.foo.bar/simple\nrequests==2.31.0\n",
+		"extra/requirements.txt": "-i https://pypi.org/simple\n--extra-index-url=https://extra.foo.bar/simple\n",
+		"conf/requirements.txt":  "requests==2.31.0\n",
+		"/conf/pip.conf":         "[global]\ntimeout = 60\nextra-index-url =\n    https://pypi.org/simple\n    https://conf.foo.bar/simple\n",
+		"poetry/pyproject.toml":  "[tool.poetry]\nname = \"foo\"\n\n[[tool.poetry.source]]\nname = \"private\"\nurl = \"https://poetry.foo.bar/simple\"\n",
+		"/uv/pyproject.toml":     "[project]\nname = \"foo\"\n\n[[tool.uv.index]]\nname = \"private\"\nurl = \"https://uv.foo.bar/simple\"\n",
+		"uv/requirements.txt":    "requests==2.31.0\n",
+		"other/pyproject.toml":   "[project]\nurl = \"https://other.foo.bar\"\n",
+		"pipenv/Pipfile":         "[[source]]\nurl = \"https://pipenv.foo.bar/simple\"\nverify_ssl = true\nname = \"private\"\n",
+		"/pipenv/Pipfile.lock":   `{"_meta": {"sources": [{"name": "private", "url": "https://lock.foo.bar/simple"}]}}`,
+	})
+	for _, tt := range []struct {
+		manifestFile string
+		registryURL  string
+		expected     bool
+	}{
+		{"index/requirements.txt", "https://pypi.foo.bar", true},
+		{"extra/requirements.txt", "https://extra.foo.bar", true},
+		{"extra/requirements.txt", "https://pypi.foo.bar", false},
+		{"conf/requirements.txt", "https://conf.foo.bar", true},
+		{"poetry/pyproject.toml", "https://poetry.foo.bar", true},
+		{"uv/requirements.txt", "https://uv.foo.bar", true},
+		{"other/pyproject.toml", "https://other.foo.bar", false},
+		{"pipenv/Pipfile", "https://pipenv.foo.bar", true},
+		{"pipenv/Pipfile", "https://lock.foo.bar", true},
+	} {
+		manifestPath := GetManifestPath(tt.manifestFile, "pip")
+		registry := DefaultRegistry{Type: "python-index", URL: tt.registryURL, URLMatchRequired: true}
+		got := IsRegistryUsed(tt.manifestFile, "pip", manifestPath, registry, loadFileFn, LoadFileContentParameters{})
+		if tt.expected != got {
+			t.Errorf("IsRegistryUsed(%v, %v) failed; expected %t got %t", tt.manifestFile, tt.registryURL, tt.expected, got)
+		}
+	}
+}
+
+func TestIsRegistryUsedNuget(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"/app/NuGet.config": `<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <clear />
+    <add key="nuget.org" value="https://api.nuget.org/v3/index.json" />
+    <add key="private" value="https://nuget.foo.bar/v3/index.json" />
+  </packageSources>
+</configuration>`,
+		"/nuget.config": `<configuration><packageSources><add key="root" value="https://root.foo.bar/nuget" /></packageSources></configuration>`,
+	})
+	for _, tt := range []struct {
+		manifestFile string
+		registryURL  string
+		expected     bool
+	}{
+		{"app/App.csproj", "https://nuget.foo.bar", true},
+		{"app/sub/Sub.csproj", "https://nuget.foo.bar", true},
+		{"app/App.csproj", "https://root.foo.bar", true},
+		{"other/Other.csproj", "https://nuget.foo.bar", false},
+		{"other/Other.csproj", "https://root.foo.bar", true},
+	} {
+		manifestPath := GetManifestPath(tt.manifestFile, "nuget")
+		registry := DefaultRegistry{Type: "nuget-feed", URL: tt.registryURL, URLMatchRequired: true}
+		got := IsRegistryUsed(tt.manifestFile, "nuget", manifestPath, registry, loadFileFn, LoadFileContentParameters{})
+		if tt.expected != got {
+			t.Errorf("IsRegistryUsed(%v, %v) failed; expected %t got %t", tt.manifestFile, tt.registryURL, tt.expected, got)
+		}
+	}
+}
+
+func TestIsRegistryUsedNpm(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"publish/package.json":    `{"name": "foo", "publishConfig": {"registry": "https://publish.foo.bar/npm/"}}`,
+		"/npmrc/.npmrc":           "@acme:registry=https://scoped.foo.bar/npm/\n//auth.foo.bar/npm/:_authToken=${NPM_TOKEN}\n",
+		"/yarn/.yarnrc.yml":       "npmScopes:\n  acme:\n    npmRegistryServer: \"https://yarnrc.foo.bar\"\n",
+		"/yarn/yarn.lock":         "left-pad@^1.3.0:\n  version \"1.3.0\"\n  resolved \"https://yarn.foo.bar/left-pad/-/left-pad-1.3.0.tgz#5b8a3a7765dfe001261dde915589e782f8c94d1e\"\n",
+		"/lock/package-lock.json": `{"packages": {"node_modules/left-pad": {"resolved": "https://lock.foo.bar/left-pad/-/left-pad-1.3.0.tgz"}}}`,
+	})
+	for _, tt := range []struct {
+		manifestFile string
+		registryURL  string
+		expected     bool
+	}{
+		{"publish/package.json", "https://publish.foo.bar", true},
+		{"npmrc/package.json", "https://scoped.foo.bar", true},
+		{"npmrc/package.json", "https://auth.foo.bar", true},
+		{"yarn/package.json", "https://yarnrc.foo.bar", true},
+		{"yarn/package.json", "https://yarn.foo.bar", true},
+		{"lock/package.json", "https://lock.foo.bar", true},
+		{"lock/package.json", "https://yarn.foo.bar", false},
+	} {
+		manifestPath := GetManifestPath(tt.manifestFile, "npm")
+		registry := DefaultRegistry{Type: "npm-registry", URL: tt.registryURL, URLMatchRequired: true}
+		got := IsRegistryUsed(tt.manifestFile, "npm", manifestPath, registry, loadFileFn, LoadFileContentParameters{})
+		if tt.expected != got {
+			t.Errorf("IsRegistryUsed(%v, %v) failed; expected %t got %t", tt.manifestFile, tt.registryURL, tt.expected, got)
+		}
+	}
+}
+
+func TestIsRegistryUsedMavenAndGradle(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"pom/pom.xml":                 "<project><repositories><repository><url>https://pom.foo.bar/maven2</url></repository></repositories></project>",
+		"/settings/.mvn/settings.xml": "<settings><mirrors><mirror><url>https://mirror.foo.bar/maven2</url></mirror></mirrors></settings>",
+		"app/build.gradle.kts":        "repositories {\n  maven(\"https://kts.foo.bar/maven2\")\n}\n",
+		"/app/gradle.properties":      "repoUrl=https://properties.foo.bar/maven2\n",
+		"/settings.gradle":            "dependencyResolutionManagement {\n  repositories {\n    maven { url 'https://root.foo.bar/maven2' }\n  }\n}\n",
+	})
+	for _, tt := range []struct {
+		manifestFile string
+		manifestType string
+		registryURL  string
+		expected     bool
+	}{
+		{"pom/pom.xml", "maven", "https://pom.foo.bar", true},
+		{"settings/pom.xml", "maven", "https://mirror.foo.bar", true},
+		{"pom/pom.xml", "maven", "https://mirror.foo.bar", false},
+		{"app/build.gradle.kts", "gradle", "https://kts.foo.bar", true},
+		{"app/build.gradle.kts", "gradle", "https://properties.foo.bar", true},
+		{"app/sub/build.gradle", "gradle", "https://root.foo.bar", true},
+		{"app/sub/build.gradle", "gradle", "https://kts.foo.bar", false},
+	} {
+		manifestPath := GetManifestPath(tt.manifestFile, tt.manifestType)
+		registry := DefaultRegistry{Type: "maven-repository", URL: tt.registryURL, URLMatchRequired: true}
+		got := IsRegistryUsed(tt.manifestFile, tt.manifestType, manifestPath, registry, loadFileFn, LoadFileContentParameters{})
+		if tt.expected != got {
+			t.Errorf("IsRegistryUsed(%v, %v) failed; expected %t got %t", tt.manifestFile, tt.registryURL, tt.expected, got)
+		}
+	}
+}
+
+func TestRegistryName(t *testing.T) {
+	toolConfig := ToolConfig{
+		Registries: map[string]DefaultRegistries{
+			"npm": {
+				"internal":                   {Type: "npm-registry", URL: "https://npm.foo.bar"},
+				"artifactory-{{.Ecosystem}}": {Type: "npm-registry", URL: "https://artifactory.foo.bar/npm"},
+				"broken-{{.Unknown}}":        {Type: "npm-registry", URL: "https://broken.foo.bar"},
+			},
+			"docker": {
+				"internal":                   {Type: "docker-registry", URL: "https://docker.foo.bar"},
+				"artifactory-{{.Ecosystem}}": {Type: "docker-registry", URL: "https://artifactory.foo.bar/docker"},
+			},
+			"maven":  {"shared": {Type: "maven-repository", URL: "https://maven.foo.bar"}},
+			"gradle": {"shared": {Type: "maven-repository", URL: "https://maven.foo.bar"}},
+		},
+	}
+	for _, tt := range []struct {
+		manifestType string
+		name         string
+		expected     string
+	}{
+		{"npm", "internal", "internal-npm"},
+		{"docker", "internal", "internal-docker"},
+		{"npm", "artifactory-{{.Ecosystem}}", "artifactory-npm"},
+		{"docker", "artifactory-{{.Ecosystem}}", "artifactory-docker"},
+		{"npm", "broken-{{.Unknown}}", "broken-{{.Unknown}}"},
+		{"maven", "shared", "shared"},
+		{"gradle", "shared", "shared"},
+	} {
+		if got := toolConfig.RegistryName(tt.manifestType, tt.name); got != tt.expected {
+			t.Errorf("RegistryName(%v, %v) failed; expected %v got %v", tt.manifestType, tt.name, tt.expected, got)
+		}
+	}
+}
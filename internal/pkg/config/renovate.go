@@ -0,0 +1,509 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+var (
+	// RenovateConfigFiles holds the locations of renovate configs in a repo, in the order renovate looks them up.
+	RenovateConfigFiles = []string{
+		"renovate.json", "renovate.json5", ".github/renovate.json", ".github/renovate.json5",
+		".gitlab/renovate.json", ".gitlab/renovate.json5", ".renovaterc", ".renovaterc.json", ".renovaterc.json5",
+	}
+	// renovateManagers maps the renovate managers to the package ecosystems they correspond to.
+	renovateManagers = map[string]string{
+		"bun": "bun", "bundler": "bundler", "cargo": "cargo", "composer": "composer", "devcontainer": "devcontainers",
+		"docker-compose": "docker-compose", "dockerfile": "docker", "git-submodules": "gitsubmodule",
+		"github-actions": "github-actions", "gomod": "gomod", "gradle": "gradle", "gradle-wrapper": "gradle",
+		"helmv3": "helm", "maven": "maven", "maven-wrapper": "maven", "mix": "mix", "npm": "npm", "nuget": "nuget",
+		"pep621": "pip", "pip_requirements": "pip", "pip_setup": "pip", "pipenv": "pip", "poetry": "pip", "pub": "pub",
+		"swift": "swift", "terraform": "terraform",
+	}
+	// renovateHostTypes maps the renovate host types to registry types, and the package ecosystems using them.
+	renovateHostTypes = map[string]struct {
+		registryType string
+		ecosystems   []string
+	}{
+		"docker":           {"docker-registry", []string{"docker", "docker-compose", "helm"}},
+		"maven":            {"maven-repository", []string{"gradle", "maven"}},
+		"npm":              {"npm-registry", []string{"bun", "npm"}},
+		"nuget":            {"nuget-feed", []string{"nuget"}},
+		"packagist":        {"composer-repository", []string{"composer"}},
+		"pypi":             {"python-index", []string{"pip", "uv"}},
+		"rubygems":         {"rubygems-server", []string{"bundler"}},
+		"terraform-module": {"terraform-registry", []string{"terraform"}},
+	}
+	// renovateUpdateTypes holds the update types dependabot supports of the renovate ones.
+	renovateUpdateTypes = []string{"major", "minor", "patch"}
+	// renovateDepTypes maps the renovate dependency types to the dependabot ones, for groups.
+	renovateDepTypes = map[string]string{"dependencies": "production", "devDependencies": "development"}
+	weekdays         = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	// renovateTimePattern matches the times in renovate schedules, like "before 5am" or "after 10:30pm".
+	renovateTimePattern = regexp.MustCompile(`(before|after) (\d{1,2})(?::(\d{2}))?\s*(am|pm)?`)
+	// literalPattern matches regular expressions without special characters besides anchors and ".*".
+	literalPattern = regexp.MustCompile(`^\^?([\w@/.\-]|\\[./@-]|\.\*)*\$?$`)
+)
+
+// RenovateConfig holds the settings of a renovate config, which can be migrated to dependabot.
+type RenovateConfig struct {
+	Extends           renovateStrings       `json:"extends"`
+	Schedule          renovateStrings       `json:"schedule"`
+	Timezone          string                `json:"timezone"`
+	Labels            []string              `json:"labels"`
+	Reviewers         []string              `json:"reviewers"`
+	Assignees         []string              `json:"assignees"`
+	PrConcurrentLimit int                   `json:"prConcurrentLimit"`
+	IgnoreDeps        []string              `json:"ignoreDeps"`
+	EnabledManagers   []string              `json:"enabledManagers"`
+	PackageRules      []RenovatePackageRule `json:"packageRules"`
+	HostRules         []RenovateHostRule    `json:"hostRules"`
+}
+
+// RenovatePackageRule holds the settings of a renovate package rule.
+type RenovatePackageRule struct {
+	MatchManagers        []string        `json:"matchManagers"`
+	MatchPackageNames    []string        `json:"matchPackageNames"`
+	MatchPackagePatterns []string        `json:"matchPackagePatterns"`
+	MatchPackagePrefixes []string        `json:"matchPackagePrefixes"`
+	MatchDepTypes        []string        `json:"matchDepTypes"`
+	MatchUpdateTypes     []string        `json:"matchUpdateTypes"`
+	GroupName            string          `json:"groupName"`
+	Enabled              *bool           `json:"enabled"`
+	Schedule             renovateStrings `json:"schedule"`
+}
+
+// RenovateHostRule holds the settings of a renovate host rule, i.e. a private registry.
+type RenovateHostRule struct {
+	HostType  string `json:"hostType"`
+	MatchHost string `json:"matchHost"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Token     string `json:"token"`
+}
+
+// renovateStrings holds a renovate setting given as string or list of strings.
+type renovateStrings []string
+
+// UnmarshalJSON reads a string or a list of strings.
+func (values *renovateStrings) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*values = []string{value}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*values = list
+	return nil
+}
+
+// ParseRenovateConfig parses a renovate config, in JSON or JSON5.
+func ParseRenovateConfig(content []byte) (*RenovateConfig, error) {
+	var renovateConfig RenovateConfig
+	if err := json.Unmarshal(json5ToJSON(content), &renovateConfig); err != nil {
+		return nil, fmt.Errorf("invalid renovate config: %w", err)
+	}
+	return &renovateConfig, nil
+}
+
+// json5ToJSON converts the JSON5 syntax used in renovate configs to JSON: comments are dropped, single-quoted
+// strings and keys without quotes are double-quoted, and trailing commas are removed.
+func json5ToJSON(content []byte) []byte {
+	text := []rune(string(content))
+	result := make([]rune, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"' || c == '\'':
+			value := make([]rune, 0)
+			for i++; i < len(text) && text[i] != c; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+					if text[i] != '\'' {
+						value = append(value, '\\')
+					}
+				} else if text[i] == '"' {
+					value = append(value, '\\')
+				}
+				value = append(value, text[i])
+			}
+			result = append(append(append(result, '"'), value...), '"')
+		case c == '/' && i+1 < len(text) && text[i+1] == '/':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			result = append(result, '\n')
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			for i += 2; i+1 < len(text) && (text[i] != '*' || text[i+1] != '/'); i++ {
+			}
+			i++
+		case c == '}' || c == ']':
+			// drop a trailing comma
+			end := len(result)
+			for end > 0 && unicode.IsSpace(result[end-1]) {
+				end--
+			}
+			if end > 0 && result[end-1] == ',' {
+				result = result[:end-1]
+			}
+			result = append(result, c)
+		case unicode.IsLetter(c) || c == '_' || c == '$':
+			start := i
+			for i+1 < len(text) && (unicode.IsLetter(text[i+1]) || unicode.IsDigit(text[i+1]) || text[i+1] == '_' || text[i+1] == '$') {
+				i++
+			}
+			word := text[start : i+1]
+			next := i + 1
+			for next < len(text) && unicode.IsSpace(text[next]) {
+				next++
+			}
+			if next < len(text) && text[next] == ':' {
+				word = append(append([]rune{'"'}, word...), '"')
+			}
+			result = append(result, word...)
+		default:
+			result = append(result, c)
+		}
+	}
+	return []byte(string(result))
+}
+
+// ApplyTo applies the settings of the renovate config to the updates and registries of a dependabot config:
+// schedule, labels, reviewers, assignees and PR limit, package rules as groups and ignores, and host rules as
+// registries. Updates protected by a comment are left unchanged. Returned are notes on the settings which could not
+// be migrated, and on the secrets to be created for the registries.
+func (renovateConfig *RenovateConfig) ApplyTo(dependabotConfig *DependabotConfig) []string {
+	notes := make([]string, 0)
+	if len(renovateConfig.Extends) > 0 {
+		notes = append(notes, fmt.Sprintf("The presets %v are not resolved, their settings need to be migrated manually.",
+			strings.Join(renovateConfig.Extends, ", ")))
+	}
+	schedule, ok := renovateSchedule(renovateConfig.Schedule, renovateConfig.Timezone)
+	if !ok {
+		notes = append(notes, fmt.Sprintf("The schedule %q can't be expressed in dependabot, the default schedule is kept.",
+			strings.Join(renovateConfig.Schedule, ", ")))
+	}
+	for i, rule := range renovateConfig.PackageRules {
+		notes = append(notes, rule.notes(i)...)
+	}
+	registries, registryNotes := renovateConfig.registries()
+	notes = append(notes, registryNotes...)
+	for name, registry := range registries {
+		if dependabotConfig.Registries == nil {
+			dependabotConfig.Registries = map[string]Registry{}
+		}
+		dependabotConfig.Registries[name] = registry
+	}
+	enabled := map[string]bool{}
+	for _, manager := range renovateConfig.EnabledManagers {
+		enabled[renovateManagers[manager]] = true
+	}
+	for i := range dependabotConfig.Updates {
+		update := &dependabotConfig.Updates[i]
+		if update.IsProtected() {
+			continue
+		}
+		if len(enabled) > 0 && !enabled[update.PackageEcosystem] {
+			notes = append(notes, fmt.Sprintf("The updates of %v in %v are not enabled in the renovate config, but kept.",
+				update.PackageEcosystem, strings.Join(update.DirectoryList(), ", ")))
+		}
+		if schedule.Interval != "" {
+			update.Schedule = schedule
+		}
+		update.Labels = appendMissing(update.Labels, renovateConfig.Labels...)
+		update.Reviewers = appendMissing(update.Reviewers, renovateConfig.Reviewers...)
+		update.Assignees = appendMissing(update.Assignees, renovateConfig.Assignees...)
+		if renovateConfig.PrConcurrentLimit > 0 {
+			update.OpenPullRequestsLimit = renovateConfig.PrConcurrentLimit
+		}
+		for _, dependency := range renovateConfig.IgnoreDeps {
+			update.Ignore = appendIgnore(update.Ignore, Ignore{DependencyName: dependency})
+		}
+		for _, rule := range renovateConfig.PackageRules {
+			if rule.appliesTo(update.PackageEcosystem) {
+				rule.applyTo(update)
+			}
+		}
+		for _, name := range util.SortedKeys(registries) {
+			if util.Contains(registryEcosystems(registries[name].Type), update.PackageEcosystem) {
+				update.Registries = appendMissing(update.Registries, name)
+			}
+		}
+	}
+	return notes
+}
+
+// appliesTo returns if a package rule applies to the updates of a package ecosystem.
+func (rule RenovatePackageRule) appliesTo(ecosystem string) bool {
+	if len(rule.MatchManagers) == 0 {
+		return true
+	}
+	for _, manager := range rule.MatchManagers {
+		if renovateManagers[manager] == ecosystem {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTo adds a package rule to an update: disabled dependencies are ignored, others grouped if the rule has a group.
+// A schedule of a rule without package matchers becomes the schedule of the update.
+func (rule RenovatePackageRule) applyTo(update *Update) {
+	patterns, unsupported := rule.patterns()
+	if len(patterns) == 0 && len(unsupported) > 0 {
+		// a rule for unsupported patterns must not apply to all dependencies
+		return
+	}
+	updateTypes := make([]string, 0)
+	for _, updateType := range rule.MatchUpdateTypes {
+		if util.Contains(renovateUpdateTypes, updateType) {
+			updateTypes = append(updateTypes, updateType)
+		}
+	}
+	switch {
+	case rule.Enabled != nil && !*rule.Enabled:
+		if len(patterns) == 0 && len(updateTypes) == 0 {
+			// disabling the whole ecosystem is left to the notes
+			return
+		}
+		if len(patterns) == 0 {
+			patterns = []string{"*"}
+		}
+		ignoredTypes := make([]string, 0)
+		for _, updateType := range updateTypes {
+			ignoredTypes = append(ignoredTypes, "version-update:semver-"+updateType)
+		}
+		for _, pattern := range patterns {
+			update.Ignore = appendIgnore(update.Ignore, Ignore{DependencyName: pattern, UpdateTypes: ignoredTypes})
+		}
+	case rule.GroupName != "":
+		if len(patterns) == 0 {
+			patterns = []string{"*"}
+		}
+		group := Group{Patterns: patterns, UpdateTypes: updateTypes}
+		if len(rule.MatchDepTypes) == 1 {
+			group.DependencyType = renovateDepTypes[rule.MatchDepTypes[0]]
+		}
+		if update.Groups == nil {
+			update.Groups = map[string]Group{}
+		}
+		update.Groups[groupKey(rule.GroupName)] = group
+	case len(patterns) == 0 && len(rule.MatchManagers) > 0:
+		if schedule, ok := renovateSchedule(rule.Schedule, update.Schedule.Timezone); ok && schedule.Interval != "" {
+			update.Schedule = schedule
+		}
+	}
+}
+
+// notes returns the parts of a package rule which can't be migrated.
+func (rule RenovatePackageRule) notes(index int) []string {
+	notes := make([]string, 0)
+	name := fmt.Sprintf("packageRules[%v]", index)
+	if rule.GroupName != "" {
+		name += fmt.Sprintf(" (%v)", rule.GroupName)
+	}
+	patterns, unsupported := rule.patterns()
+	if len(unsupported) > 0 {
+		notes = append(notes, fmt.Sprintf("%v: the package patterns %v are not supported by dependabot.", name, strings.Join(unsupported, ", ")))
+	}
+	for _, manager := range rule.MatchManagers {
+		if renovateManagers[manager] == "" {
+			notes = append(notes, fmt.Sprintf("%v: the manager %v has no dependabot package ecosystem.", name, manager))
+		}
+	}
+	for _, updateType := range rule.MatchUpdateTypes {
+		if !util.Contains(renovateUpdateTypes, updateType) {
+			notes = append(notes, fmt.Sprintf("%v: the update type %v is not supported by dependabot.", name, updateType))
+		}
+	}
+	if len(rule.MatchDepTypes) > 1 || len(rule.MatchDepTypes) == 1 && renovateDepTypes[rule.MatchDepTypes[0]] == "" {
+		notes = append(notes, fmt.Sprintf("%v: the dependency types %v are not supported by dependabot.", name, strings.Join(rule.MatchDepTypes, ", ")))
+	}
+	if rule.Enabled != nil && !*rule.Enabled && len(patterns) == 0 && len(unsupported) == 0 && len(rule.MatchUpdateTypes) == 0 {
+		notes = append(notes, fmt.Sprintf("%v: the managers %v are disabled, their updates are kept.", name, strings.Join(rule.MatchManagers, ", ")))
+	}
+	if len(rule.Schedule) > 0 {
+		if _, ok := renovateSchedule(rule.Schedule, ""); !ok || len(patterns) > 0 || len(rule.MatchManagers) == 0 {
+			notes = append(notes, fmt.Sprintf("%v: the schedule %q is not migrated.", name, strings.Join(rule.Schedule, ", ")))
+		}
+	}
+	return notes
+}
+
+// patterns returns the package names and patterns of a rule in dependabot syntax, and the ones which can't be
+// converted, like complex regular expressions.
+func (rule RenovatePackageRule) patterns() ([]string, []string) {
+	patterns := make([]string, 0)
+	unsupported := make([]string, 0)
+	add := func(original string, regex string) {
+		if pattern, ok := regexToPattern(regex); ok {
+			patterns = appendMissing(patterns, pattern)
+		} else {
+			unsupported = append(unsupported, original)
+		}
+	}
+	for _, name := range rule.MatchPackageNames {
+		if len(name) > 2 && strings.HasPrefix(name, "/") && strings.HasSuffix(name, "/") {
+			add(name, name[1:len(name)-1])
+		} else {
+			patterns = appendMissing(patterns, strings.ReplaceAll(name, "**", "*"))
+		}
+	}
+	for _, pattern := range rule.MatchPackagePatterns {
+		add(pattern, pattern)
+	}
+	for _, prefix := range rule.MatchPackagePrefixes {
+		patterns = appendMissing(patterns, prefix+"*")
+	}
+	return patterns, unsupported
+}
+
+// regexToPattern converts a simple regular expression to a dependabot pattern, with * as wildcard.
+func regexToPattern(regex string) (string, bool) {
+	if !literalPattern.MatchString(regex) {
+		return "", false
+	}
+	pattern := strings.ReplaceAll(regex, ".*", "*")
+	if !strings.HasPrefix(pattern, "^") {
+		pattern = "*" + pattern
+	}
+	if !strings.HasSuffix(pattern, "$") {
+		pattern += "*"
+	}
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	pattern = strings.ReplaceAll(pattern, `\`, "")
+	return strings.ReplaceAll(pattern, "**", "*"), true
+}
+
+// renovateSchedule converts a renovate schedule, like "before 5am on monday" or "every weekend", to a dependabot one.
+// An empty schedule is returned for no schedule, and false if the schedule can't be converted.
+func renovateSchedule(schedule []string, timezone string) (Schedule, bool) {
+	if len(schedule) == 0 || len(schedule) == 1 && schedule[0] == "at any time" {
+		return Schedule{}, true
+	}
+	if len(schedule) > 1 {
+		return Schedule{}, false
+	}
+	text := strings.ToLower(schedule[0])
+	result := Schedule{Interval: "daily", Timezone: timezone}
+	switch {
+	case strings.Contains(text, "month"):
+		result.Interval = "monthly"
+	case strings.Contains(text, "weekend"):
+		result.Interval, result.Day = "weekly", "saturday"
+	case strings.Contains(text, "weekday"):
+		result.Interval = "daily"
+	default:
+		for _, day := range weekdays {
+			if strings.Contains(text, day) {
+				if result.Day != "" {
+					// several days can't be expressed
+					return Schedule{}, false
+				}
+				result.Interval, result.Day = "weekly", day
+			}
+		}
+	}
+	if match := renovateTimePattern.FindStringSubmatch(text); match != nil {
+		hour, minute := 0, 0
+		fmt.Sscan(match[2], &hour)
+		fmt.Sscan(match[3], &minute)
+		if match[4] == "pm" && hour < 12 {
+			hour += 12
+		} else if match[4] == "am" && hour == 12 {
+			hour = 0
+		}
+		if match[1] == "before" {
+			// one hour before, to start in the window
+			hour = (hour + 23) % 24
+		}
+		result.Time = fmt.Sprintf("%02d:%02d", hour, minute)
+	}
+	return result, true
+}
+
+// registries returns the registries of the host rules, keyed by name, and notes on the secrets to be created.
+func (renovateConfig *RenovateConfig) registries() (map[string]Registry, []string) {
+	registries := map[string]Registry{}
+	notes := make([]string, 0)
+	for _, rule := range renovateConfig.HostRules {
+		hostType, found := renovateHostTypes[rule.HostType]
+		if !found || rule.MatchHost == "" {
+			notes = append(notes, fmt.Sprintf("The host rule for %q of type %q is not migrated.", rule.MatchHost, rule.HostType))
+			continue
+		}
+		url := rule.MatchHost
+		if !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+		host := strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+		name := rule.HostType + "-" + strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(host), "-"), "-")
+		secret := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		registry := Registry{Type: hostType.registryType, URL: url, Username: rule.Username}
+		if rule.Password != "" {
+			registry.Password = "${{secrets." + secret + "_PASSWORD}}"
+			notes = append(notes, fmt.Sprintf("The registry %v needs the dependabot secret %v_PASSWORD.", name, secret))
+		}
+		if rule.Token != "" {
+			registry.Token = "${{secrets." + secret + "_TOKEN}}"
+			notes = append(notes, fmt.Sprintf("The registry %v needs the dependabot secret %v_TOKEN.", name, secret))
+		}
+		registries[name] = registry
+	}
+	return registries, notes
+}
+
+// registryEcosystems returns the package ecosystems using the registries of a registry type.
+func registryEcosystems(registryType string) []string {
+	for _, hostType := range renovateHostTypes {
+		if hostType.registryType == registryType {
+			return hostType.ecosystems
+		}
+	}
+	return nil
+}
+
+// appendIgnore adds an ignore definition, if not defined for the dependency already.
+func appendIgnore(ignores []Ignore, ignore Ignore) []Ignore {
+	for _, existing := range ignores {
+		if existing.DependencyName == ignore.DependencyName {
+			return ignores
+		}
+	}
+	return append(ignores, ignore)
+}
+
+// groupKey returns the key of a group from a renovate group name, e.g. "aws-sdk" for "AWS SDK".
+func groupKey(name string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// nonAlphanumeric matches the characters which are not allowed in registry names and group keys.
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// appendMissing adds the values not contained in a list yet.
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if !util.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// FindRenovateConfig returns the renovate config of a file list, the first one in lookup order, or "" if there's none.
+func FindRenovateConfig(fileList []string) string {
+	for _, file := range RenovateConfigFiles {
+		if util.Contains(fileList, file) {
+			return file
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRenovateConfig(t *testing.T) {
+	content := []byte(`{
+  // renovate config of the app
+  extends: ['config:recommended'],
+  schedule: "before 5am on monday",
+  "labels": ["deps", 'it\'s "renovate"'],
+  packageRules: [
+    /* grouped */
+    {matchPackagePatterns: ["^@acme/"], groupName: "Acme Packages",},
+  ],
+}`)
+	got, err := ParseRenovateConfig(content)
+	if err != nil {
+		t.Fatalf("ParseRenovateConfig() failed; unexpected error %v", err)
+	}
+	expected := &RenovateConfig{
+		Extends:      renovateStrings{"config:recommended"},
+		Schedule:     renovateStrings{"before 5am on monday"},
+		Labels:       []string{"deps", `it's "renovate"`},
+		PackageRules: []RenovatePackageRule{{MatchPackagePatterns: []string{"^@acme/"}, GroupName: "Acme Packages"}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseRenovateConfig() failed; expected %+v, got %+v", expected, got)
+	}
+	if _, err := ParseRenovateConfig([]byte(`{"schedule": `)); err == nil {
+		t.Errorf("ParseRenovateConfig() failed; expected error for invalid config")
+	}
+}
+
+func TestRenovateSchedule(t *testing.T) {
+	for _, tt := range []struct {
+		schedule []string
+		expected Schedule
+		ok       bool
+	}{
+		{nil, Schedule{}, true},
+		{[]string{"before 5am on monday"}, Schedule{Interval: "weekly", Day: "monday", Time: "04:00", Timezone: "Europe/Berlin"}, true},
+		{[]string{"every weekend"}, Schedule{Interval: "weekly", Day: "saturday", Timezone: "Europe/Berlin"}, true},
+		{[]string{"after 10pm every weekday"}, Schedule{Interval: "daily", Time: "22:00", Timezone: "Europe/Berlin"}, true},
+		{[]string{"on the first day of the month"}, Schedule{Interval: "monthly", Timezone: "Europe/Berlin"}, true},
+		{[]string{"on monday and thursday"}, Schedule{}, false},
+		{[]string{"before 5am", "after 10pm"}, Schedule{}, false},
+	} {
+		got, ok := renovateSchedule(tt.schedule, "Europe/Berlin")
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("renovateSchedule() failed for %v; expected %+v, %v, got %+v, %v", tt.schedule, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestRenovateApplyTo(t *testing.T) {
+	disabled := false
+	renovateConfig := &RenovateConfig{
+		Schedule:          renovateStrings{"every weekend"},
+		Labels:            []string{"dependencies"},
+		PrConcurrentLimit: 3,
+		IgnoreDeps:        []string{"left-pad"},
+		PackageRules: []RenovatePackageRule{
+			{MatchManagers: []string{"npm"}, MatchPackagePatterns: []string{"^@acme/"}, GroupName: "Acme Packages", MatchUpdateTypes: []string{"minor", "patch"}},
+			{MatchPackageNames: []string{"react"}, MatchUpdateTypes: []string{"major"}, Enabled: &disabled},
+			{MatchPackagePatterns: []string{"^(foo|bar)$"}, GroupName: "foobar"},
+		},
+		HostRules: []RenovateHostRule{{HostType: "npm", MatchHost: "npm.acme.com", Username: "ci", Password: "encrypted"}},
+	}
+	dependabotConfig := &DependabotConfig{Version: 2, Updates: []Update{
+		{PackageEcosystem: "npm", Directory: "/"},
+		{PackageEcosystem: "docker", Directory: "/", Labels: []string{"docker"}},
+	}}
+	notes := renovateConfig.ApplyTo(dependabotConfig)
+	schedule := Schedule{Interval: "weekly", Day: "saturday"}
+	expected := &DependabotConfig{
+		Version: 2,
+		Registries: map[string]Registry{
+			"npm-npm-acme-com": {Type: "npm-registry", URL: "https://npm.acme.com", Username: "ci", Password: "${{secrets.NPM_NPM_ACME_COM_PASSWORD}}"},
+		},
+		Updates: []Update{
+			{
+				PackageEcosystem: "npm", Directory: "/", Schedule: schedule, Labels: []string{"dependencies"}, OpenPullRequestsLimit: 3,
+				Ignore: []Ignore{
+					{DependencyName: "left-pad"},
+					{DependencyName: "react", UpdateTypes: []string{"version-update:semver-major"}},
+				},
+				Groups:     map[string]Group{"acme-packages": {Patterns: []string{"@acme/*"}, UpdateTypes: []string{"minor", "patch"}}},
+				Registries: []string{"npm-npm-acme-com"},
+			},
+			{
+				PackageEcosystem: "docker", Directory: "/", Schedule: schedule, Labels: []string{"docker", "dependencies"}, OpenPullRequestsLimit: 3,
+				Ignore: []Ignore{
+					{DependencyName: "left-pad"},
+					{DependencyName: "react", UpdateTypes: []string{"version-update:semver-major"}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(dependabotConfig, expected) {
+		t.Errorf("ApplyTo() failed; expected %+v, got %+v", expected, dependabotConfig)
+	}
+	expectedNotes := []string{
+		"packageRules[2] (foobar): the package patterns ^(foo|bar)$ are not supported by dependabot.",
+		"The registry npm-npm-acme-com needs the dependabot secret NPM_NPM_ACME_COM_PASSWORD.",
+	}
+	if !reflect.DeepEqual(notes, expectedNotes) {
+		t.Errorf("ApplyTo() failed; expected notes %v, got %v", expectedNotes, notes)
+	}
+}
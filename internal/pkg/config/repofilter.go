@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// repoVisibilities holds the visibilities of repos, as reported by GitHub.
+var repoVisibilities = []string{"public", "private", "internal"}
+
+// RepoFilter holds the conditions for processing a repo in remote mode, evaluated on the repos listed by repo,
+// repoFile or repoQuery. Repos not meeting all conditions set are skipped.
+type RepoFilter struct {
+	// Topics requires any of the topics, SkipTopics skips repos with any of them.
+	Topics     []string `yaml:"topics"`
+	SkipTopics []string `yaml:"skip-topics"`
+	// Languages holds the primary languages of the repos, as detected by GitHub, compared case-insensitively.
+	Languages    []string `yaml:"languages"`
+	Visibilities []string `yaml:"visibilities"`
+	// MaxPushAge skips repos not pushed to for more days.
+	MaxPushAge int `yaml:"max-push-age"`
+}
+
+// SkipReason returns why a repo doesn't meet the conditions of the filter, or "" if it's processed.
+func (filter RepoFilter) SkipReason(repo RepoFacts, now time.Time) string {
+	if len(filter.Topics) > 0 && !containsAny(repo.Topics, filter.Topics) {
+		return fmt.Sprintf("none of the topics %v", strings.Join(filter.Topics, ", "))
+	}
+	for _, topic := range filter.SkipTopics {
+		if util.Contains(repo.Topics, topic) {
+			return "topic " + topic
+		}
+	}
+	if len(filter.Languages) > 0 && !containsFold(filter.Languages, repo.Language) {
+		return fmt.Sprintf("language %v", orNone(repo.Language))
+	}
+	if len(filter.Visibilities) > 0 && !util.Contains(filter.Visibilities, repo.Visibility) {
+		return "visibility " + repo.Visibility
+	}
+	if filter.MaxPushAge > 0 && !repo.PushedAt.IsZero() && now.Sub(repo.PushedAt) > time.Duration(filter.MaxPushAge)*24*time.Hour {
+		return fmt.Sprintf("no push for more than %v days", filter.MaxPushAge)
+	}
+	return ""
+}
+
+// containsAny returns if any of the values is in the list.
+func containsAny(list []string, values []string) bool {
+	for _, value := range values {
+		if util.Contains(list, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// orNone returns the value, or "none" if empty.
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// validateRepoFilter returns the problems of the repo filter.
+func (config *ToolConfig) validateRepoFilter() []string {
+	problems := make([]string, 0)
+	for _, visibility := range config.RepoFilter.Visibilities {
+		if !util.Contains(repoVisibilities, visibility) {
+			problems = append(problems, fmt.Sprintf("repo-filter.visibilities: %v is none of %v", visibility, repoVisibilities))
+		}
+	}
+	if config.RepoFilter.MaxPushAge < 0 {
+		problems = append(problems, "repo-filter.max-push-age: must not be negative")
+	}
+	return problems
+}
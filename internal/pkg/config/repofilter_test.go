@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepoFilterSkipReason(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := RepoFacts{
+		Name: "service", Topics: []string{"backend", "payments"}, Language: "Go", Visibility: "private",
+		PushedAt: now.AddDate(0, 0, -100),
+	}
+	for _, tt := range []struct {
+		filter   RepoFilter
+		expected string
+	}{
+		{RepoFilter{}, ""},
+		{RepoFilter{Topics: []string{"frontend", "backend"}}, ""},
+		{RepoFilter{Topics: []string{"frontend"}}, "none of the topics frontend"},
+		{RepoFilter{SkipTopics: []string{"sandbox", "payments"}}, "topic payments"},
+		{RepoFilter{Languages: []string{"go", "java"}}, ""},
+		{RepoFilter{Languages: []string{"Java"}}, "language Go"},
+		{RepoFilter{Visibilities: []string{"private", "internal"}}, ""},
+		{RepoFilter{Visibilities: []string{"public"}}, "visibility private"},
+		{RepoFilter{MaxPushAge: 365}, ""},
+		{RepoFilter{MaxPushAge: 30}, "no push for more than 30 days"},
+	} {
+		if got := tt.filter.SkipReason(repo, now); got != tt.expected {
+			t.Errorf("SkipReason(%+v) failed; expected %q got %q", tt.filter, tt.expected, got)
+		}
+	}
+	if got := (RepoFilter{Languages: []string{"Go"}}).SkipReason(RepoFacts{}, now); got != "language none" {
+		t.Errorf("SkipReason() failed; expected %q got %q", "language none", got)
+	}
+}
+
+func TestValidateRepoFilter(t *testing.T) {
+	config := ToolConfig{RepoFilter: RepoFilter{Visibilities: []string{"private", "secret"}, MaxPushAge: -1}}
+	expected := []string{
+		"repo-filter.visibilities: secret is none of [public private internal]",
+		"repo-filter.max-push-age: must not be negative",
+	}
+	if got := config.validateRepoFilter(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("validateRepoFilter() failed; expected %v got %v", expected, got)
+	}
+}
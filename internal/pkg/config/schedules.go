@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// SchedulePolicy sets the schedule of new updates of matching repos and ecosystems, instead of the one of the update
+// defaults and overrides. All conditions set must be met.
+type SchedulePolicy struct {
+	// Repos holds repository names, or regular expressions matching the whole repository name.
+	Repos      []string `yaml:"repos"`
+	Ecosystems []string `yaml:"ecosystems"`
+	// Properties holds regular expressions matching the full values of custom properties of the repo.
+	Properties map[string]string `yaml:"properties"`
+	// MinSize and MaxSize are in KB, as reported by GitHub.
+	MinSize  int      `yaml:"min-size"`
+	MaxSize  int      `yaml:"max-size"`
+	Schedule Schedule `yaml:"schedule"`
+}
+
+// RepoFacts holds the attributes of a repo the schedule policies and the repo filter are evaluated on.
+type RepoFacts struct {
+	Name       string
+	Size       int
+	Properties map[string]string
+	Topics     []string
+	Language   string
+	Visibility string
+	PushedAt   time.Time
+}
+
+// UsesRepoProperties returns if any schedule policy depends on custom properties, which need to be fetched then.
+func (config *ToolConfig) UsesRepoProperties() bool {
+	for _, policy := range config.SchedulePolicies {
+		if len(policy.Properties) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleFor returns the schedule of the first policy matching a repo and an ecosystem, if any.
+func (config *ToolConfig) scheduleFor(manifestType string) (Schedule, bool) {
+	for _, policy := range config.SchedulePolicies {
+		if policy.matches(config.RepoFacts, manifestType) {
+			return policy.Schedule, true
+		}
+	}
+	return Schedule{}, false
+}
+
+// matches returns if a repo and an ecosystem meet the conditions of a policy.
+func (policy SchedulePolicy) matches(repo RepoFacts, manifestType string) bool {
+	if len(policy.Ecosystems) > 0 && !util.Contains(policy.Ecosystems, manifestType) {
+		return false
+	}
+	if len(policy.Repos) > 0 && !matchesAnyRepo(policy.Repos, repo.Name) {
+		return false
+	}
+	if policy.MinSize > 0 && repo.Size < policy.MinSize {
+		return false
+	}
+	if policy.MaxSize > 0 && repo.Size > policy.MaxSize {
+		return false
+	}
+	for name, pattern := range policy.Properties {
+		value, found := repo.Properties[name]
+		re := util.CompileRePattern("^(" + pattern + ")$")
+		if !found || re == nil || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAnyRepo returns if a repo name equals or fully matches any of the patterns.
+func matchesAnyRepo(patterns []string, repo string) bool {
+	for _, pattern := range patterns {
+		if pattern == repo {
+			return true
+		}
+		if re := util.CompileRePattern("^(" + pattern + ")$"); re != nil && re.MatchString(repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSchedulePolicies returns the problems of the schedule policies.
+func (config *ToolConfig) validateSchedulePolicies() []string {
+	problems := make([]string, 0)
+	for i, policy := range config.SchedulePolicies {
+		path := fmt.Sprintf("schedule-policies[%v]", i)
+		if policy.Schedule.Interval == "" {
+			problems = append(problems, path+".schedule.interval: not set")
+		}
+		for _, ecosystem := range policy.Ecosystems {
+			if !util.Contains(supportedEcosystems, ecosystem) {
+				problems = append(problems, fmt.Sprintf("%v.ecosystems: %v is not supported by dependabot", path, ecosystem))
+			}
+		}
+		for _, pattern := range policy.Repos {
+			if _, err := regexp.Compile("^(" + pattern + ")$"); err != nil {
+				problems = append(problems, fmt.Sprintf("%v.repos: invalid pattern %v: %v", path, pattern, err))
+			}
+		}
+		for _, name := range util.SortedKeys(policy.Properties) {
+			if _, err := regexp.Compile("^(" + policy.Properties[name] + ")$"); err != nil {
+				problems = append(problems, fmt.Sprintf("%v.properties.%v: invalid pattern: %v", path, name, err))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+func TestSchedulePolicies(t *testing.T) {
+	daily := Schedule{Interval: "daily"}
+	monthly := Schedule{Interval: "monthly"}
+	weekly := Schedule{Interval: "weekly", Day: "monday"}
+	policies := []SchedulePolicy{
+		{Properties: map[string]string{"tier": "critical"}, Schedule: daily},
+		{Ecosystems: []string{"docker"}, Repos: []string{"legacy-.*"}, Schedule: monthly},
+		{MinSize: 100000, Schedule: monthly},
+	}
+	for _, tt := range []struct {
+		repo         RepoFacts
+		manifestType string
+		expected     Schedule
+	}{
+		{RepoFacts{Name: "payments", Properties: map[string]string{"tier": "critical"}}, "npm", daily},
+		{RepoFacts{Name: "legacy-app", Properties: map[string]string{"tier": "low"}}, "docker", monthly},
+		{RepoFacts{Name: "legacy-app"}, "npm", weekly},
+		{RepoFacts{Name: "monolith", Size: 250000}, "npm", monthly},
+		{RepoFacts{Name: "service", Size: 2000}, "gomod", weekly},
+	} {
+		toolConfig := ToolConfig{UpdateDefaults: UpdateDefaults{Schedule: weekly}, SchedulePolicies: policies, RepoFacts: tt.repo}
+		update := createUpdateEntry(tt.manifestType, "/", toolConfig)
+		if !reflect.DeepEqual(update.Schedule, tt.expected) {
+			t.Errorf("createUpdateEntry(%v, %v) failed; expected schedule %v got %v", tt.repo.Name, tt.manifestType, tt.expected, update.Schedule)
+		}
+	}
+	if !(&ToolConfig{SchedulePolicies: policies}).UsesRepoProperties() || (&ToolConfig{SchedulePolicies: policies[1:]}).UsesRepoProperties() {
+		t.Errorf("UsesRepoProperties() failed")
+	}
+	problems := (&ToolConfig{SchedulePolicies: []SchedulePolicy{{Ecosystems: []string{"bazel"}, Repos: []string{"("}}}}).Validate()
+	for _, expected := range []string{
+		"schedule-policies[0].schedule.interval: not set",
+		"schedule-policies[0].ecosystems: bazel is not supported by dependabot",
+	} {
+		if !util.Contains(problems, expected) {
+			t.Errorf("Validate() failed; expected problem %v, got %v", expected, problems)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SameOrgSubmodules returns the names of the repos of an org on a GitHub host, referenced by the .gitmodules file of a repo.
+// Relative submodule URLs (e.g. ../shared-lib.git) are resolved against the URL of the repo. The content is nil if the
+// repo has no .gitmodules file.
+func SameOrgSubmodules(gitmodules []byte, host string, org string, repo string) []string {
+	repos := make([]string, 0)
+	if gitmodules == nil {
+		return repos
+	}
+	for _, line := range strings.Split(string(gitmodules), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		submodule := submoduleRepo(strings.TrimSpace(value), host, org, repo)
+		if submodule != "" && !containsFold(repos, submodule) {
+			repos = append(repos, submodule)
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// submoduleRepo returns the repo name of a submodule URL, if it's a repo of the org on the host.
+func submoduleRepo(submoduleURL string, host string, org string, repo string) string {
+	var repoHost, repoPath string
+	switch {
+	case strings.HasPrefix(submoduleURL, "../"):
+		repoHost, repoPath = host, path.Join(org, repo, submoduleURL)
+	case strings.Contains(submoduleURL, "://"):
+		parsed, err := url.Parse(submoduleURL)
+		if err != nil {
+			return ""
+		}
+		repoHost, repoPath = parsed.Hostname(), parsed.Path
+	default:
+		// scp-like syntax, e.g. git@github.com:acme/shared-lib.git
+		userHost, scpPath, found := strings.Cut(submoduleURL, ":")
+		if !found {
+			return ""
+		}
+		_, repoHost, _ = strings.Cut(userHost, "@")
+		if repoHost == "" {
+			repoHost = userHost
+		}
+		repoPath = scpPath
+	}
+	repoOrg, name, found := strings.Cut(strings.Trim(repoPath, "/"), "/")
+	if !found || strings.Contains(name, "/") || !strings.EqualFold(repoHost, host) || !strings.EqualFold(repoOrg, org) {
+		return ""
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// containsFold returns if a list contains a string, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSameOrgSubmodules(t *testing.T) {
+	gitmodules := `[submodule "shared"]
+	path = vendor/shared
+	url = https://github.com/acme/shared-lib.git
+[submodule "proto"]
+	path = proto
+	url = git@github.com:ACME/proto
+[submodule "tools"]
+	path = tools
+	url = ../tools.git
+[submodule "ssh"]
+	path = ssh
+	url = ssh://git@github.com/acme/ssh-lib.git
+[submodule "other-org"]
+	path = other
+	url = https://github.com/other/lib.git
+[submodule "other-host"]
+	path = gitlab
+	url = https://gitlab.com/acme/lib.git
+[submodule "relative-other-org"]
+	path = relative
+	url = ../../other/lib.git
+[submodule "duplicate"]
+	path = shared2
+	url = https://github.com/acme/Shared-Lib
+`
+	expected := []string{"proto", "shared-lib", "ssh-lib", "tools"}
+	got := SameOrgSubmodules([]byte(gitmodules), "github.com", "acme", "monorepo")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("SameOrgSubmodules() failed; expected %v got %v", expected, got)
+	}
+	// repos without .gitmodules
+	if got := SameOrgSubmodules(nil, "github.com", "acme", "monorepo"); !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("SameOrgSubmodules() failed; expected no repos without .gitmodules, got %v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import "sort"
+
+// Checks recorded in the decision trace.
+const (
+	TracePattern    = "pattern"
+	TracePathFilter = "path-filter"
+	TraceWorkspace  = "workspace"
+	TraceEcosystem  = "ecosystem"
+	TraceCoverage   = "coverage"
+	TraceRegistry   = "registry"
+)
+
+// TraceEntry holds a decision taken for a manifest, for auditing why the config has been changed or not.
+type TraceEntry struct {
+	Manifest string `json:"manifest"`
+	Type     string `json:"type"`
+	Check    string `json:"check"`
+	Subject  string `json:"subject,omitempty"`
+	Result   string `json:"result"`
+}
+
+// addTrace adds an entry to the decision trace, if tracing is enabled.
+func (changeInfo *ChangeInfo) addTrace(enabled bool, entry TraceEntry) {
+	if enabled {
+		changeInfo.Trace = append(changeInfo.Trace, entry)
+	}
+}
+
+// traceManifests adds the pattern each manifest has been detected by to the decision trace.
+func (changeInfo *ChangeInfo) traceManifests(manifests map[string]string, manifestPatterns map[string]string) {
+	for _, manifestFile := range sortedManifestFiles(manifests) {
+		manifestType := manifests[manifestFile]
+		changeInfo.addTrace(true, TraceEntry{
+			Manifest: manifestFile, Type: manifestType, Check: TracePattern,
+			Subject: manifestPatterns[manifestType], Result: "matched",
+		})
+	}
+}
+
+// traceDropped adds the manifests which are not part of the remaining manifests anymore to the decision trace.
+func (changeInfo *ChangeInfo) traceDropped(manifests map[string]string, remaining map[string]string, check string, subject string, result string) {
+	for _, manifestFile := range sortedManifestFiles(manifests) {
+		if _, found := remaining[manifestFile]; !found {
+			changeInfo.addTrace(true, TraceEntry{Manifest: manifestFile, Type: manifests[manifestFile], Check: check, Subject: subject, Result: result})
+		}
+	}
+}
+
+// sortedManifestFiles returns the manifest files, sorted by name.
+func sortedManifestFiles(manifests map[string]string) []string {
+	manifestFiles := make([]string, 0, len(manifests))
+	for manifestFile := range manifests {
+		manifestFiles = append(manifestFiles, manifestFile)
+	}
+	sort.Strings(manifestFiles)
+	return manifestFiles
+}
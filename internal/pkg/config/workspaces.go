@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// workspaceRoot holds an npm workspace root and the patterns of its member packages.
+type workspaceRoot struct {
+	manifestFile string
+	directory    string
+	patterns     []string
+}
+
+// CollapseWorkspaces returns the manifests, with the package.json files of npm, yarn and pnpm workspace members
+// replaced by the one of their workspace root. Dependabot updates workspace members from the root.
+func CollapseWorkspaces(manifests map[string]string, loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters) map[string]string {
+	roots := findWorkspaceRoots(manifests, loadFileFn, loadFileParams)
+	collapsed := make(map[string]string, len(manifests))
+	for manifestFile, manifestType := range manifests {
+		if manifestType == "npm" {
+			if root := memberOfWorkspace(manifestFile, roots); root != nil {
+				collapsed[root.manifestFile] = manifestType
+				continue
+			}
+		}
+		collapsed[manifestFile] = manifestType
+	}
+	return collapsed
+}
+
+// findWorkspaceRoots returns the npm manifests defining workspaces, either by the "workspaces" property
+// of package.json, or by a pnpm-workspace.yaml next to a pnpm lock file.
+func findWorkspaceRoots(manifests map[string]string, loadFileFn LoadFileContent, loadFileParams LoadFileContentParameters) []workspaceRoot {
+	manifestFiles := make([]string, 0, len(manifests))
+	for manifestFile, manifestType := range manifests {
+		if manifestType == "npm" && filepath.Base(manifestFile) == "package.json" {
+			manifestFiles = append(manifestFiles, manifestFile)
+		}
+	}
+	sort.Strings(manifestFiles)
+
+	roots := make([]workspaceRoot, 0)
+	for _, manifestFile := range manifestFiles {
+		manifestPath := GetManifestPath(manifestFile, "npm")
+		patterns := workspacePatterns(loadFileFn(manifestFile, loadFileParams))
+		lockFile := strings.TrimPrefix(filepath.Join(manifestPath, "pnpm-lock"), "/")
+		if manifests[lockFile+".yaml"] != "" || manifests[lockFile+".yml"] != "" {
+			patterns = append(patterns, pnpmWorkspacePatterns(loadFileFn(filepath.Join(manifestPath, "pnpm-workspace.yaml"), loadFileParams))...)
+		}
+		if len(patterns) > 0 {
+			roots = append(roots, workspaceRoot{manifestFile: manifestFile, directory: manifestPath, patterns: patterns})
+		}
+	}
+	return roots
+}
+
+// workspacePatterns returns the member patterns of a package.json, given either as list or as object (yarn).
+func workspacePatterns(packageJSON string) []string {
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal([]byte(packageJSON), &manifest); err != nil || manifest.Workspaces == nil {
+		return nil
+	}
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return patterns
+	}
+	var workspaces struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &workspaces); err == nil {
+		return workspaces.Packages
+	}
+	return nil
+}
+
+// pnpmWorkspacePatterns returns the member patterns of a pnpm-workspace.yaml.
+func pnpmWorkspacePatterns(pnpmWorkspace string) []string {
+	var workspace struct {
+		Packages []string `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal([]byte(pnpmWorkspace), &workspace); err != nil {
+		return nil
+	}
+	return workspace.Packages
+}
+
+// memberOfWorkspace returns the closest workspace root a manifest is a member of, or nil.
+func memberOfWorkspace(manifestFile string, roots []workspaceRoot) *workspaceRoot {
+	manifestPath := GetManifestPath(manifestFile, "npm")
+	var member *workspaceRoot
+	for i, root := range roots {
+		if root.directory == manifestPath || !strings.HasPrefix(manifestPath, PathWithEndingSlash(root.directory)) {
+			continue
+		}
+		if member != nil && len(member.directory) > len(root.directory) {
+			continue
+		}
+		relativePath := strings.TrimPrefix(manifestPath, PathWithEndingSlash(root.directory))
+		included := false
+		for _, pattern := range root.patterns {
+			pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+			if strings.HasPrefix(pattern, "!") {
+				if util.MatchPathPattern(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "./"), relativePath) {
+					included = false
+					break
+				}
+			} else if util.MatchPathPattern(pattern, relativePath) {
+				included = true
+			}
+		}
+		if included {
+			member = &roots[i]
+		}
+	}
+	return member
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseWorkspaces(t *testing.T) {
+	loadFileFn := LoadFileContentFromMap(map[string]string{
+		"package.json":              `{"name": "root", "workspaces": ["packages/*", "!packages/legacy"]}`,
+		"web/package.json":          `{"name": "web", "workspaces": {"packages": ["apps/**"]}}`,
+		"pnpm/package.json":         `{"name": "pnpm"}`,
+		"/pnpm/pnpm-workspace.yaml": "packages:\n  - 'libs/*'\n",
+		"plain/package.json":        `{"name": "plain"}`,
+	})
+	manifests := map[string]string{
+		"package.json":                   "npm",
+		"packages/a/package.json":        "npm",
+		"packages/b/package.json":        "npm",
+		"packages/legacy/package.json":   "npm",
+		"tools/package.json":             "npm",
+		"web/package.json":               "npm",
+		"web/apps/site/package.json":     "npm",
+		"web/apps/site/sub/package.json": "npm",
+		"pnpm/package.json":              "npm",
+		"pnpm/pnpm-lock.yaml":            "npm",
+		"pnpm/libs/x/package.json":       "npm",
+		"plain/package.json":             "npm",
+		"plain/sub/package.json":         "npm",
+		"packages/a/Dockerfile":          "docker",
+	}
+	expected := map[string]string{
+		"package.json":                 "npm",
+		"packages/legacy/package.json": "npm",
+		"tools/package.json":           "npm",
+		"web/package.json":             "npm",
+		"pnpm/package.json":            "npm",
+		"pnpm/pnpm-lock.yaml":          "npm",
+		"plain/package.json":           "npm",
+		"plain/sub/package.json":       "npm",
+		"packages/a/Dockerfile":        "docker",
+	}
+	got := CollapseWorkspaces(manifests, loadFileFn, LoadFileContentParameters{})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("CollapseWorkspaces() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lineEdit replaces the lines from start to end (excluding, 0-based) of a document. Insertions have start == end.
+type lineEdit struct {
+	start int
+	end   int
+	lines []string
+}
+
+// yamlPatch holds the lines of the original document, and the edits turning it into the updated config.
+type yamlPatch struct {
+	lines []string
+	edits []lineEdit
+}
+
+// ToYamlMinimalDiff returns a YAML representation of a dependabot config as the original content, with only the lines
+// of the changed entries edited, for minimal diffs: updates keep their order, new ones are appended, and unchanged
+// values keep their formatting. If the changes can't be applied that way, e.g. for flow style collections or anchors,
+// the config is written like ToYamlWithComments.
+func (config *DependabotConfig) ToYamlMinimalDiff(original []byte, newUpdates []UpdateInfo, comment string) []byte {
+	full := config.ToYamlWithComments(original, newUpdates, comment)
+	if config.usesAnchors {
+		return full
+	}
+	patched, ok := config.patchYaml(original, newUpdates, comment)
+	if !ok {
+		return full
+	}
+	// the patched document must result in the very same config
+	check, err := ParseDependabotConfig(patched)
+	if err != nil || !bytes.Equal(check.ToYaml(), config.ToYaml()) {
+		return full
+	}
+	return patched
+}
+
+// patchYaml returns the original document, with the changes of the config applied to its lines.
+func (config *DependabotConfig) patchYaml(original []byte, newUpdates []UpdateInfo, comment string) ([]byte, bool) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(original, &document); err != nil || len(document.Content) == 0 {
+		return nil, false
+	}
+	var desired yaml.Node
+	if err := desired.Encode(config); err != nil {
+		return nil, false
+	}
+	if comment != "" {
+		annotateNewUpdates(&desired, &document, newUpdates, comment)
+	}
+	patch := &yamlPatch{lines: strings.Split(string(original), "\n")}
+	if !patch.mapping(document.Content[0], &desired) {
+		return nil, false
+	}
+	return patch.apply(), true
+}
+
+// apply returns the document with the edits applied, the last one first, so the line numbers stay valid.
+func (patch *yamlPatch) apply() []byte {
+	sort.SliceStable(patch.edits, func(i, j int) bool { return patch.edits[i].start > patch.edits[j].start })
+	lines := patch.lines
+	for _, edit := range patch.edits {
+		updated := make([]string, 0, len(lines)+len(edit.lines))
+		updated = append(updated, lines[:edit.start]...)
+		updated = append(updated, edit.lines...)
+		lines = append(updated, lines[edit.end:]...)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// value patches a node of the original document to the desired one.
+func (patch *yamlPatch) value(key *yaml.Node, orig *yaml.Node, want *yaml.Node) bool {
+	if equalNodes(orig, want) {
+		return true
+	}
+	switch {
+	case orig.Kind == yaml.ScalarNode && want.Kind == yaml.ScalarNode:
+		return patch.scalar(key, orig, want)
+	case orig.Kind == yaml.MappingNode && want.Kind == yaml.MappingNode:
+		return patch.mapping(orig, want)
+	case orig.Kind == yaml.SequenceNode && want.Kind == yaml.SequenceNode:
+		return patch.sequence(orig, want)
+	}
+	return false
+}
+
+// scalar replaces the value of a single-line scalar, keeping its quoting style and line comment.
+func (patch *yamlPatch) scalar(key *yaml.Node, orig *yaml.Node, want *yaml.Node) bool {
+	if key == nil || key.Line != orig.Line || strings.Contains(orig.Value, "\n") || strings.Contains(want.Value, "\n") ||
+		orig.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return false
+	}
+	replacement := *want
+	replacement.Style = orig.Style
+	rendered := strings.TrimSuffix(string(encodeYaml(&replacement)), "\n")
+	if strings.Contains(rendered, "\n") {
+		return false
+	}
+	line := patch.lines[orig.Line-1]
+	if orig.Column-1 > len(line) {
+		return false
+	}
+	text := line[:orig.Column-1] + rendered
+	if orig.LineComment != "" {
+		text += " " + orig.LineComment
+	}
+	patch.edits = append(patch.edits, lineEdit{start: orig.Line - 1, end: orig.Line, lines: []string{text}})
+	return true
+}
+
+// mapping patches the entries of a block mapping: changed values are patched, removed keys deleted, and new keys
+// appended after the last entry.
+func (patch *yamlPatch) mapping(orig *yaml.Node, want *yaml.Node) bool {
+	if orig.Style&yaml.FlowStyle != 0 || len(orig.Content) == 0 {
+		return false
+	}
+	wanted := map[string]bool{}
+	added := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(want.Content); i += 2 {
+		wanted[want.Content[i].Value] = true
+		origKey, origValue := mappingEntry(orig, want.Content[i].Value)
+		if origKey == nil {
+			added.Content = append(added.Content, want.Content[i], want.Content[i+1])
+		} else if !patch.value(origKey, origValue, want.Content[i+1]) {
+			return false
+		}
+	}
+	for i := 0; i+1 < len(orig.Content); i += 2 {
+		if !wanted[orig.Content[i].Value] && !patch.remove(orig.Content[i], orig.Content[i+1]) {
+			return false
+		}
+	}
+	if len(added.Content) > 0 {
+		indent := strings.Repeat(" ", orig.Content[0].Column-1)
+		return patch.insert(nodeEnd(orig), indent, added)
+	}
+	return true
+}
+
+// sequence patches the items of a block sequence: updates are matched by ecosystem, directory and target branch,
+// scalars by value, other items by index. Items not wanted anymore are deleted, new ones appended.
+func (patch *yamlPatch) sequence(orig *yaml.Node, want *yaml.Node) bool {
+	if orig.Style&yaml.FlowStyle != 0 || len(orig.Content) == 0 {
+		return false
+	}
+	matched := map[*yaml.Node]bool{}
+	added := &yaml.Node{Kind: yaml.SequenceNode}
+	for i, item := range want.Content {
+		match := matchingItem(orig, item, i)
+		if match == nil || matched[match] {
+			added.Content = append(added.Content, item)
+			continue
+		}
+		matched[match] = true
+		if !patch.value(nil, match, item) {
+			return false
+		}
+	}
+	for _, item := range orig.Content {
+		if !matched[item] && !patch.remove(nil, item) {
+			return false
+		}
+	}
+	if len(added.Content) > 0 {
+		first := orig.Content[0]
+		line := patch.lines[first.Line-1]
+		dash := strings.LastIndex(line[:min(first.Column-1, len(line))], "-")
+		if dash < 0 {
+			return false
+		}
+		return patch.insert(nodeEnd(orig), line[:dash], added)
+	}
+	return true
+}
+
+// remove deletes the lines of a mapping entry or sequence item, including its head comment.
+func (patch *yamlPatch) remove(key *yaml.Node, value *yaml.Node) bool {
+	first := value
+	if key != nil {
+		first = key
+	}
+	end := nodeEnd(value)
+	if end < 0 {
+		return false
+	}
+	start := first.Line - 1
+	if first.HeadComment != "" {
+		start -= strings.Count(first.HeadComment, "\n") + 1
+	}
+	for start < first.Line-1 && !strings.HasPrefix(strings.TrimSpace(patch.lines[start]), "#") {
+		start++
+	}
+	patch.edits = append(patch.edits, lineEdit{start: start, end: end})
+	return true
+}
+
+// insert adds the rendered node after a line, with each line indented.
+func (patch *yamlPatch) insert(after int, indent string, node *yaml.Node) bool {
+	if after < 0 {
+		return false
+	}
+	rendered := strings.Split(strings.TrimSuffix(string(encodeYaml(node)), "\n"), "\n")
+	for i, line := range rendered {
+		if line != "" {
+			rendered[i] = indent + line
+		}
+	}
+	patch.edits = append(patch.edits, lineEdit{start: after, end: after, lines: rendered})
+	return true
+}
+
+// nodeEnd returns the last line of a node and its children (1-based), or -1 if it can't be determined,
+// for multi-line scalars.
+func nodeEnd(node *yaml.Node) int {
+	if node.Kind == yaml.ScalarNode && (strings.Contains(node.Value, "\n") || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0) {
+		return -1
+	}
+	end := node.Line
+	for _, child := range node.Content {
+		childEnd := nodeEnd(child)
+		if childEnd < 0 {
+			return -1
+		}
+		end = max(end, childEnd)
+	}
+	return end
+}
+
+// equalNodes returns if two nodes hold the same values, regardless of formatting and comments.
+func equalNodes(a *yaml.Node, b *yaml.Node) bool {
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode {
+		return a.Value == b.Value && a.ShortTag() == b.ShortTag()
+	}
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestToYamlMinimalDiff(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		original string
+		expected string
+	}{
+		{"appended to the unsorted updates", `version: 2
+updates:
+# docker images
+- package-ecosystem: "docker"
+  directory: "/"
+  schedule:
+    interval: "daily" # every day
+- package-ecosystem: "composer"
+  directory: "/"
+  schedule: { interval: "weekly" }
+`, `version: 2
+updates:
+# docker images
+- package-ecosystem: "docker"
+  directory: "/"
+  schedule:
+    interval: "daily" # every day
+- package-ecosystem: "composer"
+  directory: "/"
+  schedule: { interval: "weekly" }
+- package-ecosystem: npm # managed by dependabutler
+  directory: /
+`},
+		{"rewritten for flow style updates", `version: 2
+updates: [{package-ecosystem: docker, directory: /}]
+`, `version: 2
+updates:
+  - package-ecosystem: docker
+    directory: /
+  - package-ecosystem: npm # managed by dependabutler
+    directory: /
+`},
+		{"updates added to a config without", `version: 2 # the version
+`, `version: 2 # the version
+updates:
+  - package-ecosystem: npm # managed by dependabutler
+    directory: /
+`},
+	} {
+		dependabotConfig, _ := ParseDependabotConfig([]byte(tt.original))
+		changeInfo := dependabotConfig.UpdateConfig(map[string]string{"package.json": "npm"}, ToolConfig{},
+			LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		got := string(dependabotConfig.ToYamlMinimalDiff([]byte(tt.original), changeInfo.NewUpdates, "managed by dependabutler"))
+		if got != tt.expected {
+			t.Errorf("ToYamlMinimalDiff() %v failed; expected\n%v\ngot\n%v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestToYamlMinimalDiffChangedValues(t *testing.T) {
+	original := `version: 2
+updates:
+  - package-ecosystem: "npm"
+    directory: "/"
+    schedule:
+      interval: 'monthly' # for now
+  - package-ecosystem: "pip"
+    directory: "/legacy"
+`
+	expected := `version: 2
+updates:
+  - package-ecosystem: "npm"
+    directory: "/"
+    schedule:
+      interval: 'daily' # for now
+`
+	dependabotConfig, _ := ParseDependabotConfig([]byte(original))
+	dependabotConfig.Updates[0].Schedule.Interval = "daily"
+	dependabotConfig.Updates = dependabotConfig.Updates[:1]
+	got := string(dependabotConfig.ToYamlMinimalDiff([]byte(original), nil, ""))
+	if got != expected {
+		t.Errorf("ToYamlMinimalDiff() failed; expected\n%v\ngot\n%v", expected, got)
+	}
+}
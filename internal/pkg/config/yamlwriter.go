@@ -0,0 +1,137 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToYamlWithComments returns a YAML representation of a dependabot config like ToYaml, keeping the comments of the
+// original config on the registries, updates and properties still present. The new updates are annotated with the
+// comment as line comment, if given.
+func (config *DependabotConfig) ToYamlWithComments(original []byte, newUpdates []UpdateInfo, comment string) []byte {
+	config.sortUpdates()
+	var node yaml.Node
+	if err := node.Encode(config); err != nil {
+		return config.ToYaml()
+	}
+	document := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}
+	var originalDocument yaml.Node
+	if len(original) > 0 && yaml.Unmarshal(original, &originalDocument) == nil && len(originalDocument.Content) > 0 {
+		copyComments(originalDocument.Content[0], &node)
+		document.HeadComment = originalDocument.HeadComment
+		document.FootComment = originalDocument.FootComment
+	}
+	if comment != "" {
+		annotateNewUpdates(&node, &originalDocument, newUpdates, comment)
+	}
+	return encodeYaml(&document)
+}
+
+// copyComments copies the comments of a node and its children to the matching node of the new document.
+// Mapping entries are matched by key, updates by ecosystem, directory and target branch, scalars in lists by value.
+func copyComments(from *yaml.Node, to *yaml.Node) {
+	if from.Kind != to.Kind {
+		return
+	}
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+	switch to.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(to.Content); i += 2 {
+			if fromKey, fromValue := mappingEntry(from, to.Content[i].Value); fromKey != nil {
+				copyComments(fromKey, to.Content[i])
+				copyComments(fromValue, to.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range to.Content {
+			if match := matchingItem(from, item, i); match != nil {
+				copyComments(match, item)
+			}
+		}
+	}
+}
+
+// mappingEntry returns the key and value nodes of a mapping for a key, or nil if not found.
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// matchingItem returns the item of a sequence matching an item of the new document: updates by their key,
+// scalars by value, and other items by index.
+func matchingItem(sequence *yaml.Node, item *yaml.Node, index int) *yaml.Node {
+	if key := updateNodeKey(item); key != "" {
+		for _, candidate := range sequence.Content {
+			if updateNodeKey(candidate) == key {
+				return candidate
+			}
+		}
+		return nil
+	}
+	if item.Kind == yaml.ScalarNode {
+		for _, candidate := range sequence.Content {
+			if candidate.Kind == yaml.ScalarNode && candidate.Value == item.Value {
+				return candidate
+			}
+		}
+		return nil
+	}
+	if index < len(sequence.Content) {
+		return sequence.Content[index]
+	}
+	return nil
+}
+
+// updateNodeKey returns the ecosystem, first directory and target branch of an update node, or "" for other nodes.
+func updateNodeKey(node *yaml.Node) string {
+	_, ecosystem := mappingEntry(node, "package-ecosystem")
+	if ecosystem == nil {
+		return ""
+	}
+	directory := ""
+	if _, value := mappingEntry(node, "directory"); value != nil {
+		directory = value.Value
+	} else if _, values := mappingEntry(node, "directories"); values != nil && len(values.Content) > 0 {
+		directory = values.Content[0].Value
+	}
+	targetBranch := ""
+	if _, value := mappingEntry(node, "target-branch"); value != nil {
+		targetBranch = value.Value
+	}
+	return ecosystem.Value + "|" + NormalizeDirectory(directory) + "|" + targetBranch
+}
+
+// annotateNewUpdates adds the comment to the package-ecosystem line of the new updates, which are not part of the
+// original document.
+func annotateNewUpdates(node *yaml.Node, originalDocument *yaml.Node, newUpdates []UpdateInfo, comment string) {
+	_, updates := mappingEntry(node, "updates")
+	if updates == nil {
+		return
+	}
+	var originalUpdates *yaml.Node
+	if len(originalDocument.Content) > 0 {
+		_, originalUpdates = mappingEntry(originalDocument.Content[0], "updates")
+	}
+	for _, update := range updates.Content {
+		key := updateNodeKey(update)
+		if key == "" || originalUpdates != nil && matchingItem(originalUpdates, update, -1) != nil {
+			continue
+		}
+		for _, newUpdate := range newUpdates {
+			if strings.HasPrefix(key, newUpdate.Type+"|"+NormalizeDirectory(newUpdate.Directory)+"|") {
+				update.Content[1].LineComment = "# " + strings.TrimPrefix(comment, "# ")
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToYamlWithComments(t *testing.T) {
+	original := []byte(`# maintained by the platform team
+
+version: 2
+registries:
+  npm-reg: # private npm
+    type: npm-registry
+    url: https://npm.acme.com
+updates:
+  # docker images
+  - package-ecosystem: docker
+    directory: "/" # root
+    labels:
+      - deps # the label
+`)
+	for _, tt := range []struct {
+		comment  string
+		expected string
+	}{
+		{"", `# maintained by the platform team
+
+version: 2
+registries:
+  npm-reg: # private npm
+    type: npm-registry
+    url: https://npm.acme.com
+updates:
+  # docker images
+  - package-ecosystem: docker
+    directory: / # root
+    labels:
+      - deps # the label
+  - package-ecosystem: npm
+    directory: /
+`},
+		{"managed by dependabutler", `# maintained by the platform team
+
+version: 2
+registries:
+  npm-reg: # private npm
+    type: npm-registry
+    url: https://npm.acme.com
+updates:
+  # docker images
+  - package-ecosystem: docker
+    directory: / # root
+    labels:
+      - deps # the label
+  - package-ecosystem: npm # managed by dependabutler
+    directory: /
+`},
+	} {
+		dependabotConfig, _ := ParseDependabotConfig(original)
+		changeInfo := dependabotConfig.UpdateConfig(map[string]string{"package.json": "npm"}, ToolConfig{},
+			LoadFileContentDummy, CheckDirectoryExistsDummy, LoadFileContentParameters{})
+		got := string(dependabotConfig.ToYamlWithComments(original, changeInfo.NewUpdates, tt.comment))
+		if got != tt.expected {
+			t.Errorf("ToYamlWithComments() failed; expected\n%v\ngot\n%v", tt.expected, got)
+		}
+	}
+}
+
+func TestRenderGeneratedComment(t *testing.T) {
+	toolConfig := ToolConfig{GeneratedComment: "managed by dependabutler {{version}} on {{date}}"}
+	got, err := toolConfig.RenderGeneratedComment(TemplateData{Version: "v1.2.0", Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil || got != "managed by dependabutler v1.2.0 on 2025-01-01" {
+		t.Errorf("RenderGeneratedComment() failed; got %v, %v", got, err)
+	}
+}
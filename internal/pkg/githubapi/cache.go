@@ -0,0 +1,207 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"github.com/google/go-github/v50/github"
+)
+
+// CacheStats holds the number of file reads served from the cache, and the ones requested from GitHub.
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// cache holds the repository trees and file contents read during a run, so each of them is requested once only.
+// With a directory, trees and contents are persisted across runs too, keyed by the SHAs of their commits and blobs,
+// which never change.
+type cache struct {
+	mutex sync.Mutex
+	dir   string
+	// trees are keyed by org/repo@ref, contents by org/repo@ref:path, with nil for missing files
+	trees    map[string]*github.Tree
+	contents map[string][]byte
+	// blobs holds the blob SHAs of the files in the trees listed, keyed by org/repo@ref:path
+	blobs map[string]string
+	// repos and commits hold the repo metadata and the commit SHAs of branches prefetched, keyed by org/repo and org/repo@ref
+	repos   map[string]*github.Repository
+	commits map[string]string
+	stats   CacheStats
+}
+
+// fileCache is the cache used by GetRepoFileList and GetFileContent.
+var fileCache = newCache()
+
+func newCache() *cache {
+	return &cache{
+		trees: map[string]*github.Tree{}, contents: map[string][]byte{}, blobs: map[string]string{},
+		repos: map[string]*github.Repository{}, commits: map[string]string{},
+	}
+}
+
+// EnableDiskCache persists the trees and file contents read in a directory, for use in later runs.
+func EnableDiskCache(dir string) {
+	fileCache.mutex.Lock()
+	defer fileCache.mutex.Unlock()
+	fileCache.dir = dir
+}
+
+// ClearCache drops the trees and file contents kept in memory, e.g. before processing a repo again in mode=server.
+// Files persisted on disk are kept, as they are keyed by SHA.
+func ClearCache() {
+	fileCache.mutex.Lock()
+	defer fileCache.mutex.Unlock()
+	fileCache.trees = map[string]*github.Tree{}
+	fileCache.contents = map[string][]byte{}
+	fileCache.blobs = map[string]string{}
+	fileCache.repos = map[string]*github.Repository{}
+	fileCache.commits = map[string]string{}
+}
+
+// GetCacheStats returns the number of file reads served from the cache so far.
+func GetCacheStats() CacheStats {
+	fileCache.mutex.Lock()
+	defer fileCache.mutex.Unlock()
+	return fileCache.stats
+}
+
+func refKey(org string, repo string, ref string) string {
+	return org + "/" + repo + "@" + ref
+}
+
+// persistent returns if the cache is kept on disk.
+func (c *cache) persistent() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.dir != ""
+}
+
+// getRepository returns the metadata of a repo, if prefetched.
+func (c *cache) getRepository(org string, repo string) (*github.Repository, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	repository, found := c.repos[org+"/"+repo]
+	return repository, found
+}
+
+// getCommitSHA returns the commit SHA of a branch, if prefetched.
+func (c *cache) getCommitSHA(org string, repo string, ref string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.commits[refKey(org, repo, ref)]
+}
+
+// storeCommitSHA keeps the commit SHA of a branch.
+func (c *cache) storeCommitSHA(org string, repo string, ref string, commitSHA string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.commits[refKey(org, repo, ref)] = commitSHA
+}
+
+// getTree returns the tree of a ref, from memory, or from disk by the SHA of its commit.
+func (c *cache) getTree(org string, repo string, ref string, commitSHA string) (*github.Tree, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if tree, found := c.trees[refKey(org, repo, ref)]; found {
+		return tree, true
+	}
+	if c.dir == "" || commitSHA == "" {
+		return nil, false
+	}
+	content, err := util.ReadFile(filepath.Join(c.dir, org, repo, "commits", commitSHA+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var tree github.Tree
+	if err := json.Unmarshal(content, &tree); err != nil {
+		return nil, false
+	}
+	c.putTree(org, repo, ref, &tree)
+	return &tree, true
+}
+
+// storeTree keeps the tree of a ref, and on disk by the SHA of its commit.
+func (c *cache) storeTree(org string, repo string, ref string, commitSHA string, tree *github.Tree) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.putTree(org, repo, ref, tree)
+	if c.dir == "" || commitSHA == "" {
+		return
+	}
+	if content, err := json.Marshal(tree); err == nil {
+		c.writeFile(filepath.Join(c.dir, org, repo, "commits", commitSHA+".json"), content)
+	}
+}
+
+// putTree keeps a tree and the blob SHAs of its files in memory. The mutex must be held.
+func (c *cache) putTree(org string, repo string, ref string, tree *github.Tree) {
+	key := refKey(org, repo, ref)
+	c.trees[key] = tree
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			c.blobs[key+":"+entry.GetPath()] = entry.GetSHA()
+		}
+	}
+}
+
+// getContent returns the content of a file, from memory, or from disk by the SHA of its blob, if the tree is known.
+func (c *cache) getContent(org string, repo string, ref string, path string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := refKey(org, repo, ref) + ":" + path
+	if content, found := c.contents[key]; found {
+		c.stats.Hits++
+		return content, true
+	}
+	if blobSHA := c.blobs[key]; c.dir != "" && blobSHA != "" {
+		if content, err := util.ReadFile(filepath.Join(c.dir, org, repo, "blobs", blobSHA)); err == nil {
+			c.contents[key] = content
+			c.stats.Hits++
+			return content, true
+		}
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+// storeContent keeps the content of a file, nil for a missing one, and on disk by the SHA of its blob, if known.
+func (c *cache) storeContent(org string, repo string, ref string, path string, content []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := refKey(org, repo, ref) + ":" + path
+	c.contents[key] = content
+	if blobSHA := c.blobs[key]; c.dir != "" && blobSHA != "" && content != nil {
+		c.writeFile(filepath.Join(c.dir, org, repo, "blobs", blobSHA), content)
+	}
+}
+
+// forget drops the tree and the file contents of a ref from memory, after it has been changed.
+func (c *cache) forget(org string, repo string, ref string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	prefix := refKey(org, repo, ref) + ":"
+	delete(c.trees, refKey(org, repo, ref))
+	delete(c.commits, refKey(org, repo, ref))
+	for key := range c.contents {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.contents, key)
+		}
+	}
+	for key := range c.blobs {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.blobs, key)
+		}
+	}
+}
+
+// writeFile saves a cache file. Failing to do so only costs requests in later runs, so errors are ignored.
+func (c *cache) writeFile(file string, content []byte) {
+	if err := util.MakeDirIfNotExists(filepath.Dir(file)); err != nil {
+		return
+	}
+	_ = util.SaveFile(file, content)
+}
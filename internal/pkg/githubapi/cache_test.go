@@ -0,0 +1,53 @@
+package githubapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	tree := &github.Tree{Entries: []*github.TreeEntry{
+		{Path: github.String("app"), Type: github.String("tree"), SHA: github.String("t1")},
+		{Path: github.String("app/package.json"), Type: github.String("blob"), SHA: github.String("b1")},
+	}}
+	c := newCache()
+	c.dir = dir
+	if _, found := c.getTree("acme", "repo", "main", "c1"); found {
+		t.Errorf("getTree() failed; expected empty cache")
+	}
+	c.storeTree("acme", "repo", "main", "c1", tree)
+	c.storeContent("acme", "repo", "main", "app/package.json", []byte("{}"))
+	c.storeContent("acme", "repo", "main", "app/.npmrc", nil)
+	if content, found := c.getContent("acme", "repo", "main", "app/.npmrc"); !found || content != nil {
+		t.Errorf("getContent() failed; expected missing file to be cached, got %v %v", found, content)
+	}
+
+	// a new run: the tree and the content are read from disk, by commit and blob SHA
+	c = newCache()
+	c.dir = dir
+	if _, found := c.getContent("acme", "repo", "main", "app/package.json"); found {
+		t.Errorf("getContent() failed; expected no content without the tree")
+	}
+	got, found := c.getTree("acme", "repo", "main", "c1")
+	if !found || !reflect.DeepEqual(got, tree) {
+		t.Errorf("getTree() failed; expected %v, got %v", tree, got)
+	}
+	if content, found := c.getContent("acme", "repo", "main", "app/package.json"); !found || string(content) != "{}" {
+		t.Errorf("getContent() failed; expected {}, got %v %v", found, string(content))
+	}
+	if _, found := c.getTree("acme", "repo", "develop", "c2"); found {
+		t.Errorf("getTree() failed; expected no tree for other commit")
+	}
+	if c.stats != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("cache stats failed; got %+v", c.stats)
+	}
+
+	// a changed branch is read again
+	c.forget("acme", "repo", "main")
+	if _, found := c.getContent("acme", "repo", "main", "app/package.json"); found {
+		t.Errorf("forget() failed; expected content to be dropped")
+	}
+}
@@ -0,0 +1,113 @@
+package githubapi
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+	"github.com/google/go-github/v50/github"
+)
+
+// codeownersFiles holds the locations of the CODEOWNERS file, in the order GitHub looks for them.
+var codeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// defaultCodeownersPath is the path whose owners are requested, if not configured.
+const defaultCodeownersPath = ".github/dependabot.yml"
+
+// requestCodeowners requests reviews from the owners of the configured path, and/or assigns them to the PR.
+// Failing to do so is not critical for the PR itself, so only warnings are logged.
+func requestCodeowners(client *github.Client, org string, repo string, pr *github.PullRequest, prParams config.PullRequestParameters) {
+	path := prParams.CodeownersPath
+	if path == "" {
+		path = defaultCodeownersPath
+	}
+	var owners []string
+	for _, file := range codeownersFiles {
+		// missing files have no content
+		content, err := GetFileContent(client, org, repo, file, "")
+		if err == nil && content != nil {
+			owners = codeownersOf(string(content), path)
+			break
+		}
+	}
+	// GitHub rejects review requests for the author of the PR
+	users, teams := splitOwners(owners, org, pr.GetUser().GetLogin())
+	if len(users) == 0 && len(teams) == 0 {
+		log.Printf("INFO  No code owners found for %v in repo %v.", path, repo)
+		return
+	}
+	ctx := context.Background()
+	if prParams.ReviewersFromCodeowners {
+		request := github.ReviewersRequest{Reviewers: users, TeamReviewers: teams}
+		if _, _, err := client.PullRequests.RequestReviewers(ctx, org, repo, pr.GetNumber(), request); err != nil {
+			log.Printf("WARN  Could not request reviews from %v for PR %v: %v", owners, pr.GetHTMLURL(), err)
+		}
+	}
+	if prParams.AssigneesFromCodeowners && len(users) > 0 {
+		if _, _, err := client.Issues.AddAssignees(ctx, org, repo, pr.GetNumber(), users); err != nil {
+			log.Printf("WARN  Could not assign %v to PR %v: %v", users, pr.GetHTMLURL(), err)
+		}
+	}
+}
+
+// codeownersOf returns the owners of a file according to a CODEOWNERS file. The last matching rule wins.
+func codeownersOf(codeowners string, file string) []string {
+	file = strings.TrimPrefix(file, "/")
+	var owners []string
+	for _, line := range strings.Split(codeowners, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if !matchCodeownersPattern(fields[0], file) {
+			continue
+		}
+		owners = []string{}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// matchCodeownersPattern checks if a file matches a CODEOWNERS pattern, which follows the gitignore rules:
+// patterns containing a slash other than at the end are relative to the root, others match at any level,
+// and patterns naming a directory match all files within.
+func matchCodeownersPattern(pattern string, file string) bool {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+	if !anchored && !strings.HasPrefix(pattern, "**") {
+		pattern = "**/" + pattern
+	}
+	if util.MatchPathPattern(pattern, file) {
+		return true
+	}
+	// "docs/*" only matches the files in docs, not the ones in its subdirectories
+	return !strings.HasSuffix(pattern, "*") && util.MatchPathPattern(pattern+"/**", file)
+}
+
+// splitOwners returns the users and the team slugs of the org among the owners, except the given user.
+// Owners given by email address and teams of other orgs are skipped.
+func splitOwners(owners []string, org string, exceptUser string) ([]string, []string) {
+	users := make([]string, 0)
+	teams := make([]string, 0)
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") {
+			continue
+		}
+		owner = strings.TrimPrefix(owner, "@")
+		if teamOrg, team, isTeam := strings.Cut(owner, "/"); isTeam {
+			if strings.EqualFold(teamOrg, org) {
+				teams = append(teams, team)
+			}
+		} else if !strings.EqualFold(owner, exceptUser) {
+			users = append(users, owner)
+		}
+	}
+	return users, teams
+}
@@ -0,0 +1,44 @@
+package githubapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeownersOf(t *testing.T) {
+	codeowners := `# default owners
+*       @acme/everyone
+
+*.yml   @yaml-lover # formatting
+/.github/ @acme/platform @octocat dev@example.com
+docs/*  @acme/docs
+apps/   @acme/apps
+`
+	for _, tt := range []struct {
+		file     string
+		expected []string
+	}{
+		{".github/dependabot.yml", []string{"@acme/platform", "@octocat", "dev@example.com"}},
+		{"/.github/workflows/ci.yml", []string{"@acme/platform", "@octocat", "dev@example.com"}},
+		{"config/app.yml", []string{"@yaml-lover"}},
+		{"README.md", []string{"@acme/everyone"}},
+		{"docs/index.md", []string{"@acme/docs"}},
+		{"docs/api/index.md", []string{"@acme/everyone"}},
+		{"services/apps/main.go", []string{"@acme/apps"}},
+	} {
+		got := codeownersOf(codeowners, tt.file)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("codeownersOf(%v) failed; expected %v got %v", tt.file, tt.expected, got)
+		}
+	}
+	if got := codeownersOf("/src/ @dev", ".github/dependabot.yml"); got != nil {
+		t.Errorf("codeownersOf() failed; expected no owners got %v", got)
+	}
+}
+
+func TestSplitOwners(t *testing.T) {
+	users, teams := splitOwners([]string{"@acme/platform", "@octocat", "@dependabutler-bot", "dev@example.com", "@other/team"}, "ACME", "dependabutler-bot")
+	if !reflect.DeepEqual(users, []string{"octocat"}) || !reflect.DeepEqual(teams, []string{"platform"}) {
+		t.Errorf("splitOwners() failed; expected [octocat] [platform] got %v %v", users, teams)
+	}
+}
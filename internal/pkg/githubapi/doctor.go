@@ -0,0 +1,52 @@
+package githubapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// TokenInfo holds the user of a token and its OAuth scopes.
+// Only classic tokens have scopes, fine-grained tokens and GitHub App tokens have permissions instead.
+type TokenInfo struct {
+	Login     string
+	Scopes    []string
+	HasScopes bool
+}
+
+// GetTokenInfo returns the user and the OAuth scopes of the token the client uses.
+func GetTokenInfo(client *github.Client) (TokenInfo, error) {
+	user, resp, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	info := TokenInfo{Login: user.GetLogin()}
+	if header, hasScopes := resp.Header["X-Oauth-Scopes"]; hasScopes {
+		info.HasScopes = true
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.Scopes = append(info.Scopes, scope)
+			}
+		}
+	}
+	return info, nil
+}
+
+// GetRateLimit returns the state of the primary rate limit of the REST API.
+func GetRateLimit(client *github.Client) (*github.Rate, error) {
+	limits, _, err := client.RateLimits(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return limits.GetCore(), nil
+}
+
+// GetRepoPermissions returns the permissions the token has on a repo (admin, maintain, push, triage, pull).
+func GetRepoPermissions(client *github.Client, org string, repo string) (map[string]bool, error) {
+	repository, err := GetRepository(client, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	return repository.GetPermissions(), nil
+}
@@ -4,29 +4,108 @@ package githubapi
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/getyourguide/dependabutler/internal/pkg/config"
 	"github.com/getyourguide/dependabutler/internal/pkg/util"
 	"github.com/google/go-github/v50/github"
 	"golang.org/x/oauth2"
 )
 
-// GetGitHubClient returns a GitHub client for API calls
-func GetGitHubClient(accessToken string) *github.Client {
+// discoveryLabel is the label of all PRs created by dependabutler, used for finding them.
+const discoveryLabel = "dependabutler"
+
+// maxCommitAttempts is the number of attempts for committing, in case the branch moves meanwhile.
+const maxCommitAttempts = 3
+
+// orgSecret holds the visibility and the selected repositories of an org-level Dependabot secret.
+type orgSecret struct {
+	exists        bool
+	visibility    string
+	selectedRepos []string
+}
+
+// orgSecrets caches the org-level Dependabot secrets, keyed by org and secret name.
+var orgSecrets = map[string]*orgSecret{}
+
+// GetGitHubClient returns a GitHub client for API calls.
+// With a base URL, the client connects to a GitHub Enterprise Server instead of github.com.
+// With a rate limit buffer, requests wait for the rate limit reset once less requests are remaining.
+func GetGitHubClient(accessToken string, baseURL string, uploadURL string, rateLimitBuffer int) (*github.Client, error) {
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	return github.NewClient(tc)
+	tc.Transport = &rateLimitTransport{base: tc.Transport, buffer: rateLimitBuffer}
+	if baseURL == "" {
+		return github.NewClient(tc), nil
+	}
+	if uploadURL == "" {
+		// GitHub Enterprise Server serves uploads from /api/uploads/, next to the API at /api/v3/
+		uploadURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v3")
+	}
+	return github.NewEnterpriseClient(baseURL, uploadURL, tc)
+}
+
+// APIURLFromEnvironment returns the API URL of a GitHub Enterprise Server, given the values of the GITHUB_API_URL
+// and GITHUB_SERVER_URL environment variables set by GitHub Actions. The API URL is derived from the server URL,
+// if it's not set. For github.com, an empty URL is returned, so the default client is used.
+func APIURLFromEnvironment(apiURL string, serverURL string) string {
+	if apiURL == "" && serverURL != "" {
+		apiURL = strings.TrimSuffix(serverURL, "/") + "/api/v3/"
+	}
+	if parsed, err := url.Parse(apiURL); err == nil && (parsed.Host == "github.com" || parsed.Host == "api.github.com") {
+		return ""
+	}
+	return apiURL
+}
+
+// searchResultLimit is the maximum number of results the search API returns for a query.
+const searchResultLimit = 1000
+
+// SearchRepos returns the names of the repos of an org matching a search query, e.g. "org:acme topic:backend archived:false".
+// Repos of other owners are skipped, as the repos are processed as repos of the org.
+func SearchRepos(client *github.Client, org string, query string) ([]string, error) {
+	ctx := context.Background()
+	opts := &github.SearchOptions{Sort: "updated", ListOptions: github.ListOptions{PerPage: 100}}
+	repos := make([]string, 0)
+	for {
+		result, resp, err := client.Search.Repositories(ctx, query, opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Page == 0 && result.GetTotal() > searchResultLimit {
+			log.Printf("WARN  Search query matches %v repos, only the first %v are returned. Narrow down the query.", result.GetTotal(), searchResultLimit)
+		}
+		for _, repository := range result.Repositories {
+			if !strings.EqualFold(repository.GetOwner().GetLogin(), org) {
+				log.Printf("WARN  Skipping repo %v of search result, not owned by %v.", repository.GetFullName(), org)
+				continue
+			}
+			repos = append(repos, repository.GetName())
+		}
+		if resp.NextPage == 0 {
+			return repos, nil
+		}
+		opts.Page = resp.NextPage
+	}
 }
 
 // GetRepository gets a repository object.
 func GetRepository(client *github.Client, org string, repo string) (*github.Repository, error) {
+	if repository, found := fileCache.getRepository(org, repo); found {
+		return repository, nil
+	}
 	ctx := context.Background()
 	repository, _, err := client.Repositories.Get(ctx, org, repo)
 	if err != nil {
@@ -40,24 +119,80 @@ func GetRepository(client *github.Client, org string, repo string) (*github.Repo
 	return repository, nil
 }
 
-// GetRepoFileList returns a list (strings) of all files in a repo, including their path.
-func GetRepoFileList(client *github.Client, org string, repo string, defaultBranch string) []string {
-	// get the file tree
+// GetRepoFileList returns a list (strings) of all files in a repo, including their path, and if the list is truncated,
+// as the tree of the repo is too large to be listed by GitHub. A truncated list can be scanned for manifests, but it's
+// no reliable source for the existence of directories.
+func GetRepoFileList(client *github.Client, org string, repo string, defaultBranch string) ([]string, bool) {
+	// get the file tree, from the cache if it has been listed already
 	ctx := context.Background()
-	tree, _, err := client.Git.GetTree(ctx, org, repo, defaultBranch, true)
-	if err != nil {
-		log.Printf("ERROR Got error when requesting GitHub repo tree.\n%v", err)
-		return nil
+	commitSHA := fileCache.getCommitSHA(org, repo, defaultBranch)
+	if commitSHA == "" && fileCache.persistent() {
+		// trees persisted are keyed by commit, as branches move
+		commitSHA, _ = GetHeadCommit(client, org, repo, defaultBranch)
+	}
+	tree, found := fileCache.getTree(org, repo, defaultBranch, commitSHA)
+	if !found {
+		// get the tree of the commit it's cached for, as the branch may have moved since
+		treeSHA := defaultBranch
+		if commitSHA != "" {
+			treeSHA = commitSHA
+		}
+		var err error
+		tree, _, err = client.Git.GetTree(ctx, org, repo, treeSHA, true)
+		if err != nil {
+			log.Printf("ERROR Got error when requesting GitHub repo tree.\n%v", err)
+			return nil, false
+		}
+		fileCache.storeTree(org, repo, defaultBranch, commitSHA, tree)
+	}
+	if tree.GetTruncated() {
+		log.Printf("WARN  GitHub repo tree of %v is truncated, not all files are listed.", repo)
 	}
 	result := make([]string, 0)
 	for _, entry := range tree.Entries {
 		result = append(result, *entry.Path)
 	}
-	return result
+	return result, tree.GetTruncated()
+}
+
+// GetBranches returns the names of all branches of a repo.
+func GetBranches(client *github.Client, org string, repo string) ([]string, error) {
+	ctx := context.Background()
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	branches := make([]string, 0)
+	for {
+		result, resp, err := client.Repositories.ListBranches(ctx, org, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range result {
+			branches = append(branches, branch.GetName())
+		}
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
 }
 
-// GetFileContent returns the content of a file
+// GetHeadCommit returns the SHA of the head commit of a branch, cached for the run.
+func GetHeadCommit(client *github.Client, org string, repo string, branch string) (string, error) {
+	if commitSHA := fileCache.getCommitSHA(org, repo, branch); commitSHA != "" {
+		return commitSHA, nil
+	}
+	commitSHA, _, err := client.Repositories.GetCommitSHA1(context.Background(), org, repo, branch, "")
+	if err != nil {
+		return "", err
+	}
+	fileCache.storeCommitSHA(org, repo, branch, commitSHA)
+	return commitSHA, nil
+}
+
+// GetFileContent returns the content of a file, nil if it's missing. Contents are cached for the run.
 func GetFileContent(client *github.Client, org string, repo string, path string, branchName string) ([]byte, error) {
+	if cached, found := fileCache.getContent(org, repo, branchName, path); found {
+		return cached, nil
+	}
 	ctx := context.Background()
 	opts := &github.RepositoryContentGetOptions{}
 	if branchName != "" {
@@ -68,66 +203,222 @@ func GetFileContent(client *github.Client, org string, repo string, path string,
 		return nil, err
 	}
 	if content == nil {
+		fileCache.storeContent(org, repo, branchName, path, nil)
 		return nil, nil
 	}
 	fileContent, err := content.GetContent()
 	if err != nil {
 		return nil, err
 	}
-	return bytes.NewBufferString(fileContent).Bytes(), nil
+	result := bytes.NewBufferString(fileContent).Bytes()
+	fileCache.storeContent(org, repo, branchName, path, result)
+	return result, nil
+}
+
+// GetSubmoduleRepos returns the repos of the org referenced as submodules by a repo, on its default branch.
+func GetSubmoduleRepos(client *github.Client, org string, repo string) ([]string, error) {
+	content, err := GetFileContent(client, org, repo, ".gitmodules", "")
+	if err != nil {
+		return nil, err
+	}
+	// the API of github.com is served by api.github.com, the one of GitHub Enterprise Server by the host itself
+	host := strings.TrimPrefix(client.BaseURL.Hostname(), "api.")
+	return config.SameOrgSubmodules(content, host, org, repo), nil
 }
 
-// CreateOrUpdatePullRequest creates or updates a PR for changes in dependabot.yml
-func CreateOrUpdatePullRequest(client *github.Client, org string, repo string, baseBranch string, prDesc string, content string, toolConfig config.ToolConfig) error {
+// GetPullRequest returns a PR by its number
+func GetPullRequest(client *github.Client, org string, repo string, number int) (*github.PullRequest, error) {
+	pr, _, err := client.PullRequests.Get(context.Background(), org, repo, number)
+	return pr, err
+}
+
+// GetLastCommit returns the latest commit changing a file on the default branch, or nil if there's none.
+func GetLastCommit(client *github.Client, org string, repo string, path string) (*github.RepositoryCommit, error) {
+	opts := &github.CommitsListOptions{Path: path, ListOptions: github.ListOptions{PerPage: 1}}
+	commits, _, err := client.Repositories.ListCommits(context.Background(), org, repo, opts)
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+	return commits[0], nil
+}
+
+// CreateIssue opens an issue in a repo, and returns it.
+func CreateIssue(client *github.Client, org string, repo string, title string, body string, labels []string) (*github.Issue, error) {
+	request := &github.IssueRequest{Title: github.String(title), Body: github.String(body)}
+	if len(labels) > 0 {
+		request.Labels = &labels
+	}
+	issue, _, err := client.Issues.Create(context.Background(), org, repo, request)
+	return issue, err
+}
+
+// CreateOrUpdateIssue opens an issue, or updates the open issue with the same title, so repeated runs don't open
+// duplicates. It returns the issue, and if it has been opened.
+func CreateOrUpdateIssue(client *github.Client, org string, repo string, title string, body string, labels []string) (*github.Issue, bool, error) {
+	opts := &github.IssueListByRepoOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		issues, resp, err := client.Issues.ListByRepo(context.Background(), org, repo, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, issue := range issues {
+			if issue.IsPullRequest() || issue.GetTitle() != title {
+				continue
+			}
+			if issue.GetBody() == body {
+				return issue, false, nil
+			}
+			issue, _, err = client.Issues.Edit(context.Background(), org, repo, issue.GetNumber(), &github.IssueRequest{Body: github.String(body)})
+			return issue, false, err
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	issue, err := CreateIssue(client, org, repo, title, body, labels)
+	return issue, err == nil, err
+}
+
+// IsPermissionError returns if an error has been caused by missing permissions or by branch protections,
+// preventing dependabutler from pushing its branch or opening a PR.
+func IsPermissionError(err error) bool {
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusForbidden {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, text := range []string{
+		"protected branch", "rule violation", "resource not accessible", "must have admin rights",
+		"permission",
+	} {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// customPropertyValue holds the value of a custom property of a repo, a string or a list of strings.
+type customPropertyValue struct {
+	PropertyName string      `json:"property_name"`
+	Value        interface{} `json:"value"`
+}
+
+// GetCustomProperties returns the custom properties of a repo. Values of multi-select properties are joined by commas.
+func GetCustomProperties(client *github.Client, org string, repo string) (map[string]string, error) {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/properties/values", org, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	var values []customPropertyValue
+	if _, err := client.Do(context.Background(), req, &values); err != nil {
+		return nil, err
+	}
+	properties := make(map[string]string, len(values))
+	for _, property := range values {
+		switch value := property.Value.(type) {
+		case string:
+			properties[property.PropertyName] = value
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			properties[property.PropertyName] = strings.Join(items, ",")
+		}
+	}
+	return properties, nil
+}
+
+// ConfigPath is the path of the dependabot config in a repo.
+const ConfigPath = ".github/dependabot.yml"
+
+// FileChange holds a file committed for a PR, with its content, or its deletion.
+type FileChange struct {
+	Path    string
+	Content string
+	Delete  bool
+}
+
+// CreateOrUpdatePullRequest creates or updates a PR for changes of files, dependabot.yml and companion files,
+// and returns its number.
+func CreateOrUpdatePullRequest(client *github.Client, org string, repo string, baseBranch string, prDesc string, files []FileChange,
+	toolConfig config.ToolConfig,
+) (int, error) {
 	prParams := toolConfig.PullRequestParameters
 
 	// Check if there already is a PR open, from dependabutler. If so, re-use its branch.
-	existingPr, err := getExistingPr(client, org, repo)
+	existingPr, err := getExistingPr(client, org, repo, baseBranch, prParams)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	var branchName string
+	var branchName, previousContent, content string
 	if existingPr != nil {
 		branchName = *existingPr.Head.Ref
-		// In case a PR exists, check if the file content has changed meanwhile.
-		prContent, err := GetFileContent(client, org, repo, ".github/dependabot.yml", branchName)
-		if err != nil {
-			return err
+		// In case a PR exists, check if the file contents have changed meanwhile.
+		changed := false
+		for _, file := range files {
+			prContent, err := GetFileContent(client, org, repo, file.Path, branchName)
+			if err != nil {
+				return 0, err
+			}
+			if file.Path == ConfigPath {
+				previousContent, content = string(prContent), file.Content
+			}
+			if (file.Delete && prContent != nil) || (!file.Delete && string(prContent) != file.Content) {
+				changed = true
+			}
 		}
-		if string(prContent) == content {
+		if !changed {
 			log.Printf("INFO  Found open PR, no update required: %v", *existingPr.HTMLURL)
-			return nil
+			return existingPr.GetNumber(), nil
 		}
 	} else {
 		branchName, err = getNewBranchName(prParams)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
-	// Get the reference (existing or new).
-	ref, err := getReference(client, org, repo, baseBranch, branchName)
-	if err != nil {
-		return err
-	}
-
-	// Create a tree with one entry, for the commit.
-	tree, err := getTree(client, ref, org, repo, ".github/dependabot.yml", content)
-	if err != nil {
-		return err
+	var signingKey *openpgp.Entity
+	if prParams.CommitSigning == CommitSigningGPG {
+		if signingKey, err = ReadSigningKey(toolConfig.Credentials.GPGPrivateKey, toolConfig.Credentials.GPGPassphrase); err != nil {
+			return 0, fmt.Errorf("could not read GPG key for signing commits: %w", err)
+		}
 	}
 
-	// Push the commit.
-	err = pushCommit(client, ref, tree, org, repo, prParams.CommitMessage, prParams.AuthorName, prParams.AuthorEmail)
-	if err != nil {
-		return err
+	// Commit the file. In case the branch has moved meanwhile, e.g. because the base branch advanced
+	// between the creation of the reference and the push, retry on top of the current state.
+	for attempt := 1; ; attempt++ {
+		err = commitFiles(client, org, repo, baseBranch, branchName, existingPr == nil && attempt > 1, files, prParams, signingKey)
+		if err == nil {
+			break
+		}
+		if attempt >= maxCommitAttempts || !isReferenceConflict(err) {
+			return 0, err
+		}
+		log.Printf("WARN  Branch %v of repo %v has moved meanwhile, retrying the commit (attempt %v).", branchName, repo, attempt+1)
 	}
+	fileCache.forget(org, repo, branchName)
 
 	ctx := context.Background()
+	var number int
 	if existingPr != nil {
+		number = existingPr.GetNumber()
+		existingPr.Title = &prParams.PRTitle
 		existingPr.Body = &prDesc
 		if _, _, err := client.PullRequests.Edit(ctx, org, repo, *existingPr.Number, existingPr); err != nil {
-			return err
+			return 0, err
+		}
+		// Labels added to the config meanwhile are added to the PR too.
+		if missing := missingLabels(existingPr, prLabels(prParams)); len(missing) > 0 {
+			addLabels(client, org, repo, existingPr, missing)
+		}
+		// Let the subscribers know what has changed since the previous revision.
+		comment := &github.IssueComment{Body: github.String(createPRUpdateComment(previousContent, content))}
+		if _, _, err := client.Issues.CreateComment(ctx, org, repo, *existingPr.Number, comment); err != nil {
+			log.Printf("WARN  Could not comment on PR %v: %v", existingPr.GetHTMLURL(), err)
 		}
 		log.Printf("INFO  PR successfully updated: %s\n", existingPr.GetHTMLURL())
 	} else {
@@ -139,21 +430,26 @@ func CreateOrUpdatePullRequest(client *github.Client, org string, repo string, b
 		newPR.Base = &baseBranch
 		pr, _, err := client.PullRequests.Create(ctx, org, repo, newPR)
 		if err != nil {
-			return err
-		}
-		labels := []string{"dependabutler"}
-		_, _, err = client.Issues.AddLabelsToIssue(ctx, org, repo, *pr.Number, labels)
-		if err != nil {
-			return err
+			return 0, err
 		}
+		number = pr.GetNumber()
 		log.Printf("INFO  PR successfully created: %s\n", pr.GetHTMLURL())
+		addLabels(client, org, repo, pr, prLabels(prParams))
+		if prParams.ReviewersFromCodeowners || prParams.AssigneesFromCodeowners {
+			requestCodeowners(client, org, repo, pr, prParams)
+		}
+		if prParams.AutoMerge != "" {
+			if err := enableAutoMerge(client, pr, prParams.AutoMerge); err != nil {
+				log.Printf("WARN  Could not enable auto-merge for PR %v: %v", pr.GetHTMLURL(), err)
+			}
+		}
 	}
 	sleepSeconds := toolConfig.PullRequestParameters.SleepAfterPRAction
 	if sleepSeconds > 0 {
 		// Sleep - can help to avoid issues with second rate limit.
-		time.Sleep(time.Duration(sleepSeconds) * time.Second)
+		waitForRateLimit(time.Duration(sleepSeconds)*time.Second, &rateLimitStats.PRActionWait)
 	}
-	return nil
+	return number, nil
 }
 
 // CreatePRDescription renders the body of the PR to be created.
@@ -168,6 +464,15 @@ func CreatePRDescription(changeInfo config.ChangeInfo) string {
 			lines = append(lines, fmt.Sprintf("| %v | %v |", registry.Type, registry.Name))
 		}
 	}
+	if len(changeInfo.RemovedRegistries) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### 🗑 unused registries removed")
+		lines = append(lines, "| type | name |")
+		lines = append(lines, "| - | - |")
+		for _, registry := range changeInfo.RemovedRegistries {
+			lines = append(lines, fmt.Sprintf("| %v | %v |", registry.Type, registry.Name))
+		}
+	}
 	if len(changeInfo.NewUpdates) > 0 {
 		lines = append(lines, "")
 		lines = append(lines, "#### ♻ updates added")
@@ -177,16 +482,269 @@ func CreatePRDescription(changeInfo config.ChangeInfo) string {
 			lines = append(lines, fmt.Sprintf("| %v | %v | %v |", update.Type, update.Directory, update.File))
 		}
 	}
+	if len(changeInfo.RemovedUpdates) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### 🗑 updates removed")
+		lines = append(lines, "| type | directory |")
+		lines = append(lines, "| - | - |")
+		for _, update := range changeInfo.RemovedUpdates {
+			lines = append(lines, fmt.Sprintf("| %v | %v |", update.Type, update.Directory))
+		}
+	}
+	if len(changeInfo.FixedUpdates) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### 🔧 updates fixed")
+		lines = append(lines, "| type | directory | change |")
+		lines = append(lines, "| - | - | - |")
+		for _, fix := range changeInfo.FixedUpdates {
+			lines = append(lines, fmt.Sprintf("| %v | %v | %v |", fix.Type, fix.Directory, fix.Change))
+		}
+	}
+	if len(changeInfo.UpdatedFiles) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### 📄 companion files updated")
+		for _, file := range changeInfo.UpdatedFiles {
+			lines = append(lines, fmt.Sprintf("* %v", file))
+		}
+	}
+	for _, section := range []struct {
+		title string
+		refs  []config.RegistryRefInfo
+	}{
+		{"🔗 registry references added", changeInfo.AddedRegistryRefs},
+		{"🔗 registry references missing", changeInfo.MissingRegistryRefs},
+	} {
+		if len(section.refs) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, "#### "+section.title)
+			lines = append(lines, "| type | directory | registry |")
+			lines = append(lines, "| - | - | - |")
+			for _, ref := range section.refs {
+				lines = append(lines, fmt.Sprintf("| %v | %v | %v |", ref.Type, ref.Directory, ref.Registry))
+			}
+		}
+	}
+	if len(changeInfo.PausedRemovedUpdates) > 0 || len(changeInfo.PausedRemovedRegistries) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### ⏸ removals paused, pending confirmation (exceeding the removal limits)")
+		lines = append(lines, "| kind | type | name / directory |")
+		lines = append(lines, "| - | - | - |")
+		for _, update := range changeInfo.PausedRemovedUpdates {
+			lines = append(lines, fmt.Sprintf("| update | %v | %v |", update.Type, update.Directory))
+		}
+		for _, registry := range changeInfo.PausedRemovedRegistries {
+			lines = append(lines, fmt.Sprintf("| registry | %v | %v |", registry.Type, registry.Name))
+		}
+	}
+	if len(changeInfo.SkippedManifests) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### ⏭ manifests skipped")
+		lines = append(lines, "| type | file | reason |")
+		lines = append(lines, "| - | - | - |")
+		for _, skipped := range changeInfo.SkippedManifests {
+			lines = append(lines, fmt.Sprintf("| %v | %v | %v |", skipped.Type, skipped.File, skipped.Reason))
+		}
+	}
+	if len(changeInfo.Warnings) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "#### ⚠ warnings")
+		for _, warning := range changeInfo.Warnings {
+			lines = append(lines, fmt.Sprintf("* %v", warning))
+		}
+	}
 	lines = append(lines, "")
 	lines = append(lines, "#### note")
 	lines = append(lines, "* Check the default settings applied (schedule, open-pull-requests-limit, etc.) and change if required.")
 	return strings.Join(lines, "\n")
 }
 
-func getTree(client *github.Client, ref *github.Reference, org string, repo string, file string, content string) (*github.Tree, error) {
+// maxPRDiffLength is the length up to which diffs are included in PR descriptions, below GitHub's limit of 65536
+// characters for the whole description.
+const maxPRDiffLength = 50000
+
+// PRDescriptionDiff renders the diff of the config as collapsible section for the PR description, or "" if there
+// are no differences. Diffs exceeding maxPRDiffLength are left out, with a note.
+func PRDescriptionDiff(currentConfig []byte, newConfig []byte) string {
+	diff := util.UnifiedDiff(string(currentConfig), string(newConfig), "a/.github/dependabot.yml", "b/.github/dependabot.yml")
+	if diff == "" {
+		return ""
+	}
+	lines := []string{"", "<details>", "<summary>diff of .github/dependabot.yml</summary>", ""}
+	if len(diff) > maxPRDiffLength {
+		lines = append(lines, "The diff is too large to be shown here, see the files changed.")
+	} else {
+		lines = append(lines, "```diff", strings.TrimSuffix(diff, "\n"), "```")
+	}
+	lines = append(lines, "", "</details>")
+	return strings.Join(lines, "\n")
+}
+
+// addLabels adds labels to a PR, creating them in the repo if necessary.
+// Failing to do so is not critical for the PR itself, so only warnings are logged.
+func addLabels(client *github.Client, org string, repo string, pr *github.PullRequest, labels []string) {
+	ctx := context.Background()
+	for _, label := range labels {
+		if err := ensureLabelExists(client, org, repo, label); err != nil {
+			log.Printf("WARN  Could not create label %v in repo %v: %v", label, repo, err)
+		}
+	}
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, org, repo, pr.GetNumber(), labels); err != nil {
+		log.Printf("WARN  Could not add labels %v to PR %v: %v", labels, pr.GetHTMLURL(), err)
+	}
+}
+
+// prLabels returns the labels of PRs, the discovery label followed by the configured ones.
+func prLabels(prParams config.PullRequestParameters) []string {
+	labels := []string{discoveryLabel}
+	for _, label := range prParams.Labels {
+		if !util.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// missingLabels returns the labels a PR doesn't have yet.
+func missingLabels(pr *github.PullRequest, labels []string) []string {
+	existing := make([]string, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		existing = append(existing, label.GetName())
+	}
+	missing := make([]string, 0)
+	for _, label := range labels {
+		if !util.Contains(existing, label) {
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// ensureLabelExists creates a label in a repo, unless it exists already.
+func ensureLabelExists(client *github.Client, org string, repo string, name string) error {
+	ctx := context.Background()
+	_, _, err := client.Issues.GetLabel(ctx, org, repo, name)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "404 Not Found") {
+		return err
+	}
+	_, _, err = client.Issues.CreateLabel(ctx, org, repo, &github.Label{Name: github.String(name)})
+	if err != nil && strings.Contains(err.Error(), "already_exists") {
+		return nil
+	}
+	return err
+}
+
+// createPRUpdateComment renders a comment listing the changes between two revisions of the PR's config.
+func createPRUpdateComment(previousContent string, content string) string {
+	lines := []string{"### dependabutler has updated this PR"}
+	if previousContent == content {
+		lines = append(lines, "")
+		lines = append(lines, "Companion files have changed, the config is unchanged.")
+		return strings.Join(lines, "\n")
+	}
+	previousConfig, errPrevious := config.ParseDependabotConfig([]byte(previousContent))
+	currentConfig, errCurrent := config.ParseDependabotConfig([]byte(content))
+	if errPrevious != nil || errCurrent != nil {
+		return strings.Join(lines, "\n")
+	}
+	diff := config.CompareConfigs(previousConfig, currentConfig)
+	if diff.IsEmpty() {
+		lines = append(lines, "")
+		lines = append(lines, "Settings of existing entries have changed, no registries or updates have been added or removed.")
+	}
+	for _, section := range []struct {
+		title      string
+		registries []config.RegistryInfo
+	}{
+		{"🏛 registries added", diff.AddedRegistries},
+		{"🗑 registries removed", diff.RemovedRegistries},
+	} {
+		if len(section.registries) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, "#### "+section.title)
+			lines = append(lines, "| type | name |")
+			lines = append(lines, "| - | - |")
+			for _, registry := range section.registries {
+				lines = append(lines, fmt.Sprintf("| %v | %v |", registry.Type, registry.Name))
+			}
+		}
+	}
+	for _, section := range []struct {
+		title   string
+		updates []config.UpdateInfo
+	}{
+		{"♻ updates added", diff.AddedUpdates},
+		{"🗑 updates removed", diff.RemovedUpdates},
+	} {
+		if len(section.updates) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, "#### "+section.title)
+			lines = append(lines, "| type | directory |")
+			lines = append(lines, "| - | - |")
+			for _, update := range section.updates {
+				lines = append(lines, fmt.Sprintf("| %v | %v |", update.Type, update.Directory))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// commitFiles commits the file changes to a branch, which is created if needed.
+// With resetToBase, an existing branch is moved to the current head of the base branch first.
+func commitFiles(client *github.Client, org string, repo string, baseBranch string, branchName string, resetToBase bool,
+	files []FileChange, prParams config.PullRequestParameters, signingKey *openpgp.Entity,
+) error {
+	// Get the reference (existing or new).
+	ref, err := getReference(client, org, repo, baseBranch, branchName)
+	if err != nil {
+		return err
+	}
+	if resetToBase {
+		ctx := context.Background()
+		baseRef, _, err := client.Git.GetRef(ctx, org, repo, "refs/heads/"+baseBranch)
+		if err != nil {
+			return err
+		}
+		ref.Object.SHA = baseRef.Object.SHA
+		if ref, _, err = client.Git.UpdateRef(ctx, org, repo, ref, true); err != nil {
+			return err
+		}
+	}
+
+	// Let GitHub create and sign the commit.
+	if prParams.CommitSigning == CommitSigningAPI {
+		return commitOnBranch(client, org, repo, branchName, ref.Object.GetSHA(), files, prParams.CommitMessage)
+	}
+
+	// Create a tree with the files changed, and the files to delete, for the commit.
+	tree, err := getTree(client, ref, org, repo, files)
+	if err != nil {
+		return err
+	}
+
+	// Push the commit.
+	return pushCommit(client, ref, tree, org, repo, prParams.CommitMessage, prParams.AuthorName, prParams.AuthorEmail, signingKey)
+}
+
+// isReferenceConflict returns if an error has been caused by a reference that has moved meanwhile.
+func isReferenceConflict(err error) bool {
+	// the GraphQL API reports a moved branch by the expected head
+	return strings.Contains(err.Error(), "is not a fast forward") || strings.Contains(err.Error(), "Reference update failed") ||
+		strings.Contains(err.Error(), "Expected branch to point to")
+}
+
+func getTree(client *github.Client, ref *github.Reference, org string, repo string, files []FileChange) (*github.Tree, error) {
 	ctx := context.Background()
-	entries := []*github.TreeEntry{
-		{Path: github.String(file), Type: github.String("blob"), Content: github.String(content), Mode: github.String("100644")},
+	entries := make([]*github.TreeEntry, 0, len(files))
+	for _, file := range files {
+		entry := &github.TreeEntry{Path: github.String(file.Path), Type: github.String("blob"), Mode: github.String("100644")}
+		// entries without SHA and content delete the file
+		if !file.Delete {
+			entry.Content = github.String(file.Content)
+		}
+		entries = append(entries, entry)
 	}
 	tree, _, err := client.Git.CreateTree(ctx, org, repo, *ref.Object.SHA, entries)
 	if err != nil {
@@ -219,7 +777,9 @@ func getReference(client *github.Client, org string, repo string, baseBranch str
 	return ref, nil
 }
 
-func pushCommit(client *github.Client, ref *github.Reference, tree *github.Tree, org string, repo string, commitMessage string, authorName string, authorEmail string) error {
+func pushCommit(client *github.Client, ref *github.Reference, tree *github.Tree, org string, repo string, commitMessage string, authorName string, authorEmail string,
+	signingKey *openpgp.Entity,
+) error {
 	ctx := context.Background()
 	parent, _, err := client.Repositories.GetCommit(ctx, org, repo, *ref.Object.SHA, nil)
 	if err != nil {
@@ -228,7 +788,7 @@ func pushCommit(client *github.Client, ref *github.Reference, tree *github.Tree,
 	parent.Commit.SHA = parent.SHA
 	now := time.Now()
 	author := &github.CommitAuthor{Date: &github.Timestamp{Time: now}, Name: &authorName, Email: &authorEmail}
-	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}}
+	commit := &github.Commit{Author: author, Message: &commitMessage, Tree: tree, Parents: []*github.Commit{parent.Commit}, SigningKey: signingKey}
 	newCommit, _, err := client.Git.CreateCommit(ctx, org, repo, commit)
 	if err != nil {
 		return err
@@ -241,31 +801,111 @@ func pushCommit(client *github.Client, ref *github.Reference, tree *github.Tree,
 	return nil
 }
 
-func getExistingPr(client *github.Client, org string, repo string) (*github.PullRequest, error) {
+// getExistingPr returns the open PR created by dependabutler for the base branch, if any.
+// PRs from branches not matching the configured branch name are superseded, and get closed, if dependabutler opened
+// them: they are authored by the user of the token, and their branch is in the repo.
+func getExistingPr(client *github.Client, org string, repo string, baseBranch string, prParams config.PullRequestParameters) (*github.PullRequest, error) {
 	ctx := context.Background()
 	opts := github.IssueListByRepoOptions{
-		State:  "open",
-		Labels: []string{"dependabutler"},
+		State:       "open",
+		Labels:      []string{discoveryLabel},
+		ListOptions: github.ListOptions{PerPage: 100},
 	}
-	issues, _, err := client.Issues.ListByRepo(ctx, org, repo, &opts)
+	existingPr := (*github.PullRequest)(nil)
+	login, loginRead := "", false
+	var loginErr error
+	for {
+		issues, resp, err := client.Issues.ListByRepo(ctx, org, repo, &opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() {
+				continue
+			}
+			pr, _, err := client.PullRequests.Get(ctx, org, repo, *issue.Number)
+			if err != nil {
+				return nil, err
+			}
+			if pr.GetBase().GetRef() != baseBranch {
+				// PRs for other base branches, e.g. created with -ref, are left as they are
+				continue
+			}
+			if !isBranchNameMatching(pr.GetHead().GetRef(), prParams) {
+				if !loginRead {
+					login, loginErr = ownLogin(client, prParams)
+					loginRead = true
+					if loginErr != nil {
+						log.Printf("WARN  Could not get the authenticated user, not closing superseded PRs (set github-app-slug for GitHub App tokens): %v", loginErr)
+					}
+				}
+				if loginErr == nil && isOwnPr(pr, login) {
+					closeSupersededPr(client, org, repo, pr)
+				}
+			} else if existingPr == nil {
+				existingPr = pr
+			}
+		}
+		if resp.NextPage == 0 {
+			return existingPr, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ownLogin returns the login PRs are opened with: the bot of the GitHub App given by github-app-slug, as installation
+// tokens can't read the authenticated user, or else the user of the token.
+func ownLogin(client *github.Client, prParams config.PullRequestParameters) (string, error) {
+	if prParams.GitHubAppSlug != "" {
+		return prParams.GitHubAppSlug + "[bot]", nil
+	}
+	user, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	existingPrIssue := (*github.Issue)(nil)
-	for _, issue := range issues {
-		if issue.IsPullRequest() {
-			existingPrIssue = issue
-			break
-		}
+	return user.GetLogin(), nil
+}
+
+// isOwnPr returns if a PR has been opened with the login given, from a branch of the repo itself.
+func isOwnPr(pr *github.PullRequest, login string) bool {
+	if login == "" || pr.GetHead().GetRepo() == nil || pr.GetHead().GetRepo().GetFullName() != pr.GetBase().GetRepo().GetFullName() {
+		return false
 	}
-	if existingPrIssue != nil {
-		existingPr, _, err := client.PullRequests.Get(ctx, org, repo, *existingPrIssue.Number)
-		if err != nil {
-			return nil, err
+	return strings.EqualFold(pr.GetUser().GetLogin(), login)
+}
+
+// isBranchNameMatching returns if a branch name has been created using the configured branch name, on this or an
+// earlier run.
+func isBranchNameMatching(branchName string, prParams config.PullRequestParameters) bool {
+	if prParams.BranchNamePattern != "" {
+		pattern := "^(" + prParams.BranchNamePattern + ")$"
+		if prParams.BranchNameRandomSuffix {
+			pattern = "^(" + prParams.BranchNamePattern + ")-.+$"
 		}
-		return existingPr, nil
+		matched, err := regexp.MatchString(pattern, branchName)
+		return err == nil && matched
+	}
+	if prParams.BranchNameRandomSuffix {
+		return strings.HasPrefix(branchName, prParams.BranchName+"-")
+	}
+	return branchName == prParams.BranchName
+}
+
+// closeSupersededPr closes a PR whose branch name doesn't match the configuration anymore, and deletes its branch.
+func closeSupersededPr(client *github.Client, org string, repo string, pr *github.PullRequest) {
+	ctx := context.Background()
+	comment := &github.IssueComment{Body: github.String("Closing this PR, as the branch naming of dependabutler has changed. A new PR will replace it.")}
+	if _, _, err := client.Issues.CreateComment(ctx, org, repo, pr.GetNumber(), comment); err != nil {
+		log.Printf("WARN  Could not comment on superseded PR %v: %v", pr.GetHTMLURL(), err)
+	}
+	if _, _, err := client.PullRequests.Edit(ctx, org, repo, pr.GetNumber(), &github.PullRequest{State: github.String("closed")}); err != nil {
+		log.Printf("WARN  Could not close superseded PR %v: %v", pr.GetHTMLURL(), err)
+		return
+	}
+	log.Printf("INFO  Closed superseded PR %v", pr.GetHTMLURL())
+	if _, err := client.Git.DeleteRef(ctx, org, repo, "heads/"+pr.GetHead().GetRef()); err != nil {
+		log.Printf("WARN  Could not delete branch %v of superseded PR: %v", pr.GetHead().GetRef(), err)
 	}
-	return nil, nil
 }
 
 func getNewBranchName(prParams config.PullRequestParameters) (string, error) {
@@ -279,3 +919,115 @@ func getNewBranchName(prParams config.PullRequestParameters) (string, error) {
 	}
 	return branchName, nil
 }
+
+// CheckRegistrySecretAccess returns a warning for each Dependabot secret referenced by the registries, which is not
+// accessible from the repository: secrets defined neither for the org nor for the repository, and org-level secrets
+// the repository is not granted access to. Dependabot can't authenticate against such a registry. With grant, the
+// repository is added to the selected repositories of an org-level secret instead.
+func CheckRegistrySecretAccess(client *github.Client, org string, repository *github.Repository, registries map[string]config.Registry, grant bool) []string {
+	warnings := make([]string, 0)
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, secretName := range registries[name].SecretReferences() {
+			secret, err := getOrgSecret(client, org, secretName)
+			if err != nil {
+				log.Printf("WARN  Could not check Dependabot secret %v of org %v: %v", secretName, org, err)
+				continue
+			}
+			if !secret.exists {
+				exists, err := repoSecretExists(client, org, repository.GetName(), secretName)
+				if err != nil {
+					log.Printf("WARN  Could not check Dependabot secret %v of repo %v: %v", secretName, repository.GetName(), err)
+					continue
+				}
+				if !exists {
+					warning := fmt.Sprintf("registry `%v` uses the Dependabot secret `%v`, which is defined neither for the org nor for this repository", name, secretName)
+					log.Printf("WARN  %v: %v", repository.GetName(), warning)
+					warnings = append(warnings, warning)
+				}
+				continue
+			}
+			accessible := true
+			switch secret.visibility {
+			case "selected":
+				accessible = util.Contains(secret.selectedRepos, repository.GetName())
+				if !accessible && grant {
+					if err := grantOrgSecretAccess(client, org, secretName, repository); err != nil {
+						log.Printf("WARN  Could not grant repo %v access to Dependabot secret %v: %v", repository.GetName(), secretName, err)
+					} else {
+						log.Printf("INFO  Granted repo %v access to the org-level Dependabot secret %v.", repository.GetName(), secretName)
+						secret.selectedRepos = append(secret.selectedRepos, repository.GetName())
+						accessible = true
+					}
+				}
+			case "private":
+				accessible = repository.GetPrivate()
+			}
+			if !accessible {
+				warning := fmt.Sprintf("registry `%v` uses the org-level Dependabot secret `%v`, which is not accessible from this repository", name, secretName)
+				log.Printf("WARN  %v: %v", repository.GetName(), warning)
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+	return warnings
+}
+
+// repoSecretExists returns if a repository-level Dependabot secret exists.
+func repoSecretExists(client *github.Client, org string, repo string, name string) (bool, error) {
+	if _, _, err := client.Dependabot.GetRepoSecret(context.Background(), org, repo, name); err != nil {
+		if strings.Contains(err.Error(), "404 Not Found") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// grantOrgSecretAccess adds a repository to the selected repositories of an org-level Dependabot secret.
+func grantOrgSecretAccess(client *github.Client, org string, name string, repository *github.Repository) error {
+	_, err := client.Dependabot.AddSelectedRepoToOrgSecret(context.Background(), org, name, repository)
+	return err
+}
+
+func getOrgSecret(client *github.Client, org string, name string) (*orgSecret, error) {
+	cacheKey := org + "/" + name
+	if secret, cached := orgSecrets[cacheKey]; cached {
+		return secret, nil
+	}
+	ctx := context.Background()
+	secret := &orgSecret{}
+	dependabotSecret, _, err := client.Dependabot.GetOrgSecret(ctx, org, name)
+	if err != nil {
+		if !strings.Contains(err.Error(), "404 Not Found") {
+			return nil, err
+		}
+		// no org-level secret, might be defined on repository level
+		orgSecrets[cacheKey] = secret
+		return secret, nil
+	}
+	secret.exists = true
+	secret.visibility = dependabotSecret.Visibility
+	if secret.visibility == "selected" {
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			selectedRepos, resp, err := client.Dependabot.ListSelectedReposForOrgSecret(ctx, org, name, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, repository := range selectedRepos.Repositories {
+				secret.selectedRepos = append(secret.selectedRepos, repository.GetName())
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	orgSecrets[cacheKey] = secret
+	return secret, nil
+}
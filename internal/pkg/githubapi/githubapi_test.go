@@ -0,0 +1,471 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/google/go-github/v50/github"
+)
+
+func TestPrLabels(t *testing.T) {
+	labels := prLabels(config.PullRequestParameters{Labels: []string{"infra", "dependabutler", "automation"}})
+	expected := []string{"dependabutler", "infra", "automation"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("prLabels() failed; expected %v got %v", expected, labels)
+	}
+	pr := &github.PullRequest{Labels: []*github.Label{{Name: github.String("dependabutler")}, {Name: github.String("infra")}}}
+	missing := missingLabels(pr, labels)
+	if !reflect.DeepEqual(missing, []string{"automation"}) {
+		t.Errorf("missingLabels() failed; expected [automation] got %v", missing)
+	}
+}
+
+func TestIsBranchNameMatching(t *testing.T) {
+	for _, tt := range []struct {
+		branchName string
+		prParams   config.PullRequestParameters
+		expected   bool
+	}{
+		{"dependabutler-update", config.PullRequestParameters{BranchName: "dependabutler-update"}, true},
+		{"dependabutler-update-1a2b", config.PullRequestParameters{BranchName: "dependabutler-update", BranchNameRandomSuffix: true}, true},
+		{"dependabutler-2024-03-06", config.PullRequestParameters{BranchName: "dependabutler-2024-03-07", BranchNamePattern: "dependabutler-.+"}, true},
+		{
+			"dependabutler-2024-03-06-1a2b",
+			config.PullRequestParameters{BranchName: "dependabutler-2024-03-07", BranchNamePattern: "dependabutler-.+", BranchNameRandomSuffix: true},
+			true,
+		},
+		{"other-2024-03-06", config.PullRequestParameters{BranchName: "dependabutler-2024-03-07", BranchNamePattern: "dependabutler-.+"}, false},
+		{"dependabutler-update", config.PullRequestParameters{BranchName: "dependabutler-update", BranchNamePattern: "dependabutler-update", BranchNameRandomSuffix: true}, false},
+	} {
+		if got := isBranchNameMatching(tt.branchName, tt.prParams); got != tt.expected {
+			t.Errorf("isBranchNameMatching(%v) failed; expected %t got %t", tt.branchName, tt.expected, got)
+		}
+	}
+}
+
+func TestAPIURLFromEnvironment(t *testing.T) {
+	tests := []struct {
+		apiURL    string
+		serverURL string
+		expected  string
+	}{
+		{"", "", ""},
+		{"https://api.github.com", "https://github.com", ""},
+		{"", "https://github.com", ""},
+		{"https://github.example.com/api/v3", "https://github.example.com", "https://github.example.com/api/v3"},
+		{"", "https://github.example.com/", "https://github.example.com/api/v3/"},
+	}
+	for _, test := range tests {
+		if got := APIURLFromEnvironment(test.apiURL, test.serverURL); got != test.expected {
+			t.Errorf("APIURLFromEnvironment(%v, %v) failed; expected %v got %v", test.apiURL, test.serverURL, test.expected, got)
+		}
+	}
+}
+
+func TestSearchRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "org:acme topic:backend" {
+			t.Errorf("SearchRepos() failed; unexpected query %v", r.URL.Query().Get("q"))
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?q=x&page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`{"total_count": 3, "items": [{"name": "service", "full_name": "acme/service", "owner": {"login": "acme"}},
+  {"name": "fork", "full_name": "other/fork", "owner": {"login": "other"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"total_count": 3, "items": [{"name": "api", "full_name": "acme/api", "owner": {"login": "Acme"}}]}`))
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	repos, err := SearchRepos(client, "acme", "org:acme topic:backend")
+	if err != nil || !reflect.DeepEqual(repos, []string{"service", "api"}) {
+		t.Errorf("SearchRepos() failed; expected [service api], got %v, error %v", repos, err)
+	}
+}
+
+func TestGetBranches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/acme/service/branches" {
+			t.Errorf("GetBranches() failed; unexpected path %v", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"name": "main"}, {"name": "develop"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name": "release/1.0"}]`))
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	branches, err := GetBranches(client, "acme", "service")
+	expected := []string{"main", "develop", "release/1.0"}
+	if err != nil || !reflect.DeepEqual(branches, expected) {
+		t.Errorf("GetBranches() failed; expected %v, got %v, error %v", expected, branches, err)
+	}
+}
+
+func TestGetRepoFileList(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/repos/acme/service/git/trees/main" {
+				t.Errorf("GetRepoFileList() failed; unexpected path %v", r.URL.Path)
+			}
+			_ = json.NewEncoder(w).Encode(github.Tree{
+				Entries:   []*github.TreeEntry{{Path: github.String("app/package.json"), Type: github.String("blob")}},
+				Truncated: github.Bool(truncated),
+			})
+		}))
+		ClearCache()
+		client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+		files, gotTruncated := GetRepoFileList(client, "acme", "service", "main")
+		if !reflect.DeepEqual(files, []string{"app/package.json"}) || gotTruncated != truncated {
+			t.Errorf("GetRepoFileList() failed; expected [app/package.json] and truncated %t, got %v and %t", truncated, files, gotTruncated)
+		}
+		server.Close()
+	}
+	ClearCache()
+}
+
+func TestGetRepoFileListByCommit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/acme/service/git/trees/abc123" {
+			t.Errorf("GetRepoFileList() failed; unexpected path %v", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(github.Tree{
+			Entries: []*github.TreeEntry{{Path: github.String("app/package.json"), Type: github.String("blob")}},
+		})
+	}))
+	defer server.Close()
+	ClearCache()
+	defer ClearCache()
+	fileCache.storeCommitSHA("acme", "service", "main", "abc123")
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	if files, _ := GetRepoFileList(client, "acme", "service", "main"); !reflect.DeepEqual(files, []string{"app/package.json"}) {
+		t.Errorf("GetRepoFileList() failed; expected [app/package.json], got %v", files)
+	}
+}
+
+func TestGetCustomProperties(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/acme/service/properties/values" {
+			t.Errorf("GetCustomProperties() failed; unexpected path %v", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`[{"property_name": "tier", "value": "critical"}, {"property_name": "teams", "value": ["payments", "checkout"]},
+  {"property_name": "unset", "value": null}]`))
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	properties, err := GetCustomProperties(client, "acme", "service")
+	expected := map[string]string{"tier": "critical", "teams": "payments,checkout"}
+	if err != nil || !reflect.DeepEqual(properties, expected) {
+		t.Errorf("GetCustomProperties() failed; expected %v, got %v, error %v", expected, properties, err)
+	}
+}
+
+func TestGetExistingPr(t *testing.T) {
+	closed := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pr := func(number int, head string, user string, userType string, headRepo string) string {
+			return fmt.Sprintf(`{"number": %v, "user": {"login": "%v", "type": "%v"}, "base": {"ref": "main", "repo": {"full_name": "acme/service"}},
+  "head": {"ref": "%v", "repo": {"full_name": "%v"}}}`, number, user, userType, head, headRepo)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v3/repos/acme/service/issues":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[{"number": 1, "pull_request": {"url": "x"}}, {"number": 2, "pull_request": {"url": "x"}}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"number": 3, "pull_request": {"url": "x"}}, {"number": 4, "pull_request": {"url": "x"}},
+  {"number": 5, "pull_request": {"url": "x"}}]`))
+		case "GET /api/v3/user":
+			_, _ = w.Write([]byte(`{"login": "dependabutler-bot"}`))
+		case "GET /api/v3/repos/acme/service/pulls/1":
+			_, _ = w.Write([]byte(pr(1, "dependabutler-old", "dependabutler-bot", "User", "acme/service")))
+		case "GET /api/v3/repos/acme/service/pulls/2":
+			_, _ = w.Write([]byte(pr(2, "fix-deps", "jdoe", "User", "acme/service")))
+		case "GET /api/v3/repos/acme/service/pulls/3":
+			_, _ = w.Write([]byte(pr(3, "dependabutler-old", "dependabutler-bot", "User", "jdoe/service")))
+		case "GET /api/v3/repos/acme/service/pulls/4":
+			_, _ = w.Write([]byte(pr(4, "dependabutler-update", "dependabutler-bot", "User", "acme/service")))
+		case "GET /api/v3/repos/acme/service/pulls/5":
+			_, _ = w.Write([]byte(pr(5, "dependabutler-older", "dependabutler-bot", "User", "acme/service")))
+		case "POST /api/v3/repos/acme/service/issues/1/comments", "POST /api/v3/repos/acme/service/issues/5/comments":
+			_, _ = w.Write([]byte(`{}`))
+		case "PATCH /api/v3/repos/acme/service/pulls/1", "PATCH /api/v3/repos/acme/service/pulls/5":
+			closed = append(closed, strings.TrimPrefix(r.URL.Path, "/api/v3/repos/acme/service/pulls/"))
+			_, _ = w.Write([]byte(`{}`))
+		case "DELETE /api/v3/repos/acme/service/git/refs/heads/dependabutler-old",
+			"DELETE /api/v3/repos/acme/service/git/refs/heads/dependabutler-older":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("getExistingPr() failed; unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	pr, err := getExistingPr(client, "acme", "service", "main", config.PullRequestParameters{BranchName: "dependabutler-update"})
+	if err != nil || pr.GetNumber() != 4 {
+		t.Errorf("getExistingPr() failed; expected PR 4, got %v, error %v", pr.GetNumber(), err)
+	}
+	// PRs of other users and from forks are left as they are
+	if !reflect.DeepEqual(closed, []string{"1", "5"}) {
+		t.Errorf("getExistingPr() failed; expected PRs 1 and 5 to be closed, got %v", closed)
+	}
+}
+
+func TestGetExistingPrGitHubApp(t *testing.T) {
+	for _, tt := range []struct {
+		appSlug  string
+		expected []string
+	}{
+		// without the slug, the user of the installation token can't be read, and no PRs are closed
+		{"", []string{}},
+		{"dependabutler", []string{"1"}},
+	} {
+		closed := make([]string, 0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pr := func(number int, head string, user string) string {
+				return fmt.Sprintf(`{"number": %v, "user": {"login": "%v", "type": "Bot"}, "base": {"ref": "main", "repo": {"full_name": "acme/service"}},
+  "head": {"ref": "%v", "repo": {"full_name": "acme/service"}}}`, number, user, head)
+			}
+			switch r.Method + " " + r.URL.Path {
+			case "GET /api/v3/repos/acme/service/issues":
+				_, _ = w.Write([]byte(`[{"number": 1, "pull_request": {"url": "x"}}, {"number": 2, "pull_request": {"url": "x"}},
+  {"number": 3, "pull_request": {"url": "x"}}]`))
+			case "GET /api/v3/user":
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+			case "GET /api/v3/repos/acme/service/pulls/1":
+				_, _ = w.Write([]byte(pr(1, "dependabutler-old", "dependabutler[bot]")))
+			case "GET /api/v3/repos/acme/service/pulls/2":
+				_, _ = w.Write([]byte(pr(2, "renovate/deps", "renovate[bot]")))
+			case "GET /api/v3/repos/acme/service/pulls/3":
+				_, _ = w.Write([]byte(pr(3, "dependabutler-update", "dependabutler[bot]")))
+			case "POST /api/v3/repos/acme/service/issues/1/comments":
+				_, _ = w.Write([]byte(`{}`))
+			case "PATCH /api/v3/repos/acme/service/pulls/1":
+				closed = append(closed, strings.TrimPrefix(r.URL.Path, "/api/v3/repos/acme/service/pulls/"))
+				_, _ = w.Write([]byte(`{}`))
+			case "DELETE /api/v3/repos/acme/service/git/refs/heads/dependabutler-old":
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				t.Errorf("getExistingPr() failed; unexpected request %v %v", r.Method, r.URL.Path)
+			}
+		}))
+		client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+		prParams := config.PullRequestParameters{BranchName: "dependabutler-update", GitHubAppSlug: tt.appSlug}
+		pr, err := getExistingPr(client, "acme", "service", "main", prParams)
+		if err != nil || pr.GetNumber() != 3 {
+			t.Errorf("getExistingPr() failed; expected PR 3, got %v, error %v", pr.GetNumber(), err)
+		}
+		if !reflect.DeepEqual(closed, tt.expected) {
+			t.Errorf("getExistingPr() with app slug %q failed; expected PRs %v to be closed, got %v", tt.appSlug, tt.expected, closed)
+		}
+		server.Close()
+	}
+}
+
+func TestIsOwnPr(t *testing.T) {
+	pr := func(login string, userType string, headRepo string) *github.PullRequest {
+		return &github.PullRequest{
+			User: &github.User{Login: github.String(login), Type: github.String(userType)},
+			Head: &github.PullRequestBranch{Repo: &github.Repository{FullName: github.String(headRepo)}},
+			Base: &github.PullRequestBranch{Repo: &github.Repository{FullName: github.String("acme/service")}},
+		}
+	}
+	for _, tt := range []struct {
+		pr       *github.PullRequest
+		login    string
+		expected bool
+	}{
+		{pr("dependabutler-bot", "User", "acme/service"), "dependabutler-bot", true},
+		{pr("jdoe", "User", "acme/service"), "dependabutler-bot", false},
+		{pr("dependabutler-bot", "User", "jdoe/service"), "dependabutler-bot", false},
+		{pr("dependabutler[bot]", "Bot", "acme/service"), "dependabutler[bot]", true},
+		{pr("renovate[bot]", "Bot", "acme/service"), "dependabutler[bot]", false},
+		// unknown login
+		{pr("dependabutler[bot]", "Bot", "acme/service"), "", false},
+	} {
+		if got := isOwnPr(tt.pr, tt.login); got != tt.expected {
+			t.Errorf("isOwnPr(%v, %v) failed; expected %t got %t", tt.pr.GetUser().GetLogin(), tt.login, tt.expected, got)
+		}
+	}
+}
+
+func TestCreateOrUpdateIssue(t *testing.T) {
+	edited, created := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/acme/service/issues":
+			_, _ = w.Write([]byte(`[{"number": 3, "title": "proposed changes", "pull_request": {"url": "x"}},
+  {"number": 5, "title": "proposed changes", "body": "old"}]`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v3/repos/acme/service/issues/5":
+			edited++
+			_, _ = w.Write([]byte(`{"number": 5, "title": "proposed changes", "body": "new"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/acme/api/issues":
+			_, _ = w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/acme/api/issues":
+			created++
+			_, _ = w.Write([]byte(`{"number": 1, "title": "proposed changes", "body": "new"}`))
+		default:
+			t.Errorf("CreateOrUpdateIssue() failed; unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+
+	issue, opened, err := CreateOrUpdateIssue(client, "acme", "service", "proposed changes", "new", nil)
+	if err != nil || opened || issue.GetNumber() != 5 || edited != 1 {
+		t.Errorf("CreateOrUpdateIssue() failed; expected issue 5 to be updated, got %v, opened %v, error %v", issue.GetNumber(), opened, err)
+	}
+	issue, opened, err = CreateOrUpdateIssue(client, "acme", "api", "proposed changes", "new", nil)
+	if err != nil || !opened || issue.GetNumber() != 1 || created != 1 {
+		t.Errorf("CreateOrUpdateIssue() failed; expected issue 1 to be opened, got %v, opened %v, error %v", issue.GetNumber(), opened, err)
+	}
+}
+
+func TestCheckRegistrySecretAccess(t *testing.T) {
+	granted := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v3/orgs/acme/dependabot/secrets/NPM_TOKEN":
+			_, _ = w.Write([]byte(`{"name": "NPM_TOKEN", "visibility": "selected"}`))
+		case "GET /api/v3/orgs/acme/dependabot/secrets/NPM_TOKEN/repositories":
+			_, _ = w.Write([]byte(`{"total_count": 1, "repositories": [{"id": 1, "name": "api"}]}`))
+		case "PUT /api/v3/orgs/acme/dependabot/secrets/NPM_TOKEN/repositories/2":
+			granted++
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /api/v3/repos/acme/service/dependabot/secrets/MAVEN_PASSWORD":
+			_, _ = w.Write([]byte(`{"name": "MAVEN_PASSWORD"}`))
+		case "GET /api/v3/orgs/acme/dependabot/secrets/MAVEN_PASSWORD", "GET /api/v3/orgs/acme/dependabot/secrets/DOCKER_TOKEN",
+			"GET /api/v3/repos/acme/service/dependabot/secrets/DOCKER_TOKEN":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		default:
+			t.Errorf("CheckRegistrySecretAccess() failed; unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	orgSecrets = map[string]*orgSecret{}
+	defer func() { orgSecrets = map[string]*orgSecret{} }()
+
+	repository := &github.Repository{ID: github.Int64(2), Name: github.String("service")}
+	registries := map[string]config.Registry{
+		"npm":    {Type: "npm-registry", Token: "${{secrets.NPM_TOKEN}}"},
+		"maven":  {Type: "maven-repository", Password: "${{secrets.MAVEN_PASSWORD}}"},
+		"docker": {Type: "docker-registry", Password: "${{secrets.DOCKER_TOKEN}}"},
+	}
+	expected := []string{
+		"registry `docker` uses the Dependabot secret `DOCKER_TOKEN`, which is defined neither for the org nor for this repository",
+		"registry `npm` uses the org-level Dependabot secret `NPM_TOKEN`, which is not accessible from this repository",
+	}
+	if warnings := CheckRegistrySecretAccess(client, "acme", repository, registries, false); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("CheckRegistrySecretAccess() failed; expected %q, got %q", expected, warnings)
+	}
+	if granted != 0 {
+		t.Errorf("CheckRegistrySecretAccess() failed; expected no access granted, got %v", granted)
+	}
+	if warnings := CheckRegistrySecretAccess(client, "acme", repository, registries, true); !reflect.DeepEqual(warnings, expected[:1]) {
+		t.Errorf("CheckRegistrySecretAccess() failed; expected %q, got %q", expected[:1], warnings)
+	}
+	if granted != 1 {
+		t.Errorf("CheckRegistrySecretAccess() failed; expected access granted once, got %v", granted)
+	}
+}
+
+func TestIsPermissionError(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/repos/acme/service/git/refs", nil)
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden, Request: request}, Message: "Forbidden"}, true},
+		{errors.New("Protected branch update failed for refs/heads/main"), true},
+		{errors.New("Repository rule violations found"), true},
+		{errors.New("Resource not accessible by integration"), true},
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound, Request: request}, Message: "Not Found"}, false},
+		{errors.New("Reference update failed"), false},
+	}
+	for _, tt := range tests {
+		if got := IsPermissionError(tt.err); got != tt.expected {
+			t.Errorf("IsPermissionError(%v) failed; expected %v got %v", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestPRDescriptionDiff(t *testing.T) {
+	current := []byte("version: 2\nupdates: []\n")
+	updated := []byte("version: 2\nupdates:\n  - package-ecosystem: npm\n")
+	expected := `
+<details>
+<summary>diff of .github/dependabot.yml</summary>
+
+` + "```diff" + `
+--- a/.github/dependabot.yml
++++ b/.github/dependabot.yml
+@@ -1,2 +1,3 @@
+ version: 2
+-updates: []
++updates:
++  - package-ecosystem: npm
+` + "```" + `
+
+</details>`
+	if got := PRDescriptionDiff(current, updated); got != expected {
+		t.Errorf("PRDescriptionDiff() failed; expected\n%v\ngot\n%v", expected, got)
+	}
+	if got := PRDescriptionDiff(current, current); got != "" {
+		t.Errorf("PRDescriptionDiff() failed; expected no diff for identical configs, got %v", got)
+	}
+	large := []byte(strings.Repeat("# comment\n", maxPRDiffLength/5))
+	if got := PRDescriptionDiff(nil, large); !strings.Contains(got, "too large") || strings.Contains(got, "```diff") {
+		t.Errorf("PRDescriptionDiff() failed; expected a note instead of a large diff, got %v", got[:100])
+	}
+}
+
+func TestGetTree(t *testing.T) {
+	var request struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string  `json:"path"`
+			Content *string `json:"content"`
+		} `json:"tree"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/repos/acme/service/git/trees" {
+			t.Errorf("getTree() failed; unexpected request %v %v", r.Method, r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_, _ = w.Write([]byte(`{"sha": "t1"}`))
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	ref := &github.Reference{Object: &github.GitObject{SHA: github.String("c1")}}
+	files := []FileChange{
+		{Path: ConfigPath, Content: "version: 2\n"},
+		{Path: ".github/workflows/auto-merge.yml", Content: "name: auto-merge\n"},
+		{Path: "renovate.json", Delete: true},
+	}
+	tree, err := getTree(client, ref, "acme", "service", files)
+	if err != nil || tree.GetSHA() != "t1" {
+		t.Fatalf("getTree() failed; unexpected tree %v, error %v", tree, err)
+	}
+	if request.BaseTree != "c1" || len(request.Tree) != 3 {
+		t.Fatalf("getTree() failed; unexpected request %+v", request)
+	}
+	for i, file := range files {
+		entry := request.Tree[i]
+		if entry.Path != file.Path || (entry.Content == nil) != file.Delete || (entry.Content != nil && *entry.Content != file.Content) {
+			t.Errorf("getTree() failed; expected entry %+v, got %+v", file, entry)
+		}
+	}
+}
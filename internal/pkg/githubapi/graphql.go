@@ -0,0 +1,64 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// graphQLResponse holds the response of a GraphQL request.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQL sends a GraphQL request to the API the client is connected to, and decodes the response data into result.
+func graphQL(client *github.Client, query string, variables map[string]interface{}, result interface{}) error {
+	// github.com serves GraphQL at /graphql, GitHub Enterprise Server at /api/graphql (next to /api/v3/)
+	endpoint := "graphql"
+	if strings.HasSuffix(client.BaseURL.Path, "/api/v3/") {
+		endpoint = "../graphql"
+	}
+	body := map[string]interface{}{"query": query, "variables": variables}
+	req, err := client.NewRequest("POST", endpoint, body)
+	if err != nil {
+		return err
+	}
+	var response graphQLResponse
+	if _, err := client.Do(context.Background(), req, &response); err != nil {
+		return err
+	}
+	// partial data is kept in the result, e.g. for queries of several repos with some of them not found
+	if result != nil && len(response.Data) > 0 && string(response.Data) != "null" {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return err
+		}
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, graphQLError := range response.Errors {
+			messages = append(messages, graphQLError.Message)
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// enableAutoMerge enables auto-merge for a PR, using the given merge method (merge, squash or rebase).
+func enableAutoMerge(client *github.Client, pr *github.PullRequest, mergeMethod string) error {
+	query := `mutation($pullRequestId: ID!, $mergeMethod: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $pullRequestId, mergeMethod: $mergeMethod}) {
+    clientMutationId
+  }
+}`
+	variables := map[string]interface{}{
+		"pullRequestId": pr.GetNodeID(),
+		"mergeMethod":   strings.ToUpper(mergeMethod),
+	}
+	return graphQL(client, query, variables, nil)
+}
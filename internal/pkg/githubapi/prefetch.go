@@ -0,0 +1,183 @@
+package githubapi
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	// prefetchBatchSize is the number of repos fetched per GraphQL query.
+	prefetchBatchSize = 50
+	// prefetchConcurrency is the number of GraphQL queries run in parallel.
+	prefetchConcurrency = 4
+)
+
+// PrefetchedRepo holds what the prefetch found out about a repo, for skipping and ordering the repos.
+type PrefetchedRepo struct {
+	Name string
+	// Found is false for repos failing to be fetched, e.g. missing ones.
+	Found     bool
+	Archived  bool
+	Empty     bool
+	HasConfig bool
+	// Size is the disk usage of the repo in KB, as estimate of the cost of processing it.
+	Size int
+}
+
+// skipped returns if the repo is skipped without further API calls.
+func (repo PrefetchedRepo) skipped() bool {
+	return !repo.Found || repo.Archived || repo.Empty
+}
+
+// prefetchRepo holds the data of a repo returned by the GraphQL API. Its visibility is PUBLIC, PRIVATE or INTERNAL.
+type prefetchRepo struct {
+	Name             string     `json:"name"`
+	IsArchived       bool       `json:"isArchived"`
+	IsPrivate        bool       `json:"isPrivate"`
+	URL              string     `json:"url"`
+	DiskUsage        int        `json:"diskUsage"`
+	Visibility       string     `json:"visibility"`
+	PushedAt         *time.Time `json:"pushedAt"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	DefaultBranchRef *struct {
+		Name   string `json:"name"`
+		Target struct {
+			Oid string `json:"oid"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+	Config     *prefetchBlob `json:"config"`
+	Gitmodules *prefetchBlob `json:"gitmodules"`
+}
+
+// prefetchBlob holds the content of a file returned by the GraphQL API.
+type prefetchBlob struct {
+	Text        *string `json:"text"`
+	IsTruncated bool    `json:"isTruncated"`
+}
+
+// PrefetchRepos fetches the metadata (archived, default branch and its commit, and what the repo filter checks),
+// dependabot.yml and .gitmodules of repos in batched GraphQL queries run in parallel, and keeps them in the cache.
+// Instead of several REST calls per repo, one query per batch is needed. The repo tree is still listed via REST, except if it's in the disk cache for the commit
+// already. Repos failing to be fetched, e.g. missing ones, are left to the REST calls.
+// Returned is what has been found out about the repos, in the order given.
+func PrefetchRepos(client *github.Client, org string, repos []string) []PrefetchedRepo {
+	prefetched := make([]PrefetchedRepo, len(repos))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, prefetchConcurrency)
+	for start := 0; start < len(repos); start += prefetchBatchSize {
+		end := min(start+prefetchBatchSize, len(repos))
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(start int, end int) {
+			defer func() { <-slots; wg.Done() }()
+			batch := repos[start:end]
+			query, variables := prefetchQuery(org, batch)
+			result := map[string]*prefetchRepo{}
+			if err := graphQL(client, query, variables, &result); err != nil {
+				log.Printf("WARN  Could not prefetch all repos %v to %v: %v", start+1, end, err)
+			}
+			for i, repo := range batch {
+				prefetched[start+i] = PrefetchedRepo{Name: repo}
+				if data := result[fmt.Sprintf("r%v", i)]; data != nil {
+					fileCache.storePrefetched(org, repo, data)
+					prefetched[start+i] = PrefetchedRepo{
+						Name: repo, Found: true, Archived: data.IsArchived, Empty: data.DefaultBranchRef == nil,
+						HasConfig: data.Config != nil, Size: data.DiskUsage,
+					}
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return prefetched
+}
+
+// OrderByCost returns the names of the prefetched repos, ordered by the expected cost of processing them: the ones
+// skipped without further API calls (missing, archived or empty) first, then the others by size.
+func OrderByCost(prefetched []PrefetchedRepo) []string {
+	ordered := make([]PrefetchedRepo, len(prefetched))
+	copy(ordered, prefetched)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].skipped() != ordered[j].skipped() {
+			return ordered[i].skipped()
+		}
+		return ordered[i].Size < ordered[j].Size
+	})
+	names := make([]string, 0, len(ordered))
+	for _, repo := range ordered {
+		names = append(names, repo.Name)
+	}
+	return names
+}
+
+// prefetchQuery returns the GraphQL query for a batch of repos, with one aliased repository field per repo.
+func prefetchQuery(org string, repos []string) (string, map[string]interface{}) {
+	variables := map[string]interface{}{"owner": org}
+	declarations := []string{"$owner: String!"}
+	fields := make([]string, 0, len(repos))
+	for i, repo := range repos {
+		variables[fmt.Sprintf("name%v", i)] = repo
+		declarations = append(declarations, fmt.Sprintf("$name%v: String!", i))
+		fields = append(fields, fmt.Sprintf(`r%v: repository(owner: $owner, name: $name%v) {
+    name isArchived isPrivate url diskUsage visibility pushedAt
+    repositoryTopics(first: 100) { nodes { topic { name } } }
+    primaryLanguage { name }
+    defaultBranchRef { name target { oid } }
+    config: object(expression: "HEAD:.github/dependabot.yml") { ... on Blob { text isTruncated } }
+    gitmodules: object(expression: "HEAD:.gitmodules") { ... on Blob { text isTruncated } }
+  }`, i, i))
+	}
+	return fmt.Sprintf("query(%v) {\n  %v\n}", strings.Join(declarations, ", "), strings.Join(fields, "\n  ")), variables
+}
+
+// storePrefetched keeps the repo metadata, including what the repo filter checks, and the file contents fetched via
+// GraphQL.
+// Empty repos are left to the REST calls, which report them as such.
+func (c *cache) storePrefetched(org string, repo string, data *prefetchRepo) {
+	if data.DefaultBranchRef == nil {
+		return
+	}
+	branch := data.DefaultBranchRef.Name
+	repository := &github.Repository{
+		Name: github.String(data.Name), Archived: github.Bool(data.IsArchived), Private: github.Bool(data.IsPrivate),
+		DefaultBranch: github.String(branch), HTMLURL: github.String(data.URL), Size: github.Int(data.DiskUsage),
+		Visibility: github.String(strings.ToLower(data.Visibility)), Topics: make([]string, 0, len(data.RepositoryTopics.Nodes)),
+	}
+	for _, node := range data.RepositoryTopics.Nodes {
+		repository.Topics = append(repository.Topics, node.Topic.Name)
+	}
+	if data.PrimaryLanguage != nil {
+		repository.Language = github.String(data.PrimaryLanguage.Name)
+	}
+	if data.PushedAt != nil {
+		repository.PushedAt = &github.Timestamp{Time: *data.PushedAt}
+	}
+	c.mutex.Lock()
+	c.repos[org+"/"+repo] = repository
+	c.commits[refKey(org, repo, branch)] = data.DefaultBranchRef.Target.Oid
+	c.mutex.Unlock()
+	for file, blob := range map[string]*prefetchBlob{".github/dependabot.yml": data.Config, ".gitmodules": data.Gitmodules} {
+		switch {
+		case blob == nil:
+			// missing file
+			c.storeContent(org, repo, "", file, nil)
+		case blob.Text != nil && !blob.IsTruncated:
+			c.storeContent(org, repo, "", file, []byte(*blob.Text))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+)
+
+func TestPrefetchRepos(t *testing.T) {
+	fileCache = newCache()
+	defer func() { fileCache = newCache() }()
+
+	var variables map[string]interface{}
+	restRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			restRequests++
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		variables = body.Variables
+		if !strings.Contains(body.Query, "r1: repository(owner: $owner, name: $name1)") {
+			t.Errorf("PrefetchRepos() failed; unexpected query %v", body.Query)
+		}
+		_, _ = w.Write([]byte(`{"data": {
+  "r0": {"name": "service", "isArchived": false, "isPrivate": true,
+    "defaultBranchRef": {"name": "main", "target": {"oid": "c1"}},
+    "config": {"text": "version: 2\n", "isTruncated": false}, "gitmodules": null},
+  "r1": null
+}, "errors": [{"message": "Could not resolve to a Repository with the name 'acme/missing'."}]}`))
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+
+	prefetched := PrefetchRepos(client, "acme", []string{"service", "missing"})
+	expected := []PrefetchedRepo{{Name: "service", Found: true, HasConfig: true}, {Name: "missing"}}
+	if !reflect.DeepEqual(prefetched, expected) {
+		t.Errorf("PrefetchRepos() failed; expected %+v, got %+v", expected, prefetched)
+	}
+	if variables["owner"] != "acme" || variables["name0"] != "service" || variables["name1"] != "missing" {
+		t.Errorf("PrefetchRepos() failed; unexpected variables %v", variables)
+	}
+	repository, err := GetRepository(client, "acme", "service")
+	if err != nil || repository.GetDefaultBranch() != "main" || !repository.GetPrivate() || repository.GetArchived() {
+		t.Errorf("GetRepository() failed; unexpected repository %v, error %v", repository, err)
+	}
+	content, err := GetFileContent(client, "acme", "service", ".github/dependabot.yml", "")
+	if err != nil || string(content) != "version: 2\n" {
+		t.Errorf("GetFileContent() failed; unexpected content %v, error %v", string(content), err)
+	}
+	if content, err := GetFileContent(client, "acme", "service", ".gitmodules", ""); err != nil || content != nil {
+		t.Errorf("GetFileContent() failed; expected missing file, got %v, error %v", string(content), err)
+	}
+	if fileCache.getCommitSHA("acme", "service", "main") != "c1" {
+		t.Errorf("PrefetchRepos() failed; commit of default branch not cached")
+	}
+	if restRequests != 0 {
+		t.Errorf("PrefetchRepos() failed; expected no REST requests for the prefetched repo, got %v", restRequests)
+	}
+	// repos not found are left to REST
+	if _, err := GetRepository(client, "acme", "missing"); err == nil || restRequests != 1 {
+		t.Errorf("GetRepository() failed; expected REST request for missing repo, got %v requests, error %v", restRequests, err)
+	}
+}
+
+func TestOrderByCost(t *testing.T) {
+	got := OrderByCost([]PrefetchedRepo{
+		{Name: "monolith", Found: true, HasConfig: true, Size: 900000},
+		{Name: "service", Found: true, Size: 2000},
+		{Name: "legacy", Found: true, Archived: true, Size: 50000},
+		{Name: "missing"},
+		{Name: "library", Found: true, HasConfig: true, Size: 300},
+		{Name: "new", Found: true, Empty: true},
+	})
+	expected := []string{"missing", "new", "legacy", "library", "service", "monolith"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("OrderByCost() failed; expected %v, got %v", expected, got)
+	}
+}
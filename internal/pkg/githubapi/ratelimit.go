@@ -0,0 +1,249 @@
+package githubapi
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries is the number of retries of a request hitting a rate limit.
+	maxRateLimitRetries = 3
+	// maxTransientRetries is the number of retries of a request failing transiently.
+	maxTransientRetries = 3
+	// transientBackoff is the wait before the first retry of a transient failure.
+	transientBackoff = 2 * time.Second
+	// secondaryRateLimitWait is the wait after a secondary rate limit without Retry-After header, as recommended by GitHub.
+	secondaryRateLimitWait = time.Minute
+	// minAdaptiveDelay is the delay between repos after the first secondary rate limit, doubled by each further one.
+	minAdaptiveDelay = 5 * time.Second
+	// maxAdaptiveDelay caps the delay between repos.
+	maxAdaptiveDelay = 2 * time.Minute
+)
+
+// RateLimitStats holds the wall time spent waiting for GitHub API rate limits, and the number of requests sent,
+// retries included.
+type RateLimitStats struct {
+	Requests      int
+	Waits         int
+	PrimaryWait   time.Duration
+	SecondaryWait time.Duration
+	PRActionWait  time.Duration
+	AdaptiveWait  time.Duration
+}
+
+// Total returns the total wall time spent waiting.
+func (stats RateLimitStats) Total() time.Duration {
+	return stats.PrimaryWait + stats.SecondaryWait + stats.PRActionWait + stats.AdaptiveWait
+}
+
+var (
+	rateLimitStats RateLimitStats
+	rateLimitMutex sync.Mutex
+	// rateRemaining and rateReset hold the primary rate limit state of the last response, -1 if unknown.
+	rateRemaining = -1
+	rateReset     time.Time
+	// adaptiveDelay is the delay between repos, raised by secondary rate limits, and secondaryHit tells if one has
+	// been hit since the last repo.
+	adaptiveDelay time.Duration
+	secondaryHit  bool
+	// sleep is used for waiting, replaceable in tests.
+	sleep = time.Sleep
+)
+
+// GetRateLimitStats returns the time spent waiting for rate limits so far.
+func GetRateLimitStats() RateLimitStats {
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	return rateLimitStats
+}
+
+// rateLimitTransport waits before requests once the remaining primary rate limit falls below the buffer,
+// retries requests hitting a secondary rate limit after the time requested by GitHub,
+// and retries idempotent requests failing transiently (network errors, 5xx gateway responses) with exponential backoff.
+// Other requests, like creating a PR, may have succeeded despite the failure, and are not repeated.
+type rateLimitTransport struct {
+	base   http.RoundTripper
+	buffer int
+}
+
+// RoundTrip executes a request, waiting for rate limits if needed.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.bufferWait(); wait > 0 {
+		log.Printf("INFO  Rate limit buffer of %v requests reached, waiting %v.", t.buffer, wait.Round(time.Second))
+		waitForRateLimit(wait, &rateLimitStats.PrimaryWait)
+	}
+	// requests with a body can only be repeated if the body can be read again
+	replayable := req.Body == nil || req.GetBody != nil
+	retryTransient := replayable && isIdempotent(req.Method)
+	for attempt := 0; ; attempt++ {
+		countRequest()
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			if attempt >= maxTransientRetries || !retryTransient || req.Context().Err() != nil {
+				return resp, err
+			}
+			wait := backoffWait(attempt)
+			log.Printf("WARN  Request %v %v failed: %v, retrying in %v.", req.Method, req.URL.Path, err, wait)
+			sleep(wait)
+		} else {
+			updateRateState(resp)
+			wait, secondary := retryWait(resp)
+			if secondary {
+				increaseAdaptiveDelay()
+			}
+			switch {
+			case wait > 0 && attempt < maxRateLimitRetries && replayable:
+				resp.Body.Close()
+				if secondary {
+					log.Printf("WARN  Secondary rate limit hit, waiting %v.", wait.Round(time.Second))
+					waitForRateLimit(wait, &rateLimitStats.SecondaryWait)
+				} else {
+					log.Printf("WARN  Rate limit exceeded, waiting %v.", wait.Round(time.Second))
+					waitForRateLimit(wait, &rateLimitStats.PrimaryWait)
+				}
+			case wait <= 0 && isTransientStatus(resp.StatusCode) && attempt < maxTransientRetries && retryTransient:
+				resp.Body.Close()
+				wait = backoffWait(attempt)
+				log.Printf("WARN  Request %v %v failed with status %v, retrying in %v.", req.Method, req.URL.Path, resp.StatusCode, wait)
+				sleep(wait)
+			default:
+				return resp, nil
+			}
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// countRequest counts a request sent to the API.
+func countRequest() {
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	rateLimitStats.Requests++
+}
+
+// isIdempotent returns if a request method can be repeated without further effect, if the first attempt succeeded.
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+}
+
+// isTransientStatus returns if a response status is likely to succeed on retry.
+func isTransientStatus(status int) bool {
+	return status == http.StatusInternalServerError || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// backoffWait returns the time to wait before retrying a transient failure, doubling with each attempt.
+func backoffWait(attempt int) time.Duration {
+	return transientBackoff << attempt
+}
+
+// bufferWait returns how long to wait until the rate limit resets, if the remaining requests fell below the buffer.
+func (t *rateLimitTransport) bufferWait() time.Duration {
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	if t.buffer <= 0 || rateRemaining < 0 || rateRemaining >= t.buffer {
+		return 0
+	}
+	// the state is outdated after the reset
+	rateRemaining = -1
+	return time.Until(rateReset)
+}
+
+// updateRateState keeps the primary rate limit state of a response.
+func updateRateState(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	rateRemaining = remaining
+	rateReset = time.Unix(reset, 0)
+}
+
+// retryWait returns how long to wait before retrying a request rejected due to a rate limit, and if it's a secondary one.
+func retryWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(retryAfter) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			return time.Until(time.Unix(reset, 0)), false
+		}
+	}
+	if isSecondaryRateLimit(resp) {
+		return secondaryRateLimitWait, true
+	}
+	return 0, false
+}
+
+// isSecondaryRateLimit checks the message of a rejected response for a secondary rate limit (formerly abuse detection).
+// The body is restored, so it can still be read by the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+// increaseAdaptiveDelay doubles the delay between repos, after a secondary rate limit.
+func increaseAdaptiveDelay() {
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	adaptiveDelay = min(max(2*adaptiveDelay, minAdaptiveDelay), maxAdaptiveDelay)
+	secondaryHit = true
+}
+
+// ThrottleBetweenRepos waits the adaptive delay before processing the next repo. The delay is raised by secondary rate
+// limits, which are hit by bursts of requests like PR creations, and decays by a quarter per repo processed without
+// hitting one, until it's gone.
+func ThrottleBetweenRepos() {
+	rateLimitMutex.Lock()
+	delay := adaptiveDelay
+	if !secondaryHit {
+		adaptiveDelay = adaptiveDelay * 3 / 4
+		if adaptiveDelay < time.Second {
+			adaptiveDelay = 0
+		}
+	}
+	secondaryHit = false
+	rateLimitMutex.Unlock()
+	if delay > 0 {
+		log.Printf("INFO  Throttling after secondary rate limits, waiting %v before the next repo.", delay.Round(time.Second))
+		waitForRateLimit(delay, &rateLimitStats.AdaptiveWait)
+	}
+}
+
+// waitForRateLimit sleeps, and adds the time to the given statistics value.
+func waitForRateLimit(wait time.Duration, total *time.Duration) {
+	sleep(wait)
+	rateLimitMutex.Lock()
+	defer rateLimitMutex.Unlock()
+	rateLimitStats.Waits++
+	*total += wait
+}
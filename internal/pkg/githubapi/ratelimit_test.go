@@ -0,0 +1,154 @@
+package githubapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+	rateLimitStats = RateLimitStats{}
+
+	requests := 0
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-RateLimit-Reset", reset)
+		switch {
+		case requests == 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+		case string(body) != "payload":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Header().Set("X-RateLimit-Remaining", "5")
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport, buffer: 10}}
+	// secondary rate limit: retried after the requested time, with the same body
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("RoundTrip() failed; unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 2 {
+		t.Errorf("RoundTrip() failed; expected status 200 after 2 requests, got %v after %v", resp.StatusCode, requests)
+	}
+	// remaining requests below the buffer: wait for the reset first
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("RoundTrip() failed; unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if len(waits) != 2 || waits[0] != 7*time.Second || waits[1] < 59*time.Minute {
+		t.Errorf("RoundTrip() failed; unexpected waits %v", waits)
+	}
+	stats := GetRateLimitStats()
+	if stats.Requests != 3 || stats.Waits != 2 || stats.SecondaryWait != 7*time.Second || stats.PrimaryWait < 59*time.Minute {
+		t.Errorf("GetRateLimitStats() failed; unexpected stats %v", stats)
+	}
+}
+
+func TestRateLimitTransportTransient(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case requests == 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case requests == 2:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+		case string(body) != "payload":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport}}
+	// gateway errors: retried with backoff, secondary rate limit without Retry-After: retried after a minute,
+	// until the retries are used up
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() failed; unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || requests != 4 {
+		t.Errorf("RoundTrip() failed; expected status 503 after 4 requests, got %v after %v", resp.StatusCode, requests)
+	}
+	expected := []time.Duration{2 * time.Second, time.Minute, 8 * time.Second}
+	if !reflect.DeepEqual(waits, expected) {
+		t.Errorf("RoundTrip() failed; expected waits %v, got %v", expected, waits)
+	}
+	// gateway errors of requests which aren't idempotent are not retried, as they may have succeeded
+	requests = 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("RoundTrip() failed; unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || requests != 1 || len(waits) != 3 {
+		t.Errorf("RoundTrip() failed; expected status 502 after 1 request, got %v after %v", resp.StatusCode, requests)
+	}
+	// permission errors are not retried, and their message can still be read
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("denied"))
+	})
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("RoundTrip() failed; unexpected error %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || string(body) != "denied" || len(waits) != 3 {
+		t.Errorf("RoundTrip() failed; expected status 403 with message, got %v %v", resp.StatusCode, string(body))
+	}
+}
+
+func TestThrottleBetweenRepos(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep; adaptiveDelay = 0; secondaryHit = false }()
+	rateLimitStats = RateLimitStats{}
+	adaptiveDelay, secondaryHit = 0, false
+
+	ThrottleBetweenRepos()
+	increaseAdaptiveDelay()
+	ThrottleBetweenRepos()
+	increaseAdaptiveDelay()
+	ThrottleBetweenRepos()
+	ThrottleBetweenRepos()
+	ThrottleBetweenRepos()
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 10 * time.Second, 7500 * time.Millisecond}
+	if !reflect.DeepEqual(waits, expected) {
+		t.Errorf("ThrottleBetweenRepos() failed; expected waits %v, got %v", expected, waits)
+	}
+	if stats := GetRateLimitStats(); stats.AdaptiveWait != 32500*time.Millisecond {
+		t.Errorf("ThrottleBetweenRepos() failed; expected the waits in the statistics, got %v", stats.AdaptiveWait)
+	}
+}
@@ -0,0 +1,74 @@
+package githubapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// Security features of a repo, as reported when enabled.
+const (
+	FeatureVulnerabilityAlerts = "dependabot alerts"
+	FeatureSecurityUpdates     = "dependabot security updates"
+)
+
+// automatedSecurityFixes holds the status of the dependabot security updates of a repo.
+type automatedSecurityFixes struct {
+	Enabled bool `json:"enabled"`
+}
+
+// EnsureSecurityFeatures enables the dependabot alerts and security updates of a repo, if disabled, and returns
+// the features which had to be enabled. With enable false, the features are only checked, and the ones which would
+// be enabled are returned.
+func EnsureSecurityFeatures(client *github.Client, org string, repo string, enable bool) ([]string, error) {
+	ctx := context.Background()
+	missing := make([]string, 0)
+	alerts, _, err := client.Repositories.GetVulnerabilityAlerts(ctx, org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("could not get status of dependabot alerts: %w", err)
+	}
+	if !alerts {
+		missing = append(missing, FeatureVulnerabilityAlerts)
+	}
+	securityUpdates, err := getAutomatedSecurityFixes(client, org, repo)
+	if err != nil {
+		return nil, fmt.Errorf("could not get status of dependabot security updates: %w", err)
+	}
+	if !securityUpdates {
+		missing = append(missing, FeatureSecurityUpdates)
+	}
+	if !enable {
+		return missing, nil
+	}
+	// security updates require the alerts to be enabled first
+	if !alerts {
+		if _, err := client.Repositories.EnableVulnerabilityAlerts(ctx, org, repo); err != nil {
+			return nil, fmt.Errorf("could not enable dependabot alerts: %w", err)
+		}
+	}
+	if !securityUpdates {
+		if _, err := client.Repositories.EnableAutomatedSecurityFixes(ctx, org, repo); err != nil {
+			return missing[:len(missing)-1], fmt.Errorf("could not enable dependabot security updates: %w", err)
+		}
+	}
+	return missing, nil
+}
+
+// getAutomatedSecurityFixes returns if the dependabot security updates of a repo are enabled. Older versions of
+// GitHub Enterprise Server answer with 404 Not Found if they are disabled.
+func getAutomatedSecurityFixes(client *github.Client, org string, repo string) (bool, error) {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/automated-security-fixes", org, repo), nil)
+	if err != nil {
+		return false, err
+	}
+	status := &automatedSecurityFixes{}
+	if _, err := client.Do(context.Background(), req, status); err != nil {
+		if strings.Contains(err.Error(), "404 Not Found") {
+			return false, nil
+		}
+		return false, err
+	}
+	return status.Enabled, nil
+}
@@ -0,0 +1,66 @@
+package githubapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+)
+
+func TestEnsureSecurityFeatures(t *testing.T) {
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v3/repos/acme/service/vulnerability-alerts", "GET /api/v3/repos/acme/api/vulnerability-alerts":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		case "GET /api/v3/repos/acme/service/automated-security-fixes":
+			_, _ = w.Write([]byte(`{"enabled": false, "paused": false}`))
+		case "GET /api/v3/repos/acme/api/automated-security-fixes":
+			_, _ = w.Write([]byte(`{"enabled": true, "paused": false}`))
+		case "GET /api/v3/repos/acme/secure/vulnerability-alerts":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /api/v3/repos/acme/secure/automated-security-fixes":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		case "PUT /api/v3/repos/acme/service/vulnerability-alerts", "PUT /api/v3/repos/acme/service/automated-security-fixes",
+			"PUT /api/v3/repos/acme/secure/automated-security-fixes":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("EnsureSecurityFeatures() failed; unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	client, _ := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+
+	for _, tt := range []struct {
+		repo             string
+		enable           bool
+		expected         []string
+		expectedRequests []string
+	}{
+		{"service", true, []string{FeatureVulnerabilityAlerts, FeatureSecurityUpdates}, []string{
+			"GET /api/v3/repos/acme/service/vulnerability-alerts", "GET /api/v3/repos/acme/service/automated-security-fixes",
+			"PUT /api/v3/repos/acme/service/vulnerability-alerts", "PUT /api/v3/repos/acme/service/automated-security-fixes",
+		}},
+		{"api", false, []string{FeatureVulnerabilityAlerts}, []string{
+			"GET /api/v3/repos/acme/api/vulnerability-alerts", "GET /api/v3/repos/acme/api/automated-security-fixes",
+		}},
+		{"secure", true, []string{FeatureSecurityUpdates}, []string{
+			"GET /api/v3/repos/acme/secure/vulnerability-alerts", "GET /api/v3/repos/acme/secure/automated-security-fixes",
+			"PUT /api/v3/repos/acme/secure/automated-security-fixes",
+		}},
+	} {
+		requests = requests[:0]
+		enabled, err := EnsureSecurityFeatures(client, "acme", tt.repo, tt.enable)
+		if err != nil || !reflect.DeepEqual(tt.expected, enabled) {
+			t.Errorf("EnsureSecurityFeatures(%v) failed; expected %v, got %v, error %v", tt.repo, tt.expected, enabled, err)
+		}
+		if !reflect.DeepEqual(tt.expectedRequests, requests) {
+			t.Errorf("EnsureSecurityFeatures(%v) failed; expected requests %v, got %v", tt.repo, tt.expectedRequests, requests)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package githubapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/google/go-github/v50/github"
+)
+
+// Values of pull-request-parameters.commit-signing.
+const (
+	// CommitSigningGPG signs commits with the GPG key in credentials.gpg-private-key.
+	CommitSigningGPG = "gpg"
+	// CommitSigningAPI creates commits using the GraphQL API, which GitHub signs on behalf of the token's user or app.
+	CommitSigningAPI = "api"
+)
+
+// ReadSigningKey reads an armored private GPG key, decrypting it with the passphrase if it's encrypted.
+func ReadSigningKey(armoredKey string, passphrase string) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, errors.New("no private key found")
+	}
+	key := entities[0]
+	if key.PrivateKey.Encrypted {
+		if err := key.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+			return nil, err
+		}
+	}
+	for _, subkey := range key.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return key, nil
+}
+
+// commitOnBranch commits file changes to a branch using the GraphQL API, which signs the commit.
+// The author is the user or app of the token, the configured author is not used.
+func commitOnBranch(client *github.Client, org string, repo string, branchName string, expectedHeadOid string,
+	files []FileChange, commitMessage string,
+) error {
+	query := `mutation($input: CreateCommitOnBranchInput!) {
+  createCommitOnBranch(input: $input) {
+    commit {
+      oid
+    }
+  }
+}`
+	headline, body, _ := strings.Cut(commitMessage, "\n")
+	additions, deletions := make([]map[string]interface{}, 0), make([]map[string]interface{}, 0)
+	for _, file := range files {
+		if file.Delete {
+			deletions = append(deletions, map[string]interface{}{"path": file.Path})
+		} else {
+			additions = append(additions, map[string]interface{}{"path": file.Path, "contents": base64.StdEncoding.EncodeToString([]byte(file.Content))})
+		}
+	}
+	fileChanges := map[string]interface{}{"additions": additions}
+	if len(deletions) > 0 {
+		fileChanges["deletions"] = deletions
+	}
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{
+			"branch": map[string]interface{}{
+				"repositoryNameWithOwner": org + "/" + repo,
+				"branchName":              branchName,
+			},
+			"message": map[string]interface{}{
+				"headline": headline,
+				"body":     strings.TrimSpace(body),
+			},
+			"fileChanges":     fileChanges,
+			"expectedHeadOid": expectedHeadOid,
+		},
+	}
+	return graphQL(client, query, variables, nil)
+}
@@ -0,0 +1,68 @@
+package githubapi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestReadSigningKey(t *testing.T) {
+	entity, err := openpgp.NewEntity("dependabutler", "", "dependabutler@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	armorKey := func(passphrase string) string {
+		var buf bytes.Buffer
+		w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := entity.SerializePrivate(w, nil); err != nil {
+			t.Fatal(err)
+		}
+		if passphrase != "" {
+			// serialize again, with the keys encrypted
+			buf.Reset()
+			if w, err = armor.Encode(&buf, openpgp.PrivateKeyType, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := entity.PrivateKey.Encrypt([]byte(passphrase)); err != nil {
+				t.Fatal(err)
+			}
+			for _, subkey := range entity.Subkeys {
+				if err := subkey.PrivateKey.Encrypt([]byte(passphrase)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+		return buf.String()
+	}
+
+	plainKey := armorKey("")
+	encryptedKey := armorKey("secret")
+	for _, tt := range []struct {
+		key         string
+		passphrase  string
+		expectedErr bool
+	}{
+		{plainKey, "", false},
+		{encryptedKey, "secret", false},
+		{encryptedKey, "wrong", true},
+		{"no key", "", true},
+	} {
+		key, err := ReadSigningKey(tt.key, tt.passphrase)
+		if (err != nil) != tt.expectedErr {
+			t.Errorf("ReadSigningKey() failed; expected error %t got %v", tt.expectedErr, err)
+			continue
+		}
+		if err == nil && (key.PrivateKey.Encrypted || key.PrimaryIdentity().UserId.Email != "dependabutler@example.com") {
+			t.Errorf("ReadSigningKey() failed; expected decrypted key of dependabutler@example.com")
+		}
+	}
+}
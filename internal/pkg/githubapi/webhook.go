@@ -0,0 +1,49 @@
+package githubapi
+
+import (
+	"net/http"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/google/go-github/v50/github"
+)
+
+// WebhookRepo holds a repo to be processed, due to a webhook event.
+type WebhookRepo struct {
+	Org    string
+	Repo   string
+	Reason string
+}
+
+// ParseWebhook verifies the signature of a webhook request with the secret, and returns the repo to be processed,
+// or nil if the event doesn't require processing: repositories created, and pushes to the default branch changing
+// manifest files or dependabot.yml.
+func ParseWebhook(r *http.Request, secret string) (*WebhookRepo, error) {
+	payload, err := github.ValidatePayload(r, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		return nil, err
+	}
+	switch event := event.(type) {
+	case *github.RepositoryEvent:
+		if event.GetAction() == "created" {
+			return &WebhookRepo{Org: event.GetRepo().GetOwner().GetLogin(), Repo: event.GetRepo().GetName(), Reason: "repository created"}, nil
+		}
+	case *github.PushEvent:
+		if event.GetRef() != "refs/heads/"+event.GetRepo().GetDefaultBranch() {
+			return nil, nil
+		}
+		for _, commit := range event.Commits {
+			for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+				for _, file := range files {
+					if file == ".github/dependabot.yml" || config.GetManifestType(file) != "" {
+						return &WebhookRepo{Org: event.GetRepo().GetOwner().GetLogin(), Repo: event.GetRepo().GetName(), Reason: "push changing " + file}, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,54 @@
+package githubapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func webhookRequest(event string, payload string, secret string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestParseWebhook(t *testing.T) {
+	toolConfig := config.ToolConfig{ManifestPatterns: map[string]string{"npm": "^(.*/)?package\\.json$"}}
+	toolConfig.InitializePatterns()
+	repository := `"repository": {"name": "service", "default_branch": "main", "owner": {"login": "acme"}}`
+	tests := []struct {
+		event    string
+		payload  string
+		expected *WebhookRepo
+	}{
+		{"repository", `{"action": "created", ` + repository + `}`, &WebhookRepo{Org: "acme", Repo: "service", Reason: "repository created"}},
+		{"repository", `{"action": "deleted", ` + repository + `}`, nil},
+		{
+			"push", `{"ref": "refs/heads/main", "commits": [{"modified": ["README.md"]}, {"added": ["app/package.json"]}], ` + repository + `}`,
+			&WebhookRepo{Org: "acme", Repo: "service", Reason: "push changing app/package.json"},
+		},
+		{"push", `{"ref": "refs/heads/main", "commits": [{"modified": ["README.md"]}], ` + repository + `}`, nil},
+		{"push", `{"ref": "refs/heads/feature", "commits": [{"added": ["package.json"]}], ` + repository + `}`, nil},
+		{"ping", `{"zen": "Keep it logically awesome."}`, nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseWebhook(webhookRequest(tt.event, tt.payload, "s3cret"), "s3cret")
+		if err != nil || !reflect.DeepEqual(tt.expected, got) {
+			t.Errorf("ParseWebhook() failed for %v %v; expected %+v, got %+v, error %v", tt.event, tt.payload, tt.expected, got, err)
+		}
+	}
+	if _, err := ParseWebhook(webhookRequest("repository", `{"action": "created", `+repository+`}`, "other"), "s3cret"); err == nil {
+		t.Errorf("ParseWebhook() failed; expected error for invalid signature")
+	}
+}
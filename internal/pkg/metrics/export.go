@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// Export formats, given by the -metrics parameter.
+const (
+	FormatPushgateway = "pushgateway"
+	FormatOTLP        = "otlp"
+)
+
+// pushTimeout is the timeout for pushing the metrics.
+const pushTimeout = 30 * time.Second
+
+// job is the job name of the metrics in the Pushgateway, and the service name in OpenTelemetry.
+const job = "dependabutler"
+
+// otlpAttribute holds an attribute of OTLP data points and resources, in the OTLP/HTTP JSON encoding.
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+// otlpDataPoint holds a data point of a sum, gauge or histogram. 64-bit integers are encoded as strings.
+type otlpDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          *float64        `json:"asDouble,omitempty"`
+	Count             string          `json:"count,omitempty"`
+	Sum               *float64        `json:"sum,omitempty"`
+	BucketCounts      []string        `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64       `json:"explicitBounds,omitempty"`
+}
+
+// otlpData holds the data points of a metric, with their aggregation.
+type otlpData struct {
+	AggregationTemporality int             `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool            `json:"isMonotonic,omitempty"`
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+}
+
+// otlpMetric holds a metric, as sum (counters), gauge or histogram.
+type otlpMetric struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Sum         *otlpData `json:"sum,omitempty"`
+	Gauge       *otlpData `json:"gauge,omitempty"`
+	Histogram   *otlpData `json:"histogram,omitempty"`
+}
+
+// otlpCumulative is the cumulative aggregation temporality: the values cover the whole run.
+const otlpCumulative = 2
+
+// OTLPJSON returns all metrics as OTLP/HTTP export request, in the JSON encoding.
+func OTLPJSON(now time.Time) ([]byte, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	startTime := strconv.FormatInt(started.UnixNano(), 10)
+	nowTime := strconv.FormatInt(now.UnixNano(), 10)
+	metrics := make([]otlpMetric, 0, len(registry))
+	for _, name := range util.SortedKeys(registry) {
+		m := registry[name]
+		data := &otlpData{DataPoints: make([]otlpDataPoint, 0, len(m.series))}
+		for _, key := range util.SortedKeys(m.series) {
+			s := m.series[key]
+			point := otlpDataPoint{Attributes: otlpAttributes(s.labels), StartTimeUnixNano: startTime, TimeUnixNano: nowTime}
+			value := s.value
+			switch m.kind {
+			case kindHistogram:
+				point.Count = strconv.FormatUint(s.count, 10)
+				point.Sum = &value
+				point.ExplicitBounds = m.buckets
+				for _, count := range s.counts {
+					point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(count, 10))
+				}
+			case kindGauge:
+				point.StartTimeUnixNano = ""
+				point.AsDouble = &value
+			default:
+				point.AsDouble = &value
+			}
+			data.DataPoints = append(data.DataPoints, point)
+		}
+		converted := otlpMetric{Name: m.name, Description: m.help}
+		switch m.kind {
+		case kindHistogram:
+			data.AggregationTemporality = otlpCumulative
+			converted.Histogram = data
+		case kindGauge:
+			converted.Gauge = data
+		default:
+			data.AggregationTemporality, data.IsMonotonic = otlpCumulative, true
+			converted.Sum = data
+		}
+		metrics = append(metrics, converted)
+	}
+	request := map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": otlpAttributes(Labels{"service.name": job})},
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   map[string]string{"name": job},
+				"metrics": metrics,
+			}},
+		}},
+	}
+	return json.Marshal(request)
+}
+
+// otlpAttributes returns labels as OTLP attributes, sorted by name.
+func otlpAttributes(labels Labels) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(labels))
+	for _, key := range util.SortedKeys(labels) {
+		attributes = append(attributes, otlpAttribute{Key: key, Value: map[string]string{"stringValue": labels[key]}})
+	}
+	return attributes
+}
+
+// Push sends all metrics to a Prometheus Pushgateway (format pushgateway), replacing the ones of the previous run,
+// or to an OpenTelemetry collector via OTLP/HTTP (format otlp).
+func Push(endpoint string, format string) error {
+	req, err := pushRequest(endpoint, format)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: pushTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		// the endpoint may hold credentials, and must not be logged
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
+
+// pushRequest returns the request pushing the metrics in a format. For OTLP, /v1/metrics is appended to the endpoint,
+// unless given.
+func pushRequest(endpoint string, format string) (*http.Request, error) {
+	switch format {
+	case FormatPushgateway:
+		target := strings.TrimSuffix(endpoint, "/") + "/metrics/job/" + job
+		req, err := http.NewRequest(http.MethodPut, target, strings.NewReader(PrometheusText()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+		return req, nil
+	case FormatOTLP:
+		target := endpoint
+		if !strings.HasSuffix(target, "/v1/metrics") {
+			target = strings.TrimSuffix(target, "/") + "/v1/metrics"
+		}
+		body, err := OTLPJSON(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}
+	return nil, fmt.Errorf("unknown format %v", format)
+}
@@ -0,0 +1,156 @@
+// Package metrics contains the counters, gauges and histograms of a run, and their export to a Prometheus
+// Pushgateway or an OpenTelemetry collector.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// Kinds of metrics.
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// Labels holds the label names and values of a series.
+type Labels map[string]string
+
+// metric holds the series of a metric, by their labels.
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	series  map[string]*series
+}
+
+// series holds the value of a counter or gauge, or the observations of a histogram, for a set of labels.
+type series struct {
+	labels Labels
+	value  float64
+	// counts holds the number of observations per bucket, not cumulated, the last one for +Inf.
+	counts []uint64
+	count  uint64
+}
+
+var (
+	registry = map[string]*metric{}
+	mutex    sync.Mutex
+	// started is the start of the run, for the start time of the OpenTelemetry data points.
+	started = time.Now()
+)
+
+// Add adds a value to a counter.
+func Add(name string, help string, labels Labels, value float64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	get(name, help, kindCounter, nil, labels).value += value
+}
+
+// Set sets the value of a gauge.
+func Set(name string, help string, labels Labels, value float64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	get(name, help, kindGauge, nil, labels).value = value
+}
+
+// Observe adds an observation to a histogram with the given bucket bounds, in ascending order.
+func Observe(name string, help string, buckets []float64, labels Labels, value float64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	s := get(name, help, kindHistogram, buckets, labels)
+	bucket := sort.SearchFloat64s(buckets, value)
+	s.counts[bucket]++
+	s.count++
+	s.value += value
+}
+
+// Reset removes all metrics, for tests.
+func Reset() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	registry = map[string]*metric{}
+	started = time.Now()
+}
+
+// get returns the series of a metric for the labels, created if needed.
+func get(name string, help string, kind string, buckets []float64, labels Labels) *series {
+	m, found := registry[name]
+	if !found {
+		m = &metric{name: name, help: help, kind: kind, buckets: buckets, series: map[string]*series{}}
+		registry[name] = m
+	}
+	key := labels.String()
+	s, found := m.series[key]
+	if !found {
+		s = &series{labels: labels}
+		if kind == kindHistogram {
+			s.counts = make([]uint64, len(m.buckets)+1)
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// String returns the labels in the Prometheus text format, sorted by name, e.g. {ecosystem="npm"}.
+func (labels Labels) String() string {
+	return labels.with("", "")
+}
+
+// with returns the labels in the Prometheus text format, with an additional label if the name isn't empty.
+func (labels Labels) with(name string, value string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for _, key := range util.SortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf("%v=%q", key, labels[key]))
+	}
+	if name != "" {
+		pairs = append(pairs, fmt.Sprintf("%v=%q", name, value))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// PrometheusText returns all metrics in the Prometheus text exposition format, sorted by name and labels.
+func PrometheusText() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	lines := make([]string, 0)
+	for _, name := range util.SortedKeys(registry) {
+		m := registry[name]
+		lines = append(lines, fmt.Sprintf("# HELP %v %v", m.name, m.help), fmt.Sprintf("# TYPE %v %v", m.name, m.kind))
+		for _, key := range util.SortedKeys(m.series) {
+			s := m.series[key]
+			if m.kind != kindHistogram {
+				lines = append(lines, fmt.Sprintf("%v%v %v", m.name, key, formatValue(s.value)))
+				continue
+			}
+			cumulated := uint64(0)
+			for i, bound := range append(append([]float64{}, m.buckets...), math.Inf(1)) {
+				cumulated += s.counts[i]
+				lines = append(lines, fmt.Sprintf("%v_bucket%v %v", m.name, s.labels.with("le", formatValue(bound)), cumulated))
+			}
+			lines = append(lines, fmt.Sprintf("%v_sum%v %v", m.name, key, formatValue(s.value)),
+				fmt.Sprintf("%v_count%v %v", m.name, key, s.count))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// formatValue returns a value in the Prometheus text format.
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
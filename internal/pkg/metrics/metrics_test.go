@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func record() {
+	Reset()
+	Add("repos_total", "Repos processed.", Labels{"status": "changed"}, 2)
+	Add("repos_total", "Repos processed.", Labels{"status": "error"}, 1)
+	Add("repos_total", "Repos processed.", Labels{"status": "changed"}, 1)
+	Set("run_seconds", "Duration of the run.", nil, 12.5)
+	for _, value := range []float64{0.5, 3, 100} {
+		Observe("repo_seconds", "Time per repo.", []float64{1, 10}, nil, value)
+	}
+}
+
+func TestPrometheusText(t *testing.T) {
+	record()
+	expected := `# HELP repo_seconds Time per repo.
+# TYPE repo_seconds histogram
+repo_seconds_bucket{le="1"} 1
+repo_seconds_bucket{le="10"} 2
+repo_seconds_bucket{le="+Inf"} 3
+repo_seconds_sum 103.5
+repo_seconds_count 3
+# HELP repos_total Repos processed.
+# TYPE repos_total counter
+repos_total{status="changed"} 3
+repos_total{status="error"} 1
+# HELP run_seconds Duration of the run.
+# TYPE run_seconds gauge
+run_seconds 12.5
+`
+	if got := PrometheusText(); got != expected {
+		t.Errorf("PrometheusText() failed; expected\n%v\ngot\n%v", expected, got)
+	}
+}
+
+func TestOTLPJSON(t *testing.T) {
+	record()
+	content, err := OTLPJSON(time.Unix(100, 0))
+	if err != nil {
+		t.Fatalf("OTLPJSON() failed; unexpected error %v", err)
+	}
+	var request struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []otlpMetric `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+	if err := json.Unmarshal(content, &request); err != nil {
+		t.Fatalf("OTLPJSON() failed; invalid JSON %v", err)
+	}
+	metrics := request.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 3 || metrics[0].Histogram == nil || metrics[1].Sum == nil || metrics[2].Gauge == nil {
+		t.Fatalf("OTLPJSON() failed; unexpected metrics %v", string(content))
+	}
+	histogram := metrics[0].Histogram.DataPoints[0]
+	if !reflect.DeepEqual(histogram.BucketCounts, []string{"1", "1", "1"}) || histogram.Count != "3" || *histogram.Sum != 103.5 {
+		t.Errorf("OTLPJSON() failed; unexpected histogram %v", histogram)
+	}
+	sum := metrics[1].Sum
+	if !sum.IsMonotonic || sum.AggregationTemporality != otlpCumulative || *sum.DataPoints[0].AsDouble != 3 ||
+		sum.DataPoints[0].Attributes[0].Value["stringValue"] != "changed" || sum.DataPoints[0].TimeUnixNano != "100000000000" {
+		t.Errorf("OTLPJSON() failed; unexpected sum %v", sum)
+	}
+}
+
+func TestPush(t *testing.T) {
+	record()
+	requests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = string(body)
+	}))
+	defer server.Close()
+
+	if err := Push(server.URL+"/", FormatPushgateway); err != nil {
+		t.Errorf("Push() failed; unexpected error %v", err)
+	}
+	if body := requests["PUT /metrics/job/dependabutler"]; body != PrometheusText() {
+		t.Errorf("Push() failed; expected the metrics in the text format, got %v", requests)
+	}
+	if err := Push(server.URL, FormatOTLP); err != nil {
+		t.Errorf("Push() failed; unexpected error %v", err)
+	}
+	if _, found := requests["POST /v1/metrics"]; !found {
+		t.Errorf("Push() failed; expected a request to /v1/metrics, got %v", requests)
+	}
+	if err := Push(server.URL, "statsd"); err == nil {
+		t.Errorf("Push() failed; expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GitHubActionsOutputs returns the step outputs of a run in GitHub Actions: if any repo changed,
+// the URL of the PR if there's a single one, and the URLs of all PRs, as JSON array.
+func (report *Report) GitHubActionsOutputs() map[string]string {
+	changed := report.CountByStatus(StatusChanged) > 0
+	prURLs := make([]string, 0)
+	for _, result := range report.Repos {
+		if result.PullRequestURL != "" {
+			prURLs = append(prURLs, result.PullRequestURL)
+		}
+	}
+	prURL := ""
+	if len(prURLs) == 1 {
+		prURL = prURLs[0]
+	}
+	prURLsJSON, _ := json.Marshal(prURLs)
+	return map[string]string{
+		"changed": fmt.Sprintf("%v", changed),
+		"pr-url":  prURL,
+		"pr-urls": string(prURLsJSON),
+	}
+}
+
+// WriteGitHubActions appends the Markdown report to the job summary file, and the outputs to the step output file,
+// as given by the GITHUB_STEP_SUMMARY and GITHUB_OUTPUT environment variables. Empty file names are skipped.
+func (report *Report) WriteGitHubActions(summaryFile string, outputFile string) error {
+	if summaryFile != "" {
+		if err := appendToFile(summaryFile, report.ToMarkdown()); err != nil {
+			return err
+		}
+	}
+	if outputFile != "" {
+		outputs := report.GitHubActionsOutputs()
+		names := make([]string, 0, len(outputs))
+		for name := range outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, 0, len(names))
+		for _, name := range names {
+			lines = append(lines, name+"="+outputs[name])
+		}
+		if err := appendToFile(outputFile, strings.Join(lines, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendToFile appends content to a file, as the files of GitHub Actions are shared by all steps of a job.
+func appendToFile(name string, content string) error {
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
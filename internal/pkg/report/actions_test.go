@@ -0,0 +1,37 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func TestWriteGitHubActions(t *testing.T) {
+	report := Report{}
+	result := NewRepoResult("repo-1", StatusChanged, config.ChangeInfo{NewUpdates: []config.UpdateInfo{{Type: "npm", Directory: "/"}}})
+	result.PullRequestURL = "https://github.com/acme/repo-1/pull/7"
+	report.Add(result)
+	report.Add(NewRepoResult("repo-2", StatusUnchanged, config.ChangeInfo{}))
+
+	dir := t.TempDir()
+	summaryFile := filepath.Join(dir, "summary.md")
+	outputFile := filepath.Join(dir, "output")
+	if err := os.WriteFile(outputFile, []byte("previous=step\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.WriteGitHubActions(summaryFile, outputFile); err != nil {
+		t.Fatalf("WriteGitHubActions() failed; unexpected error %v", err)
+	}
+	summary, _ := os.ReadFile(summaryFile)
+	if !strings.Contains(string(summary), "| repo-1 | changed | 0 | 1 |") {
+		t.Errorf("WriteGitHubActions() failed; unexpected summary\n%v", string(summary))
+	}
+	output, _ := os.ReadFile(outputFile)
+	expected := "previous=step\nchanged=true\npr-url=https://github.com/acme/repo-1/pull/7\npr-urls=[\"https://github.com/acme/repo-1/pull/7\"]\n"
+	if string(output) != expected {
+		t.Errorf("WriteGitHubActions() failed; expected output\n%v\ngot\n%v", expected, string(output))
+	}
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Adoption holds the outcomes of the PRs of dependabutler by owning team and repo, as tracked in the state file.
+type Adoption struct {
+	Teams []TeamAdoption `json:"teams"`
+}
+
+// Outcomes holds the number of PRs of dependabutler per outcome.
+type Outcomes struct {
+	Open       int `json:"open"`
+	Merged     int `json:"merged"`
+	Closed     int `json:"closed"`
+	Superseded int `json:"superseded"`
+}
+
+// TeamAdoption holds the outcomes of the PRs for the repos of a team.
+type TeamAdoption struct {
+	Team string `json:"team"`
+	Outcomes
+	Repos []RepoAdoption `json:"repos"`
+}
+
+// RepoAdoption holds the outcomes of the PRs for a repo.
+type RepoAdoption struct {
+	Repo string `json:"repo"`
+	Team string `json:"-"`
+	Outcomes
+}
+
+// Total returns the number of PRs created.
+func (outcomes Outcomes) Total() int {
+	return outcomes.Open + outcomes.Merged + outcomes.Closed + outcomes.Superseded
+}
+
+// add adds the outcomes of another repo or team.
+func (outcomes *Outcomes) add(other Outcomes) {
+	outcomes.Open += other.Open
+	outcomes.Merged += other.Merged
+	outcomes.Closed += other.Closed
+	outcomes.Superseded += other.Superseded
+}
+
+// NewAdoption groups the outcomes of the repos by the team owning them, the teams and repos sorted by name.
+// Repos without PRs are left out, repos without owning team are grouped as "unowned".
+func NewAdoption(repos []RepoAdoption) *Adoption {
+	teams := map[string]*TeamAdoption{}
+	for _, repo := range repos {
+		if repo.Total() == 0 {
+			continue
+		}
+		team := repo.Team
+		if team == "" {
+			team = unownedTeam
+		}
+		if teams[team] == nil {
+			teams[team] = &TeamAdoption{Team: team, Repos: []RepoAdoption{}}
+		}
+		teams[team].Repos = append(teams[team].Repos, repo)
+		teams[team].add(repo.Outcomes)
+	}
+	adoption := &Adoption{Teams: make([]TeamAdoption, 0, len(teams))}
+	for _, team := range teams {
+		sort.Slice(team.Repos, func(i, j int) bool { return team.Repos[i].Repo < team.Repos[j].Repo })
+		adoption.Teams = append(adoption.Teams, *team)
+	}
+	sort.Slice(adoption.Teams, func(i, j int) bool { return adoption.Teams[i].Team < adoption.Teams[j].Team })
+	return adoption
+}
+
+// markdownLines returns the adoption funnel as Markdown table, one row per team.
+func (adoption *Adoption) markdownLines() []string {
+	lines := []string{"## adoption", "", "| team | PRs created | open | merged | closed | superseded |", "| - | - | - | - | - | - |"}
+	for _, team := range adoption.Teams {
+		lines = append(lines, fmt.Sprintf("| %v | %v | %v | %v | %v | %v |", team.Team, team.Total(), team.Open, team.Merged,
+			team.Closed, team.Superseded))
+	}
+	return lines
+}
@@ -0,0 +1,32 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewAdoption(t *testing.T) {
+	adoption := NewAdoption([]RepoAdoption{
+		{Repo: "payments-api", Team: "payments", Outcomes: Outcomes{Merged: 2, Superseded: 1}},
+		{Repo: "checkout", Team: "payments", Outcomes: Outcomes{Open: 1}},
+		{Repo: "legacy", Outcomes: Outcomes{Closed: 1}},
+		{Repo: "untouched", Team: "platform"},
+	})
+	expected := &Adoption{Teams: []TeamAdoption{
+		{Team: "payments", Outcomes: Outcomes{Open: 1, Merged: 2, Superseded: 1}, Repos: []RepoAdoption{
+			{Repo: "checkout", Team: "payments", Outcomes: Outcomes{Open: 1}},
+			{Repo: "payments-api", Team: "payments", Outcomes: Outcomes{Merged: 2, Superseded: 1}},
+		}},
+		{Team: "unowned", Outcomes: Outcomes{Closed: 1}, Repos: []RepoAdoption{{Repo: "legacy", Outcomes: Outcomes{Closed: 1}}}},
+	}}
+	if !reflect.DeepEqual(adoption, expected) {
+		t.Errorf("NewAdoption() failed; expected %v got %v", expected, adoption)
+	}
+
+	report := Report{Adoption: adoption}
+	markdown := report.ToMarkdown()
+	if !strings.Contains(markdown, "| payments | 4 | 1 | 2 | 0 | 1 |\n| unowned | 1 | 0 | 0 | 1 | 0 |") {
+		t.Errorf("ToMarkdown() failed; expected the adoption per team, got\n%v", markdown)
+	}
+}
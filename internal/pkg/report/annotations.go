@@ -0,0 +1,128 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// configLines holds the line numbers of the entries of a dependabot.yml, for annotations.
+type configLines struct {
+	updatesKey    int
+	registriesKey int
+	// updates are keyed by package ecosystem and normalized directory, registries by name
+	updates    map[string]int
+	registries map[string]int
+}
+
+// Annotations returns the findings of a run as GitHub Actions workflow commands, which show up as annotations
+// on the config file, or on the manifest files for skipped manifests.
+func Annotations(configFile string, currentConfig []byte, changeInfo config.ChangeInfo) []string {
+	lines := findConfigLines(currentConfig)
+	commands := make([]string, 0)
+	add := func(level string, file string, line int, message string) {
+		properties := "file=" + escapeProperty(file)
+		if line > 0 {
+			properties += fmt.Sprintf(",line=%v", line)
+		}
+		commands = append(commands, fmt.Sprintf("::%v %v::%v", level, properties, escapeData(message)))
+	}
+	for _, update := range changeInfo.NewUpdates {
+		add("warning", configFile, lines.updatesKey, fmt.Sprintf("No %v update covers %v, add an update for directory %v.", update.Type, update.File, update.Directory))
+	}
+	for _, update := range changeInfo.RemovedUpdates {
+		add("warning", configFile, lines.update(update.Type, update.Directory), fmt.Sprintf("The %v update for directory %v is not needed anymore.", update.Type, update.Directory))
+	}
+	for _, fix := range changeInfo.FixedUpdates {
+		add("warning", configFile, lines.update(fix.Type, fix.Directory), fmt.Sprintf("The %v update for directory %v needs a fix: %v.", fix.Type, fix.Directory, fix.Change))
+	}
+	for _, registry := range changeInfo.NewRegistries {
+		add("warning", configFile, lines.registriesKey, fmt.Sprintf("Registry %v (%v) is missing.", registry.Name, registry.Type))
+	}
+	for _, registry := range changeInfo.RemovedRegistries {
+		add("warning", configFile, lines.registries[registry.Name], fmt.Sprintf("Registry %v is not referenced by any update.", registry.Name))
+	}
+	for _, refs := range [][]config.RegistryRefInfo{changeInfo.AddedRegistryRefs, changeInfo.MissingRegistryRefs} {
+		for _, ref := range refs {
+			add("warning", configFile, lines.update(ref.Type, ref.Directory), fmt.Sprintf("The %v update for directory %v doesn't reference registry %v.", ref.Type, ref.Directory, ref.Registry))
+		}
+	}
+	for _, warning := range changeInfo.Warnings {
+		add("warning", configFile, 0, warning)
+	}
+	for _, skipped := range changeInfo.SkippedManifests {
+		add("notice", skipped.File, 0, fmt.Sprintf("Manifest skipped (%v): %v.", skipped.Type, skipped.Reason))
+	}
+	return commands
+}
+
+// update returns the line of an update, or the line of the updates key if it's not found.
+func (lines configLines) update(manifestType string, directory string) int {
+	if line, found := lines.updates[manifestType+" "+config.NormalizeDirectory(directory)]; found {
+		return line
+	}
+	return lines.updatesKey
+}
+
+// findConfigLines returns the line numbers of the entries of a dependabot.yml.
+func findConfigLines(content []byte) configLines {
+	lines := configLines{updates: map[string]int{}, registries: map[string]int{}}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return lines
+	}
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "updates":
+			lines.updatesKey = key.Line
+			for _, update := range value.Content {
+				ecosystem, directories := updateKeys(update)
+				for _, directory := range directories {
+					if _, found := lines.updates[ecosystem+" "+directory]; !found {
+						lines.updates[ecosystem+" "+directory] = update.Line
+					}
+				}
+			}
+		case "registries":
+			lines.registriesKey = key.Line
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				lines.registries[value.Content[j].Value] = value.Content[j].Line
+			}
+		}
+	}
+	return lines
+}
+
+// updateKeys returns the package ecosystem and the normalized directories of an update node.
+func updateKeys(update *yaml.Node) (string, []string) {
+	ecosystem := ""
+	directories := make([]string, 0)
+	for i := 0; i+1 < len(update.Content); i += 2 {
+		key, value := update.Content[i], update.Content[i+1]
+		switch key.Value {
+		case "package-ecosystem":
+			ecosystem = value.Value
+		case "directory":
+			directories = append(directories, config.NormalizeDirectory(value.Value))
+		case "directories":
+			for _, directory := range value.Content {
+				directories = append(directories, config.NormalizeDirectory(directory.Value))
+			}
+		}
+	}
+	return ecosystem, directories
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
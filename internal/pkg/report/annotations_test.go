@@ -0,0 +1,49 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func TestAnnotations(t *testing.T) {
+	currentConfig := []byte(`version: 2
+registries:
+  npm-reg:
+    type: npm-registry
+    url: https://npm.foo.bar
+updates:
+  - package-ecosystem: npm
+    directory: "/"
+    schedule:
+      interval: daily
+  - package-ecosystem: pip
+    directory: "app/"
+`)
+	changeInfo := config.ChangeInfo{
+		NewUpdates:          []config.UpdateInfo{{Type: "gomod", Directory: "/tools", File: "tools/go.mod"}},
+		RemovedRegistries:   []config.RegistryInfo{{Type: "npm-registry", Name: "npm-reg"}},
+		MissingRegistryRefs: []config.RegistryRefInfo{{Registry: "pip-reg", Type: "pip", Directory: "/app"}},
+		FixedUpdates:        []config.FixInfo{{Type: "pip", Directory: "/app", Change: "directory app/ normalized"}},
+		Warnings:            []string{"50% done\nsecond line"},
+		SkippedManifests:    []config.SkippedInfo{{Type: "bazel", File: "WORKSPACE", Reason: "unsupported"}},
+	}
+	expected := []string{
+		"::warning file=.github/dependabot.yml,line=6::No gomod update covers tools/go.mod, add an update for directory /tools.",
+		"::warning file=.github/dependabot.yml,line=11::The pip update for directory /app needs a fix: directory app/ normalized.",
+		"::warning file=.github/dependabot.yml,line=3::Registry npm-reg is not referenced by any update.",
+		"::warning file=.github/dependabot.yml,line=11::The pip update for directory /app doesn't reference registry pip-reg.",
+		"::warning file=.github/dependabot.yml::50%25 done%0Asecond line",
+		"::notice file=WORKSPACE::Manifest skipped (bazel): unsupported.",
+	}
+	got := Annotations(".github/dependabot.yml", currentConfig, changeInfo)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Annotations() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+
+	got = Annotations(".github/dependabot.yml", []byte("version: 2"), changeInfo)
+	if got[0] != "::warning file=.github/dependabot.yml::No gomod update covers tools/go.mod, add an update for directory /tools." {
+		t.Errorf("Annotations() failed; expected annotation without line, got %v", got[0])
+	}
+}
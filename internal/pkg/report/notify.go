@@ -0,0 +1,102 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxNotifiedFailures is the number of failed repos listed in a notification, the report holds all of them.
+const maxNotifiedFailures = 10
+
+// notifyTimeout is the timeout for posting a notification.
+const notifyTimeout = 30 * time.Second
+
+// RunSummary holds the totals of a run, as posted to the notification endpoint.
+type RunSummary struct {
+	Title           string   `json:"title"`
+	Repos           int      `json:"repos"`
+	Changed         int      `json:"changed"`
+	Unchanged       int      `json:"unchanged"`
+	Skipped         int      `json:"skipped"`
+	Errors          int      `json:"errors"`
+	PullRequestURLs []string `json:"pull-request-urls"`
+	Failures        []string `json:"failures"`
+}
+
+// Summary returns the totals of the run, with the PRs created or updated, and the failed repos with their errors.
+func (report *Report) Summary(title string) RunSummary {
+	summary := RunSummary{
+		Title:           title,
+		Repos:           len(report.Repos),
+		Changed:         report.CountByStatus(StatusChanged),
+		Unchanged:       report.CountByStatus(StatusUnchanged),
+		Skipped:         report.CountByStatus(StatusSkipped),
+		Errors:          report.CountByStatus(StatusError),
+		PullRequestURLs: []string{},
+		Failures:        []string{},
+	}
+	for _, result := range report.Repos {
+		if result.PullRequestURL != "" {
+			summary.PullRequestURLs = append(summary.PullRequestURLs, result.PullRequestURL)
+		}
+		if result.Status == StatusError {
+			summary.Failures = append(summary.Failures, fmt.Sprintf("%v: %v", result.Repo, result.Message))
+		}
+	}
+	return summary
+}
+
+// Text returns the summary as message, in the Slack markup.
+func (summary RunSummary) Text() string {
+	lines := []string{
+		fmt.Sprintf("*%v*", summary.Title),
+		fmt.Sprintf("%v repos: %v changed, %v unchanged, %v skipped, %v errors. %v pull requests created or updated.",
+			summary.Repos, summary.Changed, summary.Unchanged, summary.Skipped, summary.Errors, len(summary.PullRequestURLs)),
+	}
+	if len(summary.Failures) > 0 {
+		lines = append(lines, "", "Failures:")
+		for i, failure := range summary.Failures {
+			if i == maxNotifiedFailures {
+				lines = append(lines, fmt.Sprintf("• and %v more, see the report", len(summary.Failures)-maxNotifiedFailures))
+				break
+			}
+			lines = append(lines, "• "+strings.ReplaceAll(failure, "\n", " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Notify posts the summary of the run to a Slack incoming webhook (format slack), or as JSON to an HTTP endpoint
+// (format json).
+func (report *Report) Notify(endpoint string, format string, title string) error {
+	summary := report.Summary(title)
+	var payload interface{} = summary
+	if format == "" || format == "slack" {
+		payload = map[string]string{"text": summary.Text()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the URL of webhooks is a secret, and must not be logged
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
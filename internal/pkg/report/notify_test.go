@@ -0,0 +1,60 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func TestNotify(t *testing.T) {
+	report := Report{}
+	result := NewRepoResult("repo-1", StatusChanged, config.ChangeInfo{NewUpdates: []config.UpdateInfo{{Type: "npm", Directory: "/"}}})
+	result.PullRequestURL = "https://github.com/acme/repo-1/pull/7"
+	report.Add(result)
+	report.Add(NewRepoResult("repo-2", StatusUnchanged, config.ChangeInfo{}))
+	report.Add(RepoResult{Repo: "repo-3", Status: StatusError, Message: "Not Found"})
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/failing" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	if err := report.Notify(server.URL+"/slack", "slack", "dependabutler run"); err != nil {
+		t.Fatalf("Notify() failed; unexpected error %v", err)
+	}
+	var message map[string]string
+	_ = json.Unmarshal([]byte(bodies[0]), &message)
+	expectedText := "*dependabutler run*\n3 repos: 1 changed, 1 unchanged, 0 skipped, 1 errors. 1 pull requests created or updated.\n\n" +
+		"Failures:\n• repo-3: Not Found"
+	if message["text"] != expectedText {
+		t.Errorf("Notify() failed; expected text\n%v\ngot\n%v", expectedText, message["text"])
+	}
+
+	if err := report.Notify(server.URL+"/json", "json", "dependabutler run"); err != nil {
+		t.Fatalf("Notify() failed; unexpected error %v", err)
+	}
+	var summary RunSummary
+	_ = json.Unmarshal([]byte(bodies[1]), &summary)
+	expected := RunSummary{
+		Title: "dependabutler run", Repos: 3, Changed: 1, Unchanged: 1, Errors: 1,
+		PullRequestURLs: []string{"https://github.com/acme/repo-1/pull/7"}, Failures: []string{"repo-3: Not Found"},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Notify() failed; expected summary %v got %v", expected, summary)
+	}
+
+	if err := report.Notify(server.URL+"/failing", "json", ""); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify() failed; expected an error for status 500, got %v", err)
+	}
+}
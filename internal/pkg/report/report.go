@@ -0,0 +1,352 @@
+// Package report contains functionality related to the summary report of a run
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// Status values of a processed repository.
+const (
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+	StatusSkipped   = "skipped"
+	StatusError     = "error"
+)
+
+// Report holds the results of all repositories processed in a run.
+type Report struct {
+	Repos      []RepoResult     `json:"repos"`
+	RateLimits *RateLimitWaits  `json:"rate-limits,omitempty"`
+	Prefetch   *PrefetchSummary `json:"prefetch,omitempty"`
+	Adoption   *Adoption        `json:"adoption,omitempty"`
+}
+
+// PrefetchSummary holds the breakdown of the repos found by the prefetch, before processing them.
+type PrefetchSummary struct {
+	Repos         int `json:"repos"`
+	Missing       int `json:"missing"`
+	Archived      int `json:"archived"`
+	Empty         int `json:"empty"`
+	WithConfig    int `json:"with-config"`
+	WithoutConfig int `json:"without-config"`
+	// SizeKB is the disk usage of the repos to be processed, as estimate of the work.
+	SizeKB int `json:"size-kb"`
+}
+
+// RateLimitWaits holds the wall time spent waiting for GitHub API rate limits, in seconds.
+type RateLimitWaits struct {
+	Waits            int `json:"waits"`
+	PrimarySeconds   int `json:"primary-seconds"`
+	SecondarySeconds int `json:"secondary-seconds"`
+	PRActionSeconds  int `json:"pr-action-seconds"`
+	// AdaptiveSeconds is the time throttled between repos after secondary rate limits.
+	AdaptiveSeconds int `json:"adaptive-seconds,omitempty"`
+}
+
+// RepoResult holds the result of processing a repository.
+type RepoResult struct {
+	Repo                    string                   `json:"repo"`
+	Status                  string                   `json:"status"`
+	Message                 string                   `json:"message,omitempty"`
+	NewRegistries           []config.RegistryInfo    `json:"new-registries"`
+	NewUpdates              []config.UpdateInfo      `json:"new-updates"`
+	Warnings                []string                 `json:"warnings,omitempty"`
+	RemovedRegistries       []config.RegistryInfo    `json:"removed-registries,omitempty"`
+	RemovedUpdates          []config.UpdateInfo      `json:"removed-updates,omitempty"`
+	AddedRegistryRefs       []config.RegistryRefInfo `json:"added-registry-references,omitempty"`
+	MissingRegistryRefs     []config.RegistryRefInfo `json:"missing-registry-references,omitempty"`
+	FixedUpdates            []config.FixInfo         `json:"fixed-updates,omitempty"`
+	SkippedManifests        []config.SkippedInfo     `json:"skipped-manifests,omitempty"`
+	UpdatedFiles            []string                 `json:"updated-files,omitempty"`
+	SecurityFeaturesEnabled []string                 `json:"security-features-enabled,omitempty"`
+	PausedRemovedUpdates    []config.UpdateInfo      `json:"paused-removed-updates,omitempty"`
+	PausedRemovedRegistries []config.RegistryInfo    `json:"paused-removed-registries,omitempty"`
+	Trace                   []config.TraceEntry      `json:"trace,omitempty"`
+	Submodules              []string                 `json:"submodules,omitempty"`
+	PullRequest             int                      `json:"pull-request,omitempty"`
+	PullRequestURL          string                   `json:"pull-request-url,omitempty"`
+	IssueURL                string                   `json:"issue-url,omitempty"`
+	ConfigDrift             *ConfigDrift             `json:"config-drift,omitempty"`
+	WhatIf                  *WhatIf                  `json:"what-if,omitempty"`
+}
+
+// ConfigDrift holds the manual changes of a config, made after the PR of dependabutler has been merged.
+type ConfigDrift struct {
+	MergedAt   *time.Time        `json:"merged-at,omitempty"`
+	DetectedAt *time.Time        `json:"detected-at,omitempty"`
+	ChangedAt  *time.Time        `json:"changed-at,omitempty"`
+	ChangedBy  string            `json:"changed-by,omitempty"`
+	Commit     string            `json:"commit,omitempty"`
+	Changes    config.ConfigDiff `json:"changes"`
+}
+
+// WhatIf holds the difference between the configs generated with the current and with the proposed tool config.
+type WhatIf struct {
+	CurrentChanges  int               `json:"current-changes"`
+	ProposedChanges int               `json:"proposed-changes"`
+	Changes         config.ConfigDiff `json:"changes"`
+	Diff            string            `json:"diff,omitempty"`
+}
+
+// NewRepoResult returns the result of a repository, using the changes applied to its config.
+func NewRepoResult(repo string, status string, changeInfo config.ChangeInfo) RepoResult {
+	result := RepoResult{
+		Repo:                    repo,
+		Status:                  status,
+		NewRegistries:           changeInfo.NewRegistries,
+		NewUpdates:              changeInfo.NewUpdates,
+		Warnings:                changeInfo.Warnings,
+		RemovedRegistries:       changeInfo.RemovedRegistries,
+		RemovedUpdates:          changeInfo.RemovedUpdates,
+		AddedRegistryRefs:       changeInfo.AddedRegistryRefs,
+		MissingRegistryRefs:     changeInfo.MissingRegistryRefs,
+		FixedUpdates:            changeInfo.FixedUpdates,
+		SkippedManifests:        changeInfo.SkippedManifests,
+		UpdatedFiles:            changeInfo.UpdatedFiles,
+		Trace:                   changeInfo.Trace,
+		PausedRemovedUpdates:    changeInfo.PausedRemovedUpdates,
+		PausedRemovedRegistries: changeInfo.PausedRemovedRegistries,
+	}
+	if result.NewRegistries == nil {
+		result.NewRegistries = []config.RegistryInfo{}
+	}
+	if result.NewUpdates == nil {
+		result.NewUpdates = []config.UpdateInfo{}
+	}
+	return result
+}
+
+// Add adds the result of a repository to the report.
+func (report *Report) Add(result RepoResult) {
+	report.Repos = append(report.Repos, result)
+}
+
+// CountByStatus returns the number of repositories with a status.
+func (report *Report) CountByStatus(status string) int {
+	count := 0
+	for _, result := range report.Repos {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ToJSON returns a JSON representation of the report.
+func (report *Report) ToJSON() ([]byte, error) {
+	if report.Repos == nil {
+		report.Repos = []RepoResult{}
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// ToMarkdown returns a Markdown representation of the report.
+func (report *Report) ToMarkdown() string {
+	lines := []string{"# dependabutler report", ""}
+	lines = append(lines, "| repo | status | registries added | updates added |")
+	lines = append(lines, "| - | - | - | - |")
+	for _, result := range report.Repos {
+		lines = append(lines, fmt.Sprintf("| %v | %v | %v | %v |", result.Repo, result.Status, len(result.NewRegistries), len(result.NewUpdates)))
+	}
+	if report.RateLimits != nil {
+		lines = append(lines, "", fmt.Sprintf("Waited %vs for rate limits (%v times): %vs primary, %vs secondary, %vs after PR actions.",
+			report.RateLimits.PrimarySeconds+report.RateLimits.SecondarySeconds+report.RateLimits.PRActionSeconds,
+			report.RateLimits.Waits, report.RateLimits.PrimarySeconds, report.RateLimits.SecondarySeconds, report.RateLimits.PRActionSeconds))
+		if report.RateLimits.AdaptiveSeconds > 0 {
+			lines = append(lines, fmt.Sprintf("Throttled %vs between repos after secondary rate limits.", report.RateLimits.AdaptiveSeconds))
+		}
+	}
+	if report.Prefetch != nil {
+		lines = append(lines, "", fmt.Sprintf("Prefetched %v repos: %v missing, %v archived, %v empty, %v with config and %v without config (%v KB).",
+			report.Prefetch.Repos, report.Prefetch.Missing, report.Prefetch.Archived, report.Prefetch.Empty,
+			report.Prefetch.WithConfig, report.Prefetch.WithoutConfig, report.Prefetch.SizeKB))
+	}
+	if report.Adoption != nil && len(report.Adoption.Teams) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, report.Adoption.markdownLines()...)
+	}
+	for _, result := range report.Repos {
+		if result.Status == StatusUnchanged && len(result.MissingRegistryRefs) == 0 && len(result.Warnings) == 0 &&
+			len(result.SkippedManifests) == 0 && result.ConfigDrift == nil && len(result.PausedRemovedUpdates) == 0 &&
+			len(result.PausedRemovedRegistries) == 0 && result.WhatIf == nil && len(result.SecurityFeaturesEnabled) == 0 {
+			continue
+		}
+		lines = append(lines, "", "## "+result.Repo, "")
+		if result.Message != "" {
+			lines = append(lines, result.Message, "")
+		}
+		if len(result.NewRegistries) > 0 {
+			lines = append(lines, "#### registries added", "| type | name |", "| - | - |")
+			for _, registry := range result.NewRegistries {
+				lines = append(lines, fmt.Sprintf("| %v | %v |", registry.Type, registry.Name))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.RemovedRegistries) > 0 {
+			lines = append(lines, "#### unused registries removed", "| type | name |", "| - | - |")
+			for _, registry := range result.RemovedRegistries {
+				lines = append(lines, fmt.Sprintf("| %v | %v |", registry.Type, registry.Name))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.NewUpdates) > 0 {
+			lines = append(lines, "#### updates added", "| type | directory | file |", "| - | - | - |")
+			for _, update := range result.NewUpdates {
+				lines = append(lines, fmt.Sprintf("| %v | %v | %v |", update.Type, update.Directory, update.File))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.RemovedUpdates) > 0 {
+			lines = append(lines, "#### updates removed", "| type | directory |", "| - | - |")
+			for _, update := range result.RemovedUpdates {
+				lines = append(lines, fmt.Sprintf("| %v | %v |", update.Type, update.Directory))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.FixedUpdates) > 0 {
+			lines = append(lines, "#### updates fixed", "| type | directory | change |", "| - | - | - |")
+			for _, fix := range result.FixedUpdates {
+				lines = append(lines, fmt.Sprintf("| %v | %v | %v |", fix.Type, fix.Directory, fix.Change))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.SecurityFeaturesEnabled) > 0 {
+			lines = append(lines, "#### security features enabled")
+			for _, feature := range result.SecurityFeaturesEnabled {
+				lines = append(lines, "* "+feature)
+			}
+			lines = append(lines, "")
+		}
+		if len(result.UpdatedFiles) > 0 {
+			lines = append(lines, "#### companion files updated")
+			for _, file := range result.UpdatedFiles {
+				lines = append(lines, "* "+file)
+			}
+			lines = append(lines, "")
+		}
+		for _, section := range []struct {
+			title string
+			refs  []config.RegistryRefInfo
+		}{
+			{"registry references added", result.AddedRegistryRefs},
+			{"registry references missing", result.MissingRegistryRefs},
+		} {
+			if len(section.refs) > 0 {
+				lines = append(lines, "#### "+section.title, "| type | directory | registry |", "| - | - | - |")
+				for _, ref := range section.refs {
+					lines = append(lines, fmt.Sprintf("| %v | %v | %v |", ref.Type, ref.Directory, ref.Registry))
+				}
+				lines = append(lines, "")
+			}
+		}
+		if len(result.PausedRemovedUpdates) > 0 || len(result.PausedRemovedRegistries) > 0 {
+			lines = append(lines, "#### removals paused, exceeding the removal limits", "| kind | type | name / directory |", "| - | - | - |")
+			for _, update := range result.PausedRemovedUpdates {
+				lines = append(lines, fmt.Sprintf("| update | %v | %v |", update.Type, update.Directory))
+			}
+			for _, registry := range result.PausedRemovedRegistries {
+				lines = append(lines, fmt.Sprintf("| registry | %v | %v |", registry.Type, registry.Name))
+			}
+			lines = append(lines, "")
+		}
+		if len(result.SkippedManifests) > 0 {
+			lines = append(lines, "#### manifests skipped", "| type | file | reason |", "| - | - | - |")
+			for _, skipped := range result.SkippedManifests {
+				lines = append(lines, fmt.Sprintf("| %v | %v | %v |", skipped.Type, skipped.File, skipped.Reason))
+			}
+			lines = append(lines, "")
+		}
+		if result.ConfigDrift != nil {
+			lines = append(lines, "#### config changed manually", result.ConfigDrift.summary(), "")
+			lines = append(lines, result.ConfigDrift.changeLines()...)
+		}
+		if result.WhatIf != nil {
+			lines = append(lines, "#### proposed tool config", fmt.Sprintf("* %v changes with the current tool config, %v with the proposed one",
+				result.WhatIf.CurrentChanges, result.WhatIf.ProposedChanges), "")
+			lines = append(lines, configDiffLines(result.WhatIf.Changes)...)
+			if result.WhatIf.Diff != "" {
+				lines = append(lines, "```diff", strings.TrimSuffix(result.WhatIf.Diff, "\n"), "```", "")
+			}
+		}
+		if len(result.Submodules) > 0 {
+			lines = append(lines, "#### submodule repos", "* "+strings.Join(result.Submodules, ", "), "")
+		}
+		if len(result.Warnings) > 0 {
+			lines = append(lines, "#### warnings")
+			for _, warning := range result.Warnings {
+				lines = append(lines, "* "+warning)
+			}
+			lines = append(lines, "")
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// summary returns who changed the config when, compared to the merge of dependabutler's PR.
+func (drift *ConfigDrift) summary() string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "unknown"
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	changedBy := drift.ChangedBy
+	if changedBy == "" {
+		changedBy = "unknown"
+	}
+	summary := fmt.Sprintf("* changed by %v at %v", changedBy, formatTime(drift.ChangedAt))
+	if drift.Commit != "" {
+		summary += fmt.Sprintf(" (commit %v)", drift.Commit)
+	}
+	return summary + fmt.Sprintf(", merged from dependabutler at %v, first detected at %v", formatTime(drift.MergedAt), formatTime(drift.DetectedAt))
+}
+
+// changeLines returns the registries and updates added and removed, as Markdown table.
+func (drift *ConfigDrift) changeLines() []string {
+	return configDiffLines(drift.Changes)
+}
+
+// configDiffLines returns the registries and updates added and removed of a config diff, as Markdown table.
+func configDiffLines(diff config.ConfigDiff) []string {
+	rows := make([]string, 0)
+	for _, registry := range diff.AddedRegistries {
+		rows = append(rows, fmt.Sprintf("| registry added | %v | %v |", registry.Type, registry.Name))
+	}
+	for _, registry := range diff.RemovedRegistries {
+		rows = append(rows, fmt.Sprintf("| registry removed | %v | %v |", registry.Type, registry.Name))
+	}
+	for _, update := range diff.AddedUpdates {
+		rows = append(rows, fmt.Sprintf("| update added | %v | %v |", update.Type, update.Directory))
+	}
+	for _, update := range diff.RemovedUpdates {
+		rows = append(rows, fmt.Sprintf("| update removed | %v | %v |", update.Type, update.Directory))
+	}
+	if len(rows) == 0 {
+		return rows
+	}
+	return append(append([]string{"| change | type | name / directory |", "| - | - | - |"}, rows...), "")
+}
+
+// Write saves the report to a file, in the given format (json or markdown).
+func (report *Report) Write(format string, file string) error {
+	var content []byte
+	switch format {
+	case "json":
+		jsonContent, err := report.ToJSON()
+		if err != nil {
+			return err
+		}
+		content = jsonContent
+	case "markdown":
+		content = []byte(report.ToMarkdown())
+	default:
+		return fmt.Errorf("unknown report format %v", format)
+	}
+	return util.SaveFile(file, content)
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func TestToJSON(t *testing.T) {
+	report := Report{}
+	report.Add(NewRepoResult("repo-1", StatusChanged, config.ChangeInfo{
+		NewRegistries: []config.RegistryInfo{{Type: "npm-registry", Name: "npm-reg"}},
+		NewUpdates:    []config.UpdateInfo{{Type: "npm", Directory: "/", File: "package.json"}},
+	}))
+	report.Add(NewRepoResult("repo-2", StatusUnchanged, config.ChangeInfo{}))
+	content, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() failed; error %v", err)
+	}
+	var got Report
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("ToJSON() failed; invalid JSON %v", err)
+	}
+	if !reflect.DeepEqual(report, got) {
+		t.Errorf("ToJSON() failed;\n  expected %v\n  got      %v", report, got)
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	report := Report{}
+	report.Add(NewRepoResult("repo-1", StatusChanged, config.ChangeInfo{
+		NewUpdates: []config.UpdateInfo{{Type: "npm", Directory: "/app", File: "app/package.json"}},
+		Warnings:   []string{"something to check"},
+	}))
+	report.Add(NewRepoResult("repo-2", StatusUnchanged, config.ChangeInfo{}))
+	report.RateLimits = &RateLimitWaits{Waits: 3, PrimarySeconds: 60, SecondarySeconds: 5, PRActionSeconds: 4, AdaptiveSeconds: 15}
+	report.Prefetch = &PrefetchSummary{Repos: 4, Archived: 1, WithConfig: 2, WithoutConfig: 1, SizeKB: 1200}
+	got := report.ToMarkdown()
+	for _, expected := range []string{
+		"| repo-1 | changed | 0 | 1 |",
+		"| repo-2 | unchanged | 0 | 0 |",
+		"## repo-1",
+		"| npm | /app | app/package.json |",
+		"* something to check",
+		"Waited 69s for rate limits (3 times): 60s primary, 5s secondary, 4s after PR actions.",
+		"Throttled 15s between repos after secondary rate limits.",
+		"Prefetched 4 repos: 0 missing, 1 archived, 0 empty, 2 with config and 1 without config (1200 KB).",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("ToMarkdown() failed; expected to contain %v, got\n%v", expected, got)
+		}
+	}
+	if strings.Contains(got, "## repo-2") {
+		t.Errorf("ToMarkdown() failed; unchanged repo listed in details")
+	}
+}
+
+func TestCountByStatus(t *testing.T) {
+	report := Report{}
+	report.Add(RepoResult{Repo: "repo-1", Status: StatusChanged})
+	report.Add(RepoResult{Repo: "repo-2", Status: StatusUnchanged})
+	report.Add(RepoResult{Repo: "repo-3", Status: StatusChanged})
+	if got := report.CountByStatus(StatusChanged); got != 2 {
+		t.Errorf("CountByStatus() failed; expected 2 got %v", got)
+	}
+	if got := report.CountByStatus(StatusError); got != 0 {
+		t.Errorf("CountByStatus() failed; expected 0 got %v", got)
+	}
+}
+
+func TestToMarkdownConfigDrift(t *testing.T) {
+	mergedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	changedAt := time.Date(2024, 3, 5, 12, 30, 0, 0, time.UTC)
+	report := Report{}
+	result := NewRepoResult("repo-1", StatusUnchanged, config.ChangeInfo{})
+	result.ConfigDrift = &ConfigDrift{
+		MergedAt: &mergedAt, DetectedAt: &changedAt, ChangedAt: &changedAt, ChangedBy: "jane", Commit: "abc123",
+		Changes: config.ConfigDiff{RemovedUpdates: []config.UpdateInfo{{Type: "npm", Directory: "/app"}}},
+	}
+	report.Add(result)
+	got := report.ToMarkdown()
+	for _, expected := range []string{
+		"## repo-1",
+		"#### config changed manually",
+		"* changed by jane at 2024-03-05T12:30:00Z (commit abc123), merged from dependabutler at 2024-03-01T10:00:00Z",
+		"| update removed | npm | /app |",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("ToMarkdown() failed; expected to contain %v, got\n%v", expected, got)
+		}
+	}
+}
+
+func TestToMarkdownWhatIf(t *testing.T) {
+	report := Report{}
+	result := NewRepoResult("repo-1", StatusChanged, config.ChangeInfo{})
+	result.WhatIf = &WhatIf{
+		CurrentChanges: 0, ProposedChanges: 1,
+		Changes: config.ConfigDiff{AddedUpdates: []config.UpdateInfo{{Type: "gomod", Directory: "/"}}},
+		Diff:    "--- current tool config\n+++ proposed tool config\n@@ -1,1 +1,3 @@\n updates:\n+  - package-ecosystem: gomod\n+    directory: /\n",
+	}
+	report.Add(result)
+	got := report.ToMarkdown()
+	for _, expected := range []string{
+		"#### proposed tool config",
+		"* 0 changes with the current tool config, 1 with the proposed one",
+		"| update added | gomod | / |",
+		"```diff\n--- current tool config\n",
+		"+    directory: /\n```",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("ToMarkdown() failed; expected to contain %v, got\n%v", expected, got)
+		}
+	}
+}
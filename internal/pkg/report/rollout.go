@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// unownedTeam is the team of the repos not owned by any team.
+const unownedTeam = "unowned"
+
+// RolloutPlan holds the pending changes of a run grouped by owning team, for coordinating the rollout.
+type RolloutPlan struct {
+	Teams []TeamRollout `json:"teams"`
+}
+
+// TeamRollout holds the pending changes of the repos of a team.
+type TeamRollout struct {
+	Team         string   `json:"team"`
+	Repos        []string `json:"repos"`
+	Ecosystems   []string `json:"ecosystems"`
+	PullRequests int      `json:"pull-requests"`
+	Changes      int      `json:"changes"`
+}
+
+// ChangeCount returns the number of changes of a repo.
+func (result RepoResult) ChangeCount() int {
+	return len(result.NewRegistries) + len(result.NewUpdates) + len(result.RemovedRegistries) + len(result.RemovedUpdates) +
+		len(result.AddedRegistryRefs) + len(result.FixedUpdates)
+}
+
+// ecosystems returns the package ecosystems affected by the changes of a repo.
+func (result RepoResult) ecosystems() []string {
+	types := make([]string, 0)
+	for _, update := range append(append([]config.UpdateInfo{}, result.NewUpdates...), result.RemovedUpdates...) {
+		types = append(types, update.Type)
+	}
+	for _, ref := range result.AddedRegistryRefs {
+		types = append(types, ref.Type)
+	}
+	for _, fix := range result.FixedUpdates {
+		types = append(types, fix.Type)
+	}
+	return types
+}
+
+// RolloutPlan groups the repos with pending changes by the team owning them, one PR per repo.
+// Repos without owning team are grouped as "unowned".
+func (report *Report) RolloutPlan(teamOf func(repo string) string) RolloutPlan {
+	teams := map[string]*TeamRollout{}
+	for _, result := range report.Repos {
+		if result.Status != StatusChanged {
+			continue
+		}
+		team := teamOf(result.Repo)
+		if team == "" {
+			team = unownedTeam
+		}
+		if teams[team] == nil {
+			teams[team] = &TeamRollout{Team: team, Repos: []string{}, Ecosystems: []string{}}
+		}
+		rollout := teams[team]
+		rollout.Repos = append(rollout.Repos, result.Repo)
+		rollout.PullRequests++
+		rollout.Changes += result.ChangeCount()
+		for _, ecosystem := range result.ecosystems() {
+			if !util.Contains(rollout.Ecosystems, ecosystem) {
+				rollout.Ecosystems = append(rollout.Ecosystems, ecosystem)
+			}
+		}
+	}
+	plan := RolloutPlan{Teams: make([]TeamRollout, 0, len(teams))}
+	for _, rollout := range teams {
+		sort.Strings(rollout.Ecosystems)
+		plan.Teams = append(plan.Teams, *rollout)
+	}
+	sort.Slice(plan.Teams, func(i, j int) bool { return plan.Teams[i].Team < plan.Teams[j].Team })
+	return plan
+}
+
+// Write saves the rollout plan to a file, as JSON.
+func (plan RolloutPlan) Write(file string) error {
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.SaveFile(file, content)
+}
@@ -0,0 +1,36 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/config"
+)
+
+func TestRolloutPlan(t *testing.T) {
+	report := Report{}
+	report.Add(NewRepoResult("payments-api", StatusChanged, config.ChangeInfo{
+		NewRegistries: []config.RegistryInfo{{Type: "npm-registry", Name: "npm-reg"}},
+		NewUpdates:    []config.UpdateInfo{{Type: "npm", Directory: "/"}, {Type: "docker", Directory: "/"}},
+	}))
+	report.Add(NewRepoResult("payments-web", StatusChanged, config.ChangeInfo{
+		FixedUpdates: []config.FixInfo{{Type: "npm", Directory: "/app", Change: "directory app/ normalized"}},
+	}))
+	report.Add(NewRepoResult("payments-old", StatusUnchanged, config.ChangeInfo{}))
+	report.Add(NewRepoResult("misc", StatusChanged, config.ChangeInfo{
+		RemovedUpdates: []config.UpdateInfo{{Type: "pip", Directory: "/gone"}},
+	}))
+	teamOf := func(repo string) string {
+		if repo == "misc" {
+			return ""
+		}
+		return "payments"
+	}
+	expected := RolloutPlan{Teams: []TeamRollout{
+		{Team: "payments", Repos: []string{"payments-api", "payments-web"}, Ecosystems: []string{"docker", "npm"}, PullRequests: 2, Changes: 4},
+		{Team: "unowned", Repos: []string{"misc"}, Ecosystems: []string{"pip"}, PullRequests: 1, Changes: 1},
+	}}
+	if got := report.RolloutPlan(teamOf); !reflect.DeepEqual(got, expected) {
+		t.Errorf("RolloutPlan() failed;\n  expected %v\n  got      %v", expected, got)
+	}
+}
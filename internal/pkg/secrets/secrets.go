@@ -0,0 +1,191 @@
+// Package secrets contains functionality for fetching credentials from secret stores
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Reference points to a secret value in a secret store, written as "<provider>://<path>[#<key>]".
+// The key selects a field, in case the secret is a JSON object.
+type Reference struct {
+	Provider string
+	Path     string
+	Key      string
+}
+
+// Provider fetches secret values from a secret store.
+type Provider interface {
+	GetSecret(ctx context.Context, ref Reference) (string, error)
+}
+
+// providers holds the available providers, by the name used in references.
+var providers = map[string]Provider{
+	"env":    envProvider{},
+	"file":   fileProvider{},
+	"vault":  vaultProvider{},
+	"aws-sm": commandProvider{command: awsSecretsManagerCommand},
+	"gcp-sm": commandProvider{command: gcpSecretManagerCommand},
+}
+
+// runCommand runs a command and returns its output, replaceable in tests.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// ParseReference returns the reference contained in a value, and if it is one.
+func ParseReference(value string) (Reference, bool) {
+	provider, path, found := strings.Cut(value, "://")
+	if !found || providers[provider] == nil {
+		return Reference{}, false
+	}
+	path, key, _ := strings.Cut(path, "#")
+	return Reference{Provider: provider, Path: path, Key: key}, true
+}
+
+// Resolve returns the secret value a reference points to. Values which are no reference are returned as they are.
+func Resolve(value string) (string, error) {
+	ref, isReference := ParseReference(value)
+	if !isReference {
+		return value, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	secret, err := providers[ref.Provider].GetSecret(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("could not get secret %v://%v: %w", ref.Provider, ref.Path, err)
+	}
+	if ref.Key == "" {
+		return strings.TrimRight(secret, "\r\n"), nil
+	}
+	return jsonField(secret, ref.Key)
+}
+
+// jsonField returns a field of a JSON object.
+func jsonField(content string, key string) (string, error) {
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(content), &fields); err != nil {
+		return "", fmt.Errorf("secret is no JSON object, can't get key %v", key)
+	}
+	value, found := fields[key]
+	if !found {
+		return "", fmt.Errorf("key %v not found in secret", key)
+	}
+	if s, isString := value.(string); isString {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// envProvider reads secrets from environment variables, e.g. env://GITHUB_TOKEN.
+type envProvider struct{}
+
+func (envProvider) GetSecret(_ context.Context, ref Reference) (string, error) {
+	value, found := os.LookupEnv(ref.Path)
+	if !found {
+		return "", errors.New("environment variable not set")
+	}
+	return value, nil
+}
+
+// fileProvider reads secrets from files, e.g. file:///run/secrets/github-token.
+type fileProvider struct{}
+
+func (fileProvider) GetSecret(_ context.Context, ref Reference) (string, error) {
+	content, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// vaultProvider reads secrets from HashiCorp Vault, e.g. vault://secret/data/dependabutler#github-token.
+// The address and token are taken from VAULT_ADDR and VAULT_TOKEN, the namespace from VAULT_NAMESPACE.
+type vaultProvider struct{}
+
+func (vaultProvider) GetSecret(ctx context.Context, ref Reference) (string, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return "", errors.New("VAULT_ADDR not set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(ref.Path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with status %v", resp.Status)
+	}
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+	// KV version 2 nests the values in data.data
+	data := secret.Data
+	if nested, isV2 := secret.Data["data"]; isV2 && secret.Data["metadata"] != nil {
+		data = map[string]json.RawMessage{}
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", err
+		}
+	}
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// commandProvider reads secrets using the CLI of a secret store, which takes care of the authentication.
+type commandProvider struct {
+	command func(ref Reference) []string
+}
+
+func (provider commandProvider) GetSecret(ctx context.Context, ref Reference) (string, error) {
+	command := provider.command(ref)
+	output, err := runCommand(ctx, command[0], command[1:]...)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%v: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// awsSecretsManagerCommand reads a secret from AWS Secrets Manager, e.g. aws-sm://dependabutler/github#token.
+func awsSecretsManagerCommand(ref Reference) []string {
+	return []string{"aws", "secretsmanager", "get-secret-value", "--secret-id", ref.Path, "--query", "SecretString", "--output", "text"}
+}
+
+// gcpSecretManagerCommand reads a secret from GCP Secret Manager, e.g. gcp-sm://projects/my-project/secrets/github-token.
+// Without a version in the path, the latest version is used.
+func gcpSecretManagerCommand(ref Reference) []string {
+	secret, version, _ := strings.Cut(ref.Path, "/versions/")
+	if version == "" {
+		version = "latest"
+	}
+	args := []string{"gcloud", "secrets", "versions", "access", version}
+	if project, name, found := strings.Cut(strings.TrimPrefix(secret, "projects/"), "/secrets/"); found {
+		return append(args, "--secret", name, "--project", project)
+	}
+	return append(args, "--secret", secret)
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	for _, tt := range []struct {
+		value       string
+		expected    Reference
+		isReference bool
+	}{
+		{"plain-value", Reference{}, false},
+		{"https://registry.foo.bar", Reference{}, false},
+		{"${{secrets.NPM_TOKEN}}", Reference{}, false},
+		{"env://GITHUB_TOKEN", Reference{Provider: "env", Path: "GITHUB_TOKEN"}, true},
+		{"vault://secret/data/dependabutler#token", Reference{Provider: "vault", Path: "secret/data/dependabutler", Key: "token"}, true},
+		{"file:///run/secrets/token", Reference{Provider: "file", Path: "/run/secrets/token"}, true},
+	} {
+		got, isReference := ParseReference(tt.value)
+		if got != tt.expected || isReference != tt.isReference {
+			t.Errorf("ParseReference(%v) failed; expected %v %t got %v %t", tt.value, tt.expected, tt.isReference, got, isReference)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("DEPENDABUTLER_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "secret.json")
+	if err := os.WriteFile(secretFile, []byte(`{"token": "from-file", "port": 8080}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/dependabutler":
+			_, _ = w.Write([]byte(`{"data": {"data": {"token": "from-vault-v2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/dependabutler":
+			_, _ = w.Write([]byte(`{"data": {"token": "from-vault-v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	var commands [][]string
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, append([]string{name}, args...))
+		return []byte(`{"token": "from-command"}`), nil
+	}
+
+	for _, tt := range []struct {
+		value       string
+		expected    string
+		expectedErr bool
+	}{
+		{"plain-value", "plain-value", false},
+		{"env://DEPENDABUTLER_TEST_SECRET", "from-env", false},
+		{"env://DEPENDABUTLER_TEST_UNSET", "", true},
+		{"file://" + secretFile + "#token", "from-file", false},
+		{"file://" + secretFile + "#port", "8080", false},
+		{"file://" + secretFile + "#unknown", "", true},
+		{"vault://secret/data/dependabutler#token", "from-vault-v2", false},
+		{"vault://kv/dependabutler#token", "from-vault-v1", false},
+		{"vault://kv/unknown#token", "", true},
+		{"aws-sm://dependabutler/github#token", "from-command", false},
+		{"gcp-sm://projects/my-project/secrets/github#token", "from-command", false},
+	} {
+		got, err := Resolve(tt.value)
+		if got != tt.expected || (err != nil) != tt.expectedErr {
+			t.Errorf("Resolve(%v) failed; expected %v (error %t) got %v (%v)", tt.value, tt.expected, tt.expectedErr, got, err)
+		}
+	}
+	expectedCommands := [][]string{
+		{"aws", "secretsmanager", "get-secret-value", "--secret-id", "dependabutler/github", "--query", "SecretString", "--output", "text"},
+		{"gcloud", "secrets", "versions", "access", "latest", "--secret", "github", "--project", "my-project"},
+	}
+	if !reflect.DeepEqual(commands, expectedCommands) {
+		t.Errorf("Resolve() failed; expected commands %v got %v", expectedCommands, commands)
+	}
+}
@@ -0,0 +1,166 @@
+// Package state contains functionality for keeping track of repositories between runs
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/getyourguide/dependabutler/internal/pkg/util"
+)
+
+// RepoState holds what is known about a repository from previous runs.
+type RepoState struct {
+	// PendingPR is the number of the open PR of dependabutler, until it's merged or closed.
+	PendingPR int `json:"pending-pr,omitempty"`
+	// Fingerprint and Content are the config merged with the last PR of dependabutler.
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	Content     string     `json:"content,omitempty"`
+	MergedAt    *time.Time `json:"merged-at,omitempty"`
+	// DriftDetectedAt is when the config was found diverged from the merged one first.
+	DriftDetectedAt *time.Time `json:"drift-detected-at,omitempty"`
+	// Failures holds the failures of the consecutive runs failing for the repo, the oldest first.
+	Failures []Failure `json:"failures,omitempty"`
+	// FailureIssue is the number of the issue opened for the failures, until the repo succeeds again.
+	FailureIssue int `json:"failure-issue,omitempty"`
+	// ProcessedCommit is the head commit of the default branch processed successfully last, and
+	// ToolConfigFingerprint the fingerprint of the tool config used. The repo is skipped until either changes.
+	ProcessedCommit       string `json:"processed-commit,omitempty"`
+	ToolConfigFingerprint string `json:"tool-config-fingerprint,omitempty"`
+	// Submodules are the submodule repos found when processing the repo last, queued when it's skipped.
+	Submodules []string `json:"submodules,omitempty"`
+	// PullRequests holds the PRs of dependabutler with their outcomes, the oldest first.
+	PullRequests []PullRequest `json:"pull-requests,omitempty"`
+}
+
+// Outcomes of the PRs of dependabutler.
+const (
+	OutcomeOpen       = "open"
+	OutcomeMerged     = "merged"
+	OutcomeClosed     = "closed"
+	OutcomeSuperseded = "superseded"
+)
+
+// PullRequest holds a PR of dependabutler, and its outcome.
+type PullRequest struct {
+	Number     int        `json:"number"`
+	CreatedAt  time.Time  `json:"created-at"`
+	Outcome    string     `json:"outcome"`
+	ResolvedAt *time.Time `json:"resolved-at,omitempty"`
+}
+
+// maxPullRequests is the number of PRs kept per repository.
+const maxPullRequests = 20
+
+// RecordPullRequest adds a PR created, unless known already. Open PRs before it are superseded, as dependabutler
+// closes them in favor of the new one.
+func (repoState *RepoState) RecordPullRequest(number int, at time.Time) {
+	for i := range repoState.PullRequests {
+		pr := &repoState.PullRequests[i]
+		if pr.Number == number {
+			return
+		}
+		if pr.Outcome == OutcomeOpen {
+			pr.Outcome, pr.ResolvedAt = OutcomeSuperseded, &at
+		}
+	}
+	repoState.PullRequests = append(repoState.PullRequests, PullRequest{Number: number, CreatedAt: at, Outcome: OutcomeOpen})
+	if len(repoState.PullRequests) > maxPullRequests {
+		repoState.PullRequests = repoState.PullRequests[len(repoState.PullRequests)-maxPullRequests:]
+	}
+}
+
+// ResolvePullRequest records the outcome of a PR, added if unknown, e.g. for PRs created before the outcomes were tracked.
+func (repoState *RepoState) ResolvePullRequest(number int, outcome string, createdAt time.Time, resolvedAt time.Time) {
+	for i := range repoState.PullRequests {
+		if pr := &repoState.PullRequests[i]; pr.Number == number {
+			pr.Outcome, pr.ResolvedAt = outcome, &resolvedAt
+			return
+		}
+	}
+	repoState.PullRequests = append(repoState.PullRequests, PullRequest{Number: number, CreatedAt: createdAt, Outcome: outcome, ResolvedAt: &resolvedAt})
+}
+
+// Outcomes returns the number of PRs per outcome.
+func (repoState RepoState) Outcomes() map[string]int {
+	outcomes := map[string]int{}
+	for _, pr := range repoState.PullRequests {
+		outcomes[pr.Outcome]++
+	}
+	return outcomes
+}
+
+// Unchanged returns if the repo has been processed successfully at the commit with the tool config already.
+func (repoState RepoState) Unchanged(commit string, toolConfigFingerprint string) bool {
+	return commit != "" && repoState.ProcessedCommit == commit && repoState.ToolConfigFingerprint == toolConfigFingerprint
+}
+
+// Failure holds a failed run for a repository.
+type Failure struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// maxFailures is the number of failures kept per repository.
+const maxFailures = 10
+
+// RecordFailure adds a failure, dropping the oldest ones beyond the ones kept.
+func (repoState *RepoState) RecordFailure(at time.Time, message string) {
+	repoState.Failures = append(repoState.Failures, Failure{At: at, Message: message})
+	if len(repoState.Failures) > maxFailures {
+		repoState.Failures = repoState.Failures[len(repoState.Failures)-maxFailures:]
+	}
+}
+
+// Store holds the state of all repositories, keyed by org/repo, saved as JSON file.
+type Store struct {
+	Repos map[string]RepoState `json:"repos"`
+	file  string
+}
+
+// Load reads the state from a file. A missing file results in an empty state.
+func Load(file string) (*Store, error) {
+	store := &Store{Repos: map[string]RepoState{}, file: file}
+	content, err := util.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, store); err != nil {
+		return nil, err
+	}
+	if store.Repos == nil {
+		store.Repos = map[string]RepoState{}
+	}
+	return store, nil
+}
+
+// Save writes the state back to its file.
+func (store *Store) Save() error {
+	content, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.SaveFile(store.file, content)
+}
+
+// Get returns the state of a repository.
+func (store *Store) Get(org string, repo string) RepoState {
+	return store.Repos[org+"/"+repo]
+}
+
+// Set replaces the state of a repository.
+func (store *Store) Set(org string, repo string, state RepoState) {
+	store.Repos[org+"/"+repo] = state
+}
+
+// Fingerprint returns the fingerprint of a file content.
+func Fingerprint(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadAndSave(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	store, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load() failed for missing file: %v", err)
+	}
+	if len(store.Repos) != 0 {
+		t.Errorf("Load() failed; expected empty state, got %v", store.Repos)
+	}
+	mergedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	expected := RepoState{Fingerprint: Fingerprint([]byte("version: 2\n")), Content: "version: 2\n", MergedAt: &mergedAt}
+	store.Set("acme", "repo-1", expected)
+	store.Set("acme", "repo-2", RepoState{PendingPR: 42})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Get("acme", "repo-1"); !reflect.DeepEqual(expected, got) {
+		t.Errorf("Get() failed;\n  expected %+v\n  got      %+v", expected, got)
+	}
+	if got := loaded.Get("acme", "repo-2").PendingPR; got != 42 {
+		t.Errorf("Get() failed; expected pending PR 42, got %v", got)
+	}
+	if got := loaded.Get("acme", "repo-3"); !reflect.DeepEqual(RepoState{}, got) {
+		t.Errorf("Get() failed; expected empty state for unknown repo, got %+v", got)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	if Fingerprint([]byte("a")) == Fingerprint([]byte("b")) {
+		t.Errorf("Fingerprint() failed; expected different fingerprints for different contents")
+	}
+	if Fingerprint([]byte("a")) != Fingerprint([]byte("a")) {
+		t.Errorf("Fingerprint() failed; expected equal fingerprints for equal contents")
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	repoState := RepoState{}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxFailures+2; i++ {
+		repoState.RecordFailure(start.AddDate(0, 0, i), fmt.Sprintf("error %v", i))
+	}
+	if len(repoState.Failures) != maxFailures {
+		t.Fatalf("RecordFailure() failed; expected %v failures, got %v", maxFailures, len(repoState.Failures))
+	}
+	if first := repoState.Failures[0]; first.Message != "error 2" || !first.At.Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("RecordFailure() failed; expected the oldest failures to be dropped, got %v", first)
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	repoState := RepoState{ProcessedCommit: "c1", ToolConfigFingerprint: "f1"}
+	for _, tt := range []struct {
+		commit      string
+		fingerprint string
+		expected    bool
+	}{
+		{"c1", "f1", true},
+		{"c2", "f1", false},
+		{"c1", "f2", false},
+		{"", "f1", false},
+	} {
+		if got := repoState.Unchanged(tt.commit, tt.fingerprint); got != tt.expected {
+			t.Errorf("Unchanged() failed for %v, %v; expected %v, got %v", tt.commit, tt.fingerprint, tt.expected, got)
+		}
+	}
+	if (RepoState{}).Unchanged("", "") {
+		t.Errorf("Unchanged() failed; expected repos never processed to be changed")
+	}
+}
+
+func TestPullRequestOutcomes(t *testing.T) {
+	repoState := RepoState{}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repoState.RecordPullRequest(4, day)
+	repoState.RecordPullRequest(4, day.AddDate(0, 0, 1))
+	repoState.RecordPullRequest(7, day.AddDate(0, 0, 2))
+	repoState.ResolvePullRequest(7, OutcomeMerged, day, day.AddDate(0, 0, 3))
+	// PR created before the outcomes were tracked
+	repoState.ResolvePullRequest(2, OutcomeClosed, day.AddDate(0, -1, 0), day.AddDate(0, 0, 4))
+	repoState.RecordPullRequest(9, day.AddDate(0, 0, 5))
+
+	expected := map[string]int{OutcomeSuperseded: 1, OutcomeMerged: 1, OutcomeClosed: 1, OutcomeOpen: 1}
+	if got := repoState.Outcomes(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Outcomes() failed; expected %v got %v", expected, got)
+	}
+	if first := repoState.PullRequests[0]; first.Number != 4 || !first.ResolvedAt.Equal(day.AddDate(0, 0, 2)) {
+		t.Errorf("RecordPullRequest() failed; expected PR 4 to be superseded by PR 7, got %v", first)
+	}
+	if merged := repoState.PullRequests[1]; !merged.CreatedAt.Equal(day.AddDate(0, 0, 2)) {
+		t.Errorf("ResolvePullRequest() failed; expected the creation of PR 7 to be kept, got %v", merged)
+	}
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// diffLine holds a line of a diff, with its kind: ' ' unchanged, '-' removed or '+' added.
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// UnifiedDiff returns the differences between two texts in the unified diff format, or "" if they are equal.
+func UnifiedDiff(before string, after string, beforeName string, afterName string) string {
+	if before == after {
+		return ""
+	}
+	lines := diffLines(splitLines(before), splitLines(after))
+	result := []string{"--- " + beforeName, "+++ " + afterName}
+	changes := make([]int, 0)
+	for i, line := range lines {
+		if line.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	// changes closer than twice the context share a hunk
+	for first := 0; first < len(changes); {
+		last := first
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContext+1 {
+			last++
+		}
+		start := max(changes[first]-diffContext, 0)
+		end := min(changes[last]+diffContext+1, len(lines))
+		result = append(result, hunkHeader(lines, start, end))
+		for _, line := range lines[start:end] {
+			result = append(result, string(line.kind)+line.text)
+		}
+		first = last + 1
+	}
+	return strings.Join(result, "\n") + "\n"
+}
+
+// hunkHeader returns the header of a hunk, with the line ranges in both texts.
+func hunkHeader(lines []diffLine, start int, end int) string {
+	beforeStart, afterStart := 1, 1
+	for _, line := range lines[:start] {
+		if line.kind != '+' {
+			beforeStart++
+		}
+		if line.kind != '-' {
+			afterStart++
+		}
+	}
+	beforeCount, afterCount := 0, 0
+	for _, line := range lines[start:end] {
+		if line.kind != '+' {
+			beforeCount++
+		}
+		if line.kind != '-' {
+			afterCount++
+		}
+	}
+	// an empty range starts at the line before
+	if beforeCount == 0 {
+		beforeStart--
+	}
+	if afterCount == 0 {
+		afterStart--
+	}
+	return fmt.Sprintf("@@ -%v,%v +%v,%v @@", beforeStart, beforeCount, afterStart, afterCount)
+}
+
+// splitLines splits a text into lines, ignoring a final line break.
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the lines of both texts as unchanged, removed and added lines, based on their longest common
+// subsequence. Configs are small, so the quadratic table is fine.
+func diffLines(before []string, after []string) []diffLine {
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	lines := make([]diffLine, 0, len(before)+len(after))
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, diffLine{' ', before[i]})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{'-', before[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', after[j]})
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package util
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "version: 2\nupdates:\n  - package-ecosystem: npm\n    directory: /\n    schedule:\n      interval: daily\n" +
+		"  - package-ecosystem: docker\n    directory: /\n    schedule:\n      interval: daily\n"
+	after := "version: 2\nupdates:\n  - package-ecosystem: npm\n    directory: /\n    schedule:\n      interval: weekly\n" +
+		"  - package-ecosystem: docker\n    directory: /\n    schedule:\n      interval: daily\n  - package-ecosystem: gomod\n    directory: /\n"
+	for _, tt := range []struct {
+		before   string
+		after    string
+		expected string
+	}{
+		{before, before, ""},
+		{before, after, `--- a
++++ b
+@@ -3,8 +3,10 @@
+   - package-ecosystem: npm
+     directory: /
+     schedule:
+-      interval: daily
++      interval: weekly
+   - package-ecosystem: docker
+     directory: /
+     schedule:
+       interval: daily
++  - package-ecosystem: gomod
++    directory: /
+`},
+		{"", "version: 2\n", "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+version: 2\n"},
+	} {
+		if got := UnifiedDiff(tt.before, tt.after, "a", "b"); got != tt.expected {
+			t.Errorf("UnifiedDiff() failed; expected\n%v\ngot\n%v", tt.expected, got)
+		}
+	}
+}
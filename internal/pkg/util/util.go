@@ -3,12 +3,17 @@ package util
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // GetEnvParameter returns the value of an environment variable
@@ -30,6 +35,16 @@ func Contains[T comparable](s []T, e T) bool {
 	return false
 }
 
+// SortedKeys returns the keys of a map, sorted
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // CompileRePattern compiles a string containing a regular expression
 func CompileRePattern(pattern string) *regexp.Regexp {
 	if pattern == "" {
@@ -43,6 +58,39 @@ func CompileRePattern(pattern string) *regexp.Regexp {
 	return re
 }
 
+// MatchPathPattern checks if a path matches a glob pattern.
+// "*" matches within one path segment, "**" matches across segments, and a trailing "/**" also matches the directory itself.
+func MatchPathPattern(pattern string, path string) bool {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case pattern[i:] == "/**":
+			sb.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			sb.WriteString("[^/]*")
+		case pattern[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		log.Printf("Could not compile path pattern: %v", pattern)
+		return false
+	}
+	return re.MatchString(path)
+}
+
 // ReadFile reads a file from the file system
 func ReadFile(name string) ([]byte, error) {
 	data, err := os.ReadFile(name)
@@ -52,15 +100,78 @@ func ReadFile(name string) ([]byte, error) {
 	return data, nil
 }
 
-// SaveFile saves a file to the file system
+// ErrFileTooLarge is returned for files exceeding the size limit.
+var ErrFileTooLarge = errors.New("file too large")
+
+// ErrBinaryFile is returned for files with binary content.
+var ErrBinaryFile = errors.New("binary file")
+
+// ReadTextFile reads a text file from the file system, at most limit bytes of it.
+// Larger files fail with ErrFileTooLarge, without being read completely, binary files with ErrBinaryFile.
+func ReadTextFile(name string, limit int64) ([]byte, error) {
+	file, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	return data, CheckTextContent(data, limit)
+}
+
+// CheckTextContent returns ErrFileTooLarge if the content exceeds the size limit, ErrBinaryFile if it's binary.
+// Like git, content with a NUL byte in its first 8000 bytes is considered binary.
+func CheckTextContent(data []byte, limit int64) error {
+	if int64(len(data)) > limit {
+		return ErrFileTooLarge
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return ErrBinaryFile
+	}
+	return nil
+}
+
+// SaveFile saves a file to the file system, atomically. An existing file keeps its mode, new files get 0644.
 func SaveFile(name string, data []byte) error {
-	return os.WriteFile(name, data, 0o644)
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return SaveFileWithMode(name, data, mode)
+}
+
+// SaveFileWithMode saves a file to the file system with a mode. The data is written to a temporary file in the same
+// directory first, which is renamed then, so the file is never left truncated.
+func SaveFileWithMode(name string, data []byte, mode os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// removing fails after the rename, which is fine
+	defer func() { _ = os.Remove(temp.Name()) }()
+	if _, err := temp.Write(data); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), name)
 }
 
-// MakeDirIfNotExists creates a directory if it does not exist yet
+// MakeDirIfNotExists creates a directory, including its parents, if it does not exist yet
 func MakeDirIfNotExists(directory string) error {
 	if _, err := os.Stat(directory); errors.Is(err, os.ErrNotExist) {
-		return os.Mkdir(directory, os.ModePerm)
+		return os.MkdirAll(directory, os.ModePerm)
 	}
 	return nil
 }
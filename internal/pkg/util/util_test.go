@@ -1,7 +1,9 @@
 package util
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -46,3 +48,77 @@ func TestGetEnvParameter(t *testing.T) {
 	_ = os.Unsetenv("TEST_ENV_VAR_NAME_1337_NONEMPTY")
 	_ = os.Unsetenv("TEST_ENV_VAR_NAME_1337_EMPTY")
 }
+
+func TestMatchPathPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/frontend", "/frontend", true},
+		{"/frontend", "/frontend/app", false},
+		{"/frontend/*", "/frontend/app", true},
+		{"/frontend/*", "/frontend/app/sub", false},
+		{"/frontend/**", "/frontend", true},
+		{"/frontend/**", "/frontend/app/sub", true},
+		{"/frontend/**", "/frontends", false},
+		{"/**/web", "/web", true},
+		{"/**/web", "/apps/foo/web", true},
+		{"/apps/??", "/apps/ab", true},
+		{"/apps/??", "/apps/abc", false},
+		{"/a.b/*", "/axb/c", false},
+	} {
+		got := MatchPathPattern(tt.pattern, tt.path)
+		if got != tt.expected {
+			t.Errorf("MatchPathPattern(%v, %v) failed; expected %t, got %t", tt.pattern, tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "repo", ".github")
+	if err := MakeDirIfNotExists(directory); err != nil {
+		t.Fatalf("MakeDirIfNotExists() failed; unexpected error %v", err)
+	}
+	file := filepath.Join(directory, "dependabot.yml")
+	if err := SaveFileWithMode(file, []byte("version: 1\n"), 0o600); err != nil {
+		t.Fatalf("SaveFileWithMode() failed; unexpected error %v", err)
+	}
+	if err := SaveFile(file, []byte("version: 2\n")); err != nil {
+		t.Fatalf("SaveFile() failed; unexpected error %v", err)
+	}
+	content, err := ReadFile(file)
+	if err != nil || string(content) != "version: 2\n" {
+		t.Errorf("SaveFile() failed; expected the new content, got %q, error %v", content, err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("SaveFile() failed; expected the mode of the existing file to be kept, got %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(directory); len(entries) != 1 {
+		t.Errorf("SaveFile() failed; expected no temporary files left, got %v", entries)
+	}
+}
+
+func TestReadTextFile(t *testing.T) {
+	directory := t.TempDir()
+	for _, tt := range []struct {
+		content  string
+		expected error
+	}{
+		{"{\"name\": \"app\"}", nil},
+		{"0123456789abcdef!", ErrFileTooLarge},
+		{"PK\x03\x04\x00\x00", ErrBinaryFile},
+	} {
+		file := filepath.Join(directory, "package.json")
+		if err := SaveFile(file, []byte(tt.content)); err != nil {
+			t.Fatalf("SaveFile() failed; unexpected error %v", err)
+		}
+		content, err := ReadTextFile(file, 16)
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("ReadTextFile() failed for %q; expected error %v, got %v", tt.content, tt.expected, err)
+		}
+		if err == nil && string(content) != tt.content {
+			t.Errorf("ReadTextFile() failed; expected %q, got %q", tt.content, content)
+		}
+	}
+}